@@ -217,6 +217,37 @@ func (acc *DB) AccountKey(address string) (key []byte) {
 	return key
 }
 
+// NonceKey return the key of address nonce in DB
+func (acc *DB) NonceKey(address string) (key []byte) {
+	key = append(key, acc.accountKeyPerfix...)
+	key = append(key, []byte("nonce-")...)
+	key = append(key, []byte(address)...)
+	return key
+}
+
+// LoadNonce 获取账户下一笔交易的nonce，只在开启sequentialNonce时使用
+func (acc *DB) LoadNonce(addr string) int64 {
+	value, err := acc.db.Get(acc.NonceKey(addr))
+	if err != nil {
+		return 0
+	}
+	var nonce types.Int64
+	err = types.Decode(value, &nonce)
+	if err != nil {
+		panic(err) //数据库已经损坏
+	}
+	return nonce.Data
+}
+
+// GetNonceKVSet 返回把账户nonce设置为给定值需要写入的kv
+func (acc *DB) GetNonceKVSet(addr string, nonce int64) (kvset []*types.KeyValue) {
+	kvset = append(kvset, &types.KeyValue{
+		Key:   acc.NonceKey(addr),
+		Value: types.Encode(&types.Int64{Data: nonce}),
+	})
+	return kvset
+}
+
 func SymbolPrefix(execer string, symbol string) string {
 	return fmt.Sprintf("mavl-%s-%s-", execer, symbol)
 }
@@ -268,6 +299,11 @@ func (acc *DB) LoadAccountsHistory(api client.QueueProtocolAPI, addrs []string,
 }
 
 func (accountdb *DB) GetBalance(api client.QueueProtocolAPI, in *types.ReqBalance) ([]*types.Account, error) {
+	stateHash, err := balanceStateHash(api, in)
+	if err != nil {
+		log.Error("GetBalance", "err", err.Error())
+		return nil, err
+	}
 	switch in.GetExecer() {
 	case types.ExecName("coins"):
 		addrs := in.GetAddresses()
@@ -278,17 +314,7 @@ func (accountdb *DB) GetBalance(api client.QueueProtocolAPI, in *types.ReqBalanc
 			}
 			exaddrs = append(exaddrs, addr)
 		}
-		var accounts []*types.Account
-		var err error
-		if len(in.StateHash) == 0 {
-			accounts, err = accountdb.LoadAccounts(api, exaddrs)
-		} else {
-			hash, err := common.FromHex(in.StateHash)
-			if err != nil {
-				return nil, err
-			}
-			accounts, err = accountdb.LoadAccountsHistory(api, exaddrs, hash)
-		}
+		accounts, err := accountdb.LoadAccountsHistory(api, exaddrs, stateHash)
 		if err != nil {
 			log.Error("GetBalance", "err", err.Error())
 			return nil, err
@@ -299,19 +325,13 @@ func (accountdb *DB) GetBalance(api client.QueueProtocolAPI, in *types.ReqBalanc
 		addrs := in.GetAddresses()
 		var accounts []*types.Account
 		for _, addr := range addrs {
-			var acc *types.Account
-			var err error
-			if len(in.StateHash) == 0 {
-				acc, err = accountdb.LoadExecAccountQueue(api, addr, execaddress)
-			} else {
-				hash, err := common.FromHex(in.StateHash)
-				if err != nil {
-					return nil, err
-				}
-				acc, err = accountdb.LoadExecAccountHistoryQueue(api, addr, execaddress, hash)
-			}
+			acc, err := accountdb.LoadExecAccountHistoryQueue(api, addr, execaddress, stateHash)
 			if err != nil {
 				log.Error("GetBalance", "err", err.Error())
+				//状态已经被裁剪时，其他地址也查询不到
+				if err == types.ErrStateNotExist {
+					return nil, err
+				}
 				continue
 			}
 			accounts = append(accounts, acc)
@@ -319,3 +339,39 @@ func (accountdb *DB) GetBalance(api client.QueueProtocolAPI, in *types.ReqBalanc
 		return accounts, nil
 	}
 }
+
+//balanceStateHash 获取查询余额的状态：优先使用stateHash，其次是height，默认是最新的状态
+func balanceStateHash(api client.QueueProtocolAPI, in *types.ReqBalance) ([]byte, error) {
+	if len(in.GetStateHash()) != 0 {
+		return common.FromHex(in.GetStateHash())
+	}
+	if in.GetHeight() > 0 {
+		return GetStateHashByHeight(api, in.GetHeight())
+	}
+	header, err := api.GetLastHeader()
+	if err != nil {
+		return nil, err
+	}
+	return header.GetStateHash(), nil
+}
+
+// GetStateHashByHeight 获取区块高度对应的状态hash
+func GetStateHashByHeight(api client.QueueProtocolAPI, height int64) ([]byte, error) {
+	header, err := GetHeaderByHeight(api, height)
+	if err != nil {
+		return nil, err
+	}
+	return header.GetStateHash(), nil
+}
+
+// GetHeaderByHeight 获取区块高度对应的区块头
+func GetHeaderByHeight(api client.QueueProtocolAPI, height int64) (*types.Header, error) {
+	headers, err := api.GetHeaders(&types.ReqBlocks{Start: height, End: height})
+	if err != nil {
+		return nil, err
+	}
+	if len(headers.GetItems()) == 0 || headers.Items[0] == nil {
+		return nil, types.ErrHeightNotExist
+	}
+	return headers.Items[0], nil
+}
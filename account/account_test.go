@@ -139,7 +139,7 @@ func storeProcess(q queue.Queue) {
 		client.Sub("store")
 		for msg := range client.Recv() {
 			switch msg.Ty {
-			case types.EventStoreGet:
+			case types.EventStoreGet, types.EventStoreGetState:
 				datas := msg.GetData().(*types.StoreGet)
 				fmt.Println("EventStoreGet data = %v", datas)
 
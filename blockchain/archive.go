@@ -0,0 +1,175 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"sync/atomic"
+	"time"
+
+	dbm "github.com/33cn/chain33/common/db"
+	"github.com/33cn/chain33/types"
+)
+
+//区块归档：数据库中只保留最近的区块体和交易结果，更早的移到归档数据库
+//区块头、区块hash和高度的索引、交易的快速索引以及地址相关的交易索引一直保留在数据库中
+//读取区块体和交易结果时数据库中不存在就从归档数据库中读取
+
+var (
+	//数据库中下一个需要归档的区块高度
+	archiveHeightKey = []byte("ArchiveHeight")
+
+	archiveInterval       = 10 * time.Second
+	archiveBatchNum int64 = 1000 //每次最多归档的区块数
+)
+
+type archiveStore struct {
+	db     dbm.DB
+	retain int64
+	next   int64 //下一个需要归档的区块高度
+}
+
+//initArchive 打开归档数据库，retain为数据库中保留的最近区块数
+func (bs *BlockStore) initArchive(db dbm.DB, retain int64) {
+	next, err := bs.loadFlag(archiveHeightKey)
+	if err != nil {
+		panic(err)
+	}
+	bs.archive = &archiveStore{db: db, retain: retain, next: next}
+}
+
+//archivedHeight 已经归档的最高区块高度，没有归档时为-1
+func (bs *BlockStore) archivedHeight() int64 {
+	if bs.archive == nil {
+		return -1
+	}
+	return atomic.LoadInt64(&bs.archive.next) - 1
+}
+
+//getArchived 数据库中不存在的区块体和交易结果从归档数据库中读取
+func (bs *BlockStore) getArchived(key []byte) ([]byte, error) {
+	value, err := bs.db.Get(key)
+	if err == dbm.ErrNotFoundInDb && bs.archive != nil {
+		return bs.archive.db.Get(key)
+	}
+	return value, err
+}
+
+//archiveBlocks 归档[start, end]高度的区块，先写入归档数据库再从数据库中删除，中途退出时最多重复保存
+func (bs *BlockStore) archiveBlocks(start, end int64) error {
+	coldBatch := bs.archive.db.NewBatch(true)
+	hotBatch := bs.db.NewBatch(true)
+	for height := start; height <= end; height++ {
+		hash, err := bs.GetBlockHashByHeight(height)
+		if err != nil {
+			return err
+		}
+		bodyKey := calcHashToBlockBodyKey(hash)
+		body, err := bs.db.Get(bodyKey)
+		if err != nil {
+			return err
+		}
+		var blockbody types.BlockBody
+		err = types.Decode(body, &blockbody)
+		if err != nil {
+			return err
+		}
+		coldBatch.Set(bodyKey, body)
+		hotBatch.Delete(bodyKey)
+		for _, tx := range blockbody.Txs {
+			txKey := types.CalcTxKey(tx.Hash())
+			txresult, err := bs.db.Get(txKey)
+			if err != nil {
+				storeLog.Error("archiveBlocks tx not exist", "height", height, "err", err)
+				continue
+			}
+			coldBatch.Set(txKey, txresult)
+			hotBatch.Delete(txKey)
+		}
+	}
+	err := coldBatch.Write()
+	if err != nil {
+		return err
+	}
+	hotBatch.Set(archiveHeightKey, types.Encode(&types.Int64{Data: end + 1}))
+	err = hotBatch.Write()
+	if err != nil {
+		return err
+	}
+	atomic.StoreInt64(&bs.archive.next, end+1)
+	return nil
+}
+
+//delArchivedTxs 回滚已经归档的区块时，同时删除归档数据库中的交易结果
+func (bs *BlockStore) delArchivedTxs(blockdetail *types.BlockDetail) error {
+	if blockdetail.Block.Height > bs.archivedHeight() {
+		return nil
+	}
+	batch := bs.archive.db.NewBatch(true)
+	for _, tx := range blockdetail.Block.Txs {
+		batch.Delete(types.CalcTxKey(tx.Hash()))
+	}
+	return batch.Write()
+}
+
+//initArchive 开启归档时打开归档数据库并启动归档的线程
+func (chain *BlockChain) initArchive() {
+	if chain.cfg.ArchiveBlockNum <= 0 {
+		return
+	}
+	dbPath := chain.cfg.ArchiveDbPath
+	if dbPath == "" {
+		dbPath = chain.cfg.DbPath
+	}
+	db := dbm.NewDB("archive", chain.cfg.Driver, dbPath, chain.cfg.DbCache)
+	chain.blockStore.initArchive(db, chain.cfg.ArchiveBlockNum)
+	chain.tickerwg.Add(1)
+	go chain.archiveRoutine()
+}
+
+func (chain *BlockChain) archiveRoutine() {
+	defer chain.tickerwg.Done()
+	ticker := time.NewTicker(archiveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-chain.quit:
+			return
+		case <-ticker.C:
+			err := chain.archive()
+			if err != nil {
+				chainlog.Error("archiveRoutine", "err", err)
+			}
+		}
+	}
+}
+
+//archive 归档比最新高度低retain以上的区块，快速同步的节点从检查点开始归档
+//归档和区块的添加以及回滚互斥，归档的区块被回滚时仍然可以从归档数据库中读取
+func (chain *BlockChain) archive() error {
+	chain.chainLock.Lock()
+	defer chain.chainLock.Unlock()
+	if chain.isFastSyncing() {
+		return nil
+	}
+	bs := chain.blockStore
+	start := bs.archivedHeight() + 1
+	if base := chain.getFastSyncBase(); start < base {
+		start = base
+	}
+	end := bs.Height() - bs.archive.retain
+	if end-start+1 > archiveBatchNum {
+		end = start + archiveBatchNum - 1
+	}
+	if start > end {
+		return nil
+	}
+	beg := types.Now()
+	err := bs.archiveBlocks(start, end)
+	if err != nil {
+		return err
+	}
+	chainlog.Info("archive blocks", "start", start, "end", end, "cost", types.Since(beg))
+	return nil
+}
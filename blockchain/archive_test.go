@@ -0,0 +1,78 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	dbm "github.com/33cn/chain33/common/db"
+	mavl "github.com/33cn/chain33/system/store/mavl/db"
+	"github.com/33cn/chain33/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveBlocks(t *testing.T) {
+	mavl.EnableMavlPrefix(false)
+	mavl.EnableMVCC(false)
+	dir, err := ioutil.TempDir("", "archive")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	db, storeDB, blocks := genSnapshotDB(t, dir)
+	defer db.Close()
+	defer storeDB.Close()
+	for _, block := range blocks {
+		batch := db.NewBatch(true)
+		txresult := &types.TxResult{Height: block.Height, Tx: block.Txs[0], Receiptdate: &types.ReceiptData{}}
+		batch.Set(types.CalcTxKey(block.Txs[0].Hash()), types.Encode(txresult))
+		require.NoError(t, batch.Write())
+	}
+	archiveDB := dbm.NewDB("archive", "leveldb", dir, 100)
+	defer archiveDB.Close()
+
+	bs := &BlockStore{db: db}
+	bs.initArchive(archiveDB, 2)
+	assert.Equal(t, int64(-1), bs.archivedHeight())
+	require.NoError(t, bs.archiveBlocks(0, 2))
+	assert.Equal(t, int64(2), bs.archivedHeight())
+
+	for _, block := range blocks {
+		detail, err := bs.LoadBlockByHeight(block.Height)
+		require.NoError(t, err)
+		assert.Equal(t, block.Hash(), detail.Block.Hash())
+		txresult, err := bs.GetTx(block.Txs[0].Hash())
+		require.NoError(t, err)
+		assert.Equal(t, block.Height, txresult.Height)
+		has, err := bs.HasTx(block.Txs[0].Hash())
+		assert.Nil(t, err)
+		assert.True(t, has)
+
+		//归档的区块体和交易结果已经从数据库中删除
+		_, err = db.Get(calcHashToBlockBodyKey(block.Hash()))
+		_, txerr := db.Get(types.CalcTxKey(block.Txs[0].Hash()))
+		if block.Height <= 2 {
+			assert.Equal(t, dbm.ErrNotFoundInDb, err)
+			assert.Equal(t, dbm.ErrNotFoundInDb, txerr)
+		} else {
+			assert.Nil(t, err)
+			assert.Nil(t, txerr)
+		}
+	}
+
+	//重新打开时从下一个高度开始归档
+	bs2 := &BlockStore{db: db}
+	bs2.initArchive(archiveDB, 2)
+	assert.Equal(t, int64(2), bs2.archivedHeight())
+
+	//回滚已经归档的区块时删除归档的交易结果
+	require.NoError(t, bs.delArchivedTxs(&types.BlockDetail{Block: blocks[2]}))
+	_, err = bs.GetTx(blocks[2].Txs[0].Hash())
+	assert.NotNil(t, err)
+	require.NoError(t, bs.delArchivedTxs(&types.BlockDetail{Block: blocks[3]}))
+	_, err = bs.GetTx(blocks[3].Txs[0].Hash())
+	assert.Nil(t, err)
+}
@@ -91,6 +91,7 @@ type BlockStore struct {
 	client    queue.Client
 	height    int64
 	lastBlock *types.Block
+	archive   *archiveStore
 }
 
 func NewBlockStore(db dbm.DB, client queue.Client) *BlockStore {
@@ -262,7 +263,7 @@ func (bs *BlockStore) HasTx(key []byte) (bool, error) {
 		}
 		return true, nil
 	}
-	if _, err := bs.db.Get(types.CalcTxKey(key)); err != nil {
+	if _, err := bs.getArchived(types.CalcTxKey(key)); err != nil {
 		if err == dbm.ErrNotFoundInDb {
 			return false, nil
 		}
@@ -384,7 +385,7 @@ func (bs *BlockStore) LoadBlockByHash(hash []byte) (*types.BlockDetail, error) {
 		return nil, err
 	}
 	//通过hash获取blockbody
-	body, err := bs.db.Get(calcHashToBlockBodyKey(hash))
+	body, err := bs.getArchived(calcHashToBlockBodyKey(hash))
 	if body == nil || err != nil {
 		if err != dbm.ErrNotFoundInDb {
 			storeLog.Error("LoadBlockByHash calcHashToBlockBodyKey ", "err", err)
@@ -516,7 +517,7 @@ func (bs *BlockStore) GetTx(hash []byte) (*types.TxResult, error) {
 		err := errors.New("input hash is null")
 		return nil, err
 	}
-	rawBytes, err := bs.db.Get(types.CalcTxKey(hash))
+	rawBytes, err := bs.getArchived(types.CalcTxKey(hash))
 	if rawBytes == nil || err != nil {
 		if err != dbm.ErrNotFoundInDb {
 			storeLog.Error("GetTx", "hash", common.ToHex(hash), "err", err)
@@ -121,6 +121,9 @@ func (chain *BlockChain) SynRoutine() {
 	//2分钟尝试检测一次最优链，确保本节点在最优链
 	checkBestChainTicker := time.NewTicker(120 * time.Second)
 
+	//1分钟从manage合约更新一次最终确认的检查点
+	refreshCheckpointsTicker := time.NewTicker(60 * time.Second)
+
 	for {
 		select {
 		case <-chain.quit:
@@ -161,6 +164,10 @@ func (chain *BlockChain) SynRoutine() {
 		case <-checkBestChainTicker.C:
 			chain.tickerwg.Add(1)
 			go chain.CheckBestChain(false)
+
+		case <-refreshCheckpointsTicker.C:
+			chain.tickerwg.Add(1)
+			go chain.refreshCheckpoints()
 		}
 	}
 }
@@ -513,6 +520,7 @@ func (chain *BlockChain) UpdateFaultPeer(pid string, reqFlag bool) {
 func (chain *BlockChain) RecordFaultPeer(pid string, height int64, hash []byte, err error) {
 
 	var faultnode FaultPeerInfo
+	chain.reportPeer(pid, err)
 
 	//通过pid获取peerinfo
 	peerinfo := chain.GetPeerInfo(pid)
@@ -528,6 +536,29 @@ func (chain *BlockChain) RecordFaultPeer(pid string, height int64, hash []byte,
 	chain.AddFaultPeer(&faultnode)
 }
 
+//peerFaultErrs 区块本身校验不通过的错误，是发送区块的节点造成的
+//本地数据库和队列的错误，以及超过本地回滚深度限制的分叉都不是节点的错误
+var peerFaultErrs = map[error]bool{
+	types.ErrSign:               true,
+	types.ErrTxDup:              true,
+	types.ErrBlockExec:          true,
+	types.ErrCheckTxHash:        true,
+	types.ErrCheckStateHash:     true,
+	types.ErrCheckpointMismatch: true,
+	types.ErrReorgFinalized:     true,
+}
+
+//reportPeer 通知p2p模块对发送错误区块的节点扣分
+func (chain *BlockChain) reportPeer(pid string, err error) {
+	if chain.client == nil || pid == "" || pid == "self" || !peerFaultErrs[err] {
+		return
+	}
+	msg := chain.client.NewMessage("p2p", types.EventReportPeer, &types.ReportPeer{Pid: pid, Reason: err.Error()})
+	if sendErr := chain.client.Send(msg, false); sendErr != nil {
+		synlog.Error("reportPeer", "pid", pid, "err", sendErr)
+	}
+}
+
 func (chain *BlockChain) PrintFaultPeer() {
 	faultpeerlock.Lock()
 	defer faultpeerlock.Unlock()
@@ -553,6 +584,11 @@ func (chain *BlockChain) SynBlocksFromPeers() {
 	}
 	//synlog.Info("SynBlocksFromPeers", "isbatchsync", chain.isbatchsync)
 
+	//快速同步完成之后再同步检查点之后的区块
+	if chain.isFastSyncing() {
+		synlog.Info("SynBlocksFromPeers fast syncing")
+		return
+	}
 	//如果任务正常，那么不重复启动任务
 	if chain.task.InProgress() {
 		synlog.Info("chain task InProgress")
@@ -713,6 +749,13 @@ func (chain *BlockChain) ProcBlockHeaders(headers *types.Headers, pid string) er
 			synlog.Error("ProcBlockHeaders Not Roll Back!", "selfheight", tipheight, "RollBackedhieght", startheight)
 			return types.ErrNotRollBack
 		}
+		//分叉点在startheight之前，超过最大回滚深度或者会回滚最终确认的区块时不再继续寻找分叉点
+		err := chain.finality.checkReorg(tipheight, startheight, chain.blockStore.GetBlockHashByHeight)
+		if err != nil {
+			synlog.Error("ProcBlockHeaders reorg rejected", "selfheight", tipheight, "startheight", startheight, "pid", pid, "err", err)
+			chain.RecordFaultPeer(pid, startheight, headers.Items[0].Hash, err)
+			return err
+		}
 		//继续向后取指定数量的headers
 		height := headers.Items[0].Height
 		if height > BackBlockNum {
@@ -724,6 +767,14 @@ func (chain *BlockChain) ProcBlockHeaders(headers *types.Headers, pid string) er
 	}
 	synlog.Info("ProcBlockHeaders find fork point", "height", ForkHeight, "hash", common.ToHex(forkhash))
 
+	//回滚超过最大深度或者回滚最终确认的区块时，不从这个peer下载分叉的区块
+	err := chain.finality.checkReorg(tipheight, ForkHeight, chain.blockStore.GetBlockHashByHeight)
+	if err != nil {
+		synlog.Error("ProcBlockHeaders reorg rejected", "selfheight", tipheight, "forkheight", ForkHeight, "pid", pid, "err", err)
+		chain.RecordFaultPeer(pid, headers.Items[count-1].Height, headers.Items[count-1].Hash, err)
+		return err
+	}
+
 	//获取此pid对应的peer信息，
 	peerinfo := chain.GetPeerInfo(pid)
 	if peerinfo == nil {
@@ -814,6 +865,10 @@ func (chain *BlockChain) ProcAddBlockHeadersMsg(headers *types.Headers, pid stri
 	}
 	count := len(headers.Items)
 	synlog.Debug("ProcAddBlockHeadersMsg", "count", count, "pid", pid)
+	if chain.isFastSyncing() {
+		chain.fastSync.procHeaders(headers, pid)
+		return nil
+	}
 	if count == 1 {
 		return chain.ProcBlockHeader(headers, pid)
 	} else {
@@ -885,6 +940,10 @@ func (chain *BlockChain) CheckTipBlockHash() {
 
 //本节点是否已经追赶上主链高度，追赶上之后通知本节点的共识模块开始挖矿
 func (chain *BlockChain) IsCaughtUp() bool {
+	//快速同步的过程中没有追赶上主链
+	if chain.isFastSyncing() {
+		return false
+	}
 
 	height := chain.GetBlockHeight()
 
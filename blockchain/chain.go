@@ -97,11 +97,24 @@ type BlockChain struct {
 	//fork block req
 	forkInfo *ForkInfo
 	forklock sync.Mutex
+
+	//rpc模块开启websocket订阅之后，新增和回滚的区块同时推送给rpc
+	pushRpc int32
+
+	//配置了检查点的新节点从检查点开始快速同步
+	fastSync *fastSync
+
+	//回滚深度的限制和最终确认的检查点
+	finality *finality
 }
 
 func New(cfg *types.BlockChain) *BlockChain {
 	initConfig(cfg)
 	futureBlocks, _ := lru.New(maxFutureBlocks)
+	finality, err := newFinality(cfg)
+	if err != nil {
+		panic(err)
+	}
 
 	blockchain := &BlockChain{
 		cache:              NewBlockCache(DefCacheSize),
@@ -127,6 +140,7 @@ func New(cfg *types.BlockChain) *BlockChain {
 		bestChainPeerList:   make(map[string]*BestPeerInfo),
 		futureBlocks:        futureBlocks,
 		forkInfo:            &ForkInfo{},
+		finality:            finality,
 	}
 
 	return blockchain
@@ -176,6 +190,9 @@ func (chain *BlockChain) Close() {
 
 	//关闭数据库
 	chain.blockStore.db.Close()
+	if chain.blockStore.archive != nil {
+		chain.blockStore.archive.db.Close()
+	}
 	chainlog.Info("blockchain module closed")
 }
 
@@ -226,7 +243,12 @@ func (chain *BlockChain) InitBlockChain() {
 		chain.blockStore.SetDbVersion(curdbver)
 	}
 	types.S("dbversion", curdbver)
+	//开启归档时定时将较早的区块体和交易结果移到归档数据库
+	chain.initArchive()
 	if !chain.cfg.IsParaChain {
+		//快速同步需要在同步区块之前启动
+		chain.initFastSync()
+
 		// 定时检测/同步block
 		go chain.SynRoutine()
 
@@ -274,6 +296,7 @@ func (chain *BlockChain) SendAddBlockEvent(block *types.BlockDetail) (err error)
 	msg = chain.client.NewMessage("wallet", types.EventAddBlock, block)
 	chain.client.Send(msg, false)
 
+	chain.pushToRpc(types.EventAddBlock, block)
 	return nil
 }
 
@@ -341,9 +364,22 @@ func (chain *BlockChain) SendDelBlockEvent(block *types.BlockDetail) (err error)
 	msg = chain.client.NewMessage("wallet", types.EventDelBlock, block)
 	chain.client.Send(msg, false)
 
+	chain.pushToRpc(types.EventDelBlock, block)
 	return nil
 }
 
+//pushToRpc 把区块推送给rpc模块的websocket订阅，不等待rpc处理，队列满了就丢弃
+func (chain *BlockChain) pushToRpc(ty int64, block *types.BlockDetail) {
+	if atomic.LoadInt32(&chain.pushRpc) != 1 {
+		return
+	}
+	msg := chain.client.NewMessage("rpc", ty, block)
+	err := chain.client.SendTimeout(msg, false, 0)
+	if err != nil {
+		chainlog.Error("pushToRpc", "height", block.GetBlock().GetHeight(), "err", err)
+	}
+}
+
 func (chain *BlockChain) GetDB() dbm.DB {
 	return chain.blockStore.db
 }
@@ -352,9 +388,11 @@ func (chain *BlockChain) InitCache(height int64) {
 	if height < 0 {
 		return
 	}
+	//快速同步的检查点之前没有区块体
+	base := chain.getFastSyncBase()
 	for i := height - DefCacheSize; i <= height; i++ {
-		if i < 0 {
-			i = 0
+		if i < base {
+			i = base
 		}
 		blockdetail, err := chain.GetBlock(i)
 		if err != nil {
@@ -0,0 +1,568 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"bytes"
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	"github.com/33cn/chain33/common"
+	dbm "github.com/33cn/chain33/common/db"
+	"github.com/33cn/chain33/common/difficulty"
+	"github.com/33cn/chain33/common/merkle"
+	"github.com/33cn/chain33/types"
+)
+
+//快速同步：从配置的检查点开始同步区块
+//检查点之前的区块头从检查点向创世区块逐个校验hash，检查点的hash是可信的，所以下载的区块头都是可信的
+//然后从创世区块向检查点下载区块，交易需要和校验过的区块头一致，只保存交易索引用于交易查重，不执行区块
+//检查点的状态树节点从根节点开始逐层下载，每个节点都通过区块头中的StateHash校验
+//状态下载完成之后保存检查点区块作为最新区块，之后的区块按照正常的同步流程下载和执行
+//每个阶段的进度都保存在数据库中，重启之后从保存的进度继续
+//
+//限制：检查点之前的区块没有区块体和执行结果，交易索引中没有收据，也没有地址相关的索引
+//提供状态树节点的peer不能开启mvcc，检查点的高度不能低于ForkBlockHash，否则区块hash不包含StateHash
+
+const (
+	fastSyncHeaderNum     = 1000 //一次请求的区块头个数，p2p最多支持2000个
+	fastSyncStateNodeNum  = 1024 //一次请求的状态树节点个数
+	fastSyncTimeout       = 2 * time.Minute
+	fastSyncRetryInterval = 10 * time.Second
+)
+
+var (
+	//记录快速同步的检查点高度，这个高度之前的区块没有区块体
+	fastSyncBaseKey = []byte("FastSyncBase")
+	//快速同步的进度，检查点改变之后进度作废
+	fastSyncCheckpointKey = []byte("FastSyncCheckpoint")
+	fastSyncHeaderKey     = []byte("FastSyncHeader")    //已经校验的最低区块头高度
+	fastSyncBodyKey       = []byte("FastSyncBody")      //已经保存交易索引的最高区块高度
+	fastSyncStateKey      = []byte("FastSyncState")     //还没有下载的状态树节点
+	fastSyncStateDoneKey  = []byte("FastSyncStateDone") //状态树下载完成
+)
+
+type fastSync struct {
+	chain    *BlockChain
+	height   int64
+	hash     []byte
+	active   int32
+	headers  chan *types.HeadersPid
+	blocks   chan *types.BlockPid
+	badPeers map[string]bool
+}
+
+func newFastSync(chain *BlockChain, cfg *types.BlockChain) (*fastSync, error) {
+	if cfg.FastSyncCheckpointHeight <= 0 {
+		return nil, nil
+	}
+	hash, err := common.FromHex(cfg.FastSyncCheckpointHash)
+	if err != nil || len(hash) != len(zeroHash) {
+		return nil, types.ErrFastSyncCheckpoint
+	}
+	if !types.IsFork(cfg.FastSyncCheckpointHeight, "ForkBlockHash") {
+		return nil, types.ErrFastSyncCheckpoint
+	}
+	fs := &fastSync{
+		chain:    chain,
+		height:   cfg.FastSyncCheckpointHeight,
+		hash:     hash,
+		active:   1,
+		headers:  make(chan *types.HeadersPid, 16),
+		blocks:   make(chan *types.BlockPid, MaxFetchBlockNum),
+		badPeers: make(map[string]bool),
+	}
+	return fs, nil
+}
+
+//initFastSync 已经有区块的节点不需要快速同步
+func (chain *BlockChain) initFastSync() {
+	if chain.GetBlockHeight() > 0 {
+		return
+	}
+	fs, err := newFastSync(chain, chain.cfg)
+	if err != nil {
+		synlog.Error("initFastSync", "height", chain.cfg.FastSyncCheckpointHeight, "hash", chain.cfg.FastSyncCheckpointHash, "err", err)
+		return
+	}
+	if fs == nil {
+		return
+	}
+	chain.fastSync = fs
+	chain.tickerwg.Add(1)
+	go fs.run()
+}
+
+//isFastSyncing 快速同步的过程中不处理同步和广播的区块
+func (chain *BlockChain) isFastSyncing() bool {
+	return chain.fastSync != nil && atomic.LoadInt32(&chain.fastSync.active) == 1
+}
+
+//getFastSyncBase 获取快速同步的检查点高度，没有快速同步时返回0
+func (chain *BlockChain) getFastSyncBase() int64 {
+	height, err := chain.blockStore.loadFlag(fastSyncBaseKey)
+	if err != nil {
+		return 0
+	}
+	return height
+}
+
+//procHeaders 快速同步时请求的区块头交给快速同步处理，其他的区块头直接丢弃
+func (fs *fastSync) procHeaders(headers *types.Headers, pid string) {
+	select {
+	case fs.headers <- &types.HeadersPid{Pid: pid, Headers: headers}:
+	default:
+		synlog.Debug("fastSync drop headers", "pid", pid)
+	}
+}
+
+//procBlock 快速同步时只接收检查点之前的区块
+func (fs *fastSync) procBlock(block *types.Block, pid string) error {
+	if block.Height > fs.height {
+		return types.ErrFastSyncing
+	}
+	select {
+	case fs.blocks <- &types.BlockPid{Pid: pid, Block: block}:
+	default:
+		synlog.Debug("fastSync drop block", "pid", pid, "height", block.Height)
+	}
+	return nil
+}
+
+func (fs *fastSync) run() {
+	defer fs.chain.tickerwg.Done()
+	defer atomic.StoreInt32(&fs.active, 0)
+	for {
+		height := fs.chain.GetBlockHeight()
+		if height > 0 {
+			synlog.Info("fastSync stop", "height", height)
+			return
+		}
+		//等待共识模块创建创世区块
+		if height == 0 {
+			err := fs.sync()
+			if err == nil {
+				return
+			}
+			synlog.Error("fastSync", "err", err)
+		}
+		select {
+		case <-fs.chain.quit:
+			return
+		case <-time.After(fastSyncRetryInterval):
+		}
+	}
+}
+
+func (fs *fastSync) sync() error {
+	pid, err := fs.selectPeer()
+	if err != nil {
+		return err
+	}
+	synlog.Info("fastSync start", "pid", pid, "height", fs.height, "hash", common.ToHex(fs.hash))
+	err = fs.checkProgress()
+	if err != nil {
+		return err
+	}
+	checkpoint, err := fs.syncHeaders(pid)
+	if err != nil {
+		fs.badPeers[pid] = true
+		return err
+	}
+	err = fs.syncBodies(pid)
+	if err != nil {
+		fs.badPeers[pid] = true
+		return err
+	}
+	err = fs.syncState(pid, checkpoint.StateHash)
+	if err != nil {
+		fs.badPeers[pid] = true
+		return err
+	}
+	blocks, err := fs.fetchBlocks(pid, fs.height, fs.height)
+	if err != nil {
+		fs.badPeers[pid] = true
+		return err
+	}
+	td, err := fs.chain.blockStore.GetTdByBlockHash(checkpoint.Hash)
+	if err != nil {
+		return err
+	}
+	err = fs.saveCheckpoint(blocks[0], td)
+	if err != nil {
+		return err
+	}
+	synlog.Info("fastSync finish", "height", fs.height, "hash", common.ToHex(fs.hash))
+	return nil
+}
+
+//selectPeer 选择高度达到检查点的peer，所有的peer都失败过之后重新尝试
+func (fs *fastSync) selectPeer() (string, error) {
+	peers := fs.chain.GetPeers()
+	for i := 0; i < 2; i++ {
+		for _, peer := range peers {
+			if peer.Height >= fs.height && !fs.badPeers[peer.Name] && !fs.chain.IsFaultPeer(peer.Name) {
+				return peer.Name, nil
+			}
+		}
+		fs.badPeers = make(map[string]bool)
+	}
+	return "", types.ErrNoPeer
+}
+
+//checkProgress 检查点改变之后删除之前保存的进度
+func (fs *fastSync) checkProgress() error {
+	bs := fs.chain.blockStore
+	hash, err := bs.db.Get(fastSyncCheckpointKey)
+	if err == nil && bytes.Equal(hash, fs.hash) {
+		return nil
+	}
+	batch := bs.NewBatch(true)
+	batch.Set(fastSyncCheckpointKey, fs.hash)
+	batch.Delete(fastSyncHeaderKey)
+	batch.Delete(fastSyncBodyKey)
+	batch.Delete(fastSyncStateKey)
+	batch.Delete(fastSyncStateDoneKey)
+	return batch.Write()
+}
+
+//syncHeaders 从检查点向创世区块下载区块头，每个区块头的hash需要和后一个区块的ParentHash一致
+//校验通过的区块头保存到数据库中，返回检查点的区块头
+func (fs *fastSync) syncHeaders(pid string) (*types.Header, error) {
+	bs := fs.chain.blockStore
+	genesis, err := bs.GetBlockHeaderByHeight(0)
+	if err != nil {
+		return nil, err
+	}
+	end := fs.height
+	hash := fs.hash
+	low, err := bs.loadFlag(fastSyncHeaderKey)
+	if err != nil {
+		return nil, err
+	}
+	//从上次保存的最低区块头继续向创世区块下载
+	if low > 0 {
+		header, err := bs.GetBlockHeaderByHeight(low)
+		if err != nil {
+			return nil, err
+		}
+		end = low - 1
+		hash = header.ParentHash
+	}
+	for ; end > 0; end -= fastSyncHeaderNum {
+		start := end - fastSyncHeaderNum + 1
+		if start < 1 {
+			start = 1
+		}
+		headers, err := fs.fetchHeaders(pid, start, end)
+		if err != nil {
+			return nil, err
+		}
+		hash, err = checkHeaders(headers, start, end, hash)
+		if err != nil {
+			return nil, err
+		}
+		batch := bs.NewBatch(true)
+		for _, header := range headers {
+			saveHeader(batch, header)
+		}
+		batch.Set(fastSyncHeaderKey, types.Encode(&types.Int64{Data: start}))
+		err = batch.Write()
+		if err != nil {
+			return nil, err
+		}
+		synlog.Debug("fastSync headers", "start", start, "end", end)
+	}
+	if !bytes.Equal(hash, genesis.Hash) {
+		bs.db.Delete(fastSyncHeaderKey)
+		return nil, types.ErrFastSyncCheckpoint
+	}
+	return bs.GetBlockHeaderByHeight(fs.height)
+}
+
+func (fs *fastSync) fetchHeaders(pid string, start, end int64) ([]*types.Header, error) {
+	err := fs.chain.FetchBlockHeaders(start, end, pid)
+	if err != nil {
+		return nil, err
+	}
+	timeout := time.After(fastSyncTimeout)
+	for {
+		select {
+		case <-fs.chain.quit:
+			return nil, types.ErrIsClosed
+		case <-timeout:
+			return nil, types.ErrTimeout
+		case headers := <-fs.headers:
+			items := headers.GetHeaders().GetItems()
+			//丢弃之前超时的请求
+			if headers.Pid != pid || len(items) == 0 || items[0].GetHeight() != start {
+				continue
+			}
+			return items, nil
+		}
+	}
+}
+
+//checkHeaders 从后向前校验区块头，hash为end高度的区块hash，返回start高度区块的ParentHash
+func checkHeaders(headers []*types.Header, start, end int64, hash []byte) ([]byte, error) {
+	if int64(len(headers)) != end-start+1 {
+		return nil, types.ErrFastSyncHeaders
+	}
+	for i := len(headers) - 1; i >= 0; i-- {
+		header := headers[i]
+		if header == nil || header.Height != start+int64(i) {
+			return nil, types.ErrFastSyncHeaders
+		}
+		header.Hash = header.CalcHash()
+		if !bytes.Equal(header.Hash, hash) {
+			return nil, types.ErrFastSyncHeaders
+		}
+		hash = header.ParentHash
+	}
+	return hash, nil
+}
+
+//syncState 从状态树的根节点开始深度优先下载，限制待下载节点的个数
+func (fs *fastSync) syncState(pid string, root []byte) error {
+	if len(root) == 0 || bytes.Equal(root, zeroHash[:]) {
+		return nil
+	}
+	bs := fs.chain.blockStore
+	done, err := bs.loadFlag(fastSyncStateDoneKey)
+	if err != nil || done == 1 {
+		return err
+	}
+	stack := [][]byte{root}
+	data, err := bs.db.Get(fastSyncStateKey)
+	if err == nil {
+		var pending types.ReqHashes
+		err = types.Decode(data, &pending)
+		if err != nil {
+			return err
+		}
+		stack = pending.Hashes
+	}
+	var count int
+	for len(stack) > 0 {
+		num := len(stack)
+		if num > fastSyncStateNodeNum {
+			num = fastSyncStateNodeNum
+		}
+		hashes := make([][]byte, num)
+		copy(hashes, stack[len(stack)-num:])
+		stack = stack[:len(stack)-num]
+		children, err := fs.fetchStateNodes(pid, hashes)
+		if err != nil {
+			return err
+		}
+		stack = append(stack, children...)
+		count += num
+		//节点已经保存到store，记录还没有下载的节点
+		batch := bs.NewBatch(true)
+		if len(stack) > 0 {
+			batch.Set(fastSyncStateKey, types.Encode(&types.ReqHashes{Hashes: stack}))
+		} else {
+			batch.Delete(fastSyncStateKey)
+			batch.Set(fastSyncStateDoneKey, types.Encode(&types.Int64{Data: 1}))
+		}
+		err = batch.Write()
+		if err != nil {
+			return err
+		}
+		synlog.Debug("fastSync state", "nodes", count, "pending", len(stack))
+	}
+	synlog.Info("fastSync state finish", "nodes", count)
+	return nil
+}
+
+//fetchStateNodes 从peer获取节点，交给store校验并保存，返回子节点的hash
+func (fs *fastSync) fetchStateNodes(pid string, hashes [][]byte) ([][]byte, error) {
+	client := fs.chain.client
+	msg := client.NewMessage("p2p", types.EventFetchStateNodes, &types.ReqStateNodes{Pid: pid, Hashes: hashes})
+	err := client.Send(msg, true)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.WaitTimeout(msg, fastSyncTimeout)
+	if err != nil {
+		return nil, err
+	}
+	nodes := resp.GetData().(*types.StateNodes)
+	msg = client.NewMessage("store", types.EventStoreSaveStateNodes, &types.StateNodes{Hashes: hashes, Nodes: nodes.GetNodes()})
+	err = client.Send(msg, true)
+	if err != nil {
+		return nil, err
+	}
+	resp, err = client.WaitTimeout(msg, fastSyncTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return resp.GetData().(*types.StateNodes).GetHashes(), nil
+}
+
+//syncBodies 从创世区块向检查点下载区块，保存交易索引和总难度
+//检查点之前的交易在检查点之后不能重复打包，交易查重需要这些交易的索引
+func (fs *fastSync) syncBodies(pid string) error {
+	bs := fs.chain.blockStore
+	done, err := bs.loadFlag(fastSyncBodyKey)
+	if err != nil {
+		return err
+	}
+	parent, err := bs.GetBlockHeaderByHeight(done)
+	if err != nil {
+		return err
+	}
+	td, err := bs.GetTdByBlockHash(parent.Hash)
+	if err != nil {
+		return err
+	}
+	for start := done + 1; start <= fs.height; start += MaxFetchBlockNum {
+		end := start + MaxFetchBlockNum - 1
+		if end > fs.height {
+			end = fs.height
+		}
+		blocks, err := fs.fetchBlocks(pid, start, end)
+		if err != nil {
+			return err
+		}
+		batch := bs.NewBatch(true)
+		for _, block := range blocks {
+			saveTxIndex(batch, block)
+			td.Add(td, difficulty.CalcWork(block.Difficulty))
+			err = bs.SaveTdByBlockHash(batch, block.Hash(), td)
+			if err != nil {
+				return err
+			}
+		}
+		batch.Set(fastSyncBodyKey, types.Encode(&types.Int64{Data: end}))
+		err = batch.Write()
+		if err != nil {
+			return err
+		}
+		synlog.Debug("fastSync bodies", "start", start, "end", end)
+	}
+	return nil
+}
+
+//fetchBlocks 下载start到end的区块，区块的hash需要和已经校验的区块头一致，交易需要和区块头中的TxHash一致
+func (fs *fastSync) fetchBlocks(pid string, start, end int64) ([]*types.Block, error) {
+	client := fs.chain.client
+	msg := client.NewMessage("p2p", types.EventFetchBlocks, &types.ReqBlocks{Start: start, End: end, Pid: []string{pid}})
+	err := client.Send(msg, true)
+	if err != nil {
+		return nil, err
+	}
+	_, err = client.WaitTimeout(msg, fastSyncTimeout)
+	if err != nil {
+		return nil, err
+	}
+	blocks := make([]*types.Block, end-start+1)
+	count := 0
+	timeout := time.After(fastSyncTimeout)
+	for count < len(blocks) {
+		select {
+		case <-fs.chain.quit:
+			return nil, types.ErrIsClosed
+		case <-timeout:
+			return nil, types.ErrTimeout
+		case blockpid := <-fs.blocks:
+			block := blockpid.Block
+			//丢弃之前超时的请求和其他peer广播的区块
+			if block.Height < start || block.Height > end || blocks[block.Height-start] != nil {
+				continue
+			}
+			err = fs.checkBlock(block)
+			if err != nil {
+				if blockpid.Pid == pid {
+					return nil, err
+				}
+				continue
+			}
+			blocks[block.Height-start] = block
+			count++
+		}
+	}
+	return blocks, nil
+}
+
+func (fs *fastSync) checkBlock(block *types.Block) error {
+	header, err := fs.chain.blockStore.GetBlockHeaderByHeight(block.Height)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(block.Hash(), header.Hash) {
+		return types.ErrBlockHashNoMatch
+	}
+	if !bytes.Equal(merkle.CalcMerkleRoot(block.Txs), block.TxHash) {
+		return types.ErrCheckTxHash
+	}
+	return nil
+}
+
+//saveTxIndex 保存交易索引用于交易查重，区块没有执行，索引中没有收据
+func saveTxIndex(batch dbm.Batch, block *types.Block) {
+	for i, tx := range block.Txs {
+		hash := tx.Hash()
+		txresult := &types.TxResult{
+			Height:     block.Height,
+			Index:      int32(i),
+			Tx:         tx,
+			Blocktime:  block.BlockTime,
+			ActionName: tx.ActionName(),
+		}
+		batch.Set(types.CalcTxKey(hash), types.Encode(txresult))
+		if types.IsEnable("quickIndex") {
+			batch.Set(types.CalcTxShortKey(hash), []byte("1"))
+		}
+	}
+}
+
+//saveCheckpoint 检查点区块作为最新的区块，更新bestchain，之后的区块从这个区块开始执行
+func (fs *fastSync) saveCheckpoint(block *types.Block, td *big.Int) error {
+	chain := fs.chain
+	chain.chainLock.Lock()
+	defer chain.chainLock.Unlock()
+
+	detail := &types.BlockDetail{Block: block}
+	batch := chain.blockStore.NewBatch(true)
+	err := chain.blockStore.SaveBlock(batch, detail, -1)
+	if err != nil {
+		return err
+	}
+	err = chain.blockStore.SaveTdByBlockHash(batch, block.Hash(), td)
+	if err != nil {
+		return err
+	}
+	batch.Delete(fastSyncCheckpointKey)
+	batch.Delete(fastSyncHeaderKey)
+	batch.Delete(fastSyncBodyKey)
+	batch.Delete(fastSyncStateDoneKey)
+	batch.Set(fastSyncBaseKey, types.Encode(&types.Int64{Data: fs.height}))
+	err = batch.Write()
+	if err != nil {
+		return err
+	}
+	chain.blockStore.UpdateHeight2(block.Height)
+	chain.blockStore.UpdateLastBlock2(block)
+
+	//检查点之前的区块不会回滚，bestchain只保留检查点的区块
+	node := newBlockNode(false, block, "self", -1)
+	chain.index.AddNode(node)
+	chain.bestChain = newChainView(node)
+	chain.query.updateStateHash(block.StateHash)
+	chain.cache.cacheBlock(detail)
+	return nil
+}
+
+//saveHeader 只保存区块头，区块体在检查点之前不存在
+func saveHeader(batch dbm.Batch, header *types.Header) {
+	data := types.Encode(header)
+	heightbytes := types.Encode(&types.Int64{Data: header.Height})
+	batch.Set(calcHashToBlockHeaderKey(header.Hash), data)
+	batch.Set(calcHeightToBlockHeaderKey(header.Height), data)
+	batch.Set(calcHashToHeightKey(header.Hash), heightbytes)
+	batch.Set(calcHeightToHashKey(header.Height), header.Hash)
+}
@@ -0,0 +1,127 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"testing"
+
+	"github.com/33cn/chain33/common"
+	dbm "github.com/33cn/chain33/common/db"
+	"github.com/33cn/chain33/common/merkle"
+	"github.com/33cn/chain33/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func genTestHeaders(count int) []*types.Header {
+	var headers []*types.Header
+	parent := zeroHash[:]
+	for i := 0; i < count; i++ {
+		block := &types.Block{
+			ParentHash: parent,
+			TxHash:     common.Sha256([]byte{byte(i)}),
+			StateHash:  common.Sha256([]byte{byte(i), 1}),
+			Height:     int64(i),
+			BlockTime:  int64(i),
+			Difficulty: 0x1effffff,
+		}
+		header := block.GetHeader()
+		header.Hash = block.Hash()
+		header.StateHash = block.StateHash
+		header.Difficulty = block.Difficulty
+		headers = append(headers, header)
+		parent = header.Hash
+	}
+	return headers
+}
+
+func TestCheckHeaders(t *testing.T) {
+	headers := genTestHeaders(10)
+	for _, header := range headers {
+		assert.Equal(t, header.Hash, header.CalcHash())
+	}
+	//从检查点向前校验，返回起始区块的ParentHash
+	hash, err := checkHeaders(headers[5:], 5, 9, headers[9].Hash)
+	assert.Nil(t, err)
+	assert.Equal(t, headers[4].Hash, hash)
+	hash, err = checkHeaders(headers[1:5], 1, 4, hash)
+	assert.Nil(t, err)
+	assert.Equal(t, headers[0].Hash, hash)
+
+	_, err = checkHeaders(headers[5:], 5, 9, headers[8].Hash)
+	assert.Equal(t, types.ErrFastSyncHeaders, err)
+	_, err = checkHeaders(headers[5:9], 5, 9, headers[9].Hash)
+	assert.Equal(t, types.ErrFastSyncHeaders, err)
+	_, err = checkHeaders(headers[4:9], 5, 9, headers[8].Hash)
+	assert.Equal(t, types.ErrFastSyncHeaders, err)
+
+	//伪造的StateHash导致hash不一致
+	fake := *headers[9]
+	fake.StateHash = zeroHash[:]
+	_, err = checkHeaders([]*types.Header{&fake}, 9, 9, headers[9].Hash)
+	assert.Equal(t, types.ErrFastSyncHeaders, err)
+}
+
+func TestNewFastSync(t *testing.T) {
+	fs, err := newFastSync(nil, &types.BlockChain{})
+	assert.Nil(t, err)
+	assert.Nil(t, fs)
+	_, err = newFastSync(nil, &types.BlockChain{FastSyncCheckpointHeight: 10, FastSyncCheckpointHash: "0x1234"})
+	assert.Equal(t, types.ErrFastSyncCheckpoint, err)
+	fs, err = newFastSync(nil, &types.BlockChain{FastSyncCheckpointHeight: 10, FastSyncCheckpointHash: common.ToHex(zeroHash[:])})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(10), fs.height)
+	assert.Equal(t, int32(1), fs.active)
+}
+
+func TestFastSyncBodies(t *testing.T) {
+	bs := &BlockStore{db: dbm.NewDB("blockchain", "memdb", "", 100)}
+	fs := &fastSync{chain: &BlockChain{blockStore: bs}, height: 2}
+	var blocks []*types.Block
+	parent := zeroHash[:]
+	for i := int64(0); i <= fs.height; i++ {
+		txs := []*types.Transaction{{Execer: []byte("none"), Payload: []byte{byte(i)}}}
+		block := &types.Block{ParentHash: parent, TxHash: merkle.CalcMerkleRoot(txs), Height: i, Txs: txs}
+		header := block.GetHeader()
+		header.Hash = block.Hash()
+		batch := bs.NewBatch(true)
+		saveHeader(batch, header)
+		assert.Nil(t, batch.Write())
+		blocks = append(blocks, block)
+		parent = header.Hash
+	}
+	fs.hash = parent
+
+	//区块需要和保存的区块头一致
+	assert.Nil(t, fs.checkBlock(blocks[1]))
+	fake := *blocks[1]
+	fake.Txs = blocks[2].Txs
+	assert.Equal(t, types.ErrCheckTxHash, fs.checkBlock(&fake))
+	fake = *blocks[1]
+	fake.BlockTime = 100
+	assert.Equal(t, types.ErrBlockHashNoMatch, fs.checkBlock(&fake))
+
+	batch := bs.NewBatch(true)
+	saveTxIndex(batch, blocks[1])
+	assert.Nil(t, batch.Write())
+	has, err := bs.HasTx(blocks[1].Txs[0].Hash())
+	assert.Nil(t, err)
+	assert.True(t, has)
+	has, err = bs.HasTx(blocks[2].Txs[0].Hash())
+	assert.Nil(t, err)
+	assert.False(t, has)
+
+	//检查点不变时保留进度，改变之后删除进度
+	assert.Nil(t, fs.checkProgress())
+	assert.Nil(t, bs.db.Set(fastSyncBodyKey, types.Encode(&types.Int64{Data: 1})))
+	assert.Nil(t, fs.checkProgress())
+	done, err := bs.loadFlag(fastSyncBodyKey)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), done)
+	fs.hash = blocks[1].Hash()
+	assert.Nil(t, fs.checkProgress())
+	done, err = bs.loadFlag(fastSyncBodyKey)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), done)
+}
@@ -0,0 +1,140 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/33cn/chain33/common"
+	"github.com/33cn/chain33/types"
+)
+
+//manage合约中发布最终确认检查点的配置项，每个值的格式为height:hash
+const finalizedCheckpointsKey = "finalized-checkpoints"
+
+//finality 限制区块回滚的深度，最终确认的检查点及之前的区块不会被回滚
+//检查点来自配置文件和manage合约，配置文件中的检查点优先
+type finality struct {
+	maxReorgDepth int64
+	static        map[int64][]byte
+
+	mu          sync.RWMutex
+	checkpoints map[int64][]byte
+}
+
+func newFinality(cfg *types.BlockChain) (*finality, error) {
+	if cfg.MaxReorgDepth < 0 {
+		return nil, types.ErrFinalizedCheckpoint
+	}
+	static, err := parseCheckpoints(cfg.FinalizedCheckpoints)
+	if err != nil {
+		return nil, err
+	}
+	return &finality{maxReorgDepth: cfg.MaxReorgDepth, static: static, checkpoints: static}, nil
+}
+
+//parseCheckpoints 解析height:hash格式的检查点，同一高度不能有不同的hash
+func parseCheckpoints(items []string) (map[int64][]byte, error) {
+	checkpoints := make(map[int64][]byte)
+	for _, item := range items {
+		kv := strings.Split(item, ":")
+		if len(kv) != 2 {
+			return nil, types.ErrFinalizedCheckpoint
+		}
+		height, err := strconv.ParseInt(strings.TrimSpace(kv[0]), 10, 64)
+		if err != nil || height < 0 {
+			return nil, types.ErrFinalizedCheckpoint
+		}
+		hash, err := common.FromHex(strings.TrimSpace(kv[1]))
+		if err != nil || len(hash) != len(zeroHash) {
+			return nil, types.ErrFinalizedCheckpoint
+		}
+		if old, ok := checkpoints[height]; ok && !bytes.Equal(old, hash) {
+			return nil, types.ErrFinalizedCheckpoint
+		}
+		checkpoints[height] = hash
+	}
+	return checkpoints, nil
+}
+
+//setManageCheckpoints 更新manage合约中发布的检查点，和配置文件冲突的检查点被忽略
+func (f *finality) setManageCheckpoints(items []string) {
+	checkpoints := make(map[int64][]byte, len(f.static))
+	for height, hash := range f.static {
+		checkpoints[height] = hash
+	}
+	for _, item := range items {
+		manage, err := parseCheckpoints([]string{item})
+		if err != nil {
+			chainlog.Error("setManageCheckpoints", "checkpoint", item, "err", err)
+			continue
+		}
+		for height, hash := range manage {
+			if old, ok := checkpoints[height]; ok && !bytes.Equal(old, hash) {
+				chainlog.Error("setManageCheckpoints conflict", "height", height, "hash", common.ToHex(hash), "old", common.ToHex(old))
+				continue
+			}
+			checkpoints[height] = hash
+		}
+	}
+	f.mu.Lock()
+	f.checkpoints = checkpoints
+	f.mu.Unlock()
+}
+
+//checkBlock 检查点高度的区块hash必须和检查点一致
+func (f *finality) checkBlock(height int64, hash []byte) error {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if checkpoint, ok := f.checkpoints[height]; ok && !bytes.Equal(checkpoint, hash) {
+		return types.ErrCheckpointMismatch
+	}
+	return nil
+}
+
+//checkReorg 从tipHeight回滚到分叉点forkHeight，回滚的区块数不能超过maxReorgDepth
+//主链上和检查点一致的区块不能被回滚，主链上和检查点不一致的区块可以回滚到检查点所在的链
+func (f *finality) checkReorg(tipHeight, forkHeight int64, getHash func(height int64) ([]byte, error)) error {
+	if forkHeight >= tipHeight {
+		return nil
+	}
+	if f.maxReorgDepth > 0 && tipHeight-forkHeight > f.maxReorgDepth {
+		return types.ErrReorgTooDeep
+	}
+	f.mu.RLock()
+	checkpoints := make(map[int64][]byte)
+	for height, hash := range f.checkpoints {
+		if height > forkHeight && height <= tipHeight {
+			checkpoints[height] = hash
+		}
+	}
+	f.mu.RUnlock()
+	for height, hash := range checkpoints {
+		mainHash, err := getHash(height)
+		if err == nil && bytes.Equal(mainHash, hash) {
+			return types.ErrReorgFinalized
+		}
+	}
+	return nil
+}
+
+//refreshCheckpoints 从manage合约获取最新发布的检查点
+func (chain *BlockChain) refreshCheckpoints() {
+	defer chain.tickerwg.Done()
+	msg, err := chain.query.Query("manage", "GetConfigItem", &types.ReqString{Data: finalizedCheckpointsKey})
+	if err != nil {
+		chainlog.Debug("refreshCheckpoints", "err", err)
+		return
+	}
+	reply, ok := msg.(*types.ReplyConfig)
+	if !ok {
+		return
+	}
+	//数组配置项的值为fmt.Sprint([]string)的格式
+	chain.finality.setManageCheckpoints(strings.Fields(strings.Trim(reply.GetValue(), "[]")))
+}
@@ -0,0 +1,72 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/33cn/chain33/common"
+	"github.com/33cn/chain33/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFinality(t *testing.T) {
+	hash := common.ToHex(common.Sha256([]byte("block")))
+	f, err := newFinality(&types.BlockChain{MaxReorgDepth: 10, FinalizedCheckpoints: []string{"100:" + hash, "100:" + hash}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), f.maxReorgDepth)
+	assert.Equal(t, 1, len(f.checkpoints))
+
+	for _, cfg := range []*types.BlockChain{
+		{MaxReorgDepth: -1},
+		{FinalizedCheckpoints: []string{"100"}},
+		{FinalizedCheckpoints: []string{"-1:" + hash}},
+		{FinalizedCheckpoints: []string{"100:0x1234"}},
+		{FinalizedCheckpoints: []string{"100:" + hash, "100:" + common.ToHex(zeroHash[:])}},
+	} {
+		_, err = newFinality(cfg)
+		assert.Equal(t, types.ErrFinalizedCheckpoint, err)
+	}
+}
+
+func TestFinalityCheck(t *testing.T) {
+	headers := genTestHeaders(20)
+	getHash := func(height int64) ([]byte, error) {
+		if height >= int64(len(headers)) {
+			return nil, types.ErrHeightNotExist
+		}
+		return headers[height].Hash, nil
+	}
+	checkpoint := func(height int64) string {
+		return fmt.Sprintf("%d:%s", height, common.ToHex(headers[height].Hash))
+	}
+	f, err := newFinality(&types.BlockChain{MaxReorgDepth: 10, FinalizedCheckpoints: []string{checkpoint(5)}})
+	require.NoError(t, err)
+
+	assert.Nil(t, f.checkBlock(5, headers[5].Hash))
+	assert.Equal(t, types.ErrCheckpointMismatch, f.checkBlock(5, headers[6].Hash))
+	assert.Nil(t, f.checkBlock(6, headers[5].Hash))
+
+	assert.Nil(t, f.checkReorg(19, 19, getHash))
+	assert.Nil(t, f.checkReorg(19, 9, getHash))
+	assert.Equal(t, types.ErrReorgTooDeep, f.checkReorg(19, 8, getHash))
+	assert.Nil(t, f.checkReorg(8, 5, getHash))
+	assert.Equal(t, types.ErrReorgFinalized, f.checkReorg(8, 4, getHash))
+
+	//manage合约发布的检查点，和配置文件冲突以及格式错误的检查点被忽略
+	f.setManageCheckpoints([]string{checkpoint(15), "5:" + common.ToHex(headers[6].Hash), "bad"})
+	assert.Equal(t, 2, len(f.checkpoints))
+	assert.Nil(t, f.checkBlock(5, headers[5].Hash))
+	assert.Equal(t, types.ErrReorgFinalized, f.checkReorg(19, 14, getHash))
+	assert.Nil(t, f.checkReorg(19, 15, getHash))
+
+	//主链上的区块和检查点不一致时可以回滚到检查点所在的链
+	f.setManageCheckpoints([]string{"12:" + common.ToHex(headers[13].Hash)})
+	assert.Nil(t, f.checkReorg(19, 11, getHash))
+	f.setManageCheckpoints(nil)
+	assert.Equal(t, 1, len(f.checkpoints))
+}
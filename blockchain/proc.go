@@ -83,12 +83,34 @@ func (chain *BlockChain) ProcRecvMsg() {
 			go chain.processMsg(msg, reqnum, chain.getSeqByHash)
 		case types.EventLocalPrefixCount:
 			go chain.processMsg(msg, reqnum, chain.localPrefixCount)
+		case types.EventSubscribePush:
+			go chain.processMsg(msg, reqnum, chain.subscribePush)
+		case types.EventExportSnapshot:
+			go chain.processMsg(msg, reqnum, chain.exportSnapshot)
 		default:
 			go chain.processMsg(msg, reqnum, chain.unknowMsg)
 		}
 	}
 }
 
+//rpc模块订阅新增和回滚的区块
+func (chain *BlockChain) subscribePush(msg queue.Message) {
+	atomic.StoreInt32(&chain.pushRpc, 1)
+	msg.ReplyErr("SubscribePush", nil)
+}
+
+//导出最新区块的状态快照到snapshotDir目录中的文件
+func (chain *BlockChain) exportSnapshot(msg queue.Message) {
+	name := (msg.Data).(*types.ReqString).GetData()
+	header, err := chain.ExportSnapshotFile(name)
+	if err != nil {
+		chainlog.Error("ExportSnapshot", "name", name, "err", err.Error())
+		msg.Reply(chain.client.NewMessage("rpc", types.EventReplyExportSnapshot, err))
+		return
+	}
+	msg.Reply(chain.client.NewMessage("rpc", types.EventReplyExportSnapshot, header))
+}
+
 func (chain *BlockChain) unknowMsg(msg queue.Message) {
 	chainlog.Warn("ProcRecvMsg unknow msg", "msgtype", msg.Ty)
 }
@@ -179,13 +179,21 @@ func (b *BlockChain) maybeAcceptBlock(broadcast bool, block *types.BlockDetail,
 		return nil, false, types.ErrBlockHeightNoMatch
 	}
 
+	//检查点高度的区块必须和检查点一致，不一致的区块不保存
+	err := b.finality.checkBlock(blockHeight, block.Block.Hash())
+	if err != nil {
+		chainlog.Error("maybeAcceptBlock checkpoint", "height", blockHeight, "hash", common.ToHex(block.Block.Hash()), "pid", pid)
+		b.RecordFaultPeer(pid, blockHeight, block.Block.Hash(), err)
+		return nil, false, err
+	}
+
 	//将此block存储到db中，方便后面blockchain重组时使用，加入到主链saveblock时通过hash重新覆盖即可
 	sync := true
 	if atomic.LoadInt32(&b.isbatchsync) == 0 {
 		sync = false
 	}
 
-	err := b.blockStore.dbMaybeStoreBlock(block, sync)
+	err = b.blockStore.dbMaybeStoreBlock(block, sync)
 	if err != nil {
 		if err == types.ErrDataBaseDamage {
 			chainlog.Error("dbMaybeStoreBlock newbatch.Write", "err", err)
@@ -259,12 +267,24 @@ func (b *BlockChain) connectBestChain(node *blockNode, block *types.BlockDetail)
 	chainlog.Debug("connectBestChain node", "height", node.height, "hash", common.ToHex(node.hash), "parentHash", common.ToHex(parentHash))
 	chainlog.Debug("connectBestChain block", "height", block.Block.Height, "hash", common.ToHex(block.Block.Hash()))
 
+	//回滚的深度超过限制或者需要回滚最终确认的区块时，拒绝重组并记录分叉区块的peer
+	forkHeight := int64(-1)
+	if fork := b.bestChain.FindFork(node); fork != nil {
+		forkHeight = fork.height
+	}
+	err := b.finality.checkReorg(b.bestChain.Height(), forkHeight, b.blockStore.GetBlockHashByHeight)
+	if err != nil {
+		chainlog.Error("connectBestChain reorg rejected", "height", node.height, "hash", common.ToHex(node.hash), "forkHeight", forkHeight, "pid", node.pid, "err", err)
+		b.RecordFaultPeer(node.pid, node.height, node.hash, err)
+		return nil, false, err
+	}
+
 	// 获取需要重组的block node
 	detachNodes, attachNodes := b.getReorganizeNodes(node)
 
 	// Reorganize the chain.
 	//chainlog.Info("connectBestChain REORGANIZE:", "block height", node.height, "block hash", common.ToHex(node.hash))
-	err := b.reorganizeChain(detachNodes, attachNodes)
+	err = b.reorganizeChain(detachNodes, attachNodes)
 	if err != nil {
 		return nil, false, err
 	}
@@ -422,6 +442,12 @@ func (b *BlockChain) disconnectBlock(node *blockNode, blockdetail *types.BlockDe
 		go util.ReportErrEventToFront(chainlog, b.client, "blockchain", "wallet", types.ErrDataBaseDamage)
 		return err
 	}
+	//回滚已经归档的区块时，归档数据库中的交易结果也需要删除
+	err = b.blockStore.delArchivedTxs(blockdetail)
+	if err != nil {
+		chainlog.Error("disconnectBlock delArchivedTxs", "height", blockdetail.Block.Height, "err", err)
+		return err
+	}
 
 	//更新最新的高度和header为上一个块
 	b.blockStore.UpdateHeight()
@@ -226,6 +226,10 @@ func (chain *BlockChain) ProcAddBlockMsg(broadcast bool, blockdetail *types.Bloc
 		chainlog.Error("ProcAddBlockMsg input block is null")
 		return nil, types.ErrInvalidParam
 	}
+	//快速同步时只处理创世区块和检查点的区块
+	if block.Height > 0 && chain.isFastSyncing() {
+		return nil, chain.fastSync.procBlock(block, pid)
+	}
 	b, ismain, isorphan, err := chain.ProcessBlock(broadcast, blockdetail, pid, true, -1)
 	if b != nil {
 		blockdetail = b
@@ -0,0 +1,558 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/33cn/chain33/common"
+	dbm "github.com/33cn/chain33/common/db"
+	"github.com/33cn/chain33/common/merkle"
+	mavl "github.com/33cn/chain33/system/store/mavl/db"
+	"github.com/33cn/chain33/types"
+	"github.com/golang/protobuf/proto"
+)
+
+//状态快照文件的格式：
+//8字节的magic，之后是多个数据帧，每个数据帧是4字节大端编码的长度和protobuf编码的数据
+//第一个数据帧是SnapshotHeader，之后是SnapshotChunk，最后一个是section为end的SnapshotChunk
+//state数据块保存状态树的节点，从根节点开始深度优先遍历，导入时每个节点都通过父节点中的hash校验
+//mavl树的形状和插入顺序有关，只保存叶子节点无法重建出相同的根hash，所以保存所有的节点
+//blockchain数据块保存blockchain数据库中除了区块体之外的数据，包括区块头和本地数据库的索引，只保留快照高度的区块体
+
+const (
+	snapshotMagic        = "C33SNAP\x01"
+	snapshotVersion      = 1
+	snapshotSectionState = "state"
+	snapshotSectionChain = "blockchain"
+	snapshotSectionEnd   = "end"
+	snapshotChunkSize    = 4 * 1024 * 1024 //数据块压缩之前的大小
+	snapshotMaxFrameSize = 64 * 1024 * 1024
+	snapshotStateNodeNum = 1024 //一次获取的状态树节点个数
+)
+
+// StateNodesGetter 按照hash获取状态树的节点，节点不存在时为空
+type StateNodesGetter func(hashes [][]byte) ([][]byte, error)
+
+type snapshotWriter struct {
+	w       io.Writer
+	section string
+	kvs     []*types.KeyValue
+	size    int
+	index   int64
+	count   int64
+}
+
+func newSnapshotWriter(w io.Writer, header *types.SnapshotHeader) (*snapshotWriter, error) {
+	_, err := w.Write([]byte(snapshotMagic))
+	if err != nil {
+		return nil, err
+	}
+	sw := &snapshotWriter{w: w}
+	return sw, sw.writeFrame(header)
+}
+
+func (sw *snapshotWriter) writeFrame(msg proto.Message) error {
+	data := types.Encode(msg)
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(data)))
+	_, err := sw.w.Write(size[:])
+	if err != nil {
+		return err
+	}
+	_, err = sw.w.Write(data)
+	return err
+}
+
+// add 添加kv到当前的数据块，切换section或者超过数据块的大小时写入文件
+func (sw *snapshotWriter) add(section string, key, value []byte) error {
+	if section != sw.section {
+		err := sw.flush()
+		if err != nil {
+			return err
+		}
+		sw.section = section
+	}
+	sw.kvs = append(sw.kvs, &types.KeyValue{Key: key, Value: value})
+	sw.size += len(key) + len(value)
+	if sw.size >= snapshotChunkSize {
+		return sw.flush()
+	}
+	return nil
+}
+
+func (sw *snapshotWriter) flush() error {
+	if len(sw.kvs) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write(types.Encode(&types.LocalDBSet{KV: sw.kvs}))
+	if err != nil {
+		return err
+	}
+	err = gz.Close()
+	if err != nil {
+		return err
+	}
+	data := buf.Bytes()
+	chunk := &types.SnapshotChunk{
+		Section:  sw.section,
+		Index:    sw.index,
+		Count:    int64(len(sw.kvs)),
+		Data:     data,
+		Checksum: common.Sha256(data),
+	}
+	err = sw.writeFrame(chunk)
+	if err != nil {
+		return err
+	}
+	sw.index++
+	sw.count += int64(len(sw.kvs))
+	sw.kvs = nil
+	sw.size = 0
+	return nil
+}
+
+// close 写入end数据块，index为数据块的个数，count为kv的总数
+func (sw *snapshotWriter) close() error {
+	err := sw.flush()
+	if err != nil {
+		return err
+	}
+	return sw.writeFrame(&types.SnapshotChunk{Section: snapshotSectionEnd, Index: sw.index, Count: sw.count})
+}
+
+type snapshotReader struct {
+	r     io.Reader
+	index int64
+	count int64
+}
+
+func newSnapshotReader(r io.Reader) (*snapshotReader, *types.SnapshotHeader, error) {
+	magic := make([]byte, len(snapshotMagic))
+	_, err := io.ReadFull(r, magic)
+	if err != nil || string(magic) != snapshotMagic {
+		return nil, nil, types.ErrSnapshotFormat
+	}
+	sr := &snapshotReader{r: r}
+	var header types.SnapshotHeader
+	err = sr.readFrame(&header)
+	if err != nil {
+		return nil, nil, err
+	}
+	if header.Version != snapshotVersion {
+		return nil, nil, types.ErrSnapshotFormat
+	}
+	return sr, &header, nil
+}
+
+func (sr *snapshotReader) readFrame(msg proto.Message) error {
+	var size [4]byte
+	_, err := io.ReadFull(sr.r, size[:])
+	if err != nil {
+		return types.ErrSnapshotFormat
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n > snapshotMaxFrameSize {
+		return types.ErrSnapshotFormat
+	}
+	data := make([]byte, n)
+	_, err = io.ReadFull(sr.r, data)
+	if err != nil {
+		return types.ErrSnapshotFormat
+	}
+	err = types.Decode(data, msg)
+	if err != nil {
+		return types.ErrSnapshotFormat
+	}
+	return nil
+}
+
+// next 读取下一个数据块并校验checksum，读到end数据块时返回的kvs为空
+func (sr *snapshotReader) next() (string, []*types.KeyValue, error) {
+	var chunk types.SnapshotChunk
+	err := sr.readFrame(&chunk)
+	if err != nil {
+		return "", nil, err
+	}
+	if chunk.Index != sr.index {
+		return "", nil, types.ErrSnapshotFormat
+	}
+	if chunk.Section == snapshotSectionEnd {
+		if chunk.Count != sr.count {
+			return "", nil, types.ErrSnapshotFormat
+		}
+		return chunk.Section, nil, nil
+	}
+	if !bytes.Equal(common.Sha256(chunk.Data), chunk.Checksum) {
+		return "", nil, types.ErrSnapshotChecksum
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(chunk.Data))
+	if err != nil {
+		return "", nil, types.ErrSnapshotFormat
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(gz, snapshotMaxFrameSize+1))
+	if err != nil || len(data) > snapshotMaxFrameSize {
+		return "", nil, types.ErrSnapshotFormat
+	}
+	var kvs types.LocalDBSet
+	err = types.Decode(data, &kvs)
+	if err != nil || int64(len(kvs.KV)) != chunk.Count {
+		return "", nil, types.ErrSnapshotFormat
+	}
+	sr.index++
+	sr.count += chunk.Count
+	return chunk.Section, kvs.KV, nil
+}
+
+// ExportSnapshot 导出blockchain数据库中最新区块的状态快照，状态树的节点通过getNodes获取
+func ExportSnapshot(db dbm.DB, getNodes StateNodesGetter, w io.Writer) (*types.SnapshotHeader, error) {
+	height, err := LoadBlockStoreHeight(db)
+	if err != nil {
+		return nil, err
+	}
+	bs := &BlockStore{db: db}
+	blockheader, err := bs.GetBlockHeaderByHeight(height)
+	if err != nil {
+		return nil, err
+	}
+	header := &types.SnapshotHeader{
+		Title:      types.GetTitle(),
+		Version:    snapshotVersion,
+		Height:     height,
+		BlockHash:  blockheader.Hash,
+		StateHash:  blockheader.StateHash,
+		CreateTime: types.Now().Unix(),
+	}
+	sw, err := newSnapshotWriter(w, header)
+	if err != nil {
+		return nil, err
+	}
+	err = exportState(sw, header.StateHash, getNodes)
+	if err != nil {
+		return nil, err
+	}
+	err = exportChain(sw, db, header.BlockHash)
+	if err != nil {
+		return nil, err
+	}
+	err = sw.close()
+	if err != nil {
+		return nil, err
+	}
+	storeLog.Info("ExportSnapshot", "height", height, "hash", common.ToHex(header.BlockHash), "chunks", sw.index, "kvs", sw.count)
+	return header, nil
+}
+
+func exportState(sw *snapshotWriter, root []byte, getNodes StateNodesGetter) error {
+	if len(root) == 0 || bytes.Equal(root, zeroHash[:]) {
+		return nil
+	}
+	stack := [][]byte{root}
+	for len(stack) > 0 {
+		num := len(stack)
+		if num > snapshotStateNodeNum {
+			num = snapshotStateNodeNum
+		}
+		hashes := make([][]byte, num)
+		copy(hashes, stack[len(stack)-num:])
+		stack = stack[:len(stack)-num]
+		nodes, err := getNodes(hashes)
+		if err != nil {
+			return err
+		}
+		if len(nodes) != len(hashes) {
+			return types.ErrStateNodeNotExist
+		}
+		for i, node := range nodes {
+			if len(node) == 0 {
+				return types.ErrStateNodeNotExist
+			}
+			children, err := mavl.GetStateNodeChildren(node)
+			if err != nil {
+				return err
+			}
+			stack = append(stack, children...)
+			err = sw.add(snapshotSectionState, hashes[i], node)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// exportChain 导出除了区块体之外的所有数据，快照高度的区块体用于继续执行之后的区块
+func exportChain(sw *snapshotWriter, db dbm.DB, blockHash []byte) error {
+	bodyKey := calcHashToBlockBodyKey(blockHash)
+	it := db.Iterator(nil, types.EmptyValue, false)
+	defer it.Close()
+	for it.Rewind(); it.Valid(); it.Next() {
+		if it.Error() != nil {
+			return it.Error()
+		}
+		key := it.Key()
+		if bytes.HasPrefix(key, bodyPerfix) && !bytes.Equal(key, bodyKey) {
+			continue
+		}
+		if bytes.Equal(key, fastSyncBaseKey) {
+			continue
+		}
+		err := sw.add(snapshotSectionChain, common.CopyBytes(key), common.CopyBytes(it.Value()))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stateImporter 只接受已经导入的父节点引用的节点，所有的节点都可以通过根hash校验
+type stateImporter struct {
+	db      dbm.DB
+	root    []byte
+	pending map[string]bool
+	leaves  int64
+}
+
+func newStateImporter(db dbm.DB, root []byte) *stateImporter {
+	si := &stateImporter{db: db, root: root, pending: make(map[string]bool)}
+	if len(root) > 0 && !bytes.Equal(root, zeroHash[:]) {
+		si.pending[string(root)] = true
+	}
+	return si
+}
+
+func (si *stateImporter) add(kvs []*types.KeyValue) error {
+	hashes := make([][]byte, len(kvs))
+	nodes := make([][]byte, len(kvs))
+	//同一个数据块中子节点在父节点之后，父节点的hash在保存的时候校验，校验失败时整个数据块都不保存
+	for i, kv := range kvs {
+		if !si.pending[string(kv.Key)] {
+			return types.ErrSnapshotState
+		}
+		delete(si.pending, string(kv.Key))
+		children, err := mavl.GetStateNodeChildren(kv.Value)
+		if err != nil {
+			return types.ErrSnapshotState
+		}
+		for _, child := range children {
+			si.pending[string(child)] = true
+		}
+		if len(children) == 0 {
+			si.leaves++
+		}
+		hashes[i] = kv.Key
+		nodes[i] = kv.Value
+	}
+	_, err := mavl.SaveStateNodes(si.db, hashes, nodes)
+	return err
+}
+
+// finish 所有的节点都已经导入，并且可以遍历到所有的叶子节点
+func (si *stateImporter) finish() error {
+	if len(si.pending) != 0 {
+		return types.ErrSnapshotState
+	}
+	if si.leaves == 0 {
+		return nil
+	}
+	var count int64
+	mavl.IterateRangeByStateHash(si.db, si.root, nil, nil, true, func(key, value []byte) bool {
+		count++
+		return false
+	})
+	if count != si.leaves {
+		return types.ErrSnapshotState
+	}
+	return nil
+}
+
+// ImportSnapshot 导入状态快照到空的数据库中，导入之后节点从快照的高度开始同步区块
+// 状态树的节点通过快照中的StateHash校验，StateHash通过区块hash校验，blockHash为可信的区块hash，必须提供
+func ImportSnapshot(db dbm.DB, storeDB dbm.DB, r io.Reader, blockHash []byte) (*types.SnapshotHeader, error) {
+	if len(blockHash) == 0 {
+		return nil, types.ErrInvalidParam
+	}
+	if _, err := LoadBlockStoreHeight(db); err == nil {
+		return nil, types.ErrSnapshotExist
+	}
+	sr, header, err := newSnapshotReader(r)
+	if err != nil {
+		return nil, err
+	}
+	if header.Title != types.GetTitle() || !types.IsFork(header.Height, "ForkBlockHash") {
+		return nil, types.ErrSnapshotFormat
+	}
+	if !bytes.Equal(blockHash, header.BlockHash) {
+		return nil, types.ErrSnapshotState
+	}
+	state := newStateImporter(storeDB, header.StateHash)
+	var chainStarted bool
+	for {
+		section, kvs, err := sr.next()
+		if err != nil {
+			return nil, err
+		}
+		if section == snapshotSectionEnd {
+			break
+		}
+		switch section {
+		case snapshotSectionState:
+			if chainStarted {
+				return nil, types.ErrSnapshotFormat
+			}
+			err = state.add(kvs)
+		case snapshotSectionChain:
+			//状态导入完成并且校验通过之后再导入blockchain的数据
+			if !chainStarted {
+				err = state.finish()
+				if err != nil {
+					return nil, err
+				}
+				chainStarted = true
+			}
+			err = importChain(db, kvs)
+		default:
+			err = types.ErrSnapshotFormat
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !chainStarted {
+		return nil, types.ErrSnapshotFormat
+	}
+	err = checkSnapshotBlock(db, header)
+	if err != nil {
+		return nil, err
+	}
+	//最后写入最新的区块高度，导入失败时数据库中没有区块高度，节点不会使用导入了一半的数据
+	heightbytes := types.Encode(&types.Int64{Data: header.Height})
+	batch := db.NewBatch(true)
+	batch.Set(fastSyncBaseKey, heightbytes)
+	batch.Set(blockLastHeight, heightbytes)
+	err = batch.Write()
+	if err != nil {
+		return nil, err
+	}
+	storeLog.Info("ImportSnapshot", "height", header.Height, "hash", common.ToHex(header.BlockHash), "chunks", sr.index, "kvs", sr.count)
+	return header, nil
+}
+
+func importChain(db dbm.DB, kvs []*types.KeyValue) error {
+	batch := db.NewBatch(false)
+	for _, kv := range kvs {
+		if bytes.Equal(kv.Key, blockLastHeight) || bytes.Equal(kv.Key, fastSyncBaseKey) {
+			continue
+		}
+		batch.Set(kv.Key, kv.Value)
+	}
+	return batch.Write()
+}
+
+// checkSnapshotBlock 快照高度的区块需要和快照的区块hash以及状态hash一致
+func checkSnapshotBlock(db dbm.DB, header *types.SnapshotHeader) error {
+	bs := &BlockStore{db: db}
+	blockheader, err := bs.GetBlockHeaderByHeight(header.Height)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(blockheader.CalcHash(), header.BlockHash) || !bytes.Equal(blockheader.StateHash, header.StateHash) {
+		return types.ErrSnapshotState
+	}
+	detail, err := bs.LoadBlockByHash(header.BlockHash)
+	if err != nil {
+		return err
+	}
+	block := detail.Block
+	if !bytes.Equal(block.Hash(), header.BlockHash) || !bytes.Equal(merkle.CalcMerkleRoot(block.Txs), block.TxHash) {
+		return types.ErrSnapshotState
+	}
+	_, err = bs.GetTdByBlockHash(header.BlockHash)
+	return err
+}
+
+// snapshotDB 导出的时候从数据库快照中读取，只使用Get和Iterator
+type snapshotDB struct {
+	dbm.DB
+	snap dbm.Snapshot
+}
+
+func (db *snapshotDB) Get(key []byte) ([]byte, error) {
+	return db.snap.Get(key)
+}
+
+func (db *snapshotDB) Iterator(start []byte, end []byte, reverse bool) dbm.Iterator {
+	return db.snap.Iterator(start, end, reverse)
+}
+
+// snapshotPath 只能导出到配置的目录，name不能包含路径
+func snapshotPath(dir, name string) (string, error) {
+	if dir == "" {
+		return "", types.ErrSnapshotPath
+	}
+	if name == "" || name == "." || name == ".." || name != filepath.Base(name) || strings.ContainsAny(name, `/\`) {
+		return "", types.ErrSnapshotPath
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// ExportSnapshotFile 导出最新区块的状态快照到snapshotDir目录中的文件
+// 从blockchain数据库的快照中导出，不阻塞区块的处理，快照高度的状态树节点不会改变
+func (chain *BlockChain) ExportSnapshotFile(name string) (*types.SnapshotHeader, error) {
+	path, err := snapshotPath(chain.cfg.SnapshotDir, name)
+	if err != nil {
+		return nil, err
+	}
+	snapshotter, ok := chain.blockStore.db.(dbm.Snapshotter)
+	if !ok {
+		return nil, types.ErrNotSupport
+	}
+	snap, err := snapshotter.NewSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	defer snap.Release()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(f)
+	header, err := ExportSnapshot(&snapshotDB{DB: chain.blockStore.db, snap: snap}, chain.getStateNodes, w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	return header, nil
+}
+
+// getStateNodes 从store模块获取状态树的节点
+func (chain *BlockChain) getStateNodes(hashes [][]byte) ([][]byte, error) {
+	msg := chain.client.NewMessage("store", types.EventStoreGetStateNodes, &types.ReqStateNodes{Hashes: hashes})
+	err := chain.client.Send(msg, true)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := chain.client.Wait(msg)
+	if err != nil {
+		return nil, err
+	}
+	return resp.GetData().(*types.StateNodes).GetNodes(), nil
+}
@@ -0,0 +1,169 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	dbm "github.com/33cn/chain33/common/db"
+	"github.com/33cn/chain33/common/merkle"
+	mavl "github.com/33cn/chain33/system/store/mavl/db"
+	"github.com/33cn/chain33/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func genSnapshotDB(t *testing.T, dir string) (dbm.DB, dbm.DB, []*types.Block) {
+	db := dbm.NewDB("blockchain", "leveldb", dir, 100)
+	storeDB := dbm.NewDB("store", "leveldb", dir, 100)
+	var kvs []*types.KeyValue
+	for i := 0; i < 100; i++ {
+		kvs = append(kvs, &types.KeyValue{Key: []byte(fmt.Sprintf("key%d", i)), Value: []byte(fmt.Sprintf("value%d", i))})
+	}
+	stateHash, err := mavl.SetKVPair(storeDB, &types.StoreSet{KV: kvs}, true)
+	require.NoError(t, err)
+
+	bs := &BlockStore{db: db}
+	var blocks []*types.Block
+	parent := zeroHash[:]
+	for i := int64(0); i < 5; i++ {
+		txs := []*types.Transaction{{Execer: []byte("none"), Payload: []byte{byte(i)}}}
+		block := &types.Block{
+			ParentHash: parent,
+			TxHash:     merkle.CalcMerkleRoot(txs),
+			StateHash:  stateHash,
+			Height:     i,
+			BlockTime:  i,
+			Txs:        txs,
+		}
+		batch := db.NewBatch(true)
+		require.NoError(t, bs.SaveBlock(batch, &types.BlockDetail{Block: block, Receipts: []*types.ReceiptData{{}}}, -1))
+		require.NoError(t, bs.SaveTdByBlockHash(batch, block.Hash(), big.NewInt(i+1)))
+		require.NoError(t, batch.Write())
+		blocks = append(blocks, block)
+		parent = block.Hash()
+	}
+	return db, storeDB, blocks
+}
+
+func TestSnapshot(t *testing.T) {
+	mavl.EnableMavlPrefix(false)
+	mavl.EnableMVCC(false)
+	dir, err := ioutil.TempDir("", "snapshot")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	db, storeDB, blocks := genSnapshotDB(t, dir+"/src")
+	defer db.Close()
+	defer storeDB.Close()
+	last := blocks[len(blocks)-1]
+
+	var buf bytes.Buffer
+	getNodes := func(hashes [][]byte) ([][]byte, error) {
+		return mavl.GetStateNodes(storeDB, hashes), nil
+	}
+	header, err := ExportSnapshot(db, getNodes, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, last.Height, header.Height)
+	assert.Equal(t, last.Hash(), header.BlockHash)
+	assert.Equal(t, last.StateHash, header.StateHash)
+	data := buf.Bytes()
+
+	newDB := dbm.NewDB("blockchain", "leveldb", dir+"/dst", 100)
+	defer newDB.Close()
+	newStoreDB := dbm.NewDB("store", "leveldb", dir+"/dst", 100)
+	defer newStoreDB.Close()
+	//可信的区块hash不一致时不导入
+	_, err = ImportSnapshot(newDB, newStoreDB, bytes.NewReader(data), blocks[0].Hash())
+	assert.Equal(t, types.ErrSnapshotState, err)
+	//数据块被修改
+	bad := make([]byte, len(data))
+	copy(bad, data)
+	bad[len(bad)/2] ^= 0xff
+	_, err = ImportSnapshot(newDB, newStoreDB, bytes.NewReader(bad), last.Hash())
+	assert.NotNil(t, err)
+	_, err = ImportSnapshot(newDB, newStoreDB, bytes.NewReader(data[:len(data)-10]), last.Hash())
+	assert.Equal(t, types.ErrSnapshotFormat, err)
+	//必须提供可信的区块hash
+	_, err = ImportSnapshot(newDB, newStoreDB, bytes.NewReader(data), nil)
+	assert.Equal(t, types.ErrInvalidParam, err)
+
+	header2, err := ImportSnapshot(newDB, newStoreDB, bytes.NewReader(data), last.Hash())
+	require.NoError(t, err)
+	assert.Equal(t, header, header2)
+	height, err := LoadBlockStoreHeight(newDB)
+	assert.Nil(t, err)
+	assert.Equal(t, last.Height, height)
+	bs := &BlockStore{db: newDB}
+	base, err := bs.loadFlag(fastSyncBaseKey)
+	assert.Nil(t, err)
+	assert.Equal(t, last.Height, base)
+	detail, err := bs.LoadBlockByHeight(last.Height)
+	require.NoError(t, err)
+	assert.Equal(t, last.Hash(), detail.Block.Hash())
+	//快照高度之前的区块只有区块头
+	_, err = bs.LoadBlockByHeight(last.Height - 1)
+	assert.NotNil(t, err)
+	blockheader, err := bs.GetBlockHeaderByHeight(1)
+	assert.Nil(t, err)
+	assert.Equal(t, blocks[1].Hash(), blockheader.Hash)
+
+	var count int
+	mavl.IterateRangeByStateHash(newStoreDB, header.StateHash, nil, nil, true, func(key, value []byte) bool {
+		count++
+		return false
+	})
+	assert.Equal(t, 100, count)
+
+	_, err = ImportSnapshot(newDB, newStoreDB, bytes.NewReader(data), last.Hash())
+	assert.Equal(t, types.ErrSnapshotExist, err)
+}
+
+func TestExportFromDBSnapshot(t *testing.T) {
+	mavl.EnableMavlPrefix(false)
+	mavl.EnableMVCC(false)
+	dir, err := ioutil.TempDir("", "snapshot")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	db, storeDB, blocks := genSnapshotDB(t, dir)
+	defer db.Close()
+	defer storeDB.Close()
+	last := blocks[len(blocks)-1]
+
+	snap, err := db.(dbm.Snapshotter).NewSnapshot()
+	require.NoError(t, err)
+	defer snap.Release()
+	//快照之后写入的新区块不影响导出
+	block := &types.Block{ParentHash: last.Hash(), StateHash: last.StateHash, Height: last.Height + 1}
+	batch := db.NewBatch(true)
+	bs := &BlockStore{db: db}
+	require.NoError(t, bs.SaveBlock(batch, &types.BlockDetail{Block: block}, -1))
+	require.NoError(t, batch.Write())
+
+	getNodes := func(hashes [][]byte) ([][]byte, error) {
+		return mavl.GetStateNodes(storeDB, hashes), nil
+	}
+	var buf bytes.Buffer
+	header, err := ExportSnapshot(&snapshotDB{DB: db, snap: snap}, getNodes, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, last.Height, header.Height)
+	assert.Equal(t, last.Hash(), header.BlockHash)
+}
+
+func TestSnapshotPath(t *testing.T) {
+	_, err := snapshotPath("", "a.snap")
+	assert.Equal(t, types.ErrSnapshotPath, err)
+	for _, name := range []string{"", ".", "..", "../a.snap", "/tmp/a.snap", "dir/a.snap", `dir\a.snap`} {
+		_, err = snapshotPath("/data/snapshot", name)
+		assert.Equal(t, types.ErrSnapshotPath, err, name)
+	}
+	path, err := snapshotPath("/data/snapshot", "a.snap")
+	assert.Nil(t, err)
+	assert.Equal(t, "/data/snapshot/a.snap", path)
+}
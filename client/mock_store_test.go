@@ -18,7 +18,7 @@ func (m *mockStore) SetQueueClient(q queue.Queue) {
 		client.Sub("store")
 		for msg := range client.Recv() {
 			switch msg.Ty {
-			case types.EventStoreGet:
+			case types.EventStoreGet, types.EventStoreGetState:
 				msg.Reply(client.NewMessage("store", types.EventStoreGetReply, &types.StoreReplyValue{}))
 			case types.EventStoreGetTotalCoins:
 				if req, ok := msg.GetData().(*types.IterateRangeByStateHash); ok {
@@ -134,6 +134,29 @@ func (_m *QueueProtocolAPI) GenSeed(param *types.GenSeedLang) (*types.ReplySeed,
 	return r0, r1
 }
 
+// GetAccountNonce provides a mock function with given fields: param
+func (_m *QueueProtocolAPI) GetAccountNonce(param *types.ReqString) (*types.Int64, error) {
+	ret := _m.Called(param)
+
+	var r0 *types.Int64
+	if rf, ok := ret.Get(0).(func(*types.ReqString) *types.Int64); ok {
+		r0 = rf(param)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.Int64)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*types.ReqString) error); ok {
+		r1 = rf(param)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetAddrOverview provides a mock function with given fields: param
 func (_m *QueueProtocolAPI) GetAddrOverview(param *types.ReqAddr) (*types.AddrOverview, error) {
 	ret := _m.Called(param)
@@ -341,6 +364,52 @@ func (_m *QueueProtocolAPI) GetLastBlockSequence() (*types.Int64, error) {
 	return r0, r1
 }
 
+// ListPeerBans provides a mock function with given fields:
+func (_m *QueueProtocolAPI) ListPeerBans() (*types.PeerBans, error) {
+	ret := _m.Called()
+
+	var r0 *types.PeerBans
+	if rf, ok := ret.Get(0).(func() *types.PeerBans); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.PeerBans)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SimulateTransaction provides a mock function with given fields: param
+func (_m *QueueProtocolAPI) SimulateTransaction(param *types.ReqSimulateTx) (*types.ReplySimulateTx, error) {
+	ret := _m.Called(param)
+
+	var r0 *types.ReplySimulateTx
+	if rf, ok := ret.Get(0).(func(*types.ReqSimulateTx) *types.ReplySimulateTx); ok {
+		r0 = rf(param)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.ReplySimulateTx)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*types.ReqSimulateTx) error); ok {
+		r1 = rf(param)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetLastHeader provides a mock function with given fields:
 func (_m *QueueProtocolAPI) GetLastHeader() (*types.Header, error) {
 	ret := _m.Called()
@@ -928,6 +997,75 @@ func (_m *QueueProtocolAPI) StoreGet(_a0 *types.StoreGet) (*types.StoreReplyValu
 	return r0, r1
 }
 
+// StoreGetProof provides a mock function with given fields: _a0
+func (_m *QueueProtocolAPI) StoreGetProof(_a0 *types.StoreGetProof) (*types.StoreReplyProof, error) {
+	ret := _m.Called(_a0)
+
+	var r0 *types.StoreReplyProof
+	if rf, ok := ret.Get(0).(func(*types.StoreGetProof) *types.StoreReplyProof); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.StoreReplyProof)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*types.StoreGetProof) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ExportSnapshot provides a mock function with given fields: param
+func (_m *QueueProtocolAPI) ExportSnapshot(param *types.ReqString) (*types.SnapshotHeader, error) {
+	ret := _m.Called(param)
+
+	var r0 *types.SnapshotHeader
+	if rf, ok := ret.Get(0).(func(*types.ReqString) *types.SnapshotHeader); ok {
+		r0 = rf(param)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.SnapshotHeader)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*types.ReqString) error); ok {
+		r1 = rf(param)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// StoreGetPruneStatus provides a mock function with given fields:
+func (_m *QueueProtocolAPI) StoreGetPruneStatus() (*types.StorePruneStatus, error) {
+	ret := _m.Called()
+
+	var r0 *types.StorePruneStatus
+	if rf, ok := ret.Get(0).(func() *types.StorePruneStatus); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.StorePruneStatus)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // StoreGetTotalCoins provides a mock function with given fields: _a0
 func (_m *QueueProtocolAPI) StoreGetTotalCoins(_a0 *types.IterateRangeByStateHash) (*types.ReplyGetTotalCoins, error) {
 	ret := _m.Called(_a0)
@@ -951,6 +1089,29 @@ func (_m *QueueProtocolAPI) StoreGetTotalCoins(_a0 *types.IterateRangeByStateHas
 	return r0, r1
 }
 
+// UnbanPeer provides a mock function with given fields: param
+func (_m *QueueProtocolAPI) UnbanPeer(param *types.ReqString) (*types.Reply, error) {
+	ret := _m.Called(param)
+
+	var r0 *types.Reply
+	if rf, ok := ret.Get(0).(func(*types.ReqString) *types.Reply); ok {
+		r0 = rf(param)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.Reply)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*types.ReqString) error); ok {
+		r1 = rf(param)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Version provides a mock function with given fields:
 func (_m *QueueProtocolAPI) Version() (*types.Reply, error) {
 	ret := _m.Called()
@@ -1020,6 +1181,52 @@ func (_m *QueueProtocolAPI) WalletGetAccountList(req *types.ReqAccountList) (*ty
 	return r0, r1
 }
 
+// WalletImportWatch provides a mock function with given fields: param
+func (_m *QueueProtocolAPI) WalletImportWatch(param *types.ReqWalletImportWatch) (*types.WalletAccounts, error) {
+	ret := _m.Called(param)
+
+	var r0 *types.WalletAccounts
+	if rf, ok := ret.Get(0).(func(*types.ReqWalletImportWatch) *types.WalletAccounts); ok {
+		r0 = rf(param)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.WalletAccounts)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*types.ReqWalletImportWatch) error); ok {
+		r1 = rf(param)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// WalletExportXpub provides a mock function with given fields: param
+func (_m *QueueProtocolAPI) WalletExportXpub(param *types.ReqNil) (*types.ReplyString, error) {
+	ret := _m.Called(param)
+
+	var r0 *types.ReplyString
+	if rf, ok := ret.Get(0).(func(*types.ReqNil) *types.ReplyString); ok {
+		r0 = rf(param)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.ReplyString)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*types.ReqNil) error); ok {
+		r1 = rf(param)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // WalletImportprivkey provides a mock function with given fields: param
 func (_m *QueueProtocolAPI) WalletImportprivkey(param *types.ReqWalletImportPrivkey) (*types.WalletAccount, error) {
 	ret := _m.Called(param)
@@ -1089,6 +1296,29 @@ func (_m *QueueProtocolAPI) WalletMergeBalance(param *types.ReqWalletMergeBalanc
 	return r0, r1
 }
 
+// WalletRescan provides a mock function with given fields: param
+func (_m *QueueProtocolAPI) WalletRescan(param *types.ReqWalletRescan) (*types.WalletRescanStatus, error) {
+	ret := _m.Called(param)
+
+	var r0 *types.WalletRescanStatus
+	if rf, ok := ret.Get(0).(func(*types.ReqWalletRescan) *types.WalletRescanStatus); ok {
+		r0 = rf(param)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.WalletRescanStatus)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*types.ReqWalletRescan) error); ok {
+		r1 = rf(param)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // WalletSendToAddress provides a mock function with given fields: param
 func (_m *QueueProtocolAPI) WalletSendToAddress(param *types.ReqWalletSendToAddress) (*types.ReplyHash, error) {
 	ret := _m.Called(param)
@@ -289,6 +289,55 @@ func (q *QueueProtocol) WalletImportprivkey(param *types.ReqWalletImportPrivkey)
 	return nil, types.ErrTypeAsset
 }
 
+//WalletImportWatch 导入只读的地址或者扩展公钥
+func (q *QueueProtocol) WalletImportWatch(param *types.ReqWalletImportWatch) (*types.WalletAccounts, error) {
+	if param == nil {
+		err := types.ErrInvalidParam
+		log.Error("WalletImportWatch", "Error", err)
+		return nil, err
+	}
+	msg, err := q.query(walletKey, types.EventWalletImportWatch, param)
+	if err != nil {
+		log.Error("WalletImportWatch", "Error", err.Error())
+		return nil, err
+	}
+	if reply, ok := msg.GetData().(*types.WalletAccounts); ok {
+		return reply, nil
+	}
+	return nil, types.ErrTypeAsset
+}
+
+//WalletExportXpub 导出钱包的扩展公钥，用于在只读钱包中导入
+func (q *QueueProtocol) WalletExportXpub(param *types.ReqNil) (*types.ReplyString, error) {
+	msg, err := q.query(walletKey, types.EventWalletExportXpub, param)
+	if err != nil {
+		log.Error("WalletExportXpub", "Error", err.Error())
+		return nil, err
+	}
+	if reply, ok := msg.GetData().(*types.ReplyString); ok {
+		return reply, nil
+	}
+	return nil, types.ErrTypeAsset
+}
+
+//WalletRescan 开始，取消或者继续扫描历史区块
+func (q *QueueProtocol) WalletRescan(param *types.ReqWalletRescan) (*types.WalletRescanStatus, error) {
+	if param == nil {
+		err := types.ErrInvalidParam
+		log.Error("WalletRescan", "Error", err)
+		return nil, err
+	}
+	msg, err := q.query(walletKey, types.EventWalletRescan, param)
+	if err != nil {
+		log.Error("WalletRescan", "Error", err.Error())
+		return nil, err
+	}
+	if reply, ok := msg.GetData().(*types.WalletRescanStatus); ok {
+		return reply, nil
+	}
+	return nil, types.ErrTypeAsset
+}
+
 func (q *QueueProtocol) WalletSendToAddress(param *types.ReqWalletSendToAddress) (*types.ReplyHash, error) {
 	if param == nil {
 		err := types.ErrInvalidParam
@@ -415,6 +464,37 @@ func (q *QueueProtocol) PeerInfo() (*types.PeerList, error) {
 	return nil, types.ErrTypeAsset
 }
 
+//ListPeerBans 因为不良行为被禁止连接的节点
+func (q *QueueProtocol) ListPeerBans() (*types.PeerBans, error) {
+	msg, err := q.query(p2pKey, types.EventListPeerBans, &types.ReqNil{})
+	if err != nil {
+		log.Error("ListPeerBans", "Error", err.Error())
+		return nil, err
+	}
+	if reply, ok := msg.GetData().(*types.PeerBans); ok {
+		return reply, nil
+	}
+	return nil, types.ErrTypeAsset
+}
+
+//UnbanPeer 解除对节点ip的禁止
+func (q *QueueProtocol) UnbanPeer(param *types.ReqString) (*types.Reply, error) {
+	if param == nil || param.Data == "" {
+		err := types.ErrInvalidParam
+		log.Error("UnbanPeer", "Error", err)
+		return nil, err
+	}
+	msg, err := q.query(p2pKey, types.EventUnbanPeer, param)
+	if err != nil {
+		log.Error("UnbanPeer", "Error", err.Error())
+		return nil, err
+	}
+	if reply, ok := msg.GetData().(*types.Reply); ok {
+		return reply, nil
+	}
+	return nil, types.ErrTypeAsset
+}
+
 func (q *QueueProtocol) GetHeaders(param *types.ReqBlocks) (*types.Headers, error) {
 	if param == nil {
 		err := types.ErrInvalidParam
@@ -444,6 +524,24 @@ func (q *QueueProtocol) GetLastMempool() (*types.ReplyTxList, error) {
 	return nil, types.ErrTypeAsset
 }
 
+//GetAccountNonce 获取账户下一笔交易可以使用的nonce，包括Mempool中已有的交易
+func (q *QueueProtocol) GetAccountNonce(param *types.ReqString) (*types.Int64, error) {
+	if param == nil {
+		err := types.ErrInvalidParam
+		log.Error("GetAccountNonce", "Error", err)
+		return nil, err
+	}
+	msg, err := q.query(mempoolKey, types.EventGetAccountNonce, param)
+	if err != nil {
+		log.Error("GetAccountNonce", "Error", err.Error())
+		return nil, err
+	}
+	if reply, ok := msg.GetData().(*types.Int64); ok {
+		return reply, nil
+	}
+	return nil, types.ErrTypeAsset
+}
+
 func (q *QueueProtocol) GetBlockOverview(param *types.ReqHash) (*types.BlockOverview, error) {
 	if param == nil {
 		err := types.ErrInvalidParam
@@ -764,7 +862,8 @@ func (q *QueueProtocol) StoreGet(param *types.StoreGet) (*types.StoreReplyValue,
 		return nil, err
 	}
 
-	msg, err := q.query(storeKey, types.EventStoreGet, param)
+	//查询历史状态时，状态可能已经被裁剪，这时返回ErrStateNotExist
+	msg, err := q.query(storeKey, types.EventStoreGetState, param)
 	if err != nil {
 		log.Error("StoreGet", "Error", err.Error())
 		return nil, err
@@ -796,6 +895,39 @@ func (q *QueueProtocol) StoreGetTotalCoins(param *types.IterateRangeByStateHash)
 	return nil, err
 }
 
+func (q *QueueProtocol) StoreGetProof(param *types.StoreGetProof) (*types.StoreReplyProof, error) {
+	if param == nil {
+		err := types.ErrInvalidParam
+		log.Error("StoreGetProof", "Error", err)
+		return nil, err
+	}
+	msg, err := q.query(storeKey, types.EventStoreGetProof, param)
+	if err != nil {
+		log.Error("StoreGetProof", "Error", err.Error())
+		return nil, err
+	}
+	if reply, ok := msg.GetData().(*types.StoreReplyProof); ok {
+		return reply, nil
+	}
+	err = types.ErrTypeAsset
+	log.Error("StoreGetProof", "Error", err.Error())
+	return nil, err
+}
+
+func (q *QueueProtocol) StoreGetPruneStatus() (*types.StorePruneStatus, error) {
+	msg, err := q.query(storeKey, types.EventStoreGetPruneStatus, &types.ReqNil{})
+	if err != nil {
+		log.Error("StoreGetPruneStatus", "Error", err.Error())
+		return nil, err
+	}
+	if reply, ok := msg.GetData().(*types.StorePruneStatus); ok {
+		return reply, nil
+	}
+	err = types.ErrTypeAsset
+	log.Error("StoreGetPruneStatus", "Error", err.Error())
+	return nil, err
+}
+
 func (q *QueueProtocol) GetFatalFailure() (*types.Int32, error) {
 	msg, err := q.query(walletKey, types.EventFatalFailure, &types.ReqNil{})
 	if err != nil {
@@ -856,6 +988,26 @@ func (q *QueueProtocol) GetBlockByHashes(param *types.ReqHashes) (*types.BlockDe
 	return nil, err
 }
 
+//ExportSnapshot 导出状态快照到节点本地的文件
+func (q *QueueProtocol) ExportSnapshot(param *types.ReqString) (*types.SnapshotHeader, error) {
+	if param == nil || param.Data == "" {
+		err := types.ErrInvalidParam
+		log.Error("ExportSnapshot", "Error", err)
+		return nil, err
+	}
+	msg, err := q.query(blockchainKey, types.EventExportSnapshot, param)
+	if err != nil {
+		log.Error("ExportSnapshot", "Error", err.Error())
+		return nil, err
+	}
+	if reply, ok := msg.GetData().(*types.SnapshotHeader); ok {
+		return reply, nil
+	}
+	err = types.ErrTypeAsset
+	log.Error("ExportSnapshot", "Error", err.Error())
+	return nil, err
+}
+
 func (q *QueueProtocol) GetBlockSequences(param *types.ReqBlocks) (*types.BlockSequences, error) {
 	if param == nil {
 		err := types.ErrInvalidParam
@@ -894,6 +1046,23 @@ func (q *QueueProtocol) QueryChain(param *types.ChainExecutor) (types.Message, e
 	return nil, err
 }
 
+func (q *QueueProtocol) SimulateTransaction(param *types.ReqSimulateTx) (*types.ReplySimulateTx, error) {
+	if param == nil || param.Tx == nil {
+		err := types.ErrInvalidParam
+		log.Error("SimulateTransaction", "Error", err)
+		return nil, err
+	}
+	msg, err := q.query(executorKey, types.EventSimulateTx, param)
+	if err != nil {
+		log.Error("SimulateTransaction", "Error", err.Error())
+		return nil, err
+	}
+	if reply, ok := msg.GetData().(*types.ReplySimulateTx); ok {
+		return reply, nil
+	}
+	return nil, types.ErrTypeAsset
+}
+
 func (q *QueueProtocol) GetTicketCount() (*types.Int64, error) {
 	msg, err := q.query(consensusKey, types.EventGetTicketCount, &types.ReqNil{})
 	if err != nil {
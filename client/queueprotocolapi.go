@@ -24,6 +24,8 @@ type QueueProtocolAPI interface {
 	GetMempool() (*types.ReplyTxList, error)
 	// types.EventGetLastMempool
 	GetLastMempool() (*types.ReplyTxList, error)
+	// types.EventGetAccountNonce
+	GetAccountNonce(param *types.ReqString) (*types.Int64, error)
 	// +++++++++++++++ execs interfaces begin
 	// types.EventBlockChainQuery
 	Query(driver, funcname string, param types.Message) (types.Message, error)
@@ -32,6 +34,8 @@ type QueueProtocolAPI interface {
 	QueryChain(param *types.ChainExecutor) (types.Message, error)
 	ExecWalletFunc(driver string, funcname string, param types.Message) (types.Message, error)
 	ExecWallet(param *types.ChainExecutor) (types.Message, error)
+	// types.EventSimulateTx
+	SimulateTransaction(param *types.ReqSimulateTx) (*types.ReplySimulateTx, error)
 	// --------------- execs interfaces end
 
 	// +++++++++++++++ p2p interfaces begin
@@ -39,6 +43,10 @@ type QueueProtocolAPI interface {
 	PeerInfo() (*types.PeerList, error)
 	// types.EventGetNetInfo
 	GetNetInfo() (*types.NodeNetInfo, error)
+	// types.EventListPeerBans
+	ListPeerBans() (*types.PeerBans, error)
+	// types.EventUnbanPeer
+	UnbanPeer(param *types.ReqString) (*types.Reply, error)
 	// --------------- p2p interfaces end
 	// +++++++++++++++ wallet interfaces begin
 	// types.EventLocalGet
@@ -53,6 +61,12 @@ type QueueProtocolAPI interface {
 	WalletTransactionList(param *types.ReqWalletTransactionList) (*types.WalletTxDetails, error)
 	// types.EventWalletImportprivkey
 	WalletImportprivkey(param *types.ReqWalletImportPrivkey) (*types.WalletAccount, error)
+	// types.EventWalletImportWatch
+	WalletImportWatch(param *types.ReqWalletImportWatch) (*types.WalletAccounts, error)
+	// types.EventWalletExportXpub
+	WalletExportXpub(param *types.ReqNil) (*types.ReplyString, error)
+	// types.EventWalletRescan
+	WalletRescan(param *types.ReqWalletRescan) (*types.WalletRescanStatus, error)
 	// types.EventWalletSendToAddress
 	WalletSendToAddress(param *types.ReqWalletSendToAddress) (*types.ReplyHash, error)
 	// types.EventWalletSetFee
@@ -111,12 +125,18 @@ type QueueProtocolAPI interface {
 	GetBlockSequences(param *types.ReqBlocks) (*types.BlockSequences, error)
 	//types.EventGetBlockByHashes:
 	GetBlockByHashes(param *types.ReqHashes) (*types.BlockDetails, error)
+	// types.EventExportSnapshot
+	ExportSnapshot(param *types.ReqString) (*types.SnapshotHeader, error)
 
 	// --------------- blockchain interfaces end
 
 	// +++++++++++++++ store interfaces begin
 	StoreGet(*types.StoreGet) (*types.StoreReplyValue, error)
 	StoreGetTotalCoins(*types.IterateRangeByStateHash) (*types.ReplyGetTotalCoins, error)
+	// types.EventStoreGetProof
+	StoreGetProof(*types.StoreGetProof) (*types.StoreReplyProof, error)
+	// types.EventStoreGetPruneStatus
+	StoreGetPruneStatus() (*types.StorePruneStatus, error)
 	// --------------- store interfaces end
 
 	// +++++++++++++++ other interfaces begin
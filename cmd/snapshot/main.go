@@ -0,0 +1,151 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+//这个软件包的主要目的是在节点停止的时候导出和导入状态快照
+//导出最新区块的状态树和blockchain数据库中除了历史区块体之外的数据，导入之后节点从快照的高度开始同步区块
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+
+	"github.com/33cn/chain33/blockchain"
+	"github.com/33cn/chain33/common"
+	dbm "github.com/33cn/chain33/common/db"
+	clog "github.com/33cn/chain33/common/log"
+	log "github.com/33cn/chain33/common/log/log15"
+	mavl "github.com/33cn/chain33/system/store/mavl/db"
+	"github.com/33cn/chain33/types"
+)
+
+var datadir = flag.String("datadir", "", "data dir of chain33, include logs and datas")
+var configPath = flag.String("f", "chain33.toml", "configfile")
+var exportPath = flag.String("export", "", "export snapshot to file")
+var importPath = flag.String("import", "", "import snapshot from file")
+var blockHash = flag.String("hash", "", "expected block hash of the imported snapshot, required by -import")
+
+func resetDatadir(cfg *types.Config, datadir string) {
+	// Check in case of paths like "/something/~/something/"
+	if datadir[:2] == "~/" {
+		usr, _ := user.Current()
+		dir := usr.HomeDir
+		datadir = filepath.Join(dir, datadir[2:])
+	}
+	log.Info("current user data dir is ", "dir", datadir)
+	cfg.BlockChain.DbPath = filepath.Join(datadir, cfg.BlockChain.DbPath)
+	cfg.Store.DbPath = filepath.Join(datadir, cfg.Store.DbPath)
+}
+
+//initMavl 快照中保存的状态树节点hash和mavl的配置有关
+func initMavl(sub *types.ConfigSubModule) error {
+	var subcfg struct {
+		EnableMavlPrefix bool `json:"enableMavlPrefix"`
+		EnableMVCC       bool `json:"enableMVCC"`
+	}
+	if sub.Store["mavl"] != nil {
+		err := json.Unmarshal(sub.Store["mavl"], &subcfg)
+		if err != nil {
+			return err
+		}
+	}
+	if subcfg.EnableMVCC {
+		return types.ErrNotSupport
+	}
+	mavl.EnableMavlPrefix(subcfg.EnableMavlPrefix)
+	mavl.EnableMVCC(subcfg.EnableMVCC)
+	return nil
+}
+
+func exportSnapshot(db, storeDB dbm.DB, path string) (*types.SnapshotHeader, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+	getNodes := func(hashes [][]byte) ([][]byte, error) {
+		return mavl.GetStateNodes(storeDB, hashes), nil
+	}
+	w := bufio.NewWriter(f)
+	header, err := blockchain.ExportSnapshot(db, getNodes, w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	return header, nil
+}
+
+func importSnapshot(db, storeDB dbm.DB, path string, hash string) (*types.SnapshotHeader, error) {
+	expected, err := common.FromHex(hash)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return blockchain.ImportSnapshot(db, storeDB, bufio.NewReader(f), expected)
+}
+
+func run() error {
+	cfg, sub := types.InitCfg(*configPath)
+	if *datadir != "" {
+		resetDatadir(cfg, *datadir)
+	}
+	types.Init(cfg.Title, cfg)
+	err := initMavl(sub)
+	if err != nil {
+		return err
+	}
+	db := dbm.NewDB("blockchain", cfg.BlockChain.Driver, cfg.BlockChain.DbPath, cfg.BlockChain.DbCache)
+	defer db.Close()
+	storeDB := dbm.NewDB("store", cfg.Store.Driver, cfg.Store.DbPath, cfg.Store.DbCache)
+	defer storeDB.Close()
+
+	var header *types.SnapshotHeader
+	if *exportPath != "" {
+		header, err = exportSnapshot(db, storeDB, *exportPath)
+	} else {
+		//导入的快照只能证明状态和区块hash一致，区块hash需要和可信的来源比较
+		header, err = importSnapshot(db, storeDB, *importPath, *blockHash)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println("title:", header.Title)
+	fmt.Println("height:", header.Height)
+	fmt.Println("block hash:", common.ToHex(header.BlockHash))
+	fmt.Println("state hash:", common.ToHex(header.StateHash))
+	return nil
+}
+
+func main() {
+	clog.SetLogLevel("info")
+	flag.Parse()
+	if (*exportPath == "") == (*importPath == "") {
+		fmt.Println("one of -export and -import is required")
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *importPath != "" && *blockHash == "" {
+		fmt.Println("-hash is required by -import")
+		flag.Usage()
+		os.Exit(1)
+	}
+	err := run()
+	if err != nil {
+		fmt.Println("snapshot failed:", err)
+		os.Exit(1)
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/33cn/chain33/common/crypto"
 	_ "github.com/33cn/chain33/system/crypto/init"
+	"github.com/33cn/chain33/system/crypto/multisig"
 	"github.com/stretchr/testify/require"
 )
 
@@ -148,3 +149,78 @@ func Verify(signType string, pubBytes, msg, signBytes []byte) bool {
 
 	return pub.VerifyBytes(msg, sign)
 }
+
+func TestMultiSig(t *testing.T) {
+	require := require.New(t)
+	var privs []crypto.PrivKey
+	var pubs [][]byte
+	for _, name := range []string{"secp256k1", "ed25519", "secp256k1"} {
+		c, err := crypto.New(name)
+		require.Nil(err)
+		priv, err := c.GenKey()
+		require.Nil(err)
+		privs = append(privs, priv)
+		pubs = append(pubs, priv.PubKey().Bytes())
+	}
+	tys := []int32{1, 2, 1}
+	_, err := multisig.NewPubKey(0, tys, pubs)
+	require.Equal(multisig.ErrThreshold, err)
+	_, err = multisig.NewPubKey(4, tys, pubs)
+	require.Equal(multisig.ErrThreshold, err)
+	_, err = multisig.NewPubKey(2, []int32{1, 2, 1}, [][]byte{pubs[0], pubs[1], pubs[0]})
+	require.Equal(multisig.ErrDupKey, err)
+	_, err = multisig.NewPubKey(1, []int32{multisig.ID}, pubs[:1])
+	require.Equal(multisig.ErrKeyType, err)
+	//成员只能是secp256k1, ed25519, sm2
+	_, err = multisig.NewPubKey(1, []int32{4}, pubs[:1])
+	require.Equal(multisig.ErrKeyType, err)
+
+	pub, err := multisig.NewPubKey(2, tys, pubs)
+	require.Nil(err)
+	c, err := crypto.New(multisig.Name)
+	require.Nil(err)
+	_, err = c.GenKey()
+	require.Equal(multisig.ErrNoPrivKey, err)
+	pub2, err := c.PubKeyFromBytes(pub.Bytes())
+	require.Nil(err)
+	require.True(pub.Equals(pub2))
+	//多余的数据不是规范的编码
+	_, err = c.PubKeyFromBytes(append(pub.Bytes(), 0))
+	require.Equal(multisig.ErrDecode, err)
+
+	var msg = []byte("hello world")
+	sig := multisig.NewSignature(pub)
+	require.Nil(sig.AddSignature(privs[2], msg))
+	require.False(pub.VerifyBytes(msg, sig))
+	require.Nil(sig.AddSignature(privs[0], msg))
+	require.Nil(sig.AddSignature(privs[0], msg))
+	require.Equal(2, len(sig.Sigs))
+	require.Equal(0, sig.Sigs[0].Index)
+	require.True(pub.VerifyBytes(msg, sig))
+	require.False(pub.VerifyBytes([]byte("hello"), sig))
+
+	sig2, err := c.SignatureFromBytes(sig.Bytes())
+	require.Nil(err)
+	require.True(sig2.Equals(sig))
+	require.True(pub2.VerifyBytes(msg, sig2))
+
+	//不是成员不能签名
+	other, err := crypto.New("secp256k1")
+	require.Nil(err)
+	priv, err := other.GenKey()
+	require.Nil(err)
+	require.Equal(multisig.ErrNotMember, sig.AddSignature(priv, msg))
+
+	//同一个成员的签名不能重复计数
+	dup := multisig.NewSignature(pub)
+	require.Nil(dup.AddSignature(privs[1], msg))
+	dup.Sigs = append(dup.Sigs, dup.Sigs[0])
+	require.False(pub.VerifyBytes(msg, dup))
+	_, err = c.SignatureFromBytes(dup.Bytes())
+	require.Equal(multisig.ErrDecode, err)
+
+	//策略不同的签名无效
+	pub3, err := multisig.NewPubKey(1, tys, pubs)
+	require.Nil(err)
+	require.False(pub3.VerifyBytes(msg, sig))
+}
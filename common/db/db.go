@@ -123,6 +123,18 @@ type IteratorDB interface {
 	Iterator(start []byte, end []byte, reserver bool) Iterator
 }
 
+//Snapshot 数据库某个时刻的只读快照，之后的写入在快照中不可见，用完之后需要Release
+type Snapshot interface {
+	Get(key []byte) ([]byte, error)
+	IteratorDB
+	Release()
+}
+
+//Snapshotter 支持快照的数据库
+type Snapshotter interface {
+	NewSnapshot() (Snapshot, error)
+}
+
 func bytesPrefix(prefix []byte) []byte {
 	var limit []byte
 	for i := len(prefix) - 1; i >= 0; i-- {
@@ -234,3 +234,27 @@ func testDBIteratorDel(t *testing.T, db DB) {
 		batch.Write()
 	}
 }
+
+//快照创建之后的写入在快照中不可见
+func testDBSnapshot(t *testing.T, db DB) {
+	require.NoError(t, db.Set([]byte("snap1"), []byte("v1")))
+	snap, err := db.(Snapshotter).NewSnapshot()
+	require.NoError(t, err)
+	defer snap.Release()
+	require.NoError(t, db.Set([]byte("snap1"), []byte("v2")))
+	require.NoError(t, db.Set([]byte("snap2"), []byte("v2")))
+
+	value, err := snap.Get([]byte("snap1"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("v1"), value)
+	_, err = snap.Get([]byte("snap2"))
+	assert.Equal(t, ErrNotFoundInDb, err)
+
+	it := snap.Iterator([]byte("snap"), nil, false)
+	defer it.Close()
+	var keys []string
+	for it.Rewind(); it.Valid(); it.Next() {
+		keys = append(keys, string(it.Key()))
+	}
+	assert.Equal(t, []string{"snap1"}, keys)
+}
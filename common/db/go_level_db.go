@@ -171,6 +171,46 @@ func (db *GoLevelDB) BatchGet(keys [][]byte) (value [][]byte, err error) {
 	return nil, nil
 }
 
+func (db *GoLevelDB) NewSnapshot() (Snapshot, error) {
+	snap, err := db.db.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	return &goLevelDBSnapshot{snap}, nil
+}
+
+type goLevelDBSnapshot struct {
+	snap *leveldb.Snapshot
+}
+
+func (s *goLevelDBSnapshot) Get(key []byte) ([]byte, error) {
+	res, err := s.snap.Get(key, nil)
+	if err != nil {
+		if err == errors.ErrNotFound {
+			return nil, ErrNotFoundInDb
+		}
+		llog.Error("Get", "error", err)
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *goLevelDBSnapshot) Iterator(start []byte, end []byte, reverse bool) Iterator {
+	if end == nil {
+		end = bytesPrefix(start)
+	}
+	if bytes.Equal(end, types.EmptyValue) {
+		end = nil
+	}
+	r := &util.Range{Start: start, Limit: end}
+	it := s.snap.NewIterator(r, nil)
+	return &goLevelDBIt{it, itBase{start, end, reverse}}
+}
+
+func (s *goLevelDBSnapshot) Release() {
+	s.snap.Release()
+}
+
 type goLevelDBIt struct {
 	iterator.Iterator
 	itBase
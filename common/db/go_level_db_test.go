@@ -56,6 +56,18 @@ func TestGoLevelDBBoundary(t *testing.T) {
 	testDBBoundary(t, leveldb)
 }
 
+func TestGoLevelDBSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goleveldb")
+	require.NoError(t, err)
+	t.Log(dir)
+
+	leveldb, err := NewGoLevelDB("goleveldb", dir, 128)
+	require.NoError(t, err)
+	defer leveldb.Close()
+
+	testDBSnapshot(t, leveldb)
+}
+
 func BenchmarkBatchWrites(b *testing.B) {
 	dir, err := ioutil.TempDir("", "example")
 	assert.Nil(b, err)
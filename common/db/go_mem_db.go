@@ -105,6 +105,24 @@ func (db *GoMemDB) DB() map[string][]byte {
 	return db.db
 }
 
+//NewSnapshot 复制当前的数据作为快照
+func (db *GoMemDB) NewSnapshot() (Snapshot, error) {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+	snap := &GoMemDB{db: make(map[string][]byte, len(db.db))}
+	for k, v := range db.db {
+		snap.db[k] = v
+	}
+	return &goMemDBSnapshot{snap}, nil
+}
+
+type goMemDBSnapshot struct {
+	*GoMemDB
+}
+
+func (s *goMemDBSnapshot) Release() {
+}
+
 func (db *GoMemDB) Close() {
 
 }
@@ -113,3 +113,11 @@ func BenchmarkRandomGoMemDBReadsWrites(b *testing.B) {
 
 	db.Close()
 }
+
+func TestGoMemDBSnapshot(t *testing.T) {
+	memdb, err := NewGoMemDB("gomemdb", "", 128)
+	require.NoError(t, err)
+	defer memdb.Close()
+
+	testDBSnapshot(t, memdb)
+}
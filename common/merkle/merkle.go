@@ -170,6 +170,15 @@ func GetMerkleRootFromBranch(merkleBranch [][]byte, leaf []byte, Index uint32) [
 	return hash
 }
 
+//验证leaf通过branch计算出的roothash和指定的roothash一致，Index从0开始
+//注意最后一个叶子在奇数层会和自己组成一对，需要严格校验Index时要结合区块的交易数
+func VerifyMerkleBranch(merkleBranch [][]byte, leaf []byte, Index uint32, roothash []byte) bool {
+	if len(leaf) == 0 || len(roothash) == 0 {
+		return false
+	}
+	return bytes.Equal(GetMerkleRootFromBranch(merkleBranch, leaf, Index), roothash)
+}
+
 //获取merkle roothash 以及指定tx index的branch，注释：position从0开始
 func GetMerkleRootAndBranch(leaves [][]byte, position uint32) (roothash []byte, branchs [][]byte) {
 	roothash, _, branchs = Computation(leaves, 3, position)
@@ -6,6 +6,7 @@ package merkle
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"testing"
 )
 
@@ -248,3 +249,34 @@ func Test_SixTxMerkle(t *testing.T) {
 		}
 	}
 }
+
+//测试通过branch验证交易在roothash中
+func Test_VerifyMerkleBranch(t *testing.T) {
+	var leaves [][]byte
+	for i := 0; i < 5; i++ {
+		hash := sha256.Sum256([]byte{byte(i)})
+		leaves = append(leaves, hash[:])
+	}
+	roothash := GetMerkleRoot(leaves)
+	for txindex := 0; txindex < len(leaves); txindex++ {
+		branchs := GetMerkleBranch(leaves, uint32(txindex))
+		if !VerifyMerkleBranch(branchs, leaves[txindex], uint32(txindex), roothash) {
+			t.Errorf("Test_VerifyMerkleBranch verify fail :%d", txindex)
+		}
+		//错误的index，叶子个数为奇数时最后一个叶子和自己组成一对，相邻的index也能验证通过
+		if txindex != len(leaves)-1 && VerifyMerkleBranch(branchs, leaves[txindex], uint32(txindex+1), roothash) {
+			t.Errorf("Test_VerifyMerkleBranch wrong index verify ok :%d", txindex)
+		}
+		//错误的leaf
+		if VerifyMerkleBranch(branchs, leaves[(txindex+1)%len(leaves)], uint32(txindex), roothash) {
+			t.Errorf("Test_VerifyMerkleBranch wrong leaf verify ok :%d", txindex)
+		}
+	}
+	//只有一个交易时branch为空
+	if !VerifyMerkleBranch(nil, leaves[0], 0, GetMerkleRoot(leaves[:1])) {
+		t.Error("Test_VerifyMerkleBranch one leaf verify fail")
+	}
+	if VerifyMerkleBranch(nil, leaves[0], 0, nil) {
+		t.Error("Test_VerifyMerkleBranch empty root verify ok")
+	}
+}
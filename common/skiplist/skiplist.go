@@ -0,0 +1,157 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package skiplist 实现按Score从大到小排序的跳跃表，
+// Score相同的元素按插入的先后顺序排列
+package skiplist
+
+import (
+	"math/rand"
+)
+
+const maxLevel = 32
+const prob = 0.35
+
+// SkipValue 跳跃表中保存的元素
+type SkipValue struct {
+	Score int64
+	Value interface{}
+}
+
+type skipListNode struct {
+	value *SkipValue
+	next  []*skipListNode
+	prev  *skipListNode
+}
+
+// SkipList 跳跃表
+type SkipList struct {
+	header *skipListNode
+	tail   *skipListNode
+	level  int
+	count  int
+	random *rand.Rand
+}
+
+// NewSkipList 创建一个空的跳跃表
+func NewSkipList() *SkipList {
+	return &SkipList{
+		header: &skipListNode{next: make([]*skipListNode, maxLevel)},
+		level:  1,
+		random: rand.New(rand.NewSource(1)),
+	}
+}
+
+func (sl *SkipList) randomLevel() int {
+	level := 1
+	for level < maxLevel && sl.random.Float64() < prob {
+		level++
+	}
+	return level
+}
+
+// Len 返回跳跃表中元素的个数
+func (sl *SkipList) Len() int {
+	return sl.count
+}
+
+// First 返回Score最大的元素
+func (sl *SkipList) First() *SkipValue {
+	if sl.header.next[0] == nil {
+		return nil
+	}
+	return sl.header.next[0].value
+}
+
+// Last 返回Score最小的元素，Score相同时返回最后插入的元素
+func (sl *SkipList) Last() *SkipValue {
+	if sl.tail == nil {
+		return nil
+	}
+	return sl.tail.value
+}
+
+// Insert 插入元素，Score相同的元素插入到已有元素之后
+func (sl *SkipList) Insert(value *SkipValue) {
+	update := make([]*skipListNode, maxLevel)
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.next[i] != nil && x.next[i].value.Score >= value.Score {
+			x = x.next[i]
+		}
+		update[i] = x
+	}
+	level := sl.randomLevel()
+	if level > sl.level {
+		for i := sl.level; i < level; i++ {
+			update[i] = sl.header
+		}
+		sl.level = level
+	}
+	node := &skipListNode{value: value, next: make([]*skipListNode, level)}
+	for i := 0; i < level; i++ {
+		node.next[i] = update[i].next[i]
+		update[i].next[i] = node
+	}
+	if update[0] != sl.header {
+		node.prev = update[0]
+	}
+	if node.next[0] != nil {
+		node.next[0].prev = node
+	} else {
+		sl.tail = node
+	}
+	sl.count++
+}
+
+// Delete 删除Score和Value都相等的元素，删除成功返回true
+func (sl *SkipList) Delete(value *SkipValue) bool {
+	update := make([]*skipListNode, maxLevel)
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.next[i] != nil && x.next[i].value.Score > value.Score {
+			x = x.next[i]
+		}
+		update[i] = x
+	}
+	//Score相同的元素按顺序查找Value相等的节点
+	for {
+		node := update[0].next[0]
+		if node == nil || node.value.Score != value.Score {
+			return false
+		}
+		if node.value.Value == value.Value {
+			break
+		}
+		for i := 0; i < sl.level && update[i].next[i] == node; i++ {
+			update[i] = node
+		}
+	}
+	node := update[0].next[0]
+	for i := 0; i < sl.level; i++ {
+		if update[i].next[i] != node {
+			break
+		}
+		update[i].next[i] = node.next[i]
+	}
+	if node.next[0] != nil {
+		node.next[0].prev = node.prev
+	} else {
+		sl.tail = node.prev
+	}
+	for sl.level > 1 && sl.header.next[sl.level-1] == nil {
+		sl.level--
+	}
+	sl.count--
+	return true
+}
+
+// Walk 按Score从大到小遍历跳跃表，cb返回false时停止遍历
+func (sl *SkipList) Walk(cb func(value *SkipValue) bool) {
+	for x := sl.header.next[0]; x != nil; x = x.next[0] {
+		if !cb(x.value) {
+			return
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package skiplist
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func walkValues(sl *SkipList) []*SkipValue {
+	var values []*SkipValue
+	sl.Walk(func(value *SkipValue) bool {
+		values = append(values, value)
+		return true
+	})
+	return values
+}
+
+func TestInsertOrder(t *testing.T) {
+	sl := NewSkipList()
+	assert.Nil(t, sl.First())
+	assert.Nil(t, sl.Last())
+
+	v1 := &SkipValue{Score: 10, Value: "a"}
+	v2 := &SkipValue{Score: 30, Value: "b"}
+	v3 := &SkipValue{Score: 10, Value: "c"}
+	v4 := &SkipValue{Score: 20, Value: "d"}
+	sl.Insert(v1)
+	sl.Insert(v2)
+	sl.Insert(v3)
+	sl.Insert(v4)
+	assert.Equal(t, 4, sl.Len())
+	assert.Equal(t, []*SkipValue{v2, v4, v1, v3}, walkValues(sl))
+	assert.Equal(t, v2, sl.First())
+	assert.Equal(t, v3, sl.Last())
+
+	var count int
+	sl.Walk(func(value *SkipValue) bool {
+		count++
+		return count < 2
+	})
+	assert.Equal(t, 2, count)
+}
+
+func TestDelete(t *testing.T) {
+	sl := NewSkipList()
+	v1 := &SkipValue{Score: 10, Value: "a"}
+	v2 := &SkipValue{Score: 10, Value: "b"}
+	v3 := &SkipValue{Score: 10, Value: "c"}
+	sl.Insert(v1)
+	sl.Insert(v2)
+	sl.Insert(v3)
+
+	assert.False(t, sl.Delete(&SkipValue{Score: 10, Value: "d"}))
+	assert.False(t, sl.Delete(&SkipValue{Score: 11, Value: "a"}))
+	assert.True(t, sl.Delete(&SkipValue{Score: 10, Value: "b"}))
+	assert.Equal(t, []*SkipValue{v1, v3}, walkValues(sl))
+	assert.True(t, sl.Delete(&SkipValue{Score: 10, Value: "c"}))
+	assert.Equal(t, v1, sl.Last())
+	assert.True(t, sl.Delete(&SkipValue{Score: 10, Value: "a"}))
+	assert.Equal(t, 0, sl.Len())
+	assert.Nil(t, sl.First())
+	assert.Nil(t, sl.Last())
+}
+
+func TestRandomInsertDelete(t *testing.T) {
+	sl := NewSkipList()
+	r := rand.New(rand.NewSource(0))
+	var values []*SkipValue
+	for i := 0; i < 2000; i++ {
+		v := &SkipValue{Score: r.Int63n(100), Value: i}
+		values = append(values, v)
+		sl.Insert(v)
+	}
+	for i := 0; i < 1000; i++ {
+		j := r.Intn(len(values))
+		assert.True(t, sl.Delete(values[j]))
+		values = append(values[:j], values[j+1:]...)
+	}
+	sort.SliceStable(values, func(i, j int) bool {
+		return values[i].Score > values[j].Score
+	})
+	assert.Equal(t, len(values), sl.Len())
+	assert.Equal(t, values, walkValues(sl))
+	assert.Equal(t, values[len(values)-1], sl.Last())
+}
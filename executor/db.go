@@ -23,6 +23,13 @@ type StateDB struct {
 	height    int64
 	local     *db.SimpleMVCC
 	opt       *StateDBOption
+	//并行执行时记录读过和写入的key，用于检测交易之间的冲突
+	reads map[string]bool
+	dirty map[string]bool
+	//交易执行时计量读写消耗的gas
+	meter *gasMeter
+	//查询时状态可能已经被裁剪，需要返回错误
+	checkState bool
 }
 
 type StateDBOption struct {
@@ -61,6 +68,36 @@ func (s *StateDB) enableMVCC() {
 	}
 }
 
+//StateDB.enableStateCheck 状态不存在或者已经被裁剪时返回ErrStateNotExist，用于查询
+func (s *StateDB) enableStateCheck() {
+	s.checkState = true
+}
+
+//StateDB.enableTrace 开启读写key的记录
+func (s *StateDB) enableTrace() {
+	s.reads = make(map[string]bool)
+	s.dirty = make(map[string]bool)
+}
+
+//StateDB.isDirty 判断keys中是否有被写入过的key
+func (s *StateDB) isDirty(keys map[string]bool) bool {
+	for key := range keys {
+		if s.dirty[key] {
+			return true
+		}
+	}
+	return false
+}
+
+//StateDB.getWrites 返回写入到cache的全部数据，被删除的key对应的值为nil
+func (s *StateDB) getWrites() map[string][]byte {
+	writes := make(map[string][]byte, len(s.dirty))
+	for key := range s.dirty {
+		writes[key] = s.cache[key]
+	}
+	return writes
+}
+
 func (s *StateDB) Begin() {
 	s.intx = true
 	s.keys = nil
@@ -76,6 +113,9 @@ func (s *StateDB) Rollback() {
 func (s *StateDB) Commit() {
 	for k, v := range s.txcache {
 		s.cache[k] = v
+		if s.dirty != nil {
+			s.dirty[k] = true
+		}
 	}
 	s.intx = false
 	s.keys = nil
@@ -93,11 +133,17 @@ func (s *StateDB) resetTx() {
 func (s *StateDB) Get(key []byte) ([]byte, error) {
 	v, err := s.get(key)
 	debugAccount("==get==", key, v)
+	if err := s.meter.consume(types.GasStateGet + int64(len(key)+len(v))*types.GasPerByte); err != nil {
+		return nil, err
+	}
 	return v, err
 }
 
 func (s *StateDB) get(key []byte) ([]byte, error) {
 	skey := string(key)
+	if s.reads != nil {
+		s.reads[skey] = true
+	}
 	if s.intx && s.txcache != nil {
 		if value, ok := s.txcache[skey]; ok {
 			return value, nil
@@ -116,9 +162,17 @@ func (s *StateDB) get(key []byte) ([]byte, error) {
 		return nil, types.ErrNotFound
 	}
 	query := &types.StoreGet{s.stateHash, [][]byte{key}}
-	msg := s.client.NewMessage("store", types.EventStoreGet, query)
+	ty := int64(types.EventStoreGet)
+	if s.checkState {
+		ty = types.EventStoreGetState
+	}
+	msg := s.client.NewMessage("store", ty, query)
 	s.client.Send(msg, true)
 	resp, err := s.client.Wait(msg)
+	//查询历史状态时，状态可能已经被裁剪
+	if err == types.ErrStateNotExist {
+		return nil, err
+	}
 	if err != nil {
 		panic(err) //no happen for ever
 	}
@@ -159,6 +213,9 @@ func (s *StateDB) GetSetKeys() (keys []string) {
 
 func (s *StateDB) Set(key []byte, value []byte) error {
 	debugAccount("==set==", key, value)
+	if err := s.meter.consume(types.GasStateSet + int64(len(key)+len(value))*types.GasPerByte); err != nil {
+		return err
+	}
 	skey := string(key)
 	if s.intx {
 		if s.txcache == nil {
@@ -168,6 +225,9 @@ func (s *StateDB) Set(key []byte, value []byte) error {
 		setmap(s.txcache, skey, value)
 	} else {
 		setmap(s.cache, skey, value)
+		if s.dirty != nil {
+			s.dirty[skey] = true
+		}
 	}
 	return nil
 }
@@ -195,19 +255,64 @@ type LocalDB struct {
 	db.TransactionDB
 	cache  map[string][]byte
 	client queue.Client
+	meter  *gasMeter
+	//并行执行时记录读过和写入的key，执行器在Exec中可以读写localdb
+	reads map[string]bool
+	dirty map[string]bool
+	//localdb只保存最新的数据，查询历史状态时不能读取
+	history bool
 }
 
 func NewLocalDB(client queue.Client) db.KVDB {
 	return &LocalDB{cache: make(map[string][]byte), client: client}
 }
 
+//LocalDB.disableHistory 查询历史状态时禁止读取localdb，避免返回和状态不一致的数据
+func (l *LocalDB) disableHistory() {
+	l.history = true
+}
+
 func (l *LocalDB) Get(key []byte) ([]byte, error) {
+	if l.history {
+		return nil, types.ErrHistoryLocalDB
+	}
 	value, err := l.get(key)
 	debugAccount("==lget==", key, value)
+	if err := l.meter.consume(types.GasLocalGet + int64(len(key)+len(value))*types.GasPerByte); err != nil {
+		return nil, err
+	}
 	return value, err
 }
 
+//LocalDB.enableTrace 开启读写key的记录
+func (l *LocalDB) enableTrace() {
+	l.reads = make(map[string]bool)
+	l.dirty = make(map[string]bool)
+}
+
+//LocalDB.isDirty 判断keys中是否有被写入过的key
+func (l *LocalDB) isDirty(keys map[string]bool) bool {
+	for key := range keys {
+		if l.dirty[key] {
+			return true
+		}
+	}
+	return false
+}
+
+//LocalDB.getWrites 返回写入到cache的全部数据，被删除的key对应的值为nil
+func (l *LocalDB) getWrites() map[string][]byte {
+	writes := make(map[string][]byte, len(l.dirty))
+	for key := range l.dirty {
+		writes[key] = l.cache[key]
+	}
+	return writes
+}
+
 func (l *LocalDB) get(key []byte) ([]byte, error) {
+	if l.reads != nil {
+		l.reads[string(key)] = true
+	}
 	if value, ok := l.cache[string(key)]; ok {
 		return value, nil
 	}
@@ -234,6 +339,9 @@ func (l *LocalDB) get(key []byte) ([]byte, error) {
 func (l *LocalDB) Set(key []byte, value []byte) error {
 	debugAccount("==lset==", key, value)
 	setmap(l.cache, string(key), value)
+	if l.dirty != nil {
+		l.dirty[string(key)] = true
+	}
 	return nil
 }
 
@@ -250,6 +358,9 @@ func (db *LocalDB) BatchGet(keys [][]byte) (values [][]byte, err error) {
 
 //从数据库中查询数据列表，set 中的cache 更新不会影响这个list
 func (l *LocalDB) List(prefix, key []byte, count, direction int32) ([][]byte, error) {
+	if l.history {
+		return nil, types.ErrHistoryLocalDB
+	}
 	query := &types.LocalDBList{Prefix: prefix, Key: key, Count: count, Direction: direction}
 	msg := l.client.NewMessage("blockchain", types.EventLocalList, query)
 	l.client.Send(msg, true)
@@ -258,6 +369,13 @@ func (l *LocalDB) List(prefix, key []byte, count, direction int32) ([][]byte, er
 		panic(err) //no happen for ever
 	}
 	values := resp.GetData().(*types.LocalReplyValue).Values
+	gas := types.GasLocalList
+	for _, value := range values {
+		gas += int64(len(value)) * types.GasPerByte
+	}
+	if err := l.meter.consume(gas); err != nil {
+		return nil, err
+	}
 	if values == nil {
 		//panic(string(key))
 		return nil, types.ErrNotFound
@@ -267,6 +385,10 @@ func (l *LocalDB) List(prefix, key []byte, count, direction int32) ([][]byte, er
 
 //从数据库中查询指定前缀的key的数量
 func (l *LocalDB) PrefixCount(prefix []byte) (count int64) {
+	//没有办法返回错误，查询历史状态时返回0
+	if l.history {
+		return 0
+	}
 	query := &types.ReqKey{Key: prefix}
 	msg := l.client.NewMessage("blockchain", types.EventLocalPrefixCount, query)
 	l.client.Send(msg, true)
@@ -275,5 +397,7 @@ func (l *LocalDB) PrefixCount(prefix []byte) (count int64) {
 		panic(err) //no happen for ever
 	}
 	count = resp.GetData().(*types.Int64).Data
+	//gas超出限制时交易执行失败，这里不需要返回错误
+	l.meter.consume(types.GasLocalList)
 	return
 }
@@ -96,3 +96,21 @@ func TestStateDBTxGetOld(t *testing.T) {
 	//fork 之前有bug，这里读到了脏数据
 	assert.Equal(t, v, []byte("v11"))
 }
+
+func TestLocalDBTrace(t *testing.T) {
+	block := NewLocalDB(nil).(*LocalDB)
+	block.enableTrace()
+	unit := NewLocalDB(nil).(*LocalDB)
+	unit.enableTrace()
+	unit.Set([]byte("k1"), []byte("v1"))
+	v, err := unit.Get([]byte("k1"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("v1"), v)
+	assert.True(t, unit.reads["k1"])
+	assert.Equal(t, map[string][]byte{"k1": []byte("v1")}, unit.getWrites())
+
+	//区块的localdb没有写入k1，预执行的结果有效
+	assert.False(t, block.isDirty(unit.reads))
+	block.Set([]byte("k1"), []byte("v0"))
+	assert.True(t, block.isDirty(unit.reads))
+}
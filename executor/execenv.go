@@ -29,6 +29,9 @@ type executor struct {
 	txs        []*types.Transaction
 	api        client.QueueProtocolAPI
 	receipts   []*types.ReceiptData
+	//区块中已经消耗的gas
+	gasUsed  int64
+	outOfGas bool
 }
 
 func newExecutor(stateHash []byte, exec *Executor, height, blocktime int64, difficulty uint64,
@@ -93,9 +96,45 @@ func (e *executor) processFee(tx *types.Transaction) (*types.Receipt, error) {
 	return nil, types.ErrNoBalance
 }
 
+//开启sequentialNonce并且到达ForkSequentialNonce高度后，才检查交易的nonce
+func (e *executor) isSequentialNonce() bool {
+	return types.IsEnableFork(e.height, "ForkSequentialNonce", types.IsEnable("sequentialNonce"))
+}
+
+//开启sequentialNonce后，交易的nonce必须等于账户当前的nonce，执行后账户nonce加一
+//交易组中同一账户的多笔交易，nonce依次递增。返回执行后需要写入的nonce
+func (e *executor) checkNonce(txs []*types.Transaction) ([]*types.KeyValue, error) {
+	var addrs []string
+	nonces := make(map[string]int64)
+	for _, tx := range txs {
+		from := tx.From()
+		nonce, ok := nonces[from]
+		if !ok {
+			nonce = e.coinsAccount.LoadNonce(from)
+			addrs = append(addrs, from)
+		}
+		if tx.Nonce != nonce {
+			return nil, types.ErrNonceNotMatch
+		}
+		nonces[from] = nonce + 1
+	}
+	var kvs []*types.KeyValue
+	for _, addr := range addrs {
+		kvs = append(kvs, e.coinsAccount.GetNonceKVSet(addr, nonces[addr])...)
+	}
+	return kvs, nil
+}
+
+func (e *executor) saveNonce(feelog *types.Receipt, kvs []*types.KeyValue) {
+	for _, kv := range kvs {
+		e.stateDB.Set(kv.Key, kv.Value)
+	}
+	feelog.KV = append(feelog.KV, kvs...)
+}
+
 func (e *executor) cutFeeReceipt(acc *types.Account, receiptBalance proto.Message) *types.Receipt {
 	feelog := &types.ReceiptLog{types.TyLogFee, types.Encode(receiptBalance)}
-	return &types.Receipt{types.ExecPack, e.coinsAccount.GetKVSet(acc), []*types.ReceiptLog{feelog}}
+	return &types.Receipt{types.ExecPack, e.coinsAccount.GetKVSet(acc), []*types.ReceiptLog{feelog}, 0}
 }
 
 func (e *executor) getRealExecName(tx *types.Transaction, index int) []byte {
@@ -167,6 +206,10 @@ func (e *executor) execCheckTx(tx *types.Transaction, index int) error {
 			return types.ErrBalanceLessThanTenTimesFee
 		}
 	}
+	//nonce 小于账户当前nonce的交易不可能再被执行，nonce更大的交易由mempool排队等待
+	if e.isSequentialNonce() && tx.Nonce < e.coinsAccount.LoadNonce(tx.From()) {
+		return types.ErrNonceTooLow
+	}
 	e.setEnv(exec)
 	return exec.CheckTx(tx, index)
 }
@@ -206,29 +249,63 @@ func (execute *executor) execTxGroup(txs []*types.Transaction, index int) ([]*ty
 	if err != nil {
 		return nil, err
 	}
+	var noncekv []*types.KeyValue
+	if execute.isSequentialNonce() {
+		noncekv, err = execute.checkNonce(txs)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := execute.checkBlockGas(); err != nil {
+		return nil, err
+	}
+	//计量gas时手续费也在事务中处理，区块gas不够时整个交易组回滚
+	gasEnable := execute.isGasEnable()
+	gasUsed := execute.gasUsed
+	if gasEnable {
+		execute.stateDB.Begin()
+	}
 	feelog, err := execute.execFee(txs[0], index)
 	if err != nil {
+		if gasEnable {
+			execute.stateDB.Rollback()
+		}
 		return nil, err
 	}
+	execute.saveNonce(feelog, noncekv)
 	//开启内存事务处理，假设系统只有一个thread 执行
 	//如果系统执行失败，回滚到这个状态
 	rollbackLog := copyReceipt(feelog)
-	execute.stateDB.Begin()
+	if !gasEnable {
+		execute.stateDB.Begin()
+	}
 	receipts := make([]*types.Receipt, len(txs))
 	for i := 1; i < len(txs); i++ {
 		receipts[i] = &types.Receipt{Ty: types.ExecPack}
 	}
 	receipts[0], err = execute.execTxOne(feelog, txs[0], index)
+	if err == types.ErrBlockOutOfGas {
+		execute.stateDB.Rollback()
+		return nil, err
+	}
 	if err != nil {
 		//状态数据库回滚
-		if types.IsFork(execute.height, "ForkExecRollback") {
+		if gasEnable || types.IsFork(execute.height, "ForkExecRollback") {
 			execute.stateDB.Rollback()
 		}
+		if gasEnable {
+			execute.restoreFee(rollbackLog.KV)
+		}
 		return receipts, nil
 	}
 	for i := 1; i < len(txs); i++ {
 		//如果有一笔执行失败了，那么全部回滚
 		receipts[i], err = execute.execTxOne(receipts[i], txs[i], index+i)
+		if err == types.ErrBlockOutOfGas {
+			execute.stateDB.Rollback()
+			execute.gasUsed = gasUsed
+			return nil, err
+		}
 		if err != nil {
 			//reset other exec , and break!
 			for k := 1; k < i; k++ {
@@ -240,6 +317,9 @@ func (execute *executor) execTxGroup(txs []*types.Transaction, index int) ([]*ty
 			}
 			//撤销所有的数据库更新
 			execute.stateDB.Rollback()
+			if gasEnable {
+				execute.restoreFee(rollbackLog.KV)
+			}
 			return receipts, nil
 		}
 	}
@@ -299,7 +379,20 @@ func copyReceipt(feelog *types.Receipt) *types.Receipt {
 func (execute *executor) execTxOne(feelog *types.Receipt, tx *types.Transaction, index int) (*types.Receipt, error) {
 	//只有到pack级别的，才会增加index
 	execute.stateDB.(*StateDB).StartTx()
+	meter := execute.newGasMeter(tx)
+	execute.setGasMeter(meter)
 	receipt, err := execute.Exec(tx, index)
+	execute.setGasMeter(nil)
+	if meter != nil {
+		//gas超出限制时，不管执行器是否返回错误，交易都执行失败
+		if gaserr := execute.useGas(feelog, meter); gaserr != nil {
+			err = gaserr
+		}
+		//区块剩余的gas不够，交易不能被打包，由调用者回滚
+		if err == types.ErrBlockOutOfGas {
+			return nil, err
+		}
+	}
 	if err != nil {
 		elog.Error("exec tx error = ", "err", err, "exec", string(tx.Execer), "action", tx.ActionName())
 		//add error log
@@ -379,13 +472,36 @@ func (execute *executor) execTx(tx *types.Transaction, index int) (*types.Receip
 	if err != nil {
 		return nil, err
 	}
+	var noncekv []*types.KeyValue
+	//共识模块构造的挖矿交易不检查nonce
+	if execute.isSequentialNonce() && !(index == 0 && tx.ActionName() == types.MinerAction) {
+		noncekv, err = execute.checkNonce([]*types.Transaction{tx})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := execute.checkBlockGas(); err != nil {
+		return nil, err
+	}
+	//计量gas时手续费和交易在同一个事务中执行，区块gas不够时整笔交易回滚
+	gasEnable := execute.isGasEnable()
+	if gasEnable {
+		execute.stateDB.Begin()
+	}
 	//处理交易手续费(先把手续费收了)
 	//如果收了手续费，表示receipt 至少是pack 级别
 	//收不了手续费的交易才是 error 级别
 	feelog, err := execute.execFee(tx, index)
 	if err != nil {
+		if gasEnable {
+			execute.stateDB.Rollback()
+		}
 		return nil, err
 	}
+	execute.saveNonce(feelog, noncekv)
+	if gasEnable {
+		return execute.execTxWithGas(feelog, tx, index)
+	}
 	//ignore err
 	matchfork := types.IsFork(execute.height, "ForkExecRollback")
 	if matchfork {
@@ -405,6 +521,24 @@ func (execute *executor) execTx(tx *types.Transaction, index int) (*types.Receip
 	return feelog, nil
 }
 
+//executor.execTxWithGas 在已经收取手续费的事务中执行交易
+//区块剩余的gas不够时回滚并返回错误，交易不打包；其他错误只保留手续费
+func (execute *executor) execTxWithGas(feelog *types.Receipt, tx *types.Transaction, index int) (*types.Receipt, error) {
+	feekv := feelog.KV
+	feelog, err := execute.execTxOne(feelog, tx, index)
+	if err == types.ErrBlockOutOfGas {
+		execute.stateDB.Rollback()
+		return nil, err
+	}
+	if err != nil {
+		execute.stateDB.Rollback()
+		execute.restoreFee(feekv)
+		return feelog, nil
+	}
+	execute.stateDB.Commit()
+	return feelog, nil
+}
+
 //allowExec key 行为判断放入 执行器
 /*
 权限控制规则:
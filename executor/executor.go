@@ -43,6 +43,7 @@ type Executor struct {
 	qclient      client.QueueProtocolAPI
 	pluginEnable map[string]bool
 	alias        map[string]string
+	parallel     int
 }
 
 func execInit(sub map[string][]byte) {
@@ -72,6 +73,7 @@ func New(cfg *types.Exec, sub map[string][]byte) *Executor {
 	exec.pluginEnable["addrindex"] = !cfg.DisableAddrIndex
 	exec.pluginEnable["txindex"] = true
 	exec.pluginEnable["fee"] = true
+	exec.parallel = int(cfg.ParallelExec)
 
 	exec.alias = make(map[string]string)
 	for _, v := range cfg.Alias {
@@ -112,32 +114,41 @@ func (exec *Executor) SetQueueClient(qcli queue.Client) {
 				go exec.procExecCheckTx(msg)
 			} else if msg.Ty == types.EventBlockChainQuery {
 				go exec.procExecQuery(msg)
+			} else if msg.Ty == types.EventSimulateTx {
+				go exec.procExecSimulateTx(msg)
 			}
 		}
 	}()
 }
 
 func (exec *Executor) procExecQuery(msg queue.Message) {
-	header, err := exec.qclient.GetLastHeader()
+	data := msg.GetData().(*types.ChainExecutor)
+	header, err := exec.queryHeader(data)
 	if err != nil {
 		msg.Reply(exec.client.NewMessage("", types.EventBlockChainQuery, err))
 		return
 	}
-	data := msg.GetData().(*types.ChainExecutor)
 	driver, err := drivers.LoadDriver(data.Driver, header.GetHeight())
 	if err != nil {
 		msg.Reply(exec.client.NewMessage("", types.EventBlockChainQuery, err))
 		return
 	}
-	if data.StateHash == nil {
-		data.StateHash = header.StateHash
+	//不能修改请求，请求的数据可能会被重复使用
+	stateHash := data.StateHash
+	if stateHash == nil {
+		stateHash = header.StateHash
 	}
 	localdb := NewLocalDB(exec.client)
+	//localdb只有最新的数据，按高度查询时只能读取状态
+	if data.StateHash == nil && data.Height > 0 {
+		localdb.(*LocalDB).disableHistory()
+	}
 	driver.SetLocalDB(localdb)
 	opt := &StateDBOption{EnableMVCC: exec.pluginEnable["mvcc"], Height: header.GetHeight()}
 
-	db := NewStateDB(exec.client, data.StateHash, localdb, opt)
+	db := NewStateDB(exec.client, stateHash, localdb, opt)
 	db.(*StateDB).enableMVCC()
+	db.(*StateDB).enableStateCheck()
 	driver.SetStateDB(db)
 
 	//查询的情况下下，执行器不做严格校验，allow，尽可能的加载执行器，并且做查询
@@ -150,6 +161,14 @@ func (exec *Executor) procExecQuery(msg queue.Message) {
 	msg.Reply(exec.client.NewMessage("", types.EventBlockChainQuery, ret))
 }
 
+//Executor.queryHeader 获取查询所在的区块头，没有指定stateHash并且height大于0时查询该高度的状态
+func (exec *Executor) queryHeader(data *types.ChainExecutor) (*types.Header, error) {
+	if data.StateHash != nil || data.Height <= 0 {
+		return exec.qclient.GetLastHeader()
+	}
+	return account.GetHeaderByHeight(exec.qclient, data.Height)
+}
+
 func (exec *Executor) procExecCheckTx(msg queue.Message) {
 	datas := msg.GetData().(*types.ExecTxList)
 	execute := newExecutor(datas.StateHash, exec, datas.Height, datas.BlockTime, datas.Difficulty, datas.Txs, nil)
@@ -179,47 +198,11 @@ func (exec *Executor) procExecTxList(msg queue.Message) {
 	execute.enableMVCC()
 	execute.api = exec.qclient
 	var receipts []*types.Receipt
-	index := 0
-	for i := 0; i < len(datas.Txs); i++ {
-		tx := datas.Txs[i]
-		//检查groupcount
-		if tx.GroupCount < 0 || tx.GroupCount == 1 || tx.GroupCount > 20 {
-			receipts = append(receipts, types.NewErrReceipt(types.ErrTxGroupCount))
-			continue
-		}
-		if tx.GroupCount == 0 {
-			receipt, err := execute.execTx(tx, index)
-			if err != nil {
-				receipts = append(receipts, types.NewErrReceipt(err))
-				continue
-			}
-			receipts = append(receipts, receipt)
-			index++
-			continue
-		}
-		//所有tx.GroupCount > 0 的交易都是错误的交易
-		if !types.IsFork(datas.Height, "ForkTxGroup") {
-			receipts = append(receipts, types.NewErrReceipt(types.ErrTxGroupNotSupport))
-			continue
-		}
-		//判断GroupCount 是否会产生越界
-		if i+int(tx.GroupCount) > len(datas.Txs) {
-			receipts = append(receipts, types.NewErrReceipt(types.ErrTxGroupCount))
-			continue
-		}
-		receiptlist, err := execute.execTxGroup(datas.Txs[i:i+int(tx.GroupCount)], index)
-		i = i + int(tx.GroupCount) - 1
-		if len(receiptlist) > 0 && len(receiptlist) != int(tx.GroupCount) {
-			panic("len(receiptlist) must be equal tx.GroupCount")
-		}
-		if err != nil {
-			for n := 0; n < int(tx.GroupCount); n++ {
-				receipts = append(receipts, types.NewErrReceipt(err))
-			}
-			continue
-		}
-		receipts = append(receipts, receiptlist...)
-		index += int(tx.GroupCount)
+	//ForkExecRollback之前交易失败时状态不回滚，只能串行执行
+	if exec.parallel > 1 && datas.Height > 0 && types.IsFork(datas.Height, "ForkExecRollback") {
+		receipts = exec.execTxListParallel(execute, datas)
+	} else {
+		receipts = execute.execTxList(datas)
 	}
 	msg.Reply(exec.client.NewMessage("", types.EventReceipts,
 		&types.Receipts{receipts}))
@@ -255,6 +238,7 @@ func (exec *Executor) procExecAddBlock(msg queue.Message) {
 			kvset.KV = append(kvset.KV, kvs...)
 		}
 	}
+	//ExecLocal依赖前面交易写入localdb的数据，不能并行执行
 	for i := 0; i < len(b.Txs); i++ {
 		tx := b.Txs[i]
 		kv, err := execute.execLocal(tx, datas.Receipts[i], i)
@@ -6,11 +6,16 @@ package executor_test
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	_ "net/http/pprof"
+	"strings"
 	"testing"
 
+	"github.com/33cn/chain33/account"
+	"github.com/33cn/chain33/common"
 	"github.com/33cn/chain33/common/address"
+	"github.com/33cn/chain33/common/crypto"
 	_ "github.com/33cn/chain33/system"
 	"github.com/33cn/chain33/types"
 	"github.com/33cn/chain33/util"
@@ -171,6 +176,41 @@ func TestExecAllow(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestExecSequentialNonce(t *testing.T) {
+	mock33 := newMockNode()
+	defer mock33.Close()
+	types.S("sequentialNonce", true)
+	defer types.S("sequentialNonce", false)
+	genkey := mock33.GetGenesisKey()
+	mock33.WaitHeight(0)
+	block := mock33.GetBlock(0)
+	addr2, _ := util.Genaddress()
+	createTx := func(nonce int64, amount int64) *types.Transaction {
+		tx := util.CreateCoinsTx(genkey, addr2, amount)
+		tx.Nonce = nonce
+		tx.Sign(types.SECP256K1, genkey)
+		return tx
+	}
+	//nonce 3 不连续，nonce 0 重复使用，都不能被打包
+	txs := []*types.Transaction{createTx(0, types.Coin), createTx(1, types.Coin), createTx(3, types.Coin), createTx(0, 2*types.Coin)}
+	var err error
+	block, err = util.ExecAndCheckBlockCB(mock33.GetClient(), block, txs, func(index int, receipt *types.ReceiptData) error {
+		if (index == 0 || index == 1) && receipt.GetTy() != types.ExecOk {
+			return errors.New("sequential nonce exec ok")
+		}
+		if (index == 2 || index == 3) && receipt != nil {
+			return fmt.Errorf("tx %d nonce not match", index)
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, mock33.GetAccount(block.StateHash, addr2).Balance, 2*types.Coin)
+
+	block, err = util.ExecAndCheckBlock(mock33.GetClient(), block, []*types.Transaction{createTx(2, types.Coin), createTx(3, types.Coin)}, types.ExecOk)
+	assert.Nil(t, err)
+	assert.Equal(t, mock33.GetAccount(block.StateHash, addr2).Balance, 4*types.Coin)
+}
+
 func TestExecBlock2(t *testing.T) {
 	mock33 := newMockNode()
 	defer mock33.Close()
@@ -238,3 +278,257 @@ func BenchmarkExecBlock(b *testing.B) {
 		util.ExecBlock(mock33.GetClient(), nil, block, false, true)
 	}
 }
+
+func TestExecParallel(t *testing.T) {
+	//创世地址的私钥
+	genkey := util.TestPrivkeyList[1]
+	var addrs []string
+	var privs []crypto.PrivKey
+	for i := 0; i < 6; i++ {
+		addr, priv := util.Genaddress()
+		addrs = append(addrs, addr)
+		privs = append(privs, priv)
+	}
+	to, _ := util.Genaddress()
+	//区块1：同一账户转出，全部冲突
+	var txs1 []*types.Transaction
+	for _, addr := range addrs {
+		txs1 = append(txs1, util.CreateCoinsTx(genkey, addr, 10*types.Coin))
+	}
+	//区块2：不同账户转出，中间有余额不足的交易和交易组
+	txs2 := []*types.Transaction{
+		util.CreateCoinsTx(privs[0], to, types.Coin),
+		util.CreateCoinsTx(privs[1], to, 100*types.Coin),
+		util.CreateCoinsTx(privs[2], addrs[0], types.Coin),
+	}
+	group, err := types.CreateTxGroup([]*types.Transaction{
+		util.CreateCoinsTx(privs[3], addrs[1], types.Coin),
+		util.CreateCoinsTx(privs[1], addrs[2], types.Coin),
+	})
+	assert.Nil(t, err)
+	group.SignN(0, types.SECP256K1, privs[3])
+	group.SignN(1, types.SECP256K1, privs[1])
+	txs2 = append(txs2, group.GetTxs()...)
+	txs2 = append(txs2, util.CreateCoinsTx(privs[0], addrs[3], types.Coin))
+	//和前面的交易不冲突，直接使用预执行的结果
+	to2, _ := util.Genaddress()
+	to3, _ := util.Genaddress()
+	txs2 = append(txs2, util.CreateCoinsTx(privs[4], to2, types.Coin))
+	txs2 = append(txs2, util.CreateCoinsTx(privs[5], to3, types.Coin))
+
+	cfgstring := testnode.GetDefaultConfigString()
+	execBlocks := func(parallel int32) []*types.BlockDetail {
+		cfg, sub := types.InitCfgString(cfgstring)
+		cfg.Consensus.Minerstart = false
+		cfg.Exec.ParallelExec = parallel
+		mock33 := testnode.NewWithConfig(cfg, sub, nil)
+		defer mock33.Close()
+		mock33.WaitHeight(0)
+		block := mock33.GetBlock(0)
+		var details []*types.BlockDetail
+		for _, txs := range [][]*types.Transaction{txs1, txs2} {
+			detail, _, err := util.ExecBlock(mock33.GetClient(), block.StateHash, util.CreateNewBlock(block, txs), false, true)
+			assert.Nil(t, err)
+			details = append(details, detail)
+			block = detail.Block
+		}
+		return details
+	}
+	checkParallel := func() []*types.BlockDetail {
+		serial := execBlocks(0)
+		parallel := execBlocks(4)
+		for i := range serial {
+			assert.Equal(t, serial[i].Block.StateHash, parallel[i].Block.StateHash)
+			assert.Equal(t, serial[i].Receipts, parallel[i].Receipts)
+			assert.Equal(t, serial[i].KV, parallel[i].KV)
+		}
+		return parallel
+	}
+	parallel := checkParallel()
+	assert.Equal(t, int32(types.ExecPack), parallel[1].Receipts[1].Ty)
+	assert.Equal(t, int32(types.ExecOk), parallel[1].Receipts[5].Ty)
+	assert.Equal(t, 8, len(parallel[1].Receipts))
+
+	//开启gas计量，区块2的gas不够打包全部交易
+	cfgstring = gasConfig(1000000, 60000)
+	defer testnode.GetDefaultConfig()
+	parallel = checkParallel()
+	assert.True(t, len(parallel[1].Receipts) < 8)
+}
+
+func TestExecGasMeter(t *testing.T) {
+	mock33 := newMockNode()
+	defer mock33.Close()
+	types.InitCfgString(gasConfig(1000000, 0))
+	defer testnode.GetDefaultConfig()
+	genkey := mock33.GetGenesisKey()
+	mock33.WaitHeight(0)
+	block := mock33.GetBlock(0)
+	addr2, _ := util.Genaddress()
+	createTx := func(gasLimit int64) *types.Transaction {
+		tx := util.CreateCoinsTx(genkey, addr2, types.Coin)
+		tx.GasLimit = gasLimit
+		tx.Sign(types.SECP256K1, genkey)
+		return tx
+	}
+	txs := []*types.Transaction{createTx(0), createTx(1000), createTx(2000000)}
+	detail, deltx, err := util.ExecBlock(mock33.GetClient(), block.StateHash, util.CreateNewBlock(block, txs), false, true)
+	assert.Nil(t, err)
+	//gasLimit超过链上配置的交易不能被打包
+	assert.Equal(t, 1, len(deltx))
+	assert.Equal(t, 2, len(detail.Receipts))
+	assert.Equal(t, int32(types.ExecOk), detail.Receipts[0].Ty)
+	gasUsed := detail.Receipts[0].GasUsed
+	assert.True(t, gasUsed > 1000)
+	//gas不够时交易执行失败，只扣手续费
+	assert.Equal(t, int32(types.ExecPack), detail.Receipts[1].Ty)
+	assert.Equal(t, int64(1000), detail.Receipts[1].GasUsed)
+	assert.Equal(t, []byte(types.ErrOutOfGas.Error()), detail.Receipts[1].Logs[1].Log)
+	assert.Equal(t, types.Coin, mock33.GetAccount(detail.Block.StateHash, addr2).Balance)
+
+	//区块剩余的gas不够执行的交易不能被打包，也不扣手续费
+	types.InitCfgString(gasConfig(1000000, gasUsed+gasUsed/2))
+	genacc := mock33.GetAccount(detail.Block.StateHash, mock33.GetGenesisAddress())
+	txs = []*types.Transaction{createTx(0), createTx(0), createTx(0)}
+	detail, deltx, err = util.ExecBlock(mock33.GetClient(), detail.Block.StateHash, util.CreateNewBlock(detail.Block, txs), false, true)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(deltx))
+	assert.Equal(t, 1, len(detail.Receipts))
+	assert.Equal(t, int32(types.ExecOk), detail.Receipts[0].Ty)
+	assert.Equal(t, 2*types.Coin, mock33.GetAccount(detail.Block.StateHash, addr2).Balance)
+	genacc2 := mock33.GetAccount(detail.Block.StateHash, mock33.GetGenesisAddress())
+	assert.Equal(t, genacc.Balance-types.Coin-txs[0].Fee, genacc2.Balance)
+}
+
+//gasConfig 返回修改了mver.exec中gas限制的默认配置，为0时不限制
+func gasConfig(maxTxGas, maxBlockGas int64) string {
+	cfgstring := testnode.GetDefaultConfigString()
+	cfgstring = strings.Replace(cfgstring, "maxTxGas=0", fmt.Sprintf("maxTxGas=%d", maxTxGas), 1)
+	cfgstring = strings.Replace(cfgstring, "maxBlockGas=0", fmt.Sprintf("maxBlockGas=%d", maxBlockGas), 1)
+	return cfgstring
+}
+
+func TestSimulateTx(t *testing.T) {
+	mock33 := newMockNode()
+	defer mock33.Close()
+	genkey := mock33.GetGenesisKey()
+	mock33.WaitHeight(0)
+	block := mock33.GetBlock(0)
+	addr2, _ := util.Genaddress()
+	tx := util.CreateCoinsTx(genkey, addr2, types.Coin)
+	reply, err := mock33.GetAPI().SimulateTransaction(&types.ReqSimulateTx{Tx: tx})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(types.ExecOk), reply.Receipt.Ty)
+	assert.Equal(t, int64(1), reply.Height)
+	assert.Equal(t, block.StateHash, reply.StateHash)
+	assert.Equal(t, tx.Fee, reply.Fee)
+	var found bool
+	for _, kv := range reply.KV {
+		if string(kv.Key) != string(account.NewCoinsAccount().AccountKey(addr2)) {
+			continue
+		}
+		found = true
+		assert.Nil(t, kv.Prev)
+		var acc types.Account
+		assert.Nil(t, types.Decode(kv.Current, &acc))
+		assert.Equal(t, types.Coin, acc.Balance)
+	}
+	assert.True(t, found)
+	//模拟执行不修改状态
+	assert.Equal(t, int64(0), mock33.GetAccount(block.StateHash, addr2).Balance)
+
+	//没有签名的交易需要提供公钥
+	unsigned := *tx
+	unsigned.Signature = nil
+	_, err = mock33.GetAPI().SimulateTransaction(&types.ReqSimulateTx{Tx: &unsigned})
+	assert.Equal(t, types.ErrSign, err)
+	reply, err = mock33.GetAPI().SimulateTransaction(&types.ReqSimulateTx{Tx: &unsigned, Pubkey: genkey.PubKey().Bytes()})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(types.ExecOk), reply.Receipt.Ty)
+	reply, err = mock33.GetAPI().SimulateTransaction(&types.ReqSimulateTx{Tx: &unsigned, Pubkey: genkey.PubKey().Bytes(), SignType: types.SECP256K1})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(types.ExecOk), reply.Receipt.Ty)
+}
+
+func TestQueryBalanceByHeight(t *testing.T) {
+	//需要打包区块
+	cfg, sub := testnode.GetDefaultConfig()
+	mock33 := testnode.NewWithConfig(cfg, sub, nil)
+	defer mock33.Close()
+	genkey := mock33.GetGenesisKey()
+	mock33.WaitHeight(0)
+	addr2, _ := util.Genaddress()
+	mock33.SendTx(util.CreateCoinsTx(genkey, addr2, types.Coin))
+	mock33.Wait()
+	mock33.SendTx(util.CreateCoinsTx(genkey, addr2, types.Coin))
+	mock33.Wait()
+
+	api := mock33.GetAPI()
+	accountdb := account.NewCoinsAccount()
+	getBalance := func(height int64) (int64, error) {
+		accs, err := accountdb.GetBalance(api, &types.ReqBalance{Addresses: []string{addr2}, Execer: "coins", Height: height})
+		if err != nil {
+			return 0, err
+		}
+		return accs[0].Balance, nil
+	}
+	last, err := api.GetLastHeader()
+	assert.Nil(t, err)
+	balance, err := getBalance(0)
+	assert.Nil(t, err)
+	assert.Equal(t, 2*types.Coin, balance)
+	balance, err = getBalance(last.Height - 1)
+	assert.Nil(t, err)
+	assert.Equal(t, types.Coin, balance)
+	balance, err = getBalance(last.Height)
+	assert.Nil(t, err)
+	assert.Equal(t, 2*types.Coin, balance)
+	_, err = getBalance(last.Height + 100)
+	assert.NotNil(t, err)
+
+	//执行器的查询也可以指定高度
+	query := &types.ChainExecutor{Driver: "manage", FuncName: "GetConfigItem", Param: types.Encode(&types.ReqString{Data: "token-blacklist"})}
+	query.Height = last.Height - 1
+	_, err = api.QueryChain(query)
+	assert.Nil(t, err)
+	query.Height = last.Height + 100
+	_, err = api.QueryChain(query)
+	assert.NotNil(t, err)
+
+	//localdb只有最新的数据，按高度查询时不能读取
+	query = &types.ChainExecutor{Driver: "coins", FuncName: "GetTxsByAddr", Param: types.Encode(&types.ReqAddr{Addr: addr2, Count: 10})}
+	_, err = api.QueryChain(query)
+	assert.NotEqual(t, types.ErrHistoryLocalDB, err)
+	query.Height = last.Height - 1
+	_, err = api.QueryChain(query)
+	assert.Equal(t, types.ErrHistoryLocalDB, err)
+
+	//状态不存在时返回错误，而不是空的账户
+	_, err = accountdb.GetBalance(api, &types.ReqBalance{Addresses: []string{addr2}, Execer: "coins", StateHash: common.ToHex(common.Sha256([]byte("notexist")))})
+	assert.Equal(t, types.ErrStateNotExist, err)
+}
+
+func TestExecBlockMvccdbStore(t *testing.T) {
+	cfg, sub := testnode.GetDefaultConfig()
+	cfg.Store.Name = "mvccdb"
+	mock33 := testnode.NewWithConfig(cfg, sub, nil)
+	defer mock33.Close()
+	genkey := mock33.GetGenesisKey()
+	mock33.WaitHeight(0)
+	addr2, _ := util.Genaddress()
+	mock33.SendTx(util.CreateCoinsTx(genkey, addr2, types.Coin))
+	mock33.Wait()
+	mock33.SendTx(util.CreateCoinsTx(genkey, addr2, types.Coin))
+	mock33.Wait()
+
+	api := mock33.GetAPI()
+	accountdb := account.NewCoinsAccount()
+	last, err := api.GetLastHeader()
+	assert.Nil(t, err)
+	accs, err := accountdb.GetBalance(api, &types.ReqBalance{Addresses: []string{addr2}, Execer: "coins"})
+	assert.Nil(t, err)
+	assert.Equal(t, 2*types.Coin, accs[0].Balance)
+	accs, err = accountdb.GetBalance(api, &types.ReqBalance{Addresses: []string{addr2}, Execer: "coins", Height: last.Height - 1})
+	assert.Nil(t, err)
+	assert.Equal(t, types.Coin, accs[0].Balance)
+}
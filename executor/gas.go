@@ -0,0 +1,115 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package executor
+
+import (
+	"github.com/33cn/chain33/types"
+)
+
+//gasMeter 记录一笔交易执行时读写StateDB和LocalDB消耗的gas
+type gasMeter struct {
+	limit int64
+	used  int64
+	//limit受区块剩余gas限制时，超出后返回ErrBlockOutOfGas
+	blockLimited bool
+}
+
+//gasMeter.consume 消耗gas，超出limit时返回错误
+func (m *gasMeter) consume(gas int64) error {
+	if m == nil {
+		return nil
+	}
+	m.used += gas
+	if m.used > m.limit {
+		return m.err()
+	}
+	return nil
+}
+
+//gasMeter.gasUsed 返回实际消耗的gas，最多为limit
+func (m *gasMeter) gasUsed() int64 {
+	if m.used > m.limit {
+		return m.limit
+	}
+	return m.used
+}
+
+func (m *gasMeter) exceeded() bool {
+	return m.used > m.limit
+}
+
+func (m *gasMeter) err() error {
+	if m.blockLimited {
+		return types.ErrBlockOutOfGas
+	}
+	return types.ErrOutOfGas
+}
+
+//executor.isGasEnable 区块是否计量gas
+func (execute *executor) isGasEnable() bool {
+	maxTxGas, _ := types.GetGasLimit(execute.height)
+	return maxTxGas > 0 && execute.height > 0
+}
+
+//executor.checkBlockGas 区块的gas已经用完时，交易不能被打包
+func (execute *executor) checkBlockGas() error {
+	_, maxBlockGas := types.GetGasLimit(execute.height)
+	if maxBlockGas > 0 && execute.gasUsed >= maxBlockGas {
+		return types.ErrBlockOutOfGas
+	}
+	return nil
+}
+
+//executor.newGasMeter 创建交易的gasMeter，没有开启gas计量时返回nil
+func (execute *executor) newGasMeter(tx *types.Transaction) *gasMeter {
+	if !execute.isGasEnable() {
+		return nil
+	}
+	maxTxGas, maxBlockGas := types.GetGasLimit(execute.height)
+	meter := &gasMeter{limit: maxTxGas}
+	if tx.GasLimit > 0 && tx.GasLimit < meter.limit {
+		meter.limit = tx.GasLimit
+	}
+	if maxBlockGas > 0 && maxBlockGas-execute.gasUsed < meter.limit {
+		meter.limit = maxBlockGas - execute.gasUsed
+		meter.blockLimited = true
+	}
+	return meter
+}
+
+//executor.setGasMeter 设置StateDB和LocalDB的gasMeter，为nil时不计量
+func (execute *executor) setGasMeter(meter *gasMeter) {
+	if statedb, ok := execute.stateDB.(*StateDB); ok {
+		statedb.meter = meter
+	}
+	if localdb, ok := execute.localDB.(*LocalDB); ok {
+		localdb.meter = meter
+	}
+}
+
+//executor.useGas 交易执行结束后把消耗的gas记录到收据和区块中
+//区块剩余的gas不够执行交易时返回ErrBlockOutOfGas，交易不能被打包，也不消耗区块的gas
+func (execute *executor) useGas(feelog *types.Receipt, meter *gasMeter) error {
+	if meter.exceeded() {
+		execute.outOfGas = true
+		if meter.blockLimited {
+			return types.ErrBlockOutOfGas
+		}
+	}
+	gas := meter.gasUsed()
+	feelog.GasUsed += gas
+	execute.gasUsed += gas
+	if meter.exceeded() {
+		return types.ErrOutOfGas
+	}
+	return nil
+}
+
+//executor.restoreFee 回滚交易的全部修改后重新写入手续费和nonce
+func (execute *executor) restoreFee(kvs []*types.KeyValue) {
+	for _, kv := range kvs {
+		execute.stateDB.Set(kv.Key, kv.Value)
+	}
+}
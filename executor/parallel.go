@@ -0,0 +1,199 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package executor
+
+import (
+	"sync"
+
+	"github.com/33cn/chain33/types"
+)
+
+//execUnit 区块中独立执行的单元：一笔交易或者一个交易组
+type execUnit struct {
+	start int
+	count int
+	//不需要执行就能确定的错误，比如GroupCount错误
+	err error
+
+	//并行预执行时假设前面的交易都能被打包，index为预计的交易序号
+	index    int
+	receipts []*types.Receipt
+	packed   bool
+	reads    map[string]bool
+	writes   map[string][]byte
+	//Exec中读写的localdb的key，同样需要检测冲突
+	localReads  map[string]bool
+	localWrites map[string][]byte
+	gasUsed     int64
+	outOfGas    bool
+}
+
+//splitExecUnits 把交易列表按交易组切分成执行单元
+func splitExecUnits(txs []*types.Transaction, height int64) []*execUnit {
+	var units []*execUnit
+	for i := 0; i < len(txs); i++ {
+		tx := txs[i]
+		unit := &execUnit{start: i, count: 1}
+		units = append(units, unit)
+		//检查groupcount
+		if tx.GroupCount < 0 || tx.GroupCount == 1 || tx.GroupCount > 20 {
+			unit.err = types.ErrTxGroupCount
+			continue
+		}
+		if tx.GroupCount == 0 {
+			continue
+		}
+		//所有tx.GroupCount > 0 的交易都是错误的交易
+		if !types.IsFork(height, "ForkTxGroup") {
+			unit.err = types.ErrTxGroupNotSupport
+			continue
+		}
+		//判断GroupCount 是否会产生越界
+		if i+int(tx.GroupCount) > len(txs) {
+			unit.err = types.ErrTxGroupCount
+			continue
+		}
+		unit.count = int(tx.GroupCount)
+		i = i + unit.count - 1
+	}
+	return units
+}
+
+//executor.execUnit 执行一个单元，返回收据以及交易是否被打包
+func (execute *executor) execUnit(txs []*types.Transaction, unit *execUnit, index int) ([]*types.Receipt, bool) {
+	if unit.err != nil {
+		return []*types.Receipt{types.NewErrReceipt(unit.err)}, false
+	}
+	if unit.count == 1 {
+		receipt, err := execute.execTx(txs[unit.start], index)
+		if err != nil {
+			return []*types.Receipt{types.NewErrReceipt(err)}, false
+		}
+		return []*types.Receipt{receipt}, true
+	}
+	receiptlist, err := execute.execTxGroup(txs[unit.start:unit.start+unit.count], index)
+	if len(receiptlist) > 0 && len(receiptlist) != unit.count {
+		panic("len(receiptlist) must be equal tx.GroupCount")
+	}
+	if err != nil {
+		receipts := make([]*types.Receipt, 0, unit.count)
+		for n := 0; n < unit.count; n++ {
+			receipts = append(receipts, types.NewErrReceipt(err))
+		}
+		return receipts, false
+	}
+	return receiptlist, true
+}
+
+//executor.execTxList 串行执行交易列表
+func (execute *executor) execTxList(datas *types.ExecTxList) []*types.Receipt {
+	var receipts []*types.Receipt
+	index := 0
+	for _, unit := range splitExecUnits(datas.Txs, datas.Height) {
+		unitReceipts, packed := execute.execUnit(datas.Txs, unit, index)
+		receipts = append(receipts, unitReceipts...)
+		if packed {
+			index += unit.count
+		}
+	}
+	return receipts
+}
+
+//Executor.execTxListParallel 乐观并行执行交易列表
+//每个执行单元在独立的StateDB和LocalDB上预执行，并记录读写的key；然后按顺序提交，
+//如果读到了前面单元写入的key，或者预计的交易序号不正确，就在区块的StateDB上重新执行。
+//执行的结果和串行执行完全一致
+//ExecLocal(procExecAddBlock)仍然串行执行：插件和执行器在ExecLocal中会直接写localdb的cache，
+//后面交易读到的数据依赖前面交易的写入，返回的kv也必须按交易顺序合并
+func (exec *Executor) execTxListParallel(execute *executor, datas *types.ExecTxList) []*types.Receipt {
+	units := splitExecUnits(datas.Txs, datas.Height)
+	index := 0
+	for _, unit := range units {
+		unit.index = index
+		if unit.err == nil {
+			index += unit.count
+		}
+	}
+	unitch := make(chan *execUnit)
+	var wg sync.WaitGroup
+	for i := 0; i < exec.parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for unit := range unitch {
+				exec.preExecUnit(datas, unit)
+			}
+		}()
+	}
+	for _, unit := range units {
+		if unit.err == nil {
+			unitch <- unit
+		}
+	}
+	close(unitch)
+	wg.Wait()
+
+	statedb := execute.stateDB.(*StateDB)
+	statedb.enableTrace()
+	localdb := execute.localDB.(*LocalDB)
+	localdb.enableTrace()
+	var receipts []*types.Receipt
+	var reexec int
+	index = 0
+	for _, unit := range units {
+		var unitReceipts []*types.Receipt
+		var packed bool
+		if unit.err == nil && unit.index == index && !statedb.isDirty(unit.reads) &&
+			!localdb.isDirty(unit.localReads) && execute.isGasValid(unit) {
+			for key, value := range unit.writes {
+				statedb.Set([]byte(key), value)
+			}
+			for key, value := range unit.localWrites {
+				localdb.Set([]byte(key), value)
+			}
+			execute.gasUsed += unit.gasUsed
+			unitReceipts, packed = unit.receipts, unit.packed
+		} else {
+			if unit.err == nil {
+				reexec++
+			}
+			unitReceipts, packed = execute.execUnit(datas.Txs, unit, index)
+		}
+		receipts = append(receipts, unitReceipts...)
+		if packed {
+			index += unit.count
+		}
+	}
+	elog.Debug("execTxListParallel", "height", datas.Height, "units", len(units), "reexec", reexec)
+	return receipts
+}
+
+//Executor.preExecUnit 在独立的StateDB上预执行一个单元
+func (exec *Executor) preExecUnit(datas *types.ExecTxList, unit *execUnit) {
+	execute := newExecutor(datas.StateHash, exec, datas.Height, datas.BlockTime, datas.Difficulty, datas.Txs, nil)
+	execute.enableMVCC()
+	execute.api = exec.qclient
+	statedb := execute.stateDB.(*StateDB)
+	statedb.enableTrace()
+	localdb := execute.localDB.(*LocalDB)
+	localdb.enableTrace()
+	unit.receipts, unit.packed = execute.execUnit(datas.Txs, unit, unit.index)
+	unit.reads = statedb.reads
+	unit.writes = statedb.getWrites()
+	unit.localReads = localdb.reads
+	unit.localWrites = localdb.getWrites()
+	unit.gasUsed = execute.gasUsed
+	unit.outOfGas = execute.outOfGas
+}
+
+//executor.isGasValid 预执行时区块的gas从0开始计算，区块有gas限制时，
+//只有没有超出gas并且加上之前的gas仍然不超过区块限制，预执行的结果才有效
+func (execute *executor) isGasValid(unit *execUnit) bool {
+	_, maxBlockGas := types.GetGasLimit(execute.height)
+	if maxBlockGas <= 0 {
+		return true
+	}
+	return !unit.outOfGas && execute.gasUsed+unit.gasUsed < maxBlockGas
+}
@@ -0,0 +1,93 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package executor
+
+import (
+	"github.com/33cn/chain33/queue"
+	"github.com/33cn/chain33/types"
+	"github.com/golang/protobuf/proto"
+)
+
+func (exec *Executor) procExecSimulateTx(msg queue.Message) {
+	req := msg.GetData().(*types.ReqSimulateTx)
+	reply, err := exec.simulateTx(req)
+	if err != nil {
+		msg.Reply(exec.client.NewMessage("", types.EventReplySimulateTx, err))
+		return
+	}
+	msg.Reply(exec.client.NewMessage("", types.EventReplySimulateTx, reply))
+}
+
+//Executor.simulateTx 把交易当作下一个区块中的交易执行，执行结果不会写入数据库
+func (exec *Executor) simulateTx(req *types.ReqSimulateTx) (*types.ReplySimulateTx, error) {
+	if req.GetTx() == nil {
+		return nil, types.ErrInvalidParam
+	}
+	//交易组需要一起执行，这里只支持单笔交易
+	if req.Tx.GroupCount != 0 {
+		return nil, types.ErrTxGroupNotSupport
+	}
+	header, err := exec.qclient.GetLastHeader()
+	if err != nil {
+		return nil, err
+	}
+	stateHash := req.StateHash
+	if len(stateHash) == 0 {
+		stateHash = header.StateHash
+	}
+	tx := proto.Clone(req.Tx).(*types.Transaction)
+	if tx.Signature == nil {
+		if len(req.Pubkey) == 0 {
+			return nil, types.ErrSign
+		}
+		signType := req.SignType
+		if signType == 0 {
+			signType = types.SECP256K1
+		}
+		tx.Signature = &types.Signature{Ty: signType, Pubkey: req.Pubkey}
+	}
+	height := header.Height + 1
+	execute := newExecutor(stateHash, exec, height, types.Now().Unix(), uint64(header.Difficulty), []*types.Transaction{tx}, nil)
+	execute.enableMVCC()
+	execute.api = exec.qclient
+	receipt, err := execute.execTx(tx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	//在执行前的状态上读取修改前的数据
+	opt := &StateDBOption{EnableMVCC: exec.pluginEnable["mvcc"], Height: height}
+	prevdb := NewStateDB(exec.client, stateHash, NewLocalDB(exec.client), opt)
+	prevdb.(*StateDB).enableMVCC()
+	reply := &types.ReplySimulateTx{
+		Receipt:   &types.ReceiptData{Ty: receipt.Ty, Logs: receipt.Logs, GasUsed: receipt.GasUsed},
+		Fee:       receiptFee(receipt),
+		Height:    height,
+		StateHash: stateHash,
+	}
+	for _, kv := range receipt.KV {
+		prev, err := prevdb.Get(kv.Key)
+		if err != nil && err != types.ErrNotFound {
+			return nil, err
+		}
+		reply.KV = append(reply.KV, &types.SimulateKV{Key: kv.Key, Prev: prev, Current: kv.Value})
+	}
+	return reply, nil
+}
+
+//receiptFee 从收据的手续费日志中计算扣除的手续费
+func receiptFee(receipt *types.Receipt) int64 {
+	for _, l := range receipt.Logs {
+		if l.Ty != types.TyLogFee {
+			continue
+		}
+		var transfer types.ReceiptAccountTransfer
+		if err := types.Decode(l.Log, &transfer); err != nil {
+			return 0
+		}
+		return transfer.GetPrev().GetBalance() - transfer.GetCurrent().GetBalance()
+	}
+	return 0
+}
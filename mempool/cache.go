@@ -5,8 +5,7 @@
 package mempool
 
 import (
-	"container/list"
-
+	"github.com/33cn/chain33/common/skiplist"
 	"github.com/33cn/chain33/types"
 )
 
@@ -15,10 +14,13 @@ import (
 
 type txCache struct {
 	size       int
-	txMap      map[string]*list.Element
-	txList     *list.List
+	txMap      map[string]*Item
+	txList     *skiplist.SkipList
 	txFrontTen []*types.Transaction
 	accMap     map[string][]*types.Transaction
+	sequential bool
+	accQueue   map[string]*accountQueue
+	queued     *skiplist.SkipList
 }
 
 // Item为Mempool中包装交易的数据结构
@@ -32,13 +34,32 @@ type Item struct {
 func newTxCache(cacheSize int64) *txCache {
 	return &txCache{
 		size:       int(cacheSize),
-		txMap:      make(map[string]*list.Element, cacheSize),
-		txList:     list.New(),
+		txMap:      make(map[string]*Item, cacheSize),
+		txList:     skiplist.NewSkipList(),
 		txFrontTen: make([]*types.Transaction, 0),
 		accMap:     make(map[string][]*types.Transaction),
+		accQueue:   make(map[string]*accountQueue),
+		queued:     skiplist.NewSkipList(),
 	}
 }
 
+// txPriority计算交易的优先级，即每千字节的交易费
+func txPriority(tx *types.Transaction) int64 {
+	size := int64(tx.Size())
+	if size == 0 {
+		return tx.Fee
+	}
+	return tx.Fee * 1000 / size
+}
+
+func newItem(tx *types.Transaction) *Item {
+	return &Item{value: tx, priority: txPriority(tx), enterTime: types.Now().Unix()}
+}
+
+func (item *Item) skipValue() *skiplist.SkipValue {
+	return &skiplist.SkipValue{Score: item.priority, Value: item}
+}
+
 // txCache.TxNumOfAccount返回账户在Mempool中交易数量
 func (cache *txCache) TxNumOfAccount(addr string) int64 {
 	return int64(len(cache.accMap[addr]))
@@ -50,41 +71,56 @@ func (cache *txCache) Exists(hash []byte) bool {
 	return exists
 }
 
-// txCache.Push把给定tx添加到txCache；如果tx已经存在txCache中或Mempool已满则返回对应error
+// txCache.Push把给定tx添加到txCache；如果tx已经存在txCache中则返回对应error
+// 同一账户相同nonce的交易每千字节交易费更高时替换原交易；Mempool已满时淘汰优先级最低的交易
+// 开启sequentialNonce时，调用前必须通过SetAccountNonce设置账户当前的nonce
 func (cache *txCache) Push(tx *types.Transaction) error {
 	hash := tx.Hash()
-	if cache.Exists(hash) {
-		addedItem, ok := cache.txMap[string(hash)].Value.(*Item)
-		if !ok {
-			mlog.Error("mempoolItemError", "item", cache.txMap[string(hash)].Value)
-			return types.ErrTxExist
-		}
-		addedTime := addedItem.enterTime
-		if types.Now().Unix()-addedTime < mempoolDupResendInterval {
+	if addedItem, ok := cache.txMap[string(hash)]; ok {
+		if types.Now().Unix()-addedItem.enterTime < mempoolDupResendInterval {
 			return types.ErrTxExist
-		} else {
-			// 超过2分钟之后的重发交易返回nil，再次发送给P2P，但是不再次加入mempool
-			// 并修改其enterTime，以避免该交易一直在节点间被重发
-			newEnterTime := types.Now().Unix()
-			resendItem := &Item{value: tx, priority: tx.Fee, enterTime: newEnterTime}
-			newItem := cache.txList.InsertAfter(resendItem, cache.txMap[string(hash)])
-			cache.txList.Remove(cache.txMap[string(hash)])
-			cache.txMap[string(hash)] = newItem
-			// ------------------
-			return nil
 		}
+		// 超过2分钟之后的重发交易返回nil，再次发送给P2P，但是不再次加入mempool
+		// 并修改其enterTime，以避免该交易一直在节点间被重发
+		addedItem.enterTime = types.Now().Unix()
+		return nil
 	}
 
-	if cache.txList.Len() >= cache.size {
-		return types.ErrMemFull
+	it := newItem(tx)
+	accountAddr := tx.From()
+	var queue *accountQueue
+	if cache.sequential {
+		queue = cache.accQueue[accountAddr]
+		if tx.Nonce < queue.nonce {
+			return types.ErrNonceTooLow
+		}
+	}
+	replaced := cache.sameNonceTx(accountAddr, tx.Nonce)
+	if replaced != nil {
+		//比较每千字节的交易费，避免更大的交易用更低的费率替换
+		if it.priority <= txPriority(replaced) {
+			return types.ErrTxFeeTooLowToReplace
+		}
+		cache.Remove(replaced.Hash())
+	} else if cache.Size() >= cache.size {
+		lowest, err := cache.evictItem(it, queue)
+		if err != nil {
+			return err
+		}
+		mlog.Debug("mempool full, evict lowest priority tx", "hash", lowest.value.Hash(), "priority", lowest.priority)
+		cache.Remove(lowest.value.Hash())
 	}
 
-	it := &Item{value: tx, priority: tx.Fee, enterTime: types.Now().Unix()}
-	txElement := cache.txList.PushBack(it)
-	cache.txMap[string(hash)] = txElement
+	cache.txMap[string(hash)] = it
+	if cache.sequential {
+		//替换或者淘汰交易后，账户队列可能已经被删除
+		cache.SetAccountNonce(accountAddr, queue.nonce)
+		cache.pushAccountQueue(cache.accQueue[accountAddr], it)
+	} else {
+		cache.txList.Insert(it.skipValue())
+	}
 
 	// 账户交易数量
-	accountAddr := tx.From()
 	cache.accMap[accountAddr] = append(cache.accMap[accountAddr], tx)
 
 	if len(cache.txFrontTen) >= 10 {
@@ -95,6 +131,38 @@ func (cache *txCache) Push(tx *types.Transaction) error {
 	return nil
 }
 
+// txCache.evictItem返回Mempool已满时为新交易腾出空间需要淘汰的交易
+// 不能被打包的queued交易优先被淘汰；nonce不连续的交易只能淘汰优先级更低的queued交易
+func (cache *txCache) evictItem(it *Item, queue *accountQueue) (*Item, error) {
+	packable := queue == nil || it.value.Nonce == queue.nextNonce()
+	if last := cache.queued.Last(); last != nil {
+		lowest := last.Value.(*Item)
+		if packable || it.priority > lowest.priority {
+			return lowest, nil
+		}
+		return nil, types.ErrMemFull
+	}
+	last := cache.txList.Last()
+	if last == nil || !packable {
+		return nil, types.ErrMemFull
+	}
+	lowest := last.Value.(*Item)
+	if it.priority <= lowest.priority {
+		return nil, types.ErrMemFull
+	}
+	return lowest, nil
+}
+
+// txCache.sameNonceTx返回账户在txCache中nonce相同的交易，不存在时返回nil
+func (cache *txCache) sameNonceTx(addr string, nonce int64) *types.Transaction {
+	for _, tx := range cache.accMap[addr] {
+		if tx.Nonce == nonce {
+			return tx
+		}
+	}
+	return nil
+}
+
 // txCache.GetLatestTx返回最新十条加入到txCache的交易
 func (cache *txCache) GetLatestTx() []*types.Transaction {
 	return cache.txFrontTen
@@ -102,32 +170,42 @@ func (cache *txCache) GetLatestTx() []*types.Transaction {
 
 // txCache.Remove移除txCache中给定tx
 func (cache *txCache) Remove(hash []byte) {
-	value := cache.txList.Remove(cache.txMap[string(hash)])
-	delete(cache.txMap, string(hash))
-	// 账户交易数量减1
-	if value == nil {
+	item, ok := cache.txMap[string(hash)]
+	if !ok {
 		return
 	}
-	tx := value.(*Item).value
-	addr := tx.From()
+	cache.txList.Delete(item.skipValue())
+	delete(cache.txMap, string(hash))
+	// 账户交易数量减1
+	addr := item.value.From()
 	if cache.TxNumOfAccount(addr) > 0 {
 		cache.AccountTxNumDecrease(addr, hash)
 	}
+	if cache.sequential {
+		cache.removeAccountQueue(addr, item)
+	}
 }
 
 // txCache.Size返回txCache中已存tx数目
 func (cache *txCache) Size() int {
-	return cache.txList.Len()
+	return len(cache.txMap)
 }
 
 // txCache.SetSize用来设置Mempool容量
 func (cache *txCache) SetSize(newSize int) {
-	if cache.txList.Len() > 0 {
+	if cache.Size() > 0 {
 		panic("only can set a empty size")
 	}
 	cache.size = newSize
 }
 
+// txCache.Walk按优先级从高到低遍历txCache中可以被打包的交易，cb返回false时停止遍历
+func (cache *txCache) Walk(cb func(item *Item) bool) {
+	cache.txList.Walk(func(value *skiplist.SkipValue) bool {
+		return cb(value.Value.(*Item))
+	})
+}
+
 // txCache.GetAccTxs用来获取对应账户地址（列表）中的全部交易详细信息
 func (cache *txCache) GetAccTxs(addrs *types.ReqAddrs) *types.TransactionDetails {
 	res := &types.TransactionDetails{}
@@ -5,10 +5,13 @@
 package mempool
 
 import (
+	"math/rand"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/33cn/chain33/account"
 	"github.com/33cn/chain33/common"
 	clog "github.com/33cn/chain33/common/log"
 	log "github.com/33cn/chain33/common/log/log15"
@@ -25,6 +28,8 @@ func DisableLog() {
 	mlog.SetHandler(log.DiscardHandler())
 }
 
+var coinsAccount = account.NewCoinsAccount()
+
 //--------------------------------------------------------------------------------
 // Module Mempool
 
@@ -44,6 +49,7 @@ type Mempool struct {
 	wg                sync.WaitGroup
 	done              chan struct{}
 	removeBlockTicket *time.Ticker
+	pushRpc           int32
 }
 
 func New(cfg *types.MemPool) *Mempool {
@@ -139,23 +145,22 @@ func (mem *Mempool) GetTxList(hashList *types.TxHashList) []*types.Transaction {
 		dupMap[string(hashList.GetHashes()[i])] = true
 	}
 	var result []*types.Transaction
-	i := 0
-	txlist := mem.cache.txList
-	for v := txlist.Front(); v != nil; v = v.Next() {
-		if v.Value.(*Item).value.IsExpire(mem.header.GetHeight(), mem.header.GetBlockTime()) {
-			continue
-		} else {
-			tx := v.Value.(*Item).value
-			if _, ok := dupMap[string(tx.Hash())]; ok {
-				continue
-			}
-			result = append(result, tx)
-			i++
-			if i == int(minSize) {
-				break
-			}
+	walk := mem.cache.Walk
+	if mem.cache.sequential {
+		walk = mem.cache.walkSequential
+	}
+	// 按优先级从高到低返回交易
+	walk(func(item *Item) bool {
+		tx := item.value
+		if tx.IsExpire(mem.header.GetHeight(), mem.header.GetBlockTime()) {
+			return true
 		}
-	}
+		if _, ok := dupMap[string(tx.Hash())]; ok {
+			return true
+		}
+		result = append(result, tx)
+		return len(result) < int(minSize)
+	})
 	return result
 }
 
@@ -165,8 +170,7 @@ func (mem *Mempool) RemoveExpiredAndDuplicateMempoolTxs() []*types.Transaction {
 	defer mem.proxyMtx.Unlock()
 
 	var result []*types.Transaction
-	for _, v := range mem.cache.txMap {
-		item := v.Value.(*Item)
+	for _, item := range mem.cache.txMap {
 		hash := item.value.Hash()
 		if types.Now().Unix()-item.enterTime >= mempoolExpiredInterval {
 			// 清理滞留Mempool中超过10分钟的交易
@@ -185,9 +189,13 @@ func (mem *Mempool) RemoveExpiredAndDuplicateMempoolTxs() []*types.Transaction {
 func (mem *Mempool) RemoveTxsOfBlock(block *types.Block) bool {
 	mem.proxyMtx.Lock()
 	defer mem.proxyMtx.Unlock()
-	for _, tx := range block.Txs {
+	for i, tx := range block.Txs {
 		hash := tx.Hash()
 		mem.addedTxs.Add(string(hash), nil)
+		// 账户nonce增加，之后nonce连续的交易可以被打包
+		if mem.cache.sequential && !isMinerTx(i, tx) {
+			mem.cache.UpdateAccountNonce(tx.From(), tx.Nonce+1)
+		}
 		exist := mem.cache.Exists(hash)
 		if exist {
 			mem.cache.Remove(hash)
@@ -216,10 +224,20 @@ func (mem *Mempool) DelBlock(block *types.Block) {
 	}
 	blkTxs := block.Txs
 	header := mem.GetHeader()
+	// 回滚后账户nonce减小，已经在Mempool中的交易等待回滚的交易补齐
+	// 倒序处理，同一账户的nonce最终回到区块中最小的nonce
+	if mem.cache.sequential {
+		mem.proxyMtx.Lock()
+		for i := len(blkTxs) - 1; i >= 0; i-- {
+			if !isMinerTx(i, blkTxs[i]) {
+				mem.cache.UpdateAccountNonce(blkTxs[i].From(), blkTxs[i].Nonce)
+			}
+		}
+		mem.proxyMtx.Unlock()
+	}
 	for i := 0; i < len(blkTxs); i++ {
 		tx := blkTxs[i]
-		//当前包括ticket和平行链的第一笔挖矿交易，统一actionName为miner
-		if i == 0 && tx.ActionName() == types.MinerAction {
+		if isMinerTx(i, tx) {
 			continue
 		}
 		groupCount := int(tx.GetGroupCount())
@@ -241,14 +259,74 @@ func (mem *Mempool) DelBlock(block *types.Block) {
 	}
 }
 
+//当前包括ticket和平行链的第一笔挖矿交易，统一actionName为miner
+func isMinerTx(index int, tx *types.Transaction) bool {
+	return index == 0 && tx.ActionName() == types.MinerAction
+}
+
 // Mempool.PushTx将交易推入Mempool，并返回结果（error）
 func (mem *Mempool) PushTx(tx *types.Transaction) error {
 	mem.proxyMtx.Lock()
 	defer mem.proxyMtx.Unlock()
+	return mem.pushTx(tx)
+}
+
+func (mem *Mempool) pushTx(tx *types.Transaction) error {
+	if mem.cache.sequential {
+		from := tx.From()
+		if !mem.cache.HasAccountQueue(from) {
+			nonce, err := mem.getAccountNonce(from)
+			if err != nil {
+				return err
+			}
+			mem.cache.SetAccountNonce(from, nonce)
+		}
+	}
 	err := mem.cache.Push(tx)
 	return err
 }
 
+// Mempool.GetAccountNonce返回账户下一笔交易可以使用的nonce，Mempool中nonce连续的交易也计算在内
+// 没有开启sequentialNonce时nonce只用于去重，返回随机数
+func (mem *Mempool) GetAccountNonce(addr string) (int64, error) {
+	mem.proxyMtx.Lock()
+	defer mem.proxyMtx.Unlock()
+	if !mem.cache.sequential {
+		return rand.Int63(), nil
+	}
+	if q, ok := mem.cache.accQueue[addr]; ok {
+		return q.nextNonce(), nil
+	}
+	return mem.getAccountNonce(addr)
+}
+
+// Mempool.getAccountNonce从store中获取账户在最新区块上的nonce
+func (mem *Mempool) getAccountNonce(addr string) (int64, error) {
+	if mem.client == nil {
+		panic("client not bind message queue.")
+	}
+	get := &types.StoreGet{StateHash: mem.header.GetStateHash(), Keys: [][]byte{coinsAccount.NonceKey(addr)}}
+	msg := mem.client.NewMessage("store", types.EventStoreGet, get)
+	err := mem.client.Send(msg, true)
+	if err != nil {
+		mlog.Error("store closed", "err", err.Error())
+		return 0, err
+	}
+	resp, err := mem.client.Wait(msg)
+	if err != nil {
+		return 0, err
+	}
+	var nonce types.Int64
+	values := resp.GetData().(*types.StoreReplyValue).GetValues()
+	if len(values) > 0 && values[0] != nil {
+		err = types.Decode(values[0], &nonce)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return nonce.Data, nil
+}
+
 // Mempool.GetLatestTx返回最新十条加入到Mempool的交易
 func (mem *Mempool) GetLatestTx() []*types.Transaction {
 	mem.proxyMtx.Lock()
@@ -268,9 +346,9 @@ func (mem *Mempool) ReTrySend() {
 func (mem *Mempool) ReTry() {
 	var result []*types.Transaction
 	mem.proxyMtx.Lock()
-	for _, v := range mem.cache.txMap {
-		if types.Now().Unix()-v.Value.(*Item).enterTime >= mempoolReSendInterval {
-			result = append(result, v.Value.(*Item).value)
+	for _, item := range mem.cache.txMap {
+		if types.Now().Unix()-item.enterTime >= mempoolReSendInterval {
+			result = append(result, item.value)
 		}
 	}
 	mem.proxyMtx.Unlock()
@@ -329,10 +407,9 @@ func (mem *Mempool) RemoveBlockedTxs() {
 
 			mem.proxyMtx.Lock()
 			for _, t := range dupTxs {
-				txValue, exists := mem.cache.txMap[string(t)]
-				if exists {
+				if mem.cache.Exists(t) {
 					mem.addedTxs.Add(string(t), nil)
-					mem.cache.Remove(txValue.Value.(*Item).value.Hash())
+					mem.cache.Remove(t)
 				}
 			}
 			mem.proxyMtx.Unlock()
@@ -381,6 +458,18 @@ func (mem *Mempool) SendTxToP2P(tx *types.Transaction) {
 	mlog.Debug("tx sent to p2p", "tx.Hash", common.ToHex(tx.Hash()))
 }
 
+// Mempool.sendTxToRpc rpc模块开启websocket订阅之后，把新进入Mempool的交易推送给rpc
+func (mem *Mempool) sendTxToRpc(tx *types.Transaction) {
+	if atomic.LoadInt32(&mem.pushRpc) != 1 {
+		return
+	}
+	msg := mem.client.NewMessage("rpc", types.EventTx, tx)
+	err := mem.client.SendTimeout(msg, false, 0)
+	if err != nil {
+		mlog.Error("tx sent to rpc", "tx.Hash", common.ToHex(tx.Hash()), "err", err)
+	}
+}
+
 // Mempool.CheckExpireValid检查交易过期有效性，过期返回false，未过期返回true
 func (mem *Mempool) CheckExpireValid(msg queue.Message) (bool, error) {
 	mem.proxyMtx.Lock()
@@ -463,9 +552,34 @@ func (mem *Mempool) pollLastHeader() {
 func (mem *Mempool) setHeader(h *types.Header) {
 	mem.proxyMtx.Lock()
 	mem.header = h
+	mem.resetSequential(isSequentialNonce(h.GetHeight() + 1))
 	mem.proxyMtx.Unlock()
 }
 
+// Mempool.resetSequential下一个区块跨过ForkSequentialNonce时切换txCache的nonce模式
+// 已有的交易按nonce从小到大重新加入，不满足新模式的交易被丢弃
+func (mem *Mempool) resetSequential(sequential bool) {
+	if mem.cache.sequential == sequential {
+		return
+	}
+	old := mem.cache
+	mem.cache = newTxCache(int64(old.size))
+	mem.cache.sequential = sequential
+	txs := make([]*types.Transaction, 0, len(old.txMap))
+	for _, item := range old.txMap {
+		txs = append(txs, item.value)
+	}
+	sort.Slice(txs, func(i, j int) bool {
+		return txs[i].Nonce < txs[j].Nonce
+	})
+	for _, tx := range txs {
+		err := mem.pushTx(tx)
+		if err != nil {
+			mlog.Debug("resetSequential drop tx", "hash", common.ToHex(tx.Hash()), "err", err)
+		}
+	}
+}
+
 func (mem *Mempool) WaitPollLastHeader() {
 	<-mem.poolHeader
 	//wait sync
@@ -586,7 +700,9 @@ func (mem *Mempool) SetQueueClient(client queue.Client) {
 				m.Reply(mem.client.NewMessage("rpc", types.EventReply,
 					&types.Reply{false, []byte(m.Err().Error())}))
 			} else {
-				mem.SendTxToP2P(m.GetData().(types.TxGroup).Tx())
+				tx := m.GetData().(types.TxGroup).Tx()
+				mem.SendTxToP2P(tx)
+				mem.sendTxToRpc(tx)
 				m.Reply(mem.client.NewMessage("rpc", types.EventReply, &types.Reply{true, nil}))
 			}
 		}
@@ -667,11 +783,24 @@ func (mem *Mempool) SetQueueClient(client queue.Client) {
 				h := lastHeader.(queue.Message).Data.(*types.Header)
 				mem.setHeader(h)
 				mem.DelBlock(block)
+			case types.EventSubscribePush:
+				// 消息类型EventSubscribePush：rpc模块订阅新进入Mempool的交易
+				atomic.StoreInt32(&mem.pushRpc, 1)
+				msg.ReplyErr("EventSubscribePush", nil)
 			case types.EventGetAddrTxs:
 				// 获取Mempool中对应账户（组）所有交易
 				addrs := msg.GetData().(*types.ReqAddrs)
 				txlist := mem.GetAccTxs(addrs)
 				msg.Reply(mem.client.NewMessage("", types.EventReplyAddrTxs, txlist))
+			case types.EventGetAccountNonce:
+				// 获取账户下一笔交易的nonce
+				addr := msg.GetData().(*types.ReqString)
+				nonce, err := mem.GetAccountNonce(addr.Data)
+				if err != nil {
+					msg.Reply(mem.client.NewMessage("", types.EventGetAccountNonce, err))
+				} else {
+					msg.Reply(mem.client.NewMessage("", types.EventGetAccountNonce, &types.Int64{Data: nonce}))
+				}
 			default:
 			}
 			mlog.Debug("mempool", "cost", types.Since(beg), "msg", types.GetEventName(int(msg.Ty)))
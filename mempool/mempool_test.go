@@ -34,21 +34,21 @@ var (
 	amount     = int64(1e8)
 	v          = &cty.CoinsAction_Transfer{&types.AssetsTransfer{Amount: amount}}
 	transfer   = &cty.CoinsAction{Value: v, Ty: cty.CoinsActionTransfer}
-	tx1        = &types.Transaction{Execer: []byte("coins"), Payload: types.Encode(transfer), Fee: 1000000, Expire: 2, To: toAddr}
-	tx2        = &types.Transaction{Execer: []byte("coins"), Payload: types.Encode(transfer), Fee: 100000000, Expire: 0, To: toAddr}
-	tx3        = &types.Transaction{Execer: []byte("coins"), Payload: types.Encode(transfer), Fee: 200000000, Expire: 0, To: toAddr}
-	tx4        = &types.Transaction{Execer: []byte("coins"), Payload: types.Encode(transfer), Fee: 300000000, Expire: 0, To: toAddr}
-	tx5        = &types.Transaction{Execer: []byte("coins"), Payload: types.Encode(transfer), Fee: 400000000, Expire: 0, To: toAddr}
-	tx6        = &types.Transaction{Execer: []byte("coins"), Payload: types.Encode(transfer), Fee: 500000000, Expire: 0, To: toAddr}
-	tx7        = &types.Transaction{Execer: []byte("coins"), Payload: types.Encode(transfer), Fee: 600000000, Expire: 0, To: toAddr}
-	tx8        = &types.Transaction{Execer: []byte("coins"), Payload: types.Encode(transfer), Fee: 700000000, Expire: 0, To: toAddr}
-	tx9        = &types.Transaction{Execer: []byte("coins"), Payload: types.Encode(transfer), Fee: 800000000, Expire: 0, To: toAddr}
-	tx10       = &types.Transaction{Execer: []byte("coins"), Payload: types.Encode(transfer), Fee: 900000000, Expire: 0, To: toAddr}
-	tx11       = &types.Transaction{Execer: []byte("coins"), Payload: types.Encode(transfer), Fee: 450000000, Expire: 0, To: toAddr}
-	tx12       = &types.Transaction{Execer: []byte("coins"), Payload: types.Encode(transfer), Fee: 460000000, Expire: 0, To: toAddr}
-	tx13       = &types.Transaction{Execer: []byte("coins"), Payload: types.Encode(transfer), Fee: 100, Expire: 0, To: toAddr}
-	tx14       = &types.Transaction{Execer: []byte("coins"), Payload: types.Encode(transfer), Fee: 100000000, Expire: 0, To: "notaddress"}
-	tx15       = &types.Transaction{Execer: []byte("user.write"), Payload: types.Encode(transfer), Fee: 100000000, Expire: 0, To: toAddr}
+	tx1        = &types.Transaction{Execer: []byte("coins"), Payload: types.Encode(transfer), Fee: 1000000, Expire: 2, To: toAddr, Nonce: 1}
+	tx2        = &types.Transaction{Execer: []byte("coins"), Payload: types.Encode(transfer), Fee: 100000000, Expire: 0, To: toAddr, Nonce: 2}
+	tx3        = &types.Transaction{Execer: []byte("coins"), Payload: types.Encode(transfer), Fee: 200000000, Expire: 0, To: toAddr, Nonce: 3}
+	tx4        = &types.Transaction{Execer: []byte("coins"), Payload: types.Encode(transfer), Fee: 300000000, Expire: 0, To: toAddr, Nonce: 4}
+	tx5        = &types.Transaction{Execer: []byte("coins"), Payload: types.Encode(transfer), Fee: 400000000, Expire: 0, To: toAddr, Nonce: 5}
+	tx6        = &types.Transaction{Execer: []byte("coins"), Payload: types.Encode(transfer), Fee: 500000000, Expire: 0, To: toAddr, Nonce: 6}
+	tx7        = &types.Transaction{Execer: []byte("coins"), Payload: types.Encode(transfer), Fee: 600000000, Expire: 0, To: toAddr, Nonce: 7}
+	tx8        = &types.Transaction{Execer: []byte("coins"), Payload: types.Encode(transfer), Fee: 700000000, Expire: 0, To: toAddr, Nonce: 8}
+	tx9        = &types.Transaction{Execer: []byte("coins"), Payload: types.Encode(transfer), Fee: 800000000, Expire: 0, To: toAddr, Nonce: 9}
+	tx10       = &types.Transaction{Execer: []byte("coins"), Payload: types.Encode(transfer), Fee: 900000000, Expire: 0, To: toAddr, Nonce: 10}
+	tx11       = &types.Transaction{Execer: []byte("coins"), Payload: types.Encode(transfer), Fee: 450000000, Expire: 0, To: toAddr, Nonce: 11}
+	tx12       = &types.Transaction{Execer: []byte("coins"), Payload: types.Encode(transfer), Fee: 460000000, Expire: 0, To: toAddr, Nonce: 12}
+	tx13       = &types.Transaction{Execer: []byte("coins"), Payload: types.Encode(transfer), Fee: 100, Expire: 0, To: toAddr, Nonce: 13}
+	tx14       = &types.Transaction{Execer: []byte("coins"), Payload: types.Encode(transfer), Fee: 100000000, Expire: 0, To: "notaddress", Nonce: 14}
+	tx15       = &types.Transaction{Execer: []byte("user.write"), Payload: types.Encode(transfer), Fee: 100000000, Expire: 0, To: toAddr, Nonce: 15}
 )
 
 //var privTo, _ = c.GenKey()
@@ -379,11 +379,12 @@ func TestGetTxList(t *testing.T) {
 	defer mem.Close()
 
 	// add tx
-	hashes, err := add4TxHash(mem.client)
+	_, err := add4TxHash(mem.client)
 	if err != nil {
 		t.Error("add tx error", err.Error())
 		return
 	}
+	hashes := []string{string(tx5.Hash()), string(tx4.Hash()), string(tx3.Hash()), string(tx2.Hash())}
 
 	msg1 := mem.client.NewMessage("mempool", types.EventTxList, &types.TxHashList{Count: 2, Hashes: nil})
 	mem.client.Send(msg1, true)
@@ -402,7 +403,7 @@ func TestGetTxList(t *testing.T) {
 	for i, tx := range txs1 {
 		hashList = append(hashList, tx.Hash())
 		if hashes[i] != string(tx.Hash()) {
-			t.Error("gettxlist not in time order1")
+			t.Error("gettxlist not in fee order1")
 		}
 	}
 	msg2 := mem.client.NewMessage("mempool", types.EventTxList, &types.TxHashList{Count: 1, Hashes: hashList})
@@ -416,7 +417,7 @@ func TestGetTxList(t *testing.T) {
 	for i, tx := range txs2 {
 		hashList = append(hashList, tx.Hash())
 		if hashes[2+i] != string(tx.Hash()) {
-			t.Error("gettxlist not in time order2")
+			t.Error("gettxlist not in fee order2")
 		}
 	}
 OutsideLoop:
@@ -471,11 +472,72 @@ func TestAddMoreTxThanPoolSize(t *testing.T) {
 	mem.client.Send(msg5, true)
 	mem.client.Wait(msg5)
 
-	if mem.Size() != 4 || mem.cache.Exists(tx5.Hash()) {
+	// tx5的交易费高于tx1，tx1被淘汰
+	if mem.Size() != 4 || !mem.cache.Exists(tx5.Hash()) || mem.cache.Exists(tx1.Hash()) {
 		t.Error("TestAddMoreTxThanPoolSize failed", mem.Size(), mem.cache.Exists(tx5.Hash()))
 	}
 }
 
+func TestAddLowFeeTxToFullPool(t *testing.T) {
+	q, mem := initEnv2(4)
+	defer q.Close()
+	defer mem.Close()
+
+	err := add4Tx(mem.client)
+	if err != nil {
+		t.Error("add tx error", err.Error())
+		return
+	}
+
+	msg13 := mem.client.NewMessage("mempool", types.EventTx, tx13)
+	mem.client.Send(msg13, true)
+	resp, _ := mem.client.Wait(msg13)
+
+	if string(resp.GetData().(*types.Reply).GetMsg()) != types.ErrMemFull.Error() {
+		t.Error("TestAddLowFeeTxToFullPool failed", string(resp.GetData().(*types.Reply).GetMsg()))
+	}
+	if mem.Size() != 4 || mem.cache.Exists(tx13.Hash()) {
+		t.Error("TestAddLowFeeTxToFullPool failed", mem.Size())
+	}
+}
+
+func TestReplaceTxByFee(t *testing.T) {
+	q, mem := initEnv(0)
+	defer q.Close()
+	defer mem.Close()
+
+	err := add4Tx(mem.client)
+	if err != nil {
+		t.Error("add tx error", err.Error())
+		return
+	}
+
+	// 与tx2相同nonce，交易费更低，不能替换
+	lowTx := *tx2
+	lowTx.Fee = tx2.Fee / 2
+	lowTx.Sign(types.SECP256K1, privKey)
+	msg := mem.client.NewMessage("mempool", types.EventTx, &lowTx)
+	mem.client.Send(msg, true)
+	resp, _ := mem.client.Wait(msg)
+	if string(resp.GetData().(*types.Reply).GetMsg()) != types.ErrTxFeeTooLowToReplace.Error() {
+		t.Error("TestReplaceTxByFee failed", string(resp.GetData().(*types.Reply).GetMsg()))
+	}
+
+	// 与tx2相同nonce，交易费更高，替换tx2
+	highTx := *tx2
+	highTx.Fee = tx2.Fee * 2
+	highTx.Sign(types.SECP256K1, privKey)
+	msg = mem.client.NewMessage("mempool", types.EventTx, &highTx)
+	mem.client.Send(msg, true)
+	resp, _ = mem.client.Wait(msg)
+	if !resp.GetData().(*types.Reply).GetIsOk() {
+		t.Error("TestReplaceTxByFee failed", string(resp.GetData().(*types.Reply).GetMsg()))
+	}
+	if mem.Size() != 4 || mem.cache.Exists(tx2.Hash()) || !mem.cache.Exists(highTx.Hash()) {
+		t.Error("TestReplaceTxByFee failed", mem.Size())
+	}
+}
+
 func TestRemoveTxOfBlock(t *testing.T) {
 	q, mem := initEnv(0)
 	defer q.Close()
@@ -0,0 +1,185 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"github.com/33cn/chain33/types"
+)
+
+// accountQueue 开启sequentialNonce后账户在Mempool中的交易队列
+// pending中的交易从账户当前nonce开始连续，可以被打包；
+// queued中的交易nonce不连续，等待前面的交易补齐后再转入pending
+type accountQueue struct {
+	nonce   int64
+	pending []*Item
+	queued  map[int64]*Item
+}
+
+func newAccountQueue(nonce int64) *accountQueue {
+	return &accountQueue{nonce: nonce, queued: make(map[int64]*Item)}
+}
+
+// accountQueue.nextNonce返回可以进入pending的下一个nonce
+func (q *accountQueue) nextNonce() int64 {
+	return q.nonce + int64(len(q.pending))
+}
+
+func (q *accountQueue) isEmpty() bool {
+	return len(q.pending) == 0 && len(q.queued) == 0
+}
+
+// txCache.HasAccountQueue判断txCache中是否已经记录了账户的nonce
+func (cache *txCache) HasAccountQueue(addr string) bool {
+	_, ok := cache.accQueue[addr]
+	return ok
+}
+
+// txCache.SetAccountNonce设置账户链上的nonce，账户队列不存在时创建
+func (cache *txCache) SetAccountNonce(addr string, nonce int64) {
+	if !cache.HasAccountQueue(addr) {
+		cache.accQueue[addr] = newAccountQueue(nonce)
+		return
+	}
+	cache.UpdateAccountNonce(addr, nonce)
+}
+
+// txCache.UpdateAccountNonce区块执行或者回滚后更新账户的nonce
+// nonce增加时删除已经不能执行的交易，nonce减少时pending中的交易全部转入queued等待补齐
+func (cache *txCache) UpdateAccountNonce(addr string, nonce int64) {
+	q, ok := cache.accQueue[addr]
+	if !ok || q.nonce == nonce {
+		return
+	}
+	if nonce < q.nonce {
+		cache.demote(q, 0)
+		q.nonce = nonce
+		cache.promote(q)
+		return
+	}
+	q.nonce = nonce
+	var stale []*Item
+	for _, item := range q.pending {
+		if item.value.Nonce < nonce {
+			stale = append(stale, item)
+		}
+	}
+	for n, item := range q.queued {
+		if n < nonce {
+			stale = append(stale, item)
+		}
+	}
+	for _, item := range stale {
+		cache.Remove(item.value.Hash())
+	}
+	//账户交易全部被删除时，队列也被删除
+	if q, ok = cache.accQueue[addr]; ok {
+		cache.promote(q)
+	}
+}
+
+// txCache.pushAccountQueue把交易加入账户队列
+func (cache *txCache) pushAccountQueue(q *accountQueue, item *Item) {
+	if item.value.Nonce != q.nextNonce() {
+		q.queued[item.value.Nonce] = item
+		cache.queued.Insert(item.skipValue())
+		return
+	}
+	q.pending = append(q.pending, item)
+	cache.txList.Insert(item.skipValue())
+	cache.promote(q)
+}
+
+// txCache.removeAccountQueue把交易从账户队列中删除
+// 删除pending中的交易后，之后的交易nonce不再连续，转入queued
+func (cache *txCache) removeAccountQueue(addr string, item *Item) {
+	q, ok := cache.accQueue[addr]
+	if !ok {
+		return
+	}
+	if q.queued[item.value.Nonce] == item {
+		delete(q.queued, item.value.Nonce)
+		cache.queued.Delete(item.skipValue())
+	}
+	for i := range q.pending {
+		if q.pending[i] != item {
+			continue
+		}
+		if item.value.Nonce < q.nonce {
+			//已经被打包的交易，只会出现在pending的队首
+			q.pending = q.pending[i+1:]
+		} else {
+			cache.demote(q, i+1)
+			q.pending = q.pending[:i]
+		}
+		break
+	}
+	if q.isEmpty() {
+		delete(cache.accQueue, addr)
+	}
+}
+
+// txCache.promote把queued中nonce已经连续的交易转入pending
+func (cache *txCache) promote(q *accountQueue) {
+	for {
+		item, ok := q.queued[q.nextNonce()]
+		if !ok {
+			return
+		}
+		delete(q.queued, item.value.Nonce)
+		cache.queued.Delete(item.skipValue())
+		q.pending = append(q.pending, item)
+		cache.txList.Insert(item.skipValue())
+	}
+}
+
+// txCache.demote把pending中从start开始的交易转入queued
+func (cache *txCache) demote(q *accountQueue, start int) {
+	for _, item := range q.pending[start:] {
+		cache.txList.Delete(item.skipValue())
+		q.queued[item.value.Nonce] = item
+		cache.queued.Insert(item.skipValue())
+	}
+	q.pending = q.pending[:start]
+}
+
+// txCache.walkSequential按优先级从高到低遍历可以被打包的交易，同一账户的交易保证按nonce从小到大
+func (cache *txCache) walkSequential(cb func(item *Item) bool) {
+	next := make(map[string]int64)
+	deferred := make(map[string]map[int64]*Item)
+	cache.Walk(func(item *Item) bool {
+		addr := item.value.From()
+		nonce, ok := next[addr]
+		if !ok {
+			q, exist := cache.accQueue[addr]
+			if !exist {
+				return true
+			}
+			nonce = q.nonce
+		}
+		if item.value.Nonce != nonce {
+			if deferred[addr] == nil {
+				deferred[addr] = make(map[int64]*Item)
+			}
+			deferred[addr][item.value.Nonce] = item
+			return true
+		}
+		for {
+			if !cb(item) {
+				return false
+			}
+			nonce++
+			next[addr] = nonce
+			if item, ok = deferred[addr][nonce]; !ok {
+				return true
+			}
+			delete(deferred[addr], nonce)
+		}
+	})
+}
+
+// isSequentialNonce判断高度为height的区块是否要求nonce按账户连续递增
+func isSequentialNonce(height int64) bool {
+	return types.IsEnableFork(height, "ForkSequentialNonce", types.IsEnable("sequentialNonce"))
+}
@@ -0,0 +1,171 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"testing"
+
+	"github.com/33cn/chain33/common/crypto"
+	"github.com/33cn/chain33/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSequentialCache(size int64) *txCache {
+	cache := newTxCache(size)
+	cache.sequential = true
+	return cache
+}
+
+func nonceTx(priv crypto.PrivKey, nonce, fee int64) *types.Transaction {
+	tx := &types.Transaction{Execer: []byte("coins"), Payload: types.Encode(transfer), Fee: fee, To: toAddr, Nonce: nonce}
+	tx.Sign(types.SECP256K1, priv)
+	return tx
+}
+
+func walkNonces(cache *txCache) []int64 {
+	var nonces []int64
+	cache.walkSequential(func(item *Item) bool {
+		nonces = append(nonces, item.value.Nonce)
+		return true
+	})
+	return nonces
+}
+
+func TestSequentialNoncePromote(t *testing.T) {
+	cache := newSequentialCache(100)
+	addr := tx1.From()
+	cache.SetAccountNonce(addr, 3)
+
+	assert.Equal(t, types.ErrNonceTooLow, cache.Push(nonceTx(privKey, 2, 100000)))
+	assert.Nil(t, cache.Push(nonceTx(privKey, 5, 100000)))
+	assert.Nil(t, cache.Push(nonceTx(privKey, 4, 100000)))
+	//nonce不连续的交易不能被打包
+	assert.Equal(t, 0, cache.txList.Len())
+	assert.Equal(t, 2, cache.Size())
+
+	assert.Nil(t, cache.Push(nonceTx(privKey, 3, 100000)))
+	assert.Equal(t, 3, cache.txList.Len())
+	assert.Equal(t, []int64{3, 4, 5}, walkNonces(cache))
+}
+
+func TestSequentialNonceWalkOrder(t *testing.T) {
+	cache := newSequentialCache(100)
+	cache.SetAccountNonce(tx1.From(), 0)
+	//后面的交易交易费更高，仍然要按nonce顺序打包
+	assert.Nil(t, cache.Push(nonceTx(privKey, 0, 100000)))
+	assert.Nil(t, cache.Push(nonceTx(privKey, 1, 200000)))
+	assert.Nil(t, cache.Push(nonceTx(privKey, 2, 300000)))
+	assert.Equal(t, []int64{0, 1, 2}, walkNonces(cache))
+
+	var count int
+	cache.walkSequential(func(item *Item) bool {
+		count++
+		return false
+	})
+	assert.Equal(t, 1, count)
+}
+
+func TestSequentialNonceUpdate(t *testing.T) {
+	cache := newSequentialCache(100)
+	addr := tx1.From()
+	cache.SetAccountNonce(addr, 0)
+	for i := int64(0); i < 4; i++ {
+		assert.Nil(t, cache.Push(nonceTx(privKey, i, 100000)))
+	}
+
+	//区块打包了nonce 0,1
+	cache.UpdateAccountNonce(addr, 2)
+	assert.Equal(t, 2, cache.Size())
+	assert.Equal(t, []int64{2, 3}, walkNonces(cache))
+
+	//区块回滚，nonce 0,1的交易重新加入
+	cache.UpdateAccountNonce(addr, 0)
+	assert.Equal(t, 0, cache.txList.Len())
+	assert.Nil(t, cache.Push(nonceTx(privKey, 1, 100000)))
+	assert.Nil(t, cache.Push(nonceTx(privKey, 0, 100000)))
+	assert.Equal(t, []int64{0, 1, 2, 3}, walkNonces(cache))
+
+	//删除pending中间的交易，之后的交易转入queued
+	var hash []byte
+	cache.Walk(func(item *Item) bool {
+		if item.value.Nonce == 1 {
+			hash = item.value.Hash()
+		}
+		return true
+	})
+	cache.Remove(hash)
+	assert.Equal(t, []int64{0}, walkNonces(cache))
+	assert.Equal(t, 3, cache.Size())
+
+	//账户全部交易被删除后，账户队列也被删除
+	cache.UpdateAccountNonce(addr, 10)
+	assert.Equal(t, 0, cache.Size())
+	assert.False(t, cache.HasAccountQueue(addr))
+}
+
+func TestSequentialNonceFullPool(t *testing.T) {
+	cache := newSequentialCache(2)
+	cache.SetAccountNonce(tx1.From(), 0)
+	assert.Nil(t, cache.Push(nonceTx(privKey, 0, 100000)))
+	assert.Nil(t, cache.Push(nonceTx(privKey, 1, 100000)))
+	//nonce不连续的交易不能淘汰其他交易
+	assert.Equal(t, types.ErrMemFull, cache.Push(nonceTx(privKey, 3, 10000000)))
+
+	_, priv := genaddress()
+	cache.SetAccountNonce(nonceTx(priv, 0, 0).From(), 0)
+	assert.Nil(t, cache.Push(nonceTx(priv, 0, 10000000)))
+	assert.Equal(t, 2, cache.Size())
+	assert.Equal(t, []int64{0, 0}, walkNonces(cache))
+}
+
+func TestSequentialNonceReplace(t *testing.T) {
+	cache := newSequentialCache(100)
+	cache.SetAccountNonce(tx1.From(), 0)
+	tx := nonceTx(privKey, 0, 200000)
+	assert.Nil(t, cache.Push(tx))
+
+	//交易费更低，不能替换
+	assert.Equal(t, types.ErrTxFeeTooLowToReplace, cache.Push(nonceTx(privKey, 0, 100000)))
+
+	//交易费更高但是交易更大，每千字节的交易费更低，不能替换
+	big := &types.Transaction{Execer: []byte("coins"), Payload: types.Encode(transfer), Fee: 300000, To: toAddr}
+	big.Payload = append(big.Payload, make([]byte, 1000)...)
+	big.Sign(types.SECP256K1, privKey)
+	assert.True(t, big.Fee > tx.Fee)
+	assert.Equal(t, types.ErrTxFeeTooLowToReplace, cache.Push(big))
+
+	//每千字节的交易费更高，替换原交易
+	high := nonceTx(privKey, 0, 400000)
+	assert.Nil(t, cache.Push(high))
+	assert.Equal(t, 1, cache.Size())
+	assert.False(t, cache.Exists(tx.Hash()))
+	assert.True(t, cache.Exists(high.Hash()))
+}
+
+func TestSequentialNonceEvictQueued(t *testing.T) {
+	cache := newSequentialCache(2)
+	cache.SetAccountNonce(tx1.From(), 0)
+	assert.Nil(t, cache.Push(nonceTx(privKey, 5, 200000)))
+	assert.Nil(t, cache.Push(nonceTx(privKey, 6, 300000)))
+	assert.Equal(t, 2, cache.queued.Len())
+
+	//nonce不连续的交易只能淘汰优先级更低的queued交易
+	assert.Equal(t, types.ErrMemFull, cache.Push(nonceTx(privKey, 7, 100000)))
+	assert.Nil(t, cache.Push(nonceTx(privKey, 8, 400000)))
+	assert.Equal(t, 2, cache.queued.Len())
+
+	//可以被打包的交易优先淘汰queued交易，即使交易费更低
+	_, priv := genaddress()
+	cache.SetAccountNonce(nonceTx(priv, 0, 0).From(), 0)
+	assert.Nil(t, cache.Push(nonceTx(priv, 0, 100000)))
+	assert.Equal(t, 2, cache.Size())
+	assert.Equal(t, 1, cache.queued.Len())
+	assert.Equal(t, []int64{0}, walkNonces(cache))
+
+	//queued交易补齐nonce后转入pending，不再在queued中
+	cache.UpdateAccountNonce(tx1.From(), 8)
+	assert.Equal(t, 0, cache.queued.Len())
+	assert.Equal(t, 2, cache.txList.Len())
+}
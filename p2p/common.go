@@ -16,6 +16,7 @@ import (
 	"github.com/33cn/chain33/common/crypto"
 	"github.com/33cn/chain33/types"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 )
 
 var P2pComm Comm
@@ -223,6 +224,9 @@ func (c Comm) CheckSign(in *types.P2PPing) bool {
 
 func (c Comm) CollectPeerStat(err error, peer *Peer) {
 	if err != nil {
+		if grpc.Code(err) == codes.DeadlineExceeded {
+			peer.node.nodeInfo.reputation.PenalizeTimeout(peer.Addr(), penaltyTimeout)
+		}
 		peer.peerStat.NotOk()
 	} else {
 		peer.peerStat.Ok()
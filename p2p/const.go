@@ -23,12 +23,39 @@ var (
 	GetAddrFromGitHubInterval   = 5 * time.Minute
 	CheckActivePeersInterVal    = 5 * time.Second
 	CheckBlackListInterVal      = 30 * time.Second
+	downloadTimeout             = 30 * time.Second
 )
 
 const (
 	msgTx           = 1
 	msgBlock        = 2
 	tryMapPortTimes = 20
+	//一次最多获取的状态树节点数
+	maxStateNodes = 1024
+)
+
+//区块下载的参数
+const (
+	minDownloadBatch     = 1
+	maxDownloadBatch     = 32
+	maxDownloadFailed    = 3    //连续失败的次数
+	maxDownloadHeaders   = 1000 //每次请求的区块头数
+	downloadWindow       = 256  //已经提交的高度之后最多同时下载的区块数
+	defaultDownloadSpeed = 1.0
+	scoreWeight          = 0.3 //指数平均中新样本的权重
+)
+
+//节点不良行为的扣分
+const (
+	penaltyInvalidBlock = 50
+	penaltyBadSign      = 20
+	penaltySpam         = 10
+	penaltyTimeout      = 5
+
+	defaultBanScore       = 100
+	defaultBanSeconds     = 3600
+	defaultScoreHalfLife  = 600
+	defaultMaxTxPerSecond = 10000 //每个节点每秒最多广播的新交易数，远大于一个区块能打包的交易数
 )
 
 var (
@@ -57,8 +84,9 @@ const (
 
 // leveldb 中p2p privkey,addrkey
 const (
-	addrkeyTag = "addrs"
-	privKeyTag = "privkey"
+	addrkeyTag   = "addrs"
+	privKeyTag   = "privkey"
+	banKeyPrefix = "ban-"
 )
 
 const (
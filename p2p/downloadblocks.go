@@ -5,195 +5,386 @@
 package p2p
 
 import (
-	"container/list"
+	"bytes"
 	"fmt"
 	"io"
-	"sync"
-	"sync/atomic"
+	"sort"
+	"time"
 
+	"github.com/33cn/chain33/common/merkle"
 	pb "github.com/33cn/chain33/types"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 )
 
-type downloadJob struct {
-	wg            sync.WaitGroup
-	retryList     *list.List
-	p2pcli        *Cli
-	canceljob     int32
-	mtx           sync.Mutex
-	busyPeer      map[string]*peerJob
-	downloadPeers []*Peer
+//区块下载先从高度最高的节点下载区块头并校验ParentHash，再按窗口从多个节点并行下载区块体
+//每个区块体的hash和TxHash都需要和区块头一致，下载完成的区块按高度顺序提交给blockchain
+//每个节点根据吞吐量和延迟打分，分数高的节点优先分配任务，批量的大小随下载的成败自动调整
+//多次下载失败的节点在本次下载中不再分配任务，发送错误数据的节点按不良行为扣分
+//区块的内容由区块头决定，提交给blockchain的区块记为提供区块头的节点，blockchain拒绝区块时扣这个节点的分
+
+//peerScore 节点在本次下载中的状态和分数
+type peerScore struct {
+	peer    *Peer
+	height  int64
+	batch   int
+	busy    bool
+	banned  bool
+	failed  int
+	samples int
+	speed   float64 //每秒下载的区块数，指数平均
+	latency float64 //收到第一个区块的秒数，指数平均
 }
 
-type peerJob struct {
-	pbPeer *pb.Peer
-	limit  int32
+func newPeerScore(peer *Peer, height int64) *peerScore {
+	return &peerScore{peer: peer, height: height, batch: minDownloadBatch}
 }
 
-func NewDownloadJob(p2pcli *Cli, peers []*Peer) *downloadJob {
-	job := new(downloadJob)
-	job.retryList = list.New()
-	job.p2pcli = p2pcli
-	job.busyPeer = make(map[string]*peerJob)
-	job.downloadPeers = peers
-	return job
+//score 吞吐量越高、延迟越低的分数越高，没有下载记录的节点按默认吞吐量计算
+func (s *peerScore) score() float64 {
+	if s.samples == 0 {
+		return defaultDownloadSpeed
+	}
+	return s.speed / (1 + s.latency)
 }
 
-func (d *downloadJob) isBusyPeer(pid string) bool {
-	d.mtx.Lock()
-	defer d.mtx.Unlock()
-	if pjob, ok := d.busyPeer[pid]; ok {
-		return atomic.LoadInt32(&pjob.limit) > 10 //每个节点最多同时接受10个下载任务
+//onSuccess 更新吞吐量和延迟，并且增大批量
+func (s *peerScore) onSuccess(count int, latency, cost time.Duration) {
+	speed := float64(count) / (cost.Seconds() + 0.001)
+	if s.samples == 0 {
+		s.speed = speed
+		s.latency = latency.Seconds()
+	} else {
+		s.speed = s.speed*(1-scoreWeight) + speed*scoreWeight
+		s.latency = s.latency*(1-scoreWeight) + latency.Seconds()*scoreWeight
+	}
+	s.samples++
+	s.failed = 0
+	s.batch *= 2
+	if s.batch > maxDownloadBatch {
+		s.batch = maxDownloadBatch
 	}
-	return false
 }
 
-func (d *downloadJob) setBusyPeer(peer *pb.Peer) {
-	d.mtx.Lock()
-	defer d.mtx.Unlock()
-	if pjob, ok := d.busyPeer[peer.GetName()]; ok {
-		atomic.AddInt32(&pjob.limit, 1)
-		d.busyPeer[peer.GetName()] = pjob
-		return
+//onFailure 减小批量，连续失败多次之后不再分配任务
+func (s *peerScore) onFailure() {
+	s.failed++
+	s.batch /= 2
+	if s.batch < minDownloadBatch {
+		s.batch = minDownloadBatch
 	}
+	if s.failed >= maxDownloadFailed {
+		s.banned = true
+	}
+}
 
-	d.busyPeer[peer.GetName()] = &peerJob{peer, 1}
+type downloadResult struct {
+	score   *peerScore
+	heights []int64
+	blocks  []*pb.Block
+	latency time.Duration
+	cost    time.Duration
+	err     error
 }
 
-func (d *downloadJob) setFreePeer(pid string) {
-	d.mtx.Lock()
-	defer d.mtx.Unlock()
+type downloadJob struct {
+	p2pcli  *Cli
+	peers   []*peerScore
+	headers map[int64]*pb.Header
+	hdrPid  string //提供区块头的节点
+	end     int64
+	next    int64   //下一个提交给blockchain的高度
+	pending []int64 //等待下载的高度，从小到大排列
+	blocks  map[int64]*pb.BlockPid
+	results chan *downloadResult
+}
 
-	if pjob, ok := d.busyPeer[pid]; ok {
-		if atomic.AddInt32(&pjob.limit, -1) <= 0 {
-			delete(d.busyPeer, pid)
-			return
+func NewDownloadJob(p2pcli *Cli, peers []*Peer) *downloadJob {
+	job := new(downloadJob)
+	job.p2pcli = p2pcli
+	job.headers = make(map[int64]*pb.Header)
+	job.blocks = make(map[int64]*pb.BlockPid)
+	_, infos := p2pcli.network.node.GetActivePeers()
+	for _, peer := range peers {
+		pbpeer, ok := infos[peer.Addr()]
+		if !ok {
+			continue
+		}
+		if len(peer.GetPeerName()) == 0 {
+			peer.SetPeerName(pbpeer.GetName())
 		}
-		d.busyPeer[pid] = pjob
+		job.peers = append(job.peers, newPeerScore(peer, pbpeer.GetHeader().GetHeight()))
 	}
+	//每个节点同时只有一个下载任务，结果不会阻塞
+	job.results = make(chan *downloadResult, len(job.peers))
+	return job
 }
 
-func (d *downloadJob) GetFreePeer(joblimit int64) *Peer {
-	_, infos := d.p2pcli.network.node.GetActivePeers()
-	for _, peer := range d.downloadPeers {
-		pbpeer, ok := infos[peer.Addr()]
-		if ok {
-			if len(peer.GetPeerName()) == 0 {
-				peer.SetPeerName(pbpeer.GetName())
-			}
+//isCancel p2p模块关闭之后停止下载
+func (d *downloadJob) isCancel() bool {
+	return d.p2pcli.network.isClose()
+}
 
-			if pbpeer.GetHeader().GetHeight() >= joblimit {
-				if d.isBusyPeer(pbpeer.GetName()) {
-					continue
-				}
-				d.setBusyPeer(pbpeer)
-				return peer
+//banPeer 发送错误数据的节点在本次下载中不再分配任务，并且扣分
+func (d *downloadJob) banPeer(s *peerScore, err error) {
+	log.Error("download ban peer", "addr", s.peer.Addr(), "err", err)
+	s.banned = true
+	d.p2pcli.network.node.penalize(s.peer.Addr(), penaltyInvalidBlock, err.Error())
+}
+
+//SyncHeaders 从高度最高的节点下载[start, end]的区块头，节点的高度不够时只下载到节点的高度
+func (d *downloadJob) SyncHeaders(start, end int64) error {
+	peers := make([]*peerScore, len(d.peers))
+	copy(peers, d.peers)
+	sort.SliceStable(peers, func(i, j int) bool { return peers[i].height > peers[j].height })
+	for _, s := range peers {
+		if d.isCancel() {
+			return pb.ErrIsClosed
+		}
+		if s.height < start {
+			break
+		}
+		headers, err := d.fetchHeaders(s.peer, start, end)
+		if err == nil {
+			err = checkHeaders(headers, start)
+			if err != nil {
+				d.banPeer(s, err)
+				continue
 			}
 		}
+		if err != nil || len(headers) == 0 {
+			log.Error("SyncHeaders", "addr", s.peer.Addr(), "err", err)
+			s.onFailure()
+			continue
+		}
+		for _, header := range headers {
+			d.headers[header.Height] = header
+			d.pending = append(d.pending, header.Height)
+		}
+		d.hdrPid = s.peer.GetPeerName()
+		d.next = start
+		d.end = headers[len(headers)-1].Height
+		return nil
 	}
-
-	return nil
+	return pb.ErrNoPeer
 }
 
-func (d *downloadJob) CancelJob() {
-	atomic.StoreInt32(&d.canceljob, 1)
+func (d *downloadJob) fetchHeaders(peer *Peer, start, end int64) ([]*pb.Header, error) {
+	var headers []*pb.Header
+	for height := start; height <= end; height += maxDownloadHeaders {
+		last := height + maxDownloadHeaders - 1
+		if last > end {
+			last = end
+		}
+		resp, err := peer.mconn.gcli.GetHeaders(context.Background(), &pb.P2PGetHeaders{StartHeight: height, EndHeight: last,
+			Version: d.p2pcli.network.node.nodeInfo.cfg.Version}, grpc.FailFast(true))
+		P2pComm.CollectPeerStat(err, peer)
+		if err != nil {
+			if err == pb.ErrVersion {
+				peer.version.SetSupport(false)
+				P2pComm.CollectPeerStat(err, peer)
+			}
+			return nil, err
+		}
+		headers = append(headers, resp.GetHeaders()...)
+		//节点的高度不够
+		if int64(len(resp.GetHeaders())) != last-height+1 {
+			break
+		}
+	}
+	return headers, nil
 }
 
-func (d *downloadJob) isCancel() bool {
-	return atomic.LoadInt32(&d.canceljob) == 1
+//checkHeaders 区块头的高度从start开始连续，并且ParentHash和前一个区块头的hash一致
+func checkHeaders(headers []*pb.Header, start int64) error {
+	var parent []byte
+	for i, header := range headers {
+		if header == nil || header.Height != start+int64(i) {
+			return pb.ErrBlockHashNoMatch
+		}
+		header.Hash = header.CalcHash()
+		if i > 0 && !bytes.Equal(header.ParentHash, parent) {
+			return pb.ErrParentHash
+		}
+		parent = header.Hash
+	}
+	return nil
 }
 
-func (d *downloadJob) DownloadBlock(invs []*pb.Inventory,
-	bchan chan *pb.BlockPid) []*pb.Inventory {
-	var errinvs []*pb.Inventory
-	if d.isCancel() {
-		return errinvs
+//checkBlock 区块的hash需要和区块头一致，交易需要和区块头中的TxHash一致
+func checkBlock(block *pb.Block, header *pb.Header) error {
+	if !bytes.Equal(block.Hash(), header.Hash) {
+		return pb.ErrBlockHashNoMatch
 	}
+	if !bytes.Equal(merkle.CalcMerkleRoot(block.Txs), header.TxHash) {
+		return pb.ErrCheckTxHash
+	}
+	return nil
+}
 
-	for _, inv := range invs { //让一个节点一次下载一个区块，下载失败区块，交给下一轮下载
-		freePeer := d.GetFreePeer(inv.GetHeight())
-		if freePeer == nil {
-			//log.Error("DownloadBlock", "freepeer is null", inv.GetHeight())
-			d.retryList.PushBack(inv)
-			continue
+//DownloadBlock 下载SyncHeaders之后的所有区块，按高度顺序通过deliver提交
+func (d *downloadJob) DownloadBlock(deliver func(*pb.BlockPid)) error {
+	var inflight int
+	for d.next <= d.end {
+		if d.isCancel() {
+			return pb.ErrIsClosed
 		}
-
-		d.wg.Add(1)
-		go func(peer *Peer, inv *pb.Inventory) {
-			defer d.wg.Done()
-			err := d.syncDownloadBlock(peer, inv, bchan)
-			if err != nil {
-				d.retryList.PushBack(inv) //失败的下载，放在下一轮ReDownload进行下载
-			} else {
-				d.setFreePeer(peer.GetPeerName())
+		inflight += d.assign()
+		if inflight == 0 {
+			log.Error("DownloadBlock no peer", "next", d.next, "end", d.end)
+			return pb.ErrNoPeer
+		}
+		timeout := time.NewTimer(time.Minute)
+		select {
+		case <-timeout.C:
+			log.Error("download timeout", "next", d.next)
+			return pb.ErrTimeout
+		case res := <-d.results:
+			inflight--
+			d.procResult(res)
+		}
+		if !timeout.Stop() {
+			<-timeout.C
+		}
+		for {
+			blockpid, ok := d.blocks[d.next]
+			if !ok {
+				break
 			}
-
-		}(freePeer, inv)
-
+			delete(d.blocks, d.next)
+			deliver(blockpid)
+			d.next++
+		}
 	}
-
-	return d.restOfInvs(bchan)
+	return nil
 }
 
-func (d *downloadJob) restOfInvs(bchan chan *pb.BlockPid) []*pb.Inventory {
-	var errinvs []*pb.Inventory
-	if d.isCancel() {
-		return errinvs
+//assign 把窗口内等待下载的高度分配给空闲的节点，分数高的节点优先，返回新的任务数
+func (d *downloadJob) assign() int {
+	var count int
+	for _, s := range d.freePeers() {
+		heights := d.takePending(s)
+		if len(heights) == 0 {
+			continue
+		}
+		s.busy = true
+		count++
+		go d.syncDownloadBlock(s, heights)
 	}
+	return count
+}
 
-	d.wg.Wait()
-	if d.retryList.Len() == 0 {
-		return errinvs
+func (d *downloadJob) freePeers() []*peerScore {
+	var peers []*peerScore
+	for _, s := range d.peers {
+		if !s.busy && !s.banned && s.peer.GetRunning() {
+			peers = append(peers, s)
+		}
 	}
+	sort.SliceStable(peers, func(i, j int) bool { return peers[i].score() > peers[j].score() })
+	return peers
+}
 
-	var invs []*pb.Inventory
-	for e := d.retryList.Front(); e != nil; {
-		if e.Value == nil {
+//takePending 从等待下载的高度中取出节点可以下载的批量，高度不能超过节点的高度和下载窗口
+func (d *downloadJob) takePending(s *peerScore) []int64 {
+	var heights, rest []int64
+	for _, height := range d.pending {
+		if len(heights) < s.batch && height <= s.height && height < d.next+downloadWindow {
+			heights = append(heights, height)
 			continue
 		}
-		log.Debug("restofInvs", "inv", e.Value.(*pb.Inventory).GetHeight())
-		invs = append(invs, e.Value.(*pb.Inventory)) //把下载遗漏的区块，重新组合进行下载
-		next := e.Next()
-		d.retryList.Remove(e)
-		e = next
+		rest = append(rest, height)
 	}
-
-	return invs
+	d.pending = rest
+	return heights
 }
 
-func (d *downloadJob) syncDownloadBlock(peer *Peer, inv *pb.Inventory, bchan chan *pb.BlockPid) error {
-	//每次下载一个高度的数据，通过bchan返回上层
-	if peer == nil {
-		return fmt.Errorf("peer is not exist")
+//procResult 校验下载的区块，没有下载成功的高度重新等待下载
+func (d *downloadJob) procResult(res *downloadResult) {
+	s := res.score
+	s.busy = false
+	requested := make(map[int64]bool, len(res.heights))
+	for _, height := range res.heights {
+		requested[height] = true
+	}
+	var invalid bool
+	for _, block := range res.blocks {
+		height := block.GetHeight()
+		if !requested[height] {
+			continue
+		}
+		//hash不一致时节点可能在另一个分叉上，只算下载失败；hash一致但交易和区块头不一致是节点发送了错误的区块
+		err := checkBlock(block, d.headers[height])
+		if err == pb.ErrCheckTxHash && !invalid {
+			d.banPeer(s, err)
+		}
+		if err != nil {
+			invalid = true
+			continue
+		}
+		delete(requested, height)
+		d.blocks[height] = &pb.BlockPid{Pid: d.hdrPid, Block: block}
+	}
+	for _, height := range res.heights {
+		if requested[height] {
+			d.pending = append(d.pending, height)
+		}
+	}
+	sort.Slice(d.pending, func(i, j int) bool { return d.pending[i] < d.pending[j] })
+	if res.err != nil || invalid || len(requested) > 0 {
+		s.onFailure()
+		return
 	}
+	s.onSuccess(len(res.blocks), res.latency, res.cost)
+}
+
+func (d *downloadJob) syncDownloadBlock(s *peerScore, heights []int64) {
+	res := &downloadResult{score: s, heights: heights}
+	beg := time.Now()
+	res.blocks, res.latency, res.err = d.getBlocks(s.peer, heights)
+	res.cost = time.Since(beg)
+	d.results <- res
+}
 
+//getBlocks 从节点一次下载多个高度的区块，返回收到第一个区块的延迟
+func (d *downloadJob) getBlocks(peer *Peer, heights []int64) ([]*pb.Block, time.Duration, error) {
 	if !peer.GetRunning() {
-		return fmt.Errorf("peer not running")
+		return nil, 0, fmt.Errorf("peer not running")
 	}
 	var p2pdata pb.P2PGetData
 	p2pdata.Version = d.p2pcli.network.node.nodeInfo.cfg.Version
-	p2pdata.Invs = []*pb.Inventory{inv}
-	resp, err := peer.mconn.gcli.GetData(context.Background(), &p2pdata, grpc.FailFast(true))
+	for _, height := range heights {
+		p2pdata.Invs = append(p2pdata.Invs, &pb.Inventory{Ty: msgBlock, Height: height})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), downloadTimeout)
+	defer cancel()
+	beg := time.Now()
+	resp, err := peer.mconn.gcli.GetData(ctx, &p2pdata, grpc.FailFast(true))
 	P2pComm.CollectPeerStat(err, peer)
 	if err != nil {
-		log.Error("syncDownloadBlock", "GetData err", err.Error())
-		return err
+		log.Error("getBlocks", "GetData err", err.Error())
+		return nil, 0, err
 	}
 	defer resp.CloseSend()
+	var blocks []*pb.Block
+	var latency time.Duration
 	for {
 		invdatas, err := resp.Recv()
 		if err != nil {
 			if err == io.EOF {
-				log.Debug("download", "from", peer.Addr(), "block", inv.GetHeight())
-				return nil
+				log.Debug("download", "from", peer.Addr(), "blocks", len(blocks))
+				return blocks, latency, nil
 			}
 			log.Error("download", "resp,Recv err", err.Error(), "download from", peer.Addr())
-			return err
+			return blocks, latency, err
+		}
+		if latency == 0 {
+			latency = time.Since(beg)
 		}
 		for _, item := range invdatas.Items {
-			bchan <- &pb.BlockPid{peer.GetPeerName(), item.GetBlock()} //下载完成后插入bchan
-
+			if item.GetBlock() != nil {
+				blocks = append(blocks, item.GetBlock())
+			}
 		}
 	}
 }
@@ -0,0 +1,144 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"testing"
+	"time"
+
+	"github.com/33cn/chain33/common/db"
+	"github.com/33cn/chain33/common/merkle"
+	pb "github.com/33cn/chain33/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	pb.Init("local", nil)
+}
+
+func genDownloadBlocks(count int) ([]*pb.Block, []*pb.Header) {
+	var blocks []*pb.Block
+	var headers []*pb.Header
+	parent := make([]byte, 32)
+	for i := 0; i < count; i++ {
+		txs := []*pb.Transaction{{Execer: []byte("none"), Payload: []byte{byte(i)}}}
+		block := &pb.Block{ParentHash: parent, TxHash: merkle.CalcMerkleRoot(txs), Height: int64(i), BlockTime: int64(i), Txs: txs}
+		header := block.GetHeader()
+		header.Hash = block.Hash()
+		blocks = append(blocks, block)
+		headers = append(headers, header)
+		parent = header.Hash
+	}
+	return blocks, headers
+}
+
+func TestCheckHeaders(t *testing.T) {
+	blocks, headers := genDownloadBlocks(5)
+	assert.Nil(t, checkHeaders(headers, 0))
+	assert.Nil(t, checkHeaders(headers[2:], 2))
+	assert.Equal(t, pb.ErrBlockHashNoMatch, checkHeaders(headers[2:], 1))
+	forged := *headers[1]
+	forged.ParentHash = headers[2].Hash
+	assert.Equal(t, pb.ErrParentHash, checkHeaders([]*pb.Header{headers[0], &forged}, 0))
+
+	assert.Nil(t, checkBlock(blocks[1], headers[1]))
+	assert.Equal(t, pb.ErrBlockHashNoMatch, checkBlock(blocks[1], headers[2]))
+	//区块头一致但交易被修改
+	bad := *blocks[1]
+	bad.Txs = []*pb.Transaction{{Execer: []byte("none"), Payload: []byte("bad")}}
+	assert.Equal(t, pb.ErrCheckTxHash, checkBlock(&bad, headers[1]))
+}
+
+func TestPeerScore(t *testing.T) {
+	s := newPeerScore(&Peer{}, 100)
+	assert.Equal(t, defaultDownloadSpeed, s.score())
+	for i := 0; i < 10; i++ {
+		s.onSuccess(s.batch, 10*time.Millisecond, time.Second)
+	}
+	assert.Equal(t, maxDownloadBatch, s.batch)
+	fast := s.score()
+	slow := newPeerScore(&Peer{}, 100)
+	slow.onSuccess(1, time.Second, 10*time.Second)
+	assert.True(t, fast > slow.score())
+
+	s.onFailure()
+	assert.Equal(t, maxDownloadBatch/2, s.batch)
+	assert.False(t, s.banned)
+	s.onSuccess(1, 0, time.Second)
+	assert.Equal(t, 0, s.failed)
+	for i := 0; i < maxDownloadFailed; i++ {
+		s.onFailure()
+	}
+	assert.True(t, s.banned)
+}
+
+func TestDownloadJobPending(t *testing.T) {
+	blocks, headers := genDownloadBlocks(10)
+	addr, err := NewNetAddressString("127.0.0.1:13802")
+	require.NoError(t, err)
+	d := &downloadJob{headers: make(map[int64]*pb.Header), blocks: make(map[int64]*pb.BlockPid)}
+	for _, header := range headers {
+		d.headers[header.Height] = header
+		d.pending = append(d.pending, header.Height)
+	}
+	d.end = 9
+
+	//批量和节点的高度限制分配的任务
+	s := newPeerScore(&Peer{peerAddr: addr}, 5)
+	s.batch = 4
+	assert.Equal(t, []int64{0, 1, 2, 3}, d.takePending(s))
+	assert.Equal(t, []int64{4, 5}, d.takePending(s))
+	assert.Equal(t, []int64{6, 7, 8, 9}, d.pending)
+
+	//缺少的和hash不一致的区块重新等待下载
+	d.procResult(&downloadResult{score: s, heights: []int64{0, 1, 2, 3}, blocks: []*pb.Block{blocks[0], blocks[2], blocks[3]}})
+	d.procResult(&downloadResult{score: s, heights: []int64{4, 5}, blocks: []*pb.Block{blocks[4], blocks[1]}})
+	assert.Equal(t, []int64{1, 5, 6, 7, 8, 9}, d.pending)
+	assert.Equal(t, 4, len(d.blocks))
+	assert.Equal(t, 2, s.failed)
+	assert.Equal(t, 1, s.batch)
+	assert.False(t, s.busy)
+}
+
+func TestDownloadJobBadBlock(t *testing.T) {
+	blocks, headers := genDownloadBlocks(3)
+	addr, err := NewNetAddressString("127.0.0.1:13802")
+	require.NoError(t, err)
+	bookDb := db.NewDB("addrbook", "memdb", "", 16)
+	r := newReputation(&pb.P2P{BanScore: 1000}, &BlackList{badPeers: make(map[string]int64)}, bookDb)
+	cli := &Cli{network: &P2p{node: &Node{nodeInfo: &NodeInfo{reputation: r}}}}
+	d := &downloadJob{p2pcli: cli, headers: make(map[int64]*pb.Header), blocks: make(map[int64]*pb.BlockPid)}
+	for _, header := range headers {
+		d.headers[header.Height] = header
+	}
+
+	d.hdrPid = "header"
+
+	//hash不一致的区块可能来自另一个分叉，只算下载失败不扣分
+	fork := *blocks[1]
+	fork.BlockTime++
+	s := newPeerScore(&Peer{peerAddr: addr}, 2)
+	d.procResult(&downloadResult{score: s, heights: []int64{0, 1}, blocks: []*pb.Block{blocks[0], &fork}})
+	assert.False(t, s.banned)
+	assert.Equal(t, 1, s.failed)
+	assert.Equal(t, []int64{1}, d.pending)
+	assert.Equal(t, int64(0), r.Score(addr.String()))
+	//区块按提供区块头的节点提交
+	assert.Equal(t, "header", d.blocks[0].Pid)
+
+	//hash一致但交易和区块头不一致的区块扣分，同一次下载只扣一次
+	bad := *blocks[1]
+	bad.Txs = []*pb.Transaction{{Execer: []byte("none"), Payload: []byte("bad")}}
+	bad2 := *blocks[2]
+	bad2.Txs = bad.Txs
+	d.pending = nil
+	d.procResult(&downloadResult{score: s, heights: []int64{1, 2}, blocks: []*pb.Block{&bad, &bad2}})
+	assert.True(t, s.banned)
+	assert.Equal(t, []int64{1, 2}, d.pending)
+	assert.Equal(t, 1, len(d.blocks))
+	assert.Equal(t, int64(penaltyInvalidBlock), r.Score(addr.String()))
+	assert.False(t, d.isCancel())
+}
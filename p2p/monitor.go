@@ -23,6 +23,44 @@ func (n *Node) destroyPeer(peer *Peer) {
 
 }
 
+//penalize 节点的不良行为扣分，被禁止之后断开这个ip的所有连接
+func (n *Node) penalize(addr string, penalty int64, reason string) {
+	if !n.nodeInfo.reputation.Penalize(addr, penalty, reason) {
+		return
+	}
+	ip := peerIP(addr)
+	for _, peer := range n.GetRegisterPeers() {
+		if peerIP(peer.Addr()) == ip {
+			n.destroyPeer(peer)
+		}
+	}
+}
+
+//sendTx 把广播收到的交易发送给mempool，在单独的goroutine中等待结果，mempool检查签名失败时给发送交易的节点扣分
+func (n *Node) sendTx(tx *types.Transaction, addr string) {
+	client := n.nodeInfo.client
+	msg := client.NewMessage("mempool", types.EventTx, tx)
+	if addr == "" {
+		client.Send(msg, false)
+		return
+	}
+	err := client.Send(msg, true)
+	if err != nil {
+		log.Error("sendTx", "send to mempool Error", err.Error())
+		return
+	}
+	go func() {
+		resp, err := client.WaitTimeout(msg, time.Minute)
+		if err != nil {
+			return
+		}
+		reply, ok := resp.GetData().(*types.Reply)
+		if ok && !reply.GetIsOk() && string(reply.GetMsg()) == types.ErrSign.Error() {
+			n.penalize(addr, penaltyBadSign, "bad tx sign")
+		}
+	}()
+}
+
 func (n *Node) monitorErrPeer() {
 	for {
 		peer := <-n.nodeInfo.monitorChan
@@ -513,6 +551,7 @@ func (n *Node) monitorBlackList() {
 		}
 
 		<-ticker.C
+		n.nodeInfo.reputation.expire()
 		badPeers := n.nodeInfo.blacklist.GetBadPeers()
 		now := types.Now().Unix()
 		for badPeer, intime := range badPeers {
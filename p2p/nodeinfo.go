@@ -25,6 +25,7 @@ type NodeInfo struct {
 	blacklist      *BlackList
 	peerInfos      *PeerInfos
 	addrBook       *AddrBook // known peers
+	reputation     *reputation
 	natDone        int32
 	outSide        int32
 	ServiceType    int32
@@ -42,6 +43,7 @@ func NewNodeInfo(cfg *types.P2P) *NodeInfo {
 	nodeInfo.externalAddr = new(NetAddress)
 	nodeInfo.listenAddr = new(NetAddress)
 	nodeInfo.addrBook = NewAddrBook(cfg)
+	nodeInfo.reputation = newReputation(cfg, nodeInfo.blacklist, nodeInfo.addrBook.bookDb)
 	return nodeInfo
 }
 
@@ -138,6 +140,7 @@ func (nf *NodeInfo) latestPeerInfo(n *Node) map[string]*types.Peer {
 		pr.Name = peerinfo.GetName()
 		pr.MempoolSize = peerinfo.GetMempoolSize()
 		pr.Header = peerinfo.GetHeader()
+		pr.Score = nf.reputation.Score(peer.Addr())
 		peerlist[fmt.Sprintf("%v:%v", peerinfo.Addr, peerinfo.Port)] = &pr
 	}
 	return peerlist
@@ -238,6 +241,10 @@ func (bl *BlackList) Has(addr string) bool {
 	if _, ok := bl.badPeers[addr]; ok {
 		return true
 	}
+	//因为不良行为按ip禁止的节点，所有的端口都不能连接
+	if _, ok := bl.badPeers[peerIP(addr)]; ok {
+		return true
+	}
 	return false
 }
 
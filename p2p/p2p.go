@@ -209,8 +209,16 @@ func (network *P2p) subP2pMsg() {
 				go network.p2pCli.GetPeerInfo(msg, taskIndex)
 			case types.EventFetchBlockHeaders:
 				go network.p2pCli.GetHeaders(msg, taskIndex)
+			case types.EventFetchStateNodes:
+				go network.p2pCli.GetStateNodes(msg, taskIndex)
 			case types.EventGetNetInfo:
 				go network.p2pCli.GetNetInfo(msg, taskIndex)
+			case types.EventReportPeer:
+				go network.p2pCli.ReportPeer(msg, taskIndex)
+			case types.EventListPeerBans:
+				go network.p2pCli.ListPeerBans(msg, taskIndex)
+			case types.EventUnbanPeer:
+				go network.p2pCli.UnbanPeer(msg, taskIndex)
 			default:
 				log.Warn("unknown msgtype", "msg", msg)
 				msg.Reply(network.client.NewMessage("", msg.Ty, types.Reply{false, []byte("unknown msgtype")}))
@@ -27,9 +27,13 @@ type EventInterface interface {
 	GetMemPool(msg queue.Message, taskindex int64)
 	GetPeerInfo(msg queue.Message, taskindex int64)
 	GetHeaders(msg queue.Message, taskindex int64)
+	GetStateNodes(msg queue.Message, taskindex int64)
 	GetBlocks(msg queue.Message, taskindex int64)
 	BlockBroadcast(msg queue.Message, taskindex int64)
 	GetNetInfo(msg queue.Message, taskindex int64)
+	ReportPeer(msg queue.Message, taskindex int64)
+	ListPeerBans(msg queue.Message, taskindex int64)
+	UnbanPeer(msg queue.Message, taskindex int64)
 }
 
 //非p2p 订阅的事件处理函数接口
@@ -373,6 +377,38 @@ func (m *Cli) GetHeaders(msg queue.Message, taskindex int64) {
 	}
 }
 
+//GetStateNodes 从指定的peer获取状态树的节点，直接回复给blockchain
+func (m *Cli) GetStateNodes(msg queue.Message, taskindex int64) {
+	defer func() {
+		<-m.network.otherFactory
+		log.Debug("GetStateNodes", "task complete:", taskindex)
+	}()
+	req := msg.GetData().(*pb.ReqStateNodes)
+	peers, infos := m.network.node.GetActivePeers()
+	for paddr, info := range infos {
+		if info.GetName() != req.GetPid() {
+			continue
+		}
+		peer, ok := peers[paddr]
+		if !ok || peer == nil {
+			break
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		nodes, err := peer.mconn.gcli.GetStateNodes(ctx, &pb.P2PGetStateNodes{Hashes: req.GetHashes(),
+			Version: m.network.node.nodeInfo.cfg.Version}, grpc.FailFast(true))
+		cancel()
+		P2pComm.CollectPeerStat(err, peer)
+		if err != nil {
+			log.Error("GetStateNodes", "pid", req.GetPid(), "Err", err.Error())
+			msg.Reply(m.network.client.NewMessage("blockchain", pb.EventStateNodes, err))
+			return
+		}
+		msg.Reply(m.network.client.NewMessage("blockchain", pb.EventStateNodes, nodes))
+		return
+	}
+	msg.Reply(m.network.client.NewMessage("blockchain", pb.EventStateNodes, pb.ErrPeerInfoIsNil))
+}
+
 func (m *Cli) GetBlocks(msg queue.Message, taskindex int64) {
 	defer func() {
 		<-m.network.otherFactory
@@ -389,145 +425,45 @@ func (m *Cli) GetBlocks(msg queue.Message, taskindex int64) {
 	req := msg.GetData().(*pb.ReqBlocks)
 	log.Info("GetBlocks", "start", req.GetStart(), "end", req.GetEnd())
 	pids := req.GetPid()
-	var MaxInvs = new(pb.P2PInv)
-	var downloadPeers []*Peer
-	peers, infos := m.network.node.GetActivePeers()
+	var pidmap map[string]bool
 	if len(pids) > 0 && pids[0] != "" { //指定Pid 下载数据
 		log.Info("fetch from peer in pids")
-		var pidmap = make(map[string]bool)
+		pidmap = make(map[string]bool)
 		for _, pid := range pids {
 			pidmap[pid] = true
 		}
-		for paddr, info := range infos {
-			if _, ok := pidmap[info.GetName()]; ok { //匹配成功
-
-				peer, ok := peers[paddr]
-				if ok && peer != nil {
-					downloadPeers = append(downloadPeers, peer)
-					//获取Invs
-					if len(MaxInvs.GetInvs()) != int(req.GetEnd()-req.GetStart())+1 {
-						var err error
-						MaxInvs, err = peer.mconn.gcli.GetBlocks(context.Background(), &pb.P2PGetBlocks{StartHeight: req.GetStart(), EndHeight: req.GetEnd(),
-							Version: m.network.node.nodeInfo.cfg.Version}, grpc.FailFast(true))
-						P2pComm.CollectPeerStat(err, peer)
-						if err != nil {
-							log.Error("GetBlocks", "Err", err.Error())
-							if err == pb.ErrVersion {
-								peer.version.SetSupport(false)
-								P2pComm.CollectPeerStat(err, peer)
-							}
-							continue
-						}
-					}
-
-				}
-			}
+	}
+	var downloadPeers []*Peer
+	peers, infos := m.network.node.GetActivePeers()
+	for paddr, peer := range peers {
+		peerinfo, ok := infos[paddr]
+		if !ok || peer == nil {
+			continue
 		}
-
-	} else {
-		log.Info("fetch from all peers in pids")
-
-		for _, peer := range peers { //限制对peer 的高频次调用
-			log.Info("peer", "addr", peer.Addr(), "start", req.GetStart(), "end", req.GetEnd())
-			peerinfo, ok := infos[peer.Addr()]
-			if !ok {
-				continue
-			}
-			var pr pb.Peer
-			pr.Addr = peerinfo.GetAddr()
-			pr.Port = peerinfo.GetPort()
-			pr.Name = peerinfo.GetName()
-			pr.MempoolSize = peerinfo.GetMempoolSize()
-			pr.Header = peerinfo.GetHeader()
-
-			if peerinfo.GetHeader().GetHeight() < req.GetEnd() {
-				continue
-			}
-
-			invs, err := peer.mconn.gcli.GetBlocks(context.Background(), &pb.P2PGetBlocks{StartHeight: req.GetStart(), EndHeight: req.GetEnd(),
-				Version: m.network.node.nodeInfo.cfg.Version}, grpc.FailFast(true))
-			P2pComm.CollectPeerStat(err, peer)
-			if err != nil {
-				log.Error("GetBlocks", "Err", err.Error())
-				if err == pb.ErrVersion {
-					peer.version.SetSupport(false)
-					P2pComm.CollectPeerStat(err, peer)
-				}
-				continue
-			}
-
-			if len(invs.Invs) > len(MaxInvs.Invs) {
-				MaxInvs = invs
-				if len(MaxInvs.GetInvs()) == int(req.GetEnd()-req.GetStart())+1 {
-					break
-				}
-			}
-
+		if peerinfo.GetHeader().GetHeight() < req.GetStart() { //高度不符合要求
+			continue
 		}
-
-		for _, peer := range peers {
-			peerinfo, ok := infos[peer.Addr()]
-			if !ok {
-				continue
-			}
-			if peerinfo.GetHeader().GetHeight() < req.GetStart() { //高度不符合要求
-				continue
-			}
-
-			downloadPeers = append(downloadPeers, peer)
+		if pidmap != nil && !pidmap[peerinfo.GetName()] {
+			continue
 		}
+		downloadPeers = append(downloadPeers, peer)
 	}
 
-	log.Debug("Invs", "Invs show", MaxInvs.GetInvs())
-	if len(MaxInvs.GetInvs()) == 0 {
-		log.Error("GetBlocks", "getInvs", 0)
+	//先下载区块头，再从多个节点并行下载区块体
+	job := NewDownloadJob(m, downloadPeers)
+	err := job.SyncHeaders(req.GetStart(), req.GetEnd())
+	if err != nil {
+		log.Error("GetBlocks SyncHeaders", "start", req.GetStart(), "end", req.GetEnd(), "err", err)
 		return
 	}
-
-	//使用新的下载模式进行下载
-	var bChan = make(chan *pb.BlockPid, 256)
-	invs := MaxInvs.GetInvs()
-	job := NewDownloadJob(m, downloadPeers)
-	var jobcancel int32
-	var maxDownloadRetryCount = 100
-	go func(cancel *int32, invs []*pb.Inventory) {
-		for {
-			if atomic.LoadInt32(cancel) == 1 {
-				return
-			}
-
-			invs = job.DownloadBlock(invs, bChan)
-			if len(invs) == 0 {
-				return
-			}
-			maxDownloadRetryCount--
-			if maxDownloadRetryCount < 0 {
-				return
-			}
-		}
-	}(&jobcancel, invs)
-	i := 0
-	for {
-		timeout := time.NewTimer(time.Minute)
-		select {
-		case <-timeout.C:
-			atomic.StoreInt32(&jobcancel, 1)
-			job.CancelJob()
-			log.Error("download timeout")
-			return
-		case blockpid := <-bChan:
-			newmsg := m.network.node.nodeInfo.client.NewMessage("blockchain", pb.EventSyncBlock, blockpid)
-			m.network.node.nodeInfo.client.SendTimeout(newmsg, false, 60*time.Second)
-			i++
-			if i == len(MaxInvs.GetInvs()) {
-				return
-			}
-		}
-		if !timeout.Stop() {
-			<-timeout.C
-		}
+	client := m.network.node.nodeInfo.client
+	err = job.DownloadBlock(func(blockpid *pb.BlockPid) {
+		newmsg := client.NewMessage("blockchain", pb.EventSyncBlock, blockpid)
+		client.SendTimeout(newmsg, false, 60*time.Second)
+	})
+	if err != nil {
+		log.Error("GetBlocks DownloadBlock", "start", req.GetStart(), "end", req.GetEnd(), "err", err)
 	}
-
 }
 
 func (m *Cli) BlockBroadcast(msg queue.Message, taskindex int64) {
@@ -554,6 +490,64 @@ func (m *Cli) GetNetInfo(msg queue.Message, taskindex int64) {
 
 }
 
+//ReportPeer blockchain报告发送了错误区块的节点，按节点的name查找地址并扣分
+func (m *Cli) ReportPeer(msg queue.Message, taskindex int64) {
+	defer func() {
+		<-m.network.otherFactory
+		log.Debug("ReportPeer", "task complete:", taskindex)
+	}()
+	req := msg.GetData().(*pb.ReportPeer)
+	addr := m.peerAddr(req.GetPid())
+	if addr == "" {
+		log.Debug("ReportPeer", "pid not found", req.GetPid())
+		return
+	}
+	m.network.node.penalize(addr, penaltyInvalidBlock, req.GetReason())
+}
+
+//peerAddr 根据节点的name查找主动连接和被动连接的节点地址
+func (m *Cli) peerAddr(pid string) string {
+	for _, peer := range m.network.node.GetRegisterPeers() {
+		if peer.GetPeerName() == pid {
+			return peer.Addr()
+		}
+	}
+	for _, info := range m.network.node.nodeInfo.peerInfos.GetPeerInfos() {
+		if info.GetName() == pid {
+			return fmt.Sprintf("%v:%v", info.GetAddr(), info.GetPort())
+		}
+	}
+	if l, ok := m.network.node.listener.(*listener); ok && l != nil {
+		if inpeer := l.p2pserver.getInBoundPeerInfo(pid); inpeer != nil {
+			return inpeer.addr
+		}
+	}
+	return ""
+}
+
+func (m *Cli) ListPeerBans(msg queue.Message, taskindex int64) {
+	defer func() {
+		<-m.network.otherFactory
+		log.Debug("ListPeerBans", "task complete:", taskindex)
+	}()
+	bans := m.network.node.nodeInfo.reputation.ListBans()
+	msg.Reply(m.network.client.NewMessage("rpc", pb.EventReplyPeerBans, &pb.PeerBans{Items: bans}))
+}
+
+func (m *Cli) UnbanPeer(msg queue.Message, taskindex int64) {
+	defer func() {
+		<-m.network.otherFactory
+		log.Debug("UnbanPeer", "task complete:", taskindex)
+	}()
+	req := msg.GetData().(*pb.ReqString)
+	err := m.network.node.nodeInfo.reputation.Unban(req.GetData())
+	if err != nil {
+		msg.Reply(m.network.client.NewMessage("rpc", pb.EventReply, err))
+		return
+	}
+	msg.Reply(m.network.client.NewMessage("rpc", pb.EventReply, &pb.Reply{IsOk: true}))
+}
+
 func (m *Cli) CheckPeerNatOk(addr string) bool {
 	//连接自己的地址信息做测试
 	return !(len(P2pComm.AddrRouteble([]string{addr})) == 0)
@@ -168,6 +168,12 @@ func (s *P2pServer) SoftVersion(ctx context.Context, in *pb.P2PPing) (*pb.Reply,
 
 func (s *P2pServer) BroadCastTx(ctx context.Context, in *pb.P2PTx) (*pb.Reply, error) {
 	log.Debug("p2pServer RECV TRANSACTION", "in", in)
+	if in.GetTx() == nil || !in.GetTx().CheckSign() {
+		if getctx, ok := pr.FromContext(ctx); ok {
+			s.node.penalize(getctx.Addr.String(), penaltyBadSign, "bad tx sign")
+		}
+		return nil, pb.ErrSign
+	}
 	client := s.node.nodeInfo.client
 	msg := client.NewMessage("mempool", pb.EventTx, in.Tx)
 	client.Send(msg, false)
@@ -319,6 +325,29 @@ func (s *P2pServer) GetHeaders(ctx context.Context, in *pb.P2PGetHeaders) (*pb.P
 	return &pb.P2PHeaders{Headers: headers.GetItems()}, nil
 }
 
+//GetStateNodes 从store获取快速同步需要的状态树节点
+func (s *P2pServer) GetStateNodes(ctx context.Context, in *pb.P2PGetStateNodes) (*pb.StateNodes, error) {
+	log.Debug("p2pServer GetStateNodes", "p2p version", in.GetVersion())
+	if !s.checkVersion(in.GetVersion()) {
+		return nil, pb.ErrVersion
+	}
+	if len(in.GetHashes()) == 0 || len(in.GetHashes()) > maxStateNodes {
+		return nil, fmt.Errorf("out of range")
+	}
+	client := s.node.nodeInfo.client
+	msg := client.NewMessage("store", pb.EventStoreGetStateNodes, &pb.ReqStateNodes{Hashes: in.GetHashes()})
+	err := client.SendTimeout(msg, true, time.Minute)
+	if err != nil {
+		log.Error("GetStateNodes", "Error", err.Error())
+		return nil, err
+	}
+	resp, err := client.WaitTimeout(msg, time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	return resp.GetData().(*pb.StateNodes), nil
+}
+
 func (s *P2pServer) GetPeerInfo(ctx context.Context, in *pb.P2PGetPeerInfo) (*pb.P2PPeerInfo, error) {
 	log.Debug("p2pServer GetPeerInfo", "p2p version", in.GetVersion())
 	if !s.checkVersion(in.GetVersion()) {
@@ -431,6 +460,12 @@ func (s *P2pServer) ServerStreamRead(stream pb.P2Pgservice_ServerStreamReadServe
 	defer s.deleteInBoundPeerInfo(peername)
 	var in = new(pb.BroadCastData)
 	var err error
+	//按连接的ip计算不良行为
+	var remoteAddr string
+	if getctx, ok := pr.FromContext(stream.Context()); ok {
+		remoteAddr = getctx.Addr.String()
+	}
+	counter := s.node.nodeInfo.reputation.newTxCounter()
 	for {
 		if s.IsClose() {
 			return fmt.Errorf("node close")
@@ -444,6 +479,9 @@ func (s *P2pServer) ServerStreamRead(stream pb.P2Pgservice_ServerStreamReadServe
 			log.Error("ServerStreamRead", "Recv", err)
 			return err
 		}
+		if remoteAddr != "" && s.node.nodeInfo.blacklist.Has(remoteAddr) {
+			return pb.ErrPeerStop
+		}
 
 		if block := in.GetBlock(); block != nil {
 			hex.Encode(hash[:], block.GetBlock().Hash())
@@ -476,9 +514,12 @@ func (s *P2pServer) ServerStreamRead(stream pb.P2Pgservice_ServerStreamReadServe
 			}
 			Filter.RegRecvData(txhash)
 			Filter.ReleaseLock()
+			//只统计没有收到过的交易
+			if remoteAddr != "" && counter.hit(pb.Now().Unix()) {
+				s.node.penalize(remoteAddr, penaltySpam, "spam")
+			}
 			if tx.GetTx() != nil {
-				msg := s.node.nodeInfo.client.NewMessage("mempool", pb.EventTx, tx.GetTx())
-				s.node.nodeInfo.client.Send(msg, false)
+				s.node.sendTx(tx.GetTx(), remoteAddr)
 			}
 			//Filter.RegRecvData(txhash)
 
@@ -301,6 +301,7 @@ func (p *Peer) readStream() {
 
 		log.Debug("SubStreamBlock", "Start", p.Addr())
 		var hash [64]byte
+		counter := p.node.nodeInfo.reputation.newTxCounter()
 		for {
 			if !p.GetRunning() {
 				resp.CloseSend()
@@ -370,8 +371,11 @@ func (p *Peer) readStream() {
 					}
 					Filter.RegRecvData(txhash)
 					Filter.ReleaseLock()
-					msg := p.node.nodeInfo.client.NewMessage("mempool", pb.EventTx, tx.GetTx())
-					p.node.nodeInfo.client.Send(msg, false)
+					//只统计没有收到过的交易
+					if counter.hit(pb.Now().Unix()) {
+						p.node.penalize(p.Addr(), penaltySpam, "spam")
+					}
+					p.node.sendTx(tx.GetTx(), p.Addr())
 					//Filter.RegRecvData(txhash) //登记
 				}
 			}
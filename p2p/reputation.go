@@ -0,0 +1,234 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"math"
+	"net"
+	"sort"
+	"sync"
+
+	"github.com/33cn/chain33/common/db"
+	"github.com/33cn/chain33/types"
+)
+
+//节点的信誉：节点的不良行为按类型扣分，分数随时间按半衰期衰减
+//分数达到阈值之后按ip禁止连接一段时间，禁止的节点保存在addrbook的数据库中，重启之后仍然有效
+
+type misbehavior struct {
+	score  float64
+	update int64
+}
+
+type reputation struct {
+	mtx        sync.Mutex
+	banScore   float64
+	banSeconds int64
+	halfLife   float64
+	maxTxRate  int64
+	scores     map[string]*misbehavior
+	bans       map[string]*types.PeerBan
+	blacklist  *BlackList
+	db         db.DB
+}
+
+func newReputation(cfg *types.P2P, blacklist *BlackList, db db.DB) *reputation {
+	r := &reputation{
+		banScore:   float64(defaultBanScore),
+		banSeconds: defaultBanSeconds,
+		halfLife:   float64(defaultScoreHalfLife),
+		maxTxRate:  defaultMaxTxPerSecond,
+		scores:     make(map[string]*misbehavior),
+		bans:       make(map[string]*types.PeerBan),
+		blacklist:  blacklist,
+		db:         db,
+	}
+	if cfg.BanScore > 0 {
+		r.banScore = float64(cfg.BanScore)
+	}
+	if cfg.BanSeconds > 0 {
+		r.banSeconds = cfg.BanSeconds
+	}
+	if cfg.ScoreHalfLife > 0 {
+		r.halfLife = float64(cfg.ScoreHalfLife)
+	}
+	if cfg.MaxTxPerSecond > 0 {
+		r.maxTxRate = cfg.MaxTxPerSecond
+	}
+	r.load()
+	return r
+}
+
+func calcBanKey(ip string) []byte {
+	return []byte(banKeyPrefix + ip)
+}
+
+//peerIP 同一个ip的不同端口共用分数
+func peerIP(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+//load 加载数据库中没有到期的禁止
+func (r *reputation) load() {
+	now := types.Now().Unix()
+	it := r.db.Iterator([]byte(banKeyPrefix), nil, false)
+	defer it.Close()
+	for it.Next() {
+		var ban types.PeerBan
+		err := types.Decode(it.Value(), &ban)
+		if err != nil || ban.Deadline <= now {
+			r.db.Delete(it.Key())
+			continue
+		}
+		r.bans[ban.Addr] = &ban
+		r.blacklist.Add(ban.Addr, ban.Deadline-now)
+	}
+}
+
+//decay 按经过的时间衰减分数
+func (r *reputation) decay(m *misbehavior, now int64) float64 {
+	if now > m.update {
+		m.score *= math.Pow(0.5, float64(now-m.update)/r.halfLife)
+		m.update = now
+	}
+	return m.score
+}
+
+//Penalize 扣分，分数达到阈值时禁止连接并返回true
+func (r *reputation) Penalize(addr string, penalty int64, reason string) bool {
+	ip := peerIP(addr)
+	now := types.Now().Unix()
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if _, ok := r.bans[ip]; ok {
+		return false
+	}
+	m, ok := r.scores[ip]
+	if !ok {
+		m = &misbehavior{update: now}
+		r.scores[ip] = m
+	}
+	m.score = r.decay(m, now) + float64(penalty)
+	log.Debug("Penalize", "addr", addr, "penalty", penalty, "score", m.score, "reason", reason)
+	if m.score < r.banScore {
+		return false
+	}
+	ban := &types.PeerBan{Addr: ip, Reason: reason, Score: int64(m.score), Deadline: now + r.banSeconds}
+	log.Warn("ban peer", "ip", ip, "score", ban.Score, "reason", reason, "seconds", r.banSeconds)
+	delete(r.scores, ip)
+	r.bans[ip] = ban
+	r.blacklist.Add(ip, r.banSeconds)
+	err := r.db.Set(calcBanKey(ip), types.Encode(ban))
+	if err != nil {
+		log.Error("Penalize save ban", "ip", ip, "err", err)
+	}
+	return true
+}
+
+//PenalizeTimeout 超时可能是网络的问题，超时扣分之后的分数最多是阈值的一半，只有超时的节点不会被禁止
+func (r *reputation) PenalizeTimeout(addr string, penalty int64) {
+	ip := peerIP(addr)
+	now := types.Now().Unix()
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if _, ok := r.bans[ip]; ok {
+		return
+	}
+	m, ok := r.scores[ip]
+	if !ok {
+		m = &misbehavior{update: now}
+		r.scores[ip] = m
+	}
+	score := r.decay(m, now)
+	limit := r.banScore / 2
+	if score < limit {
+		m.score = math.Min(score+float64(penalty), limit)
+	}
+	log.Debug("PenalizeTimeout", "addr", addr, "penalty", penalty, "score", m.score)
+}
+
+//Score 当前的分数，已经禁止的节点返回禁止时的分数
+func (r *reputation) Score(addr string) int64 {
+	ip := peerIP(addr)
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if ban, ok := r.bans[ip]; ok {
+		return ban.Score
+	}
+	if m, ok := r.scores[ip]; ok {
+		return int64(r.decay(m, types.Now().Unix()))
+	}
+	return 0
+}
+
+//ListBans 按ip排序的所有没有到期的禁止
+func (r *reputation) ListBans() []*types.PeerBan {
+	r.expire()
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	bans := make([]*types.PeerBan, 0, len(r.bans))
+	for _, ban := range r.bans {
+		bans = append(bans, ban)
+	}
+	sort.Slice(bans, func(i, j int) bool { return bans[i].Addr < bans[j].Addr })
+	return bans
+}
+
+//Unban 解除禁止并清空分数
+func (r *reputation) Unban(addr string) error {
+	ip := peerIP(addr)
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if _, ok := r.bans[ip]; !ok {
+		return types.ErrNotFound
+	}
+	delete(r.bans, ip)
+	delete(r.scores, ip)
+	r.blacklist.Delete(ip)
+	return r.db.Delete(calcBanKey(ip))
+}
+
+//expire 删除到期的禁止，黑名单中的记录由monitorBlackList删除
+func (r *reputation) expire() {
+	now := types.Now().Unix()
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	for ip, ban := range r.bans {
+		if ban.Deadline <= now {
+			delete(r.bans, ip)
+			r.db.Delete(calcBanKey(ip))
+		}
+	}
+	//分数衰减到很小的记录不再保留
+	for ip, m := range r.scores {
+		if r.decay(m, now) < 1 {
+			delete(r.scores, ip)
+		}
+	}
+}
+
+//rateCounter 统计每秒收到的新交易，超过limit时每秒只报告一次
+type rateCounter struct {
+	limit  int64
+	second int64
+	count  int64
+}
+
+func (r *reputation) newTxCounter() *rateCounter {
+	return &rateCounter{limit: r.maxTxRate}
+}
+
+func (c *rateCounter) hit(now int64) bool {
+	if now != c.second {
+		c.second = now
+		c.count = 0
+	}
+	c.count++
+	return c.count == c.limit+1
+}
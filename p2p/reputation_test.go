@@ -0,0 +1,124 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/33cn/chain33/common/db"
+	"github.com/33cn/chain33/queue"
+	"github.com/33cn/chain33/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReputation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reputation")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	bookDb := db.NewDB("addrbook", "leveldb", dir, 16)
+	defer bookDb.Close()
+	cfg := &types.P2P{BanScore: 50, BanSeconds: 600, ScoreHalfLife: 60}
+	blacklist := &BlackList{badPeers: make(map[string]int64)}
+	r := newReputation(cfg, blacklist, bookDb)
+
+	assert.False(t, r.Penalize("192.168.1.1:13802", penaltyBadSign, "bad tx sign"))
+	//同一个ip的不同端口共用分数
+	assert.Equal(t, int64(penaltyBadSign), r.Score("192.168.1.1:13803"))
+	//分数随时间衰减
+	r.scores["192.168.1.1"].update -= 60
+	assert.Equal(t, int64(penaltyBadSign/2), r.Score("192.168.1.1"))
+
+	assert.True(t, r.Penalize("192.168.1.1:13802", penaltyInvalidBlock, "invalid block"))
+	assert.True(t, blacklist.Has("192.168.1.1:13804"))
+	assert.False(t, blacklist.Has("192.168.1.2:13802"))
+	assert.False(t, r.Penalize("192.168.1.1:13802", penaltyInvalidBlock, "invalid block"))
+	bans := r.ListBans()
+	require.Equal(t, 1, len(bans))
+	assert.Equal(t, "192.168.1.1", bans[0].Addr)
+	assert.Equal(t, "invalid block", bans[0].Reason)
+	assert.Equal(t, int64(penaltyInvalidBlock+penaltyBadSign/2), bans[0].Score)
+
+	//重启之后禁止仍然有效
+	blacklist2 := &BlackList{badPeers: make(map[string]int64)}
+	r2 := newReputation(cfg, blacklist2, bookDb)
+	assert.Equal(t, 1, len(r2.ListBans()))
+	assert.True(t, blacklist2.Has("192.168.1.1:13802"))
+
+	require.NoError(t, r2.Unban("192.168.1.1:13802"))
+	assert.Equal(t, types.ErrNotFound, r2.Unban("192.168.1.1"))
+	assert.False(t, blacklist2.Has("192.168.1.1:13802"))
+	assert.Equal(t, int64(0), r2.Score("192.168.1.1"))
+	r3 := newReputation(cfg, &BlackList{badPeers: make(map[string]int64)}, bookDb)
+	assert.Equal(t, 0, len(r3.ListBans()))
+
+	//到期的禁止被删除
+	r3.Penalize("192.168.1.3:13802", 100, "spam")
+	r3.bans["192.168.1.3"].Deadline = types.Now().Unix()
+	assert.Equal(t, 0, len(r3.ListBans()))
+	assert.Equal(t, 0, len(newReputation(cfg, &BlackList{badPeers: make(map[string]int64)}, bookDb).ListBans()))
+}
+
+func TestRateCounter(t *testing.T) {
+	r := newReputation(&types.P2P{}, &BlackList{badPeers: make(map[string]int64)}, db.NewDB("addrbook", "memdb", "", 16))
+	assert.Equal(t, int64(defaultMaxTxPerSecond), r.maxTxRate)
+	r = newReputation(&types.P2P{MaxTxPerSecond: 10}, &BlackList{badPeers: make(map[string]int64)}, db.NewDB("addrbook", "memdb", "", 16))
+	counter := r.newTxCounter()
+	var hits int
+	for i := 0; i < 20; i++ {
+		if counter.hit(100) {
+			hits++
+		}
+	}
+	assert.Equal(t, 1, hits)
+	assert.False(t, counter.hit(101))
+	assert.Equal(t, int64(1), counter.count)
+}
+
+func TestSendTxBadSign(t *testing.T) {
+	q := queue.New("channel")
+	defer q.Close()
+	//mempool检查签名失败时返回ErrSign
+	go func() {
+		client := q.Client()
+		client.Sub("mempool")
+		for msg := range client.Recv() {
+			tx := msg.GetData().(*types.Transaction)
+			if tx.CheckSign() {
+				msg.Reply(client.NewMessage("", types.EventReply, &types.Reply{IsOk: true}))
+				continue
+			}
+			msg.Reply(client.NewMessage("", types.EventReply, &types.Reply{Msg: []byte(types.ErrSign.Error())}))
+		}
+	}()
+	r := newReputation(&types.P2P{BanScore: 1000}, &BlackList{badPeers: make(map[string]int64)}, db.NewDB("addrbook", "memdb", "", 16))
+	n := &Node{nodeInfo: &NodeInfo{reputation: r, client: q.Client()}}
+	addr := "192.168.1.1:13802"
+	n.sendTx(&types.Transaction{Execer: []byte("none"), Payload: []byte("unsigned")}, addr)
+	for i := 0; i < 100 && r.Score(addr) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, int64(penaltyBadSign), r.Score(addr))
+}
+
+func TestPenalizeTimeout(t *testing.T) {
+	cfg := &types.P2P{BanScore: 50, BanSeconds: 600, ScoreHalfLife: 60}
+	blacklist := &BlackList{badPeers: make(map[string]int64)}
+	r := newReputation(cfg, blacklist, db.NewDB("addrbook", "memdb", "", 16))
+
+	for i := 0; i < 10; i++ {
+		r.PenalizeTimeout("192.168.1.1:13802", penaltyTimeout)
+	}
+	//只有超时的节点分数不超过阈值的一半，不会被禁止
+	assert.Equal(t, int64(25), r.Score("192.168.1.1"))
+	assert.False(t, blacklist.Has("192.168.1.1:13802"))
+	assert.Equal(t, 0, len(r.ListBans()))
+	//再有其他的错误行为，仍然会被禁止
+	assert.True(t, r.Penalize("192.168.1.1:13802", penaltyInvalidBlock, "invalid block"))
+	assert.True(t, blacklist.Has("192.168.1.1:13802"))
+}
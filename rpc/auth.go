@@ -0,0 +1,191 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/33cn/chain33/types"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/metadata"
+)
+
+//authRole 角色以及角色可以访问的方法
+type authRole struct {
+	name    string
+	methods map[string]bool
+}
+
+//allow 没有开启认证时role为nil，可以访问所有方法
+func (role *authRole) allow(funcName string) bool {
+	if role == nil {
+		return true
+	}
+	return role.methods["*"] || role.methods[funcName]
+}
+
+type apiKey struct {
+	key  []byte
+	role *authRole
+}
+
+//authenticator 通过api key或者HS256签名的jwt确定请求者的角色
+type authenticator struct {
+	roles     map[string]*authRole
+	apiKeys   []*apiKey
+	jwtSecret []byte
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+}
+
+type jwtClaims struct {
+	Role string `json:"role"`
+	Exp  int64  `json:"exp,omitempty"`
+	Nbf  int64  `json:"nbf,omitempty"`
+}
+
+//parseAuthRoles 解析"角色名:方法1,方法2"格式的配置
+func parseAuthRoles(cfgRoles []string) (map[string]*authRole, error) {
+	roles := make(map[string]*authRole)
+	for _, cfgRole := range cfgRoles {
+		items := strings.SplitN(cfgRole, ":", 2)
+		if len(items) != 2 || items[0] == "" || items[1] == "" {
+			return nil, fmt.Errorf("authRoles %s format should be role:method1,method2", cfgRole)
+		}
+		role, ok := roles[items[0]]
+		if !ok {
+			role = &authRole{name: items[0], methods: make(map[string]bool)}
+			roles[items[0]] = role
+		}
+		for _, method := range strings.Split(items[1], ",") {
+			method = strings.TrimSpace(method)
+			if method != "" {
+				role.methods[method] = true
+			}
+		}
+	}
+	return roles, nil
+}
+
+//newAuthenticator 没有配置api key和jwt密钥时不开启认证，返回nil
+func newAuthenticator(cfg *types.Rpc) (*authenticator, error) {
+	if len(cfg.ApiKeys) == 0 && cfg.JwtSecret == "" {
+		return nil, nil
+	}
+	roles, err := parseAuthRoles(cfg.AuthRoles)
+	if err != nil {
+		return nil, err
+	}
+	a := &authenticator{roles: roles, jwtSecret: []byte(cfg.JwtSecret)}
+	for _, cfgKey := range cfg.ApiKeys {
+		index := strings.LastIndex(cfgKey, ":")
+		if index <= 0 {
+			return nil, fmt.Errorf("apiKeys %s format should be key:role", cfgKey)
+		}
+		role, ok := roles[cfgKey[index+1:]]
+		if !ok {
+			return nil, fmt.Errorf("apiKeys role %s is not defined in authRoles", cfgKey[index+1:])
+		}
+		a.apiKeys = append(a.apiKeys, &apiKey{key: []byte(cfgKey[:index]), role: role})
+	}
+	return a, nil
+}
+
+//authenticate 根据Authorization的值返回角色，没有开启认证时返回nil
+func (a *authenticator) authenticate(authorization string) (*authRole, error) {
+	if a == nil {
+		return nil, nil
+	}
+	const prefix = "bearer "
+	if len(authorization) <= len(prefix) || !strings.EqualFold(authorization[:len(prefix)], prefix) {
+		return nil, types.ErrAuthRequired
+	}
+	token := strings.TrimSpace(authorization[len(prefix):])
+	//api key使用固定时间的比较，并且比较所有的key
+	var role *authRole
+	for _, key := range a.apiKeys {
+		if subtle.ConstantTimeCompare(key.key, []byte(token)) == 1 {
+			role = key.role
+		}
+	}
+	if role != nil {
+		return role, nil
+	}
+	if len(a.jwtSecret) == 0 || strings.Count(token, ".") != 2 {
+		return nil, types.ErrInvalidToken
+	}
+	claims, err := parseJWT(token, a.jwtSecret, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	role, ok := a.roles[claims.Role]
+	if !ok {
+		return nil, types.ErrInvalidToken
+	}
+	return role, nil
+}
+
+//parseJWT 检查HS256签名和有效期，返回jwt的声明
+func parseJWT(token string, secret []byte, now time.Time) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, types.ErrInvalidToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, types.ErrInvalidToken
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return nil, types.ErrInvalidToken
+	}
+	var header jwtHeader
+	if err = decodeJWTPart(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return nil, types.ErrInvalidToken
+	}
+	var claims jwtClaims
+	if err = decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, types.ErrInvalidToken
+	}
+	if claims.Exp != 0 && now.Unix() >= claims.Exp {
+		return nil, types.ErrTokenExpired
+	}
+	if claims.Nbf != 0 && now.Unix() < claims.Nbf {
+		return nil, types.ErrInvalidToken
+	}
+	return &claims, nil
+}
+
+func decodeJWTPart(part string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+//grpcAuthorization 从grpc请求的metadata中获取authorization
+func grpcAuthorization(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	values := md["authorization"]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
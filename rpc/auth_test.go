@@ -0,0 +1,159 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/33cn/chain33/client/mocks"
+	qmocks "github.com/33cn/chain33/queue/mocks"
+	"github.com/33cn/chain33/types"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	pr "google.golang.org/grpc/peer"
+)
+
+func signTestJWT(secret string, alg string, claims *jwtClaims) string {
+	header, _ := json.Marshal(&jwtHeader{Alg: alg, Typ: "JWT"})
+	payload, _ := json.Marshal(claims)
+	data := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(data))
+	return data + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func newTestAuthCfg() *types.Rpc {
+	return &types.Rpc{
+		ApiKeys:   []string{"readkey:readonly", "adminkey:admin"},
+		JwtSecret: "secret",
+		AuthRoles: []string{"readonly:IsSync, GetLastHeader", "wallet:SendToAddress", "admin:*"},
+	}
+}
+
+func TestNewAuthenticator(t *testing.T) {
+	a, err := newAuthenticator(&types.Rpc{AuthRoles: []string{"admin:*"}})
+	assert.Nil(t, err)
+	assert.Nil(t, a)
+	role, err := a.authenticate("")
+	assert.Nil(t, err)
+	assert.True(t, role.allow("DumpPrivkey"))
+
+	a, err = newAuthenticator(newTestAuthCfg())
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(a.roles))
+	assert.True(t, a.roles["readonly"].allow("GetLastHeader"))
+	assert.False(t, a.roles["readonly"].allow("SendToAddress"))
+	assert.True(t, a.roles["admin"].allow("DumpPrivkey"))
+
+	for _, cfg := range []*types.Rpc{
+		{ApiKeys: []string{"key"}, AuthRoles: []string{"admin:*"}},
+		{ApiKeys: []string{"key:notexist"}, AuthRoles: []string{"admin:*"}},
+		{JwtSecret: "secret", AuthRoles: []string{"admin"}},
+		{JwtSecret: "secret", AuthRoles: []string{":*"}},
+	} {
+		_, err = newAuthenticator(cfg)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	a, err := newAuthenticator(newTestAuthCfg())
+	assert.Nil(t, err)
+
+	_, err = a.authenticate("")
+	assert.Equal(t, types.ErrAuthRequired, err)
+	_, err = a.authenticate("Basic readkey")
+	assert.Equal(t, types.ErrAuthRequired, err)
+	_, err = a.authenticate("Bearer wrongkey")
+	assert.Equal(t, types.ErrInvalidToken, err)
+	role, err := a.authenticate("Bearer readkey")
+	assert.Nil(t, err)
+	assert.Equal(t, "readonly", role.name)
+	role, err = a.authenticate("bearer adminkey")
+	assert.Nil(t, err)
+	assert.Equal(t, "admin", role.name)
+
+	now := time.Now().Unix()
+	role, err = a.authenticate("Bearer " + signTestJWT("secret", "HS256", &jwtClaims{Role: "wallet", Exp: now + 60}))
+	assert.Nil(t, err)
+	assert.Equal(t, "wallet", role.name)
+	_, err = a.authenticate("Bearer " + signTestJWT("secret", "HS256", &jwtClaims{Role: "wallet", Exp: now - 1}))
+	assert.Equal(t, types.ErrTokenExpired, err)
+	_, err = a.authenticate("Bearer " + signTestJWT("secret", "HS256", &jwtClaims{Role: "wallet", Nbf: now + 60}))
+	assert.Equal(t, types.ErrInvalidToken, err)
+	_, err = a.authenticate("Bearer " + signTestJWT("other", "HS256", &jwtClaims{Role: "wallet"}))
+	assert.Equal(t, types.ErrInvalidToken, err)
+	_, err = a.authenticate("Bearer " + signTestJWT("secret", "none", &jwtClaims{Role: "wallet"}))
+	assert.Equal(t, types.ErrInvalidToken, err)
+	_, err = a.authenticate("Bearer " + signTestJWT("secret", "HS256", &jwtClaims{Role: "notexist"}))
+	assert.Equal(t, types.ErrInvalidToken, err)
+}
+
+func TestJSONRPCServer_Auth(t *testing.T) {
+	rpcCfg = newTestAuthCfg()
+	rpcCfg.JrpcBindAddr = "127.0.0.1:0"
+	InitCfg(rpcCfg)
+	defer InitAuth(&types.Rpc{})
+	server := NewJSONRPCServer(&qmocks.Client{}, nil)
+	api := new(mocks.QueueProtocolAPI)
+	server.jrpc = *newTestChain33(api)
+	port, err := server.Listen()
+	assert.Nil(t, err)
+	defer server.l.Close()
+	api.On("IsSync").Return(&types.Reply{IsOk: true}, nil)
+
+	call := func(token string, body string) (int, *serverResponse) {
+		req, err := http.NewRequest("POST", fmt.Sprintf("http://127.0.0.1:%d/", port), strings.NewReader(body))
+		assert.Nil(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		assert.Nil(t, err)
+		defer resp.Body.Close()
+		var result serverResponse
+		assert.Nil(t, json.NewDecoder(resp.Body).Decode(&result))
+		return resp.StatusCode, &result
+	}
+	isSync := `{"jsonrpc":"2.0","id":1,"method":"Chain33.IsSync","params":[{}]}`
+	//本机访问也需要认证
+	status, resp := call("", isSync)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Equal(t, types.ErrAuthRequired.Error(), resp.Error)
+	_, resp = call("readkey", isSync)
+	assert.Nil(t, resp.Error)
+	assert.Equal(t, true, resp.Result)
+	_, resp = call("readkey", `{"jsonrpc":"2.0","id":2,"method":"Chain33.DumpPrivkey","params":[{"data":"addr"}]}`)
+	assert.Equal(t, uint64(2), resp.Id)
+	assert.Equal(t, "The DumpPrivkey method is not authorized!", resp.Error)
+}
+
+func TestGrpcAuth(t *testing.T) {
+	InitCfg(newTestAuthCfg())
+	defer InitAuth(&types.Rpc{})
+	addr := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 8802}
+	info := &grpc.UnaryServerInfo{FullMethod: "/types.chain33/DumpPrivkey"}
+	ctx := pr.NewContext(context.Background(), &pr.Peer{Addr: addr})
+	assert.Equal(t, types.ErrAuthRequired, auth(ctx, info))
+
+	readCtx := metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer readkey"))
+	assert.Equal(t, "The DumpPrivkey method is not authorized!", auth(readCtx, info).Error())
+	assert.Nil(t, auth(readCtx, &grpc.UnaryServerInfo{FullMethod: "/types.chain33/IsSync"}))
+
+	token := signTestJWT("secret", "HS256", &jwtClaims{Role: "admin"})
+	adminCtx := metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+token))
+	assert.Nil(t, auth(adminCtx, info))
+}
@@ -45,7 +45,26 @@ func (c *channelClient) CreateRawTransaction(param *types.CreateTx) ([]byte, err
 	if param.IsToken {
 		execer = types.ExecName("token")
 	}
-	return types.CallCreateTx(execer, "", param)
+	txHex, err := types.CallCreateTx(execer, "", param)
+	if err != nil || !types.IsEnable("sequentialNonce") {
+		return txHex, err
+	}
+	//开启sequentialNonce后随机的nonce不能被执行，需要根据from填写账户的nonce
+	if param.From == "" {
+		log.Error("CreateRawTransaction", "Error", "from is required when sequentialNonce enabled")
+		return nil, types.ErrInvalidParam
+	}
+	nonce, err := c.GetAccountNonce(&types.ReqString{Data: param.From})
+	if err != nil {
+		return nil, err
+	}
+	var tx types.Transaction
+	err = types.Decode(txHex, &tx)
+	if err != nil {
+		return nil, err
+	}
+	tx.Nonce = nonce.Data
+	return types.Encode(&tx), nil
 }
 
 func (c *channelClient) CreateRawTxGroup(param *types.CreateTransactionGroup) ([]byte, error) {
@@ -171,6 +190,15 @@ func (c *channelClient) GetAllExecBalance(in *types.ReqAddr) (*types.AllExecBala
 	if err != nil {
 		return nil, types.ErrInvalidAddress
 	}
+	//指定高度时先取得对应的状态，所有执行器都在同一个状态上查询
+	stateHash := in.StateHash
+	if stateHash == "" && in.Height > 0 {
+		hash, err := account.GetStateHashByHeight(c.QueueProtocolAPI, in.Height)
+		if err != nil {
+			return nil, err
+		}
+		stateHash = common.ToHex(hash)
+	}
 	var addrs []string
 	addrs = append(addrs, addr)
 	allBalance := &types.AllExecBalance{Addr: addr}
@@ -179,8 +207,12 @@ func (c *channelClient) GetAllExecBalance(in *types.ReqAddr) (*types.AllExecBala
 		params := &types.ReqBalance{
 			Addresses: addrs,
 			Execer:    execer,
+			StateHash: stateHash,
 		}
 		res, err := c.GetBalance(params)
+		if err == types.ErrStateNotExist {
+			return nil, err
+		}
 		if err != nil {
 			continue
 		}
@@ -197,6 +229,44 @@ func (c *channelClient) GetAllExecBalance(in *types.ReqAddr) (*types.AllExecBala
 	return allBalance, nil
 }
 
+//GetTxProof 获取交易的merkle proof以及交易所在的区块头
+func (c *channelClient) GetTxProof(in *types.ReqHash) (*types.ReplyTxProof, error) {
+	if in == nil || len(in.Hash) == 0 {
+		return nil, types.ErrInvalidParam
+	}
+	detail, err := c.QueryTx(in)
+	if err != nil {
+		return nil, err
+	}
+	header, err := account.GetHeaderByHeight(c.QueueProtocolAPI, detail.GetHeight())
+	if err != nil {
+		return nil, err
+	}
+	return &types.ReplyTxProof{Tx: detail.GetTx(), Index: detail.GetIndex(), Proofs: detail.GetProofs(), Header: header}, nil
+}
+
+//GetStateProof 获取状态数据的值和proof，以及状态所在的区块头
+func (c *channelClient) GetStateProof(in *types.ReqStateProof) (*types.ReplyStateProof, error) {
+	if in == nil || len(in.Key) == 0 {
+		return nil, types.ErrInvalidParam
+	}
+	var header *types.Header
+	var err error
+	if in.Height > 0 {
+		header, err = account.GetHeaderByHeight(c.QueueProtocolAPI, in.Height)
+	} else {
+		header, err = c.GetLastHeader()
+	}
+	if err != nil {
+		return nil, err
+	}
+	reply, err := c.StoreGetProof(&types.StoreGetProof{StateHash: header.StateHash, Key: in.Key})
+	if err != nil {
+		return nil, err
+	}
+	return &types.ReplyStateProof{Value: reply.Value, Proof: reply.Proof, Header: header}, nil
+}
+
 func (c *channelClient) GetTotalCoins(in *types.ReqGetTotalCoins) (*types.ReplyGetTotalCoins, error) {
 	//获取地址账户的余额通过account模块
 	resp, err := c.accountdb.GetTotalCoins(c.QueueProtocolAPI, in)
@@ -228,3 +298,33 @@ func (c *channelClient) GetTimeStatus() (*types.TimeStatus, error) {
 	diff := local.Sub(ntpTime) / time.Second
 	return &types.TimeStatus{NtpTime: ntpTime.Format("2006-01-02 15:04:05"), LocalTime: local.Format("2006-01-02 15:04:05"), Diff: int64(diff)}, nil
 }
+
+//GetBlockSeqs 获取[start, end]之间的区块序列以及对应的区块详情，回滚的区块也可以通过hash获取
+func (c *channelClient) GetBlockSeqs(start, end int64) ([]*types.BlockSeq, error) {
+	seqs, err := c.GetBlockSequences(&types.ReqBlocks{Start: start, End: end})
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([][]byte, len(seqs.GetItems()))
+	for i, item := range seqs.GetItems() {
+		if item == nil {
+			return nil, types.ErrBlockNotFound
+		}
+		hashes[i] = item.Hash
+	}
+	details, err := c.GetBlockByHashes(&types.ReqHashes{Hashes: hashes})
+	if err != nil {
+		return nil, err
+	}
+	if len(details.GetItems()) != len(hashes) {
+		return nil, types.ErrBlockNotFound
+	}
+	blockSeqs := make([]*types.BlockSeq, len(hashes))
+	for i, detail := range details.GetItems() {
+		if detail == nil {
+			return nil, types.ErrBlockNotFound
+		}
+		blockSeqs[i] = &types.BlockSeq{Num: start + int64(i), Seq: seqs.Items[i], Detail: detail}
+	}
+	return blockSeqs, nil
+}
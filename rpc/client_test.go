@@ -359,6 +359,32 @@ func TestChannelClient_GetBalance(t *testing.T) {
 	testChannelClient_GetBalanceOther(t)
 }
 
+func TestChannelClient_GetAllExecBalance(t *testing.T) {
+	api := new(mocks.QueueProtocolAPI)
+	client := &channelClient{
+		QueueProtocolAPI: api,
+		accountdb:        new(account.DB),
+	}
+	addr := "1Jn2qu84Z1SUUosWjySggBS9pKWdAP3tZt"
+	header := &types.Header{Height: 10, StateHash: []byte("statehash")}
+	api.On("GetHeaders", &types.ReqBlocks{Start: 10, End: 10}).Return(&types.Headers{Items: []*types.Header{header}}, nil)
+	acc := &types.Account{Addr: addr, Balance: 100}
+	api.On("StoreGet", mock.MatchedBy(func(req *types.StoreGet) bool {
+		return string(req.StateHash) == string(header.StateHash)
+	})).Return(&types.StoreReplyValue{Values: [][]byte{types.Encode(acc)}}, nil)
+
+	reply, err := client.GetAllExecBalance(&types.ReqAddr{Addr: addr, Height: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, addr, reply.Addr)
+	assert.NotEqual(t, 0, len(reply.ExecAccount))
+	assert.Equal(t, int64(100), reply.ExecAccount[0].Account.Balance)
+	api.AssertNotCalled(t, "GetLastHeader")
+
+	api.On("GetHeaders", &types.ReqBlocks{Start: 11, End: 11}).Return(&types.Headers{}, nil)
+	_, err = client.GetAllExecBalance(&types.ReqAddr{Addr: addr, Height: 11})
+	assert.Equal(t, types.ErrHeightNotExist, err)
+}
+
 // func TestChannelClient_GetTotalCoins(t *testing.T) {
 // 	client := newTestChannelClient()
 // 	data, err := client.GetTotalCoins(nil)
@@ -122,6 +122,18 @@ func (g *Grpc) ImportPrivkey(ctx context.Context, in *pb.ReqWalletImportPrivkey)
 	return g.cli.WalletImportprivkey(in)
 }
 
+func (g *Grpc) ImportWatch(ctx context.Context, in *pb.ReqWalletImportWatch) (*pb.WalletAccounts, error) {
+	return g.cli.WalletImportWatch(in)
+}
+
+func (g *Grpc) ExportXpub(ctx context.Context, in *pb.ReqNil) (*pb.ReplyString, error) {
+	return g.cli.WalletExportXpub(in)
+}
+
+func (g *Grpc) RescanWallet(ctx context.Context, in *pb.ReqWalletRescan) (*pb.WalletRescanStatus, error) {
+	return g.cli.WalletRescan(in)
+}
+
 func (g *Grpc) SendToAddress(ctx context.Context, in *pb.ReqWalletSendToAddress) (*pb.ReplyHash, error) {
 	return g.cli.WalletSendToAddress(in)
 }
@@ -162,6 +174,10 @@ func (g *Grpc) GetLastMemPool(ctx context.Context, in *pb.ReqNil) (*pb.ReplyTxLi
 	return g.cli.GetLastMempool()
 }
 
+func (g *Grpc) GetAccountNonce(ctx context.Context, in *pb.ReqString) (*pb.Int64, error) {
+	return g.cli.GetAccountNonce(in)
+}
+
 //add by hyb
 //GetBlockOverview(parm *types.ReqHash) (*types.BlockOverview, error)
 func (g *Grpc) GetBlockOverview(ctx context.Context, in *pb.ReqHash) (*pb.BlockOverview, error) {
@@ -289,3 +305,87 @@ func (g *Grpc) GetBlockByHashes(ctx context.Context, in *pb.ReqHashes) (*pb.Bloc
 func (g *Grpc) SignRawTx(ctx context.Context, in *pb.ReqSignRawTx) (*pb.ReplySignRawTx, error) {
 	return g.cli.SignRawTx(in)
 }
+
+func (g *Grpc) SimulateTransaction(ctx context.Context, in *pb.ReqSimulateTx) (*pb.ReplySimulateTx, error) {
+	return g.cli.SimulateTransaction(in)
+}
+
+func (g *Grpc) GetStateProof(ctx context.Context, in *pb.ReqStateProof) (*pb.ReplyStateProof, error) {
+	return g.cli.GetStateProof(in)
+}
+
+func (g *Grpc) GetTxProof(ctx context.Context, in *pb.ReqHash) (*pb.ReplyTxProof, error) {
+	return g.cli.GetTxProof(in)
+}
+
+func (g *Grpc) GetStorePruneStatus(ctx context.Context, in *pb.ReqNil) (*pb.StorePruneStatus, error) {
+	return g.cli.StoreGetPruneStatus()
+}
+
+func (g *Grpc) ExportSnapshot(ctx context.Context, in *pb.ReqString) (*pb.SnapshotHeader, error) {
+	return g.cli.ExportSnapshot(in)
+}
+
+func (g *Grpc) ListPeerBans(ctx context.Context, in *pb.ReqNil) (*pb.PeerBans, error) {
+	return g.cli.ListPeerBans()
+}
+
+func (g *Grpc) UnbanPeer(ctx context.Context, in *pb.ReqString) (*pb.Reply, error) {
+	return g.cli.UnbanPeer(in)
+}
+
+//每次从blockchain获取的区块序列数量，以及追上最新序列之后检查新序列的间隔
+var (
+	blockSeqStreamBatch    int64 = 32
+	blockSeqStreamInterval       = time.Second
+)
+
+//StreamBlockSequences 从fromSeq开始按顺序推送区块序列和区块详情，包括添加和回滚的区块，
+//追上最新的序列之后等待新的区块。客户端断开之后用收到的最后一个序列号+1重新订阅就可以继续
+func (g *Grpc) StreamBlockSequences(in *pb.ReqBlockSeqStream, stream pb.Chain33_StreamBlockSequencesServer) error {
+	seq := in.GetFromSeq()
+	if seq < 0 {
+		return pb.ErrInvalidParam
+	}
+	last, err := g.cli.GetLastBlockSequence()
+	if err != nil {
+		return err
+	}
+	//没有开启isRecordBlockSequence时没有序列记录
+	if last.Data < 0 {
+		return pb.ErrRecordBlockSequence
+	}
+	if seq > last.Data+1 {
+		return pb.ErrStartHeight
+	}
+	ticker := time.NewTicker(blockSeqStreamInterval)
+	defer ticker.Stop()
+	for {
+		for seq <= last.Data {
+			end := seq + blockSeqStreamBatch - 1
+			if end > last.Data {
+				end = last.Data
+			}
+			items, err := g.cli.GetBlockSeqs(seq, end)
+			if err != nil {
+				return err
+			}
+			for _, item := range items {
+				err = stream.Send(item)
+				if err != nil {
+					return err
+				}
+			}
+			seq = end + 1
+		}
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case <-ticker.C:
+		}
+		last, err = g.cli.GetLastBlockSequence()
+		if err != nil {
+			return err
+		}
+	}
+}
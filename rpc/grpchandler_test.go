@@ -6,6 +6,7 @@ package rpc
 
 import (
 	"testing"
+	"time"
 
 	"github.com/33cn/chain33/types"
 	pb "github.com/33cn/chain33/types"
@@ -17,6 +18,7 @@ import (
 	"github.com/33cn/chain33/client/mocks"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/peer"
 )
 
@@ -1046,3 +1048,78 @@ func TestQueryTransaction(t *testing.T) {
 //func Test_CreateTxGroup(t *testing.T) {
 //	testCreateTxGroupOk(t)
 //}
+
+type testBlockSeqStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	cancel func()
+	items  []*pb.BlockSeq
+	max    int
+}
+
+func (s *testBlockSeqStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *testBlockSeqStream) Send(item *pb.BlockSeq) error {
+	s.items = append(s.items, item)
+	if len(s.items) >= s.max {
+		s.cancel()
+	}
+	return nil
+}
+
+func newTestBlockSeqStream(max int) *testBlockSeqStream {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &testBlockSeqStream{ctx: ctx, cancel: cancel, max: max}
+}
+
+func TestGrpc_StreamBlockSequences(t *testing.T) {
+	api := new(mocks.QueueProtocolAPI)
+	var grpcs Grpc
+	grpcs.cli.QueueProtocolAPI = api
+	blockSeqStreamBatch = 2
+	blockSeqStreamInterval = time.Millisecond
+
+	seq := func(i int64, ty int64) *pb.BlockSequence {
+		return &pb.BlockSequence{Hash: []byte(fmt.Sprintf("hash%d", i)), Type: ty}
+	}
+	detail := func(i int64) *pb.BlockDetail {
+		return &pb.BlockDetail{Block: &pb.Block{Height: i}}
+	}
+	//序列0-2已经存在，序列3是等待之后新增的回滚区块
+	api.On("GetLastBlockSequence").Return(&pb.Int64{Data: 2}, nil).Times(3)
+	api.On("GetLastBlockSequence").Return(&pb.Int64{Data: 3}, nil)
+	api.On("GetBlockSequences", &pb.ReqBlocks{Start: 1, End: 2}).Return(&pb.BlockSequences{Items: []*pb.BlockSequence{seq(1, 1), seq(2, 1)}}, nil)
+	api.On("GetBlockByHashes", &pb.ReqHashes{Hashes: [][]byte{[]byte("hash1"), []byte("hash2")}}).Return(&pb.BlockDetails{Items: []*pb.BlockDetail{detail(1), detail(2)}}, nil)
+	api.On("GetBlockSequences", &pb.ReqBlocks{Start: 3, End: 3}).Return(&pb.BlockSequences{Items: []*pb.BlockSequence{seq(2, 2)}}, nil)
+	api.On("GetBlockByHashes", &pb.ReqHashes{Hashes: [][]byte{[]byte("hash2")}}).Return(&pb.BlockDetails{Items: []*pb.BlockDetail{detail(2)}}, nil)
+
+	stream := newTestBlockSeqStream(3)
+	err := grpcs.StreamBlockSequences(&pb.ReqBlockSeqStream{FromSeq: 1}, stream)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 3, len(stream.items))
+	for i, item := range stream.items {
+		assert.Equal(t, int64(i+1), item.Num)
+	}
+	assert.Equal(t, int64(2), stream.items[2].Seq.Type)
+	assert.Equal(t, int64(2), stream.items[2].Detail.Block.Height)
+
+	//起始序列号超过最新序列
+	err = grpcs.StreamBlockSequences(&pb.ReqBlockSeqStream{FromSeq: 5}, newTestBlockSeqStream(1))
+	assert.Equal(t, pb.ErrStartHeight, err)
+	err = grpcs.StreamBlockSequences(&pb.ReqBlockSeqStream{FromSeq: -1}, newTestBlockSeqStream(1))
+	assert.Equal(t, pb.ErrInvalidParam, err)
+
+	//区块详情缺失
+	api.On("GetBlockSequences", &pb.ReqBlocks{Start: 0, End: 1}).Return(&pb.BlockSequences{Items: []*pb.BlockSequence{seq(0, 1), nil}}, nil)
+	err = grpcs.StreamBlockSequences(&pb.ReqBlockSeqStream{FromSeq: 0}, newTestBlockSeqStream(1))
+	assert.Equal(t, pb.ErrBlockNotFound, err)
+
+	//没有开启区块序列记录
+	api = new(mocks.QueueProtocolAPI)
+	grpcs.cli.QueueProtocolAPI = api
+	api.On("GetLastBlockSequence").Return(&pb.Int64{Data: -1}, nil)
+	err = grpcs.StreamBlockSequences(&pb.ReqBlockSeqStream{FromSeq: 0}, newTestBlockSeqStream(1))
+	assert.Equal(t, pb.ErrRecordBlockSequence, err)
+}
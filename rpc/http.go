@@ -16,6 +16,7 @@ import (
 	"net/rpc/jsonrpc"
 	"strings"
 
+	"github.com/33cn/chain33/types"
 	"github.com/rs/cors"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
@@ -64,12 +65,26 @@ func (j *JSONRPCServer) Listen() (int, error) {
 			writeError(w, r, 0, fmt.Sprintf(`The %s Address is not authorized!`, ip))
 			return
 		}
+		role, err := rpcAuth.authenticate(r.Header.Get("Authorization"))
+		if err != nil {
+			writeErrorStatus(w, r, http.StatusUnauthorized, 0, err.Error())
+			return
+		}
+		if r.URL.Path == "/ws" && rpcCfg.EnableWebsocket && j.ws != nil {
+			j.ws.serveWs(w, r, ip, role)
+			return
+		}
 		if r.URL.Path == "/" {
 			data, err := ioutil.ReadAll(r.Body)
 			if err != nil {
 				writeError(w, r, 0, "Can't get request body!")
 				return
 			}
+			//批量请求
+			if isBatchRequest(data) {
+				j.serveBatch(w, r, ip, role, data)
+				return
+			}
 			//格式做一个检查
 			client, err := parseJsonRpcParams(data)
 			errstr := "nil"
@@ -81,14 +96,10 @@ func (j *JSONRPCServer) Listen() (int, error) {
 				writeError(w, r, 0, fmt.Sprintf(`parse request err %s`, err.Error()))
 				return
 			}
-			//Release local request
-			ipaddr := net.ParseIP(ip)
-			if !ipaddr.IsLoopback() {
-				funcName := strings.Split(client.Method, ".")[len(strings.Split(client.Method, "."))-1]
-				if checkJrpcFuncBlacklist(funcName) || !checkJrpcFuncWhitelist(funcName) {
-					writeError(w, r, client.Id, fmt.Sprintf(`The %s method is not authorized!`, funcName))
-					return
-				}
+			status, err := checkJrpcRequest(ip, role, client.Method)
+			if err != nil {
+				writeErrorStatus(w, r, status, client.Id, err.Error())
+				return
 			}
 			serverCodec := jsonrpc.NewServerCodec(&HTTPConn{in: ioutil.NopCloser(bytes.NewReader(data)), out: w, r: r})
 			w.Header().Set("Content-type", "application/json")
@@ -105,6 +116,13 @@ func (j *JSONRPCServer) Listen() (int, error) {
 	})
 
 	handler = co.Handler(handler)
+	if rpcCfg.EnableWebsocket {
+		if j.ws != nil {
+			j.ws.start()
+		} else {
+			log.Error("websocket is enabled but no queue client is set for it")
+		}
+	}
 	go http.Serve(listener, handler)
 	return listener.Addr().(*net.TCPAddr).Port, nil
 }
@@ -116,9 +134,13 @@ type serverResponse struct {
 }
 
 func writeError(w http.ResponseWriter, r *http.Request, id uint64, errstr string) {
-	w.Header().Set("Content-type", "application/json")
 	//错误的请求也返回 200
-	w.WriteHeader(200)
+	writeErrorStatus(w, r, 200, id, errstr)
+}
+
+func writeErrorStatus(w http.ResponseWriter, r *http.Request, status int, id uint64, errstr string) {
+	w.Header().Set("Content-type", "application/json")
+	w.WriteHeader(status)
 	resp, err := json.Marshal(&serverResponse{id, nil, errstr})
 	if err != nil {
 		log.Debug("json marshal error, nerver happen")
@@ -147,6 +169,15 @@ func isLoopBackAddr(addr net.Addr) bool {
 func auth(ctx context.Context, info *grpc.UnaryServerInfo) error {
 	getctx, ok := pr.FromContext(ctx)
 	if ok {
+		funcName := strings.Split(info.FullMethod, "/")[len(strings.Split(info.FullMethod, "/"))-1]
+		//token的认证对本机访问同样有效
+		role, err := rpcAuth.authenticate(grpcAuthorization(ctx))
+		if err != nil {
+			return err
+		}
+		if !role.allow(funcName) {
+			return fmt.Errorf("The %s method is not authorized!", funcName)
+		}
 		if isLoopBackAddr(getctx.Addr) {
 			return nil
 		}
@@ -160,7 +191,6 @@ func auth(ctx context.Context, info *grpc.UnaryServerInfo) error {
 			return fmt.Errorf("The %s Address is not authorized!", ip)
 		}
 
-		funcName := strings.Split(info.FullMethod, "/")[len(strings.Split(info.FullMethod, "/"))-1]
 		if checkGrpcFuncBlacklist(funcName) || !checkGrpcFuncWhitelist(funcName) {
 			return fmt.Errorf("The %s method is not authorized!", funcName)
 		}
@@ -175,6 +205,96 @@ type clientRequest struct {
 	Id     uint64         `json:"id"`
 }
 
+//checkJrpcRequest 检查角色的权限、方法的黑白名单和访问频率，本机访问只检查角色的权限，返回出错时使用的http状态码
+func checkJrpcRequest(ip string, role *authRole, method string) (int, error) {
+	funcName := method[strings.LastIndex(method, ".")+1:]
+	if !role.allow(funcName) {
+		return 200, fmt.Errorf(`The %s method is not authorized!`, funcName)
+	}
+	if net.ParseIP(ip).IsLoopback() {
+		return 200, nil
+	}
+	if checkJrpcFuncBlacklist(funcName) || !checkJrpcFuncWhitelist(funcName) {
+		return 200, fmt.Errorf(`The %s method is not authorized!`, funcName)
+	}
+	if !jrpcLimiter.allow(ip, funcName) {
+		return http.StatusTooManyRequests, types.ErrRateLimit
+	}
+	return 200, nil
+}
+
+//批量请求的最大数量
+const maxJrpcBatchSize = 100
+
+func isBatchRequest(data []byte) bool {
+	data = bytes.TrimLeft(data, " \t\r\n")
+	return len(data) > 0 && data[0] == '['
+}
+
+//bufConn 批量请求中每个请求单独处理，结果写到缓存中
+type bufConn struct {
+	in  io.Reader
+	out io.Writer
+}
+
+func (c *bufConn) Read(p []byte) (n int, err error) { return c.in.Read(p) }
+
+func (c *bufConn) Write(d []byte) (n int, err error) { return c.out.Write(d) }
+
+func (c *bufConn) Close() error { return nil }
+
+//serveBatch 处理JSON-RPC 2.0的批量请求，每个请求单独检查权限和访问频率，按顺序返回结果数组
+func (j *JSONRPCServer) serveBatch(w http.ResponseWriter, r *http.Request, ip string, role *authRole, data []byte) {
+	var reqs []json.RawMessage
+	err := json.Unmarshal(data, &reqs)
+	if err != nil {
+		writeError(w, r, 0, fmt.Sprintf(`parse request err %s`, err.Error()))
+		return
+	}
+	if len(reqs) == 0 || len(reqs) > maxJrpcBatchSize {
+		writeError(w, r, 0, fmt.Sprintf(`batch request size should be 1 to %d`, maxJrpcBatchSize))
+		return
+	}
+	log.Debug("JSONRPCServer batch", "size", len(reqs))
+	resps := make([]json.RawMessage, 0, len(reqs))
+	for _, req := range reqs {
+		resps = append(resps, j.serveBatchItem(ip, role, req))
+	}
+	out, err := json.Marshal(resps)
+	if err != nil {
+		log.Debug("json marshal error, nerver happen")
+		return
+	}
+	w.Header().Set("Content-type", "application/json")
+	if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		w.Header().Set("Content-Encoding", "gzip")
+	}
+	w.WriteHeader(200)
+	conn := &HTTPConn{out: w, r: r}
+	conn.Write(out)
+}
+
+func (j *JSONRPCServer) serveBatchItem(ip string, role *authRole, data []byte) json.RawMessage {
+	errorResp := func(id uint64, errstr string) json.RawMessage {
+		resp, _ := json.Marshal(&serverResponse{id, nil, errstr})
+		return resp
+	}
+	client, err := parseJsonRpcParams(data)
+	if err != nil {
+		return errorResp(0, fmt.Sprintf(`parse request err %s`, err.Error()))
+	}
+	_, err = checkJrpcRequest(ip, role, client.Method)
+	if err != nil {
+		return errorResp(client.Id, err.Error())
+	}
+	var buf bytes.Buffer
+	err = j.s.ServeRequest(jsonrpc.NewServerCodec(&bufConn{in: bytes.NewReader(data), out: &buf}))
+	if err != nil {
+		return errorResp(client.Id, err.Error())
+	}
+	return bytes.TrimSpace(buf.Bytes())
+}
+
 func parseJsonRpcParams(data []byte) (*clientRequest, error) {
 	var req clientRequest
 	err := json.Unmarshal(data, &req)
@@ -184,6 +184,7 @@ func (c *Chain33) GetBlocks(in rpctypes.BlockParam, result *interface{}) error {
 			for i, rp := range item.Receipts {
 				var recp rpctypes.ReceiptData
 				recp.Ty = rp.GetTy()
+				recp.GasUsed = rp.GetGasUsed()
 				for _, log := range rp.Logs {
 					recp.Logs = append(recp.Logs,
 						&rpctypes.ReceiptLog{Ty: log.Ty, Log: common.ToHex(log.GetLog())})
@@ -300,6 +301,7 @@ func fmtTxDetail(tx *types.TransactionDetail, disableDetail bool) (*rpctypes.Tra
 
 	var recp rpctypes.ReceiptData
 	recp.Ty = tx.GetReceipt().GetTy()
+	recp.GasUsed = tx.GetReceipt().GetGasUsed()
 	logs := tx.GetReceipt().GetLogs()
 	if disableDetail {
 		logs = nil
@@ -381,7 +383,7 @@ func (c *Chain33) GetAccounts(in *types.ReqAccountList, result *interface{}) err
 	for _, wallet := range reply.Wallets {
 		accounts.Wallets = append(accounts.Wallets, &rpctypes.WalletAccount{Label: wallet.GetLabel(),
 			Acc: &rpctypes.Account{Currency: wallet.GetAcc().GetCurrency(), Balance: wallet.GetAcc().GetBalance(),
-				Frozen: wallet.GetAcc().GetFrozen(), Addr: wallet.GetAcc().GetAddr()}})
+				Frozen: wallet.GetAcc().GetFrozen(), Addr: wallet.GetAcc().GetAddr()}, WatchOnly: wallet.GetWatchOnly()})
 	}
 	*result = &accounts
 	return nil
@@ -423,6 +425,36 @@ func (c *Chain33) ImportPrivkey(in types.ReqWalletImportPrivkey, result *interfa
 	return nil
 }
 
+//ImportWatch 导入只读的地址或者扩展公钥，钱包只跟踪地址的交易，交易需要在持有私钥的钱包中签名
+func (c *Chain33) ImportWatch(in types.ReqWalletImportWatch, result *interface{}) error {
+	reply, err := c.cli.WalletImportWatch(&in)
+	if err != nil {
+		return err
+	}
+	*result = reply
+	return nil
+}
+
+//ExportXpub 在持有私钥的冷钱包中导出扩展公钥，导入到只读钱包之后跟踪所有生成的地址
+func (c *Chain33) ExportXpub(in types.ReqNil, result *interface{}) error {
+	reply, err := c.cli.WalletExportXpub(&in)
+	if err != nil {
+		return err
+	}
+	*result = reply
+	return nil
+}
+
+//RescanWallet 后台扫描历史区块恢复地址的交易记录，进度通过GetWalletStatus查询
+func (c *Chain33) RescanWallet(in types.ReqWalletRescan, result *interface{}) error {
+	reply, err := c.cli.WalletRescan(&in)
+	if err != nil {
+		return err
+	}
+	*result = reply
+	return nil
+}
+
 func (c *Chain33) SendToAddress(in types.ReqWalletSendToAddress, result *interface{}) error {
 	log.Debug("Rpc SendToAddress", "Tx", in)
 	if types.IsPara() {
@@ -582,6 +614,7 @@ func (c *Chain33) GetPeerInfo(in types.ReqNil, result *interface{}) error {
 			pr.Name = peer.GetName()
 			pr.Port = peer.GetPort()
 			pr.Self = peer.GetSelf()
+			pr.Score = peer.GetScore()
 			pr.Header = &rpctypes.Header{
 				BlockTime:  peer.Header.GetBlockTime(),
 				Height:     peer.Header.GetHeight(),
@@ -652,6 +685,16 @@ func (c *Chain33) GetLastMemPool(in types.ReqNil, result *interface{}) error {
 	return nil
 }
 
+//GetAccountNonce 获取账户下一笔交易的nonce，没有开启sequentialNonce时返回随机数
+func (c *Chain33) GetAccountNonce(in types.ReqString, result *interface{}) error {
+	reply, err := c.cli.GetAccountNonce(&in)
+	if err != nil {
+		return err
+	}
+	*result = reply
+	return nil
+}
+
 //GetBlockOverview(parm *types.ReqHash) (*types.BlockOverview, error)
 func (c *Chain33) GetBlockOverview(in rpctypes.QueryParm, result *interface{}) error {
 	var data types.ReqHash
@@ -843,7 +886,17 @@ func (c *Chain33) Query(in rpctypes.Query4Jrpc, result *interface{}) error {
 		log.Error("EventQuery1", "err", err.Error())
 		return err
 	}
-	resp, err := c.cli.Query(types.ExecName(in.Execer), in.FuncName, decodePayload)
+	if types.IsNilP(decodePayload) {
+		log.Error("EventQuery1", "err", types.ErrInvalidParam)
+		return types.ErrInvalidParam
+	}
+	query := &types.ChainExecutor{
+		Driver:   types.ExecName(in.Execer),
+		FuncName: in.FuncName,
+		Param:    types.Encode(decodePayload),
+		Height:   in.Height,
+	}
+	resp, err := c.cli.QueryChain(query)
 	if err != nil {
 		log.Error("EventQuery2", "err", err.Error())
 		return err
@@ -926,6 +979,64 @@ func (c *Chain33) SignRawTx(in *types.ReqSignRawTx, result *interface{}) error {
 	return nil
 }
 
+//SimulateTransaction 模拟执行交易，返回收据、状态修改和手续费，不会修改区块链的状态
+func (c *Chain33) SimulateTransaction(in rpctypes.SimulateTxParam, result *interface{}) error {
+	data, err := common.FromHex(in.Data)
+	if err != nil {
+		return err
+	}
+	var tx types.Transaction
+	err = types.Decode(data, &tx)
+	if err != nil {
+		return err
+	}
+	req := &types.ReqSimulateTx{Tx: &tx, SignType: in.SignType}
+	if in.StateHash != "" {
+		req.StateHash, err = common.FromHex(in.StateHash)
+		if err != nil {
+			return err
+		}
+	}
+	if in.Pubkey != "" {
+		req.Pubkey, err = common.FromHex(in.Pubkey)
+		if err != nil {
+			return err
+		}
+	}
+	reply, err := c.cli.SimulateTransaction(req)
+	if err != nil {
+		return err
+	}
+	var recp rpctypes.ReceiptData
+	recp.Ty = reply.GetReceipt().GetTy()
+	recp.GasUsed = reply.GetReceipt().GetGasUsed()
+	for _, lg := range reply.GetReceipt().GetLogs() {
+		recp.Logs = append(recp.Logs,
+			&rpctypes.ReceiptLog{Ty: lg.Ty, Log: common.ToHex(lg.GetLog())})
+	}
+	recpResult, err := rpctypes.DecodeLog(tx.Execer, &recp)
+	if err != nil {
+		log.Error("SimulateTransaction", "Failed to DecodeLog for type", err)
+		return err
+	}
+	simResult := &rpctypes.SimulateTxResult{
+		Receipt:   recpResult,
+		Fee:       reply.GetFee(),
+		GasUsed:   recp.GasUsed,
+		Height:    reply.GetHeight(),
+		StateHash: common.ToHex(reply.GetStateHash()),
+	}
+	for _, kv := range reply.GetKV() {
+		simResult.KV = append(simResult.KV, &rpctypes.SimulateKV{
+			Key:     common.ToHex(kv.GetKey()),
+			Prev:    common.ToHex(kv.GetPrev()),
+			Current: common.ToHex(kv.GetCurrent()),
+		})
+	}
+	*result = simResult
+	return nil
+}
+
 func (c *Chain33) GetNetInfo(in *types.ReqNil, result *interface{}) error {
 	resp, err := c.cli.GetNetInfo()
 	if err != nil {
@@ -1113,3 +1224,115 @@ func (c *Chain33) ConvertExectoAddr(in rpctypes.ExecNameParm, result *string) er
 	*result = address.ExecAddress(in.ExecName)
 	return nil
 }
+
+//GetStateProof 获取状态数据的值和mavl proof，proof为MAVLProof的编码，可以用区块头的StateHash验证
+func (c *Chain33) GetStateProof(in rpctypes.StateProofParam, result *interface{}) error {
+	reply, err := c.cli.GetStateProof(&types.ReqStateProof{Key: []byte(in.Key), Height: in.Height})
+	if err != nil {
+		return err
+	}
+	*result = &rpctypes.StateProofResult{
+		Value:  common.ToHex(reply.GetValue()),
+		Proof:  common.ToHex(types.Encode(reply.GetProof())),
+		Header: convertHeader(reply.GetHeader()),
+	}
+	return nil
+}
+
+//GetTxProof 获取交易的merkle proof，txRoot即区块头的txHash，可以用merkle.VerifyMerkleBranch验证
+func (c *Chain33) GetTxProof(in rpctypes.QueryParm, result *interface{}) error {
+	hash, err := common.FromHex(in.Hash)
+	if err != nil {
+		return err
+	}
+	reply, err := c.cli.GetTxProof(&types.ReqHash{Hash: hash})
+	if err != nil {
+		return err
+	}
+	proof := &rpctypes.TxProofResult{
+		Tx:     common.ToHex(types.Encode(reply.GetTx())),
+		TxHash: common.ToHex(reply.GetTx().Hash()),
+		Index:  reply.GetIndex(),
+		TxRoot: common.ToHex(reply.GetHeader().GetTxHash()),
+		Header: convertHeader(reply.GetHeader()),
+	}
+	for _, branch := range reply.GetProofs() {
+		proof.Proofs = append(proof.Proofs, common.ToHex(branch))
+	}
+	*result = proof
+	return nil
+}
+
+func convertHeader(item *types.Header) *rpctypes.Header {
+	return &rpctypes.Header{
+		BlockTime:  item.GetBlockTime(),
+		TxCount:    item.GetTxCount(),
+		Hash:       common.ToHex(item.GetHash()),
+		Height:     item.GetHeight(),
+		ParentHash: common.ToHex(item.GetParentHash()),
+		StateHash:  common.ToHex(item.GetStateHash()),
+		TxHash:     common.ToHex(item.GetTxHash()),
+		Difficulty: item.GetDifficulty(),
+		Version:    item.GetVersion(),
+	}
+}
+
+//GetStorePruneStatus 获取mavl裁剪的进度
+func (c *Chain33) GetStorePruneStatus(in *types.ReqNil, result *interface{}) error {
+	reply, err := c.cli.StoreGetPruneStatus()
+	if err != nil {
+		return err
+	}
+	*result = &rpctypes.StorePruneStatus{
+		Enable:    reply.GetEnable(),
+		Pruning:   reply.GetPruning(),
+		Height:    reply.GetHeight(),
+		Retain:    reply.GetRetain(),
+		LastKey:   common.ToHex(reply.GetLastKey()),
+		LeafCount: reply.GetLeafCount(),
+		NodeCount: reply.GetNodeCount(),
+		Finished:  reply.GetFinished(),
+	}
+	return nil
+}
+
+//ExportSnapshot 导出最新区块的状态快照到节点配置的snapshotDir目录，参数为文件名，文件已经存在时返回错误
+func (c *Chain33) ExportSnapshot(in *types.ReqString, result *interface{}) error {
+	reply, err := c.cli.ExportSnapshot(in)
+	if err != nil {
+		return err
+	}
+	*result = &rpctypes.SnapshotHeader{
+		Title:      reply.GetTitle(),
+		Version:    reply.GetVersion(),
+		Height:     reply.GetHeight(),
+		BlockHash:  common.ToHex(reply.GetBlockHash()),
+		StateHash:  common.ToHex(reply.GetStateHash()),
+		CreateTime: reply.GetCreateTime(),
+	}
+	return nil
+}
+
+//ListPeerBans 因为不良行为被禁止连接的节点
+func (c *Chain33) ListPeerBans(in *types.ReqNil, result *interface{}) error {
+	reply, err := c.cli.ListPeerBans()
+	if err != nil {
+		return err
+	}
+	bans := make([]*rpctypes.PeerBan, 0, len(reply.GetItems()))
+	for _, ban := range reply.GetItems() {
+		bans = append(bans, &rpctypes.PeerBan{Addr: ban.GetAddr(), Reason: ban.GetReason(), Score: ban.GetScore(), Deadline: ban.GetDeadline()})
+	}
+	*result = bans
+	return nil
+}
+
+//UnbanPeer 解除对节点ip的禁止，参数为ip或者ip:port
+func (c *Chain33) UnbanPeer(in *types.ReqString, result *interface{}) error {
+	reply, err := c.cli.UnbanPeer(in)
+	if err != nil {
+		return err
+	}
+	*result = &rpctypes.Reply{IsOk: reply.GetIsOk(), Msg: string(reply.GetMsg())}
+	return nil
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/33cn/chain33/client/mocks"
 	"github.com/33cn/chain33/common"
+	"github.com/33cn/chain33/common/merkle"
 	rpctypes "github.com/33cn/chain33/rpc/types"
 	_ "github.com/33cn/chain33/system"
 	cty "github.com/33cn/chain33/system/dapp/coins/types"
@@ -768,6 +769,53 @@ func TestChain33_ImportPrivkey(t *testing.T) {
 	mock.AssertExpectationsForObjects(t, api)
 }
 
+func TestChain33_ImportWatch(t *testing.T) {
+	api := new(mocks.QueueProtocolAPI)
+	testChain33 := newTestChain33(api)
+
+	expected := &types.ReqWalletImportWatch{Addr: "1JmFaA6unrCFYEWPGRi7uuXY1KthTJxJEP", Label: "cold"}
+	reply := &types.WalletAccounts{Wallets: []*types.WalletAccount{{Acc: &types.Account{Addr: expected.Addr}, Label: "cold", WatchOnly: true}}}
+	api.On("WalletImportWatch", expected).Return(reply, nil)
+
+	var testResult interface{}
+	err := testChain33.ImportWatch(*expected, &testResult)
+	assert.NoError(t, err)
+	assert.Equal(t, reply, testResult)
+
+	mock.AssertExpectationsForObjects(t, api)
+}
+
+func TestChain33_ExportXpub(t *testing.T) {
+	api := new(mocks.QueueProtocolAPI)
+	testChain33 := newTestChain33(api)
+
+	reply := &types.ReplyString{Data: "xpub"}
+	api.On("WalletExportXpub", &types.ReqNil{}).Return(reply, nil)
+
+	var testResult interface{}
+	err := testChain33.ExportXpub(types.ReqNil{}, &testResult)
+	assert.NoError(t, err)
+	assert.Equal(t, reply, testResult)
+
+	mock.AssertExpectationsForObjects(t, api)
+}
+
+func TestChain33_RescanWallet(t *testing.T) {
+	api := new(mocks.QueueProtocolAPI)
+	testChain33 := newTestChain33(api)
+
+	expected := &types.ReqWalletRescan{FromHeight: 10}
+	reply := &types.WalletRescanStatus{Addrs: []string{"1JmFaA6unrCFYEWPGRi7uuXY1KthTJxJEP"}, FromHeight: 10, EndHeight: 100, Height: 10, Status: 1}
+	api.On("WalletRescan", expected).Return(reply, nil)
+
+	var testResult interface{}
+	err := testChain33.RescanWallet(*expected, &testResult)
+	assert.NoError(t, err)
+	assert.Equal(t, reply, testResult)
+
+	mock.AssertExpectationsForObjects(t, api)
+}
+
 func TestChain33_SendToAddress(t *testing.T) {
 	if types.IsPara() {
 		t.Skip()
@@ -1240,3 +1288,178 @@ func TestChain33_CreateTransaction(t *testing.T) {
 	err = client.CreateTransaction(in, &result)
 	assert.Nil(t, err)
 }
+
+func TestChain33_SimulateTransaction(t *testing.T) {
+	api := new(mocks.QueueProtocolAPI)
+	client := newTestChain33(api)
+	var result interface{}
+	err := client.SimulateTransaction(rpctypes.SimulateTxParam{Data: "0xzz"}, &result)
+	assert.NotNil(t, err)
+
+	tx := &types.Transaction{Execer: []byte("coins"), Payload: []byte("x")}
+	in := rpctypes.SimulateTxParam{Data: common.ToHex(types.Encode(tx)), Pubkey: "0x01", SignType: types.ED25519}
+	reply := &types.ReplySimulateTx{
+		Receipt: &types.ReceiptData{Ty: types.ExecOk, GasUsed: 100},
+		KV:      []*types.SimulateKV{{Key: []byte("k1"), Prev: []byte("v1"), Current: []byte("v2")}},
+		Fee:     100000,
+		Height:  1,
+	}
+	api.On("SimulateTransaction", &types.ReqSimulateTx{Tx: tx, Pubkey: []byte{1}, SignType: types.ED25519}).Return(reply, nil)
+	err = client.SimulateTransaction(in, &result)
+	assert.Nil(t, err)
+	simResult := result.(*rpctypes.SimulateTxResult)
+	assert.Equal(t, int64(100000), simResult.Fee)
+	assert.Equal(t, int64(100), simResult.GasUsed)
+	assert.Equal(t, common.ToHex([]byte("k1")), simResult.KV[0].Key)
+	assert.Equal(t, common.ToHex([]byte("v2")), simResult.KV[0].Current)
+
+	api = new(mocks.QueueProtocolAPI)
+	client = newTestChain33(api)
+	api.On("SimulateTransaction", mock.Anything).Return(nil, types.ErrSign)
+	err = client.SimulateTransaction(in, &result)
+	assert.Equal(t, types.ErrSign, err)
+}
+
+func TestChain33_GetStateProof(t *testing.T) {
+	api := new(mocks.QueueProtocolAPI)
+	client := newTestChain33(api)
+	var result interface{}
+	err := client.GetStateProof(rpctypes.StateProofParam{}, &result)
+	assert.Equal(t, types.ErrInvalidParam, err)
+
+	header := &types.Header{Height: 10, StateHash: []byte("statehash")}
+	api.On("GetHeaders", mock.Anything).Return(&types.Headers{Items: []*types.Header{header}}, nil)
+	proof := &types.MAVLProof{LeafHash: []byte("leaf"), RootHash: header.StateHash}
+	api.On("StoreGetProof", &types.StoreGetProof{StateHash: header.StateHash, Key: []byte("k1")}).
+		Return(&types.StoreReplyProof{Value: []byte("v1"), Proof: proof}, nil)
+	err = client.GetStateProof(rpctypes.StateProofParam{Key: "k1", Height: 10}, &result)
+	assert.Nil(t, err)
+	proofResult := result.(*rpctypes.StateProofResult)
+	assert.Equal(t, common.ToHex([]byte("v1")), proofResult.Value)
+	assert.Equal(t, common.ToHex(types.Encode(proof)), proofResult.Proof)
+	assert.Equal(t, int64(10), proofResult.Header.Height)
+	assert.Equal(t, common.ToHex(header.StateHash), proofResult.Header.StateHash)
+
+	api = new(mocks.QueueProtocolAPI)
+	client = newTestChain33(api)
+	api.On("GetHeaders", mock.Anything).Return(&types.Headers{}, nil)
+	err = client.GetStateProof(rpctypes.StateProofParam{Key: "k1", Height: 10}, &result)
+	assert.Equal(t, types.ErrHeightNotExist, err)
+}
+
+func TestChain33_GetTxProof(t *testing.T) {
+	api := new(mocks.QueueProtocolAPI)
+	client := newTestChain33(api)
+	var result interface{}
+	err := client.GetTxProof(rpctypes.QueryParm{Hash: "0xzz"}, &result)
+	assert.NotNil(t, err)
+
+	txs := []*types.Transaction{
+		{Execer: []byte("coins"), Payload: []byte("0")},
+		{Execer: []byte("coins"), Payload: []byte("1")},
+		{Execer: []byte("coins"), Payload: []byte("2")},
+	}
+	var leaves [][]byte
+	for _, tx := range txs {
+		leaves = append(leaves, tx.Hash())
+	}
+	root, branch := merkle.GetMerkleRootAndBranch(leaves, 1)
+	header := &types.Header{Height: 10, TxHash: root}
+	api.On("QueryTx", &types.ReqHash{Hash: txs[1].Hash()}).Return(&types.TransactionDetail{Tx: txs[1], Index: 1, Height: 10, Proofs: branch}, nil)
+	api.On("GetHeaders", &types.ReqBlocks{Start: 10, End: 10}).Return(&types.Headers{Items: []*types.Header{header}}, nil)
+	err = client.GetTxProof(rpctypes.QueryParm{Hash: common.ToHex(txs[1].Hash())}, &result)
+	assert.Nil(t, err)
+	proof := result.(*rpctypes.TxProofResult)
+	assert.Equal(t, int64(1), proof.Index)
+	assert.Equal(t, common.ToHex(txs[1].Hash()), proof.TxHash)
+	assert.Equal(t, common.ToHex(root), proof.TxRoot)
+	assert.Equal(t, common.ToHex(types.Encode(txs[1])), proof.Tx)
+	var proofs [][]byte
+	for _, p := range proof.Proofs {
+		b, err := common.FromHex(p)
+		assert.Nil(t, err)
+		proofs = append(proofs, b)
+	}
+	assert.True(t, merkle.VerifyMerkleBranch(proofs, txs[1].Hash(), uint32(proof.Index), root))
+
+	api = new(mocks.QueueProtocolAPI)
+	client = newTestChain33(api)
+	api.On("QueryTx", mock.Anything).Return(nil, types.ErrTxNotExist)
+	err = client.GetTxProof(rpctypes.QueryParm{Hash: common.ToHex(txs[1].Hash())}, &result)
+	assert.Equal(t, types.ErrTxNotExist, err)
+}
+
+func TestChain33_GetStorePruneStatus(t *testing.T) {
+	api := new(mocks.QueueProtocolAPI)
+	client := newTestChain33(api)
+	var result interface{}
+	status := &types.StorePruneStatus{Enable: true, Pruning: true, Height: 20000, Retain: 10000, LastKey: []byte("..mk..key"), LeafCount: 10, NodeCount: 30}
+	api.On("StoreGetPruneStatus").Return(status, nil)
+	err := client.GetStorePruneStatus(&types.ReqNil{}, &result)
+	assert.Nil(t, err)
+	reply := result.(*rpctypes.StorePruneStatus)
+	assert.True(t, reply.Pruning)
+	assert.Equal(t, int64(20000), reply.Height)
+	assert.Equal(t, int64(10000), reply.Retain)
+	assert.Equal(t, common.ToHex([]byte("..mk..key")), reply.LastKey)
+	assert.Equal(t, int64(30), reply.NodeCount)
+	assert.False(t, reply.Finished)
+
+	api = new(mocks.QueueProtocolAPI)
+	client = newTestChain33(api)
+	api.On("StoreGetPruneStatus").Return(nil, types.ErrActionNotSupport)
+	err = client.GetStorePruneStatus(&types.ReqNil{}, &result)
+	assert.Equal(t, types.ErrActionNotSupport, err)
+}
+
+func TestChain33_ExportSnapshot(t *testing.T) {
+	api := new(mocks.QueueProtocolAPI)
+	client := newTestChain33(api)
+	var result interface{}
+	req := &types.ReqString{Data: "/tmp/snapshot"}
+	header := &types.SnapshotHeader{Title: "local", Version: 1, Height: 100, BlockHash: []byte("blockhash"), StateHash: []byte("statehash")}
+	api.On("ExportSnapshot", req).Return(header, nil)
+	err := client.ExportSnapshot(req, &result)
+	assert.Nil(t, err)
+	reply := result.(*rpctypes.SnapshotHeader)
+	assert.Equal(t, int64(100), reply.Height)
+	assert.Equal(t, common.ToHex([]byte("blockhash")), reply.BlockHash)
+	assert.Equal(t, common.ToHex([]byte("statehash")), reply.StateHash)
+
+	api = new(mocks.QueueProtocolAPI)
+	client = newTestChain33(api)
+	api.On("ExportSnapshot", req).Return(nil, types.ErrInvalidParam)
+	err = client.ExportSnapshot(req, &result)
+	assert.Equal(t, types.ErrInvalidParam, err)
+}
+
+func TestChain33_ListPeerBans(t *testing.T) {
+	api := new(mocks.QueueProtocolAPI)
+	client := newTestChain33(api)
+	var result interface{}
+	bans := &types.PeerBans{Items: []*types.PeerBan{{Addr: "192.168.1.1", Reason: "invalid block", Score: 120, Deadline: 1000}}}
+	api.On("ListPeerBans").Return(bans, nil)
+	err := client.ListPeerBans(&types.ReqNil{}, &result)
+	assert.Nil(t, err)
+	reply := result.([]*rpctypes.PeerBan)
+	assert.Equal(t, 1, len(reply))
+	assert.Equal(t, "192.168.1.1", reply[0].Addr)
+	assert.Equal(t, int64(120), reply[0].Score)
+}
+
+func TestChain33_UnbanPeer(t *testing.T) {
+	api := new(mocks.QueueProtocolAPI)
+	client := newTestChain33(api)
+	var result interface{}
+	req := &types.ReqString{Data: "192.168.1.1"}
+	api.On("UnbanPeer", req).Return(&types.Reply{IsOk: true}, nil)
+	err := client.UnbanPeer(req, &result)
+	assert.Nil(t, err)
+	assert.True(t, result.(*rpctypes.Reply).IsOk)
+
+	api = new(mocks.QueueProtocolAPI)
+	client = newTestChain33(api)
+	api.On("UnbanPeer", req).Return(nil, types.ErrNotFound)
+	err = client.UnbanPeer(req, &result)
+	assert.Equal(t, types.ErrNotFound, err)
+}
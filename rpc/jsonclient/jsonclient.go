@@ -19,6 +19,15 @@ import (
 type JSONClient struct {
 	url    string
 	prefix string
+	token  string
+}
+
+//rpc服务开启认证后，请求中需要带上的token
+var authToken string
+
+//SetAuthToken 设置之后创建的client在请求中带上Authorization: Bearer token
+func SetAuthToken(token string) {
+	authToken = token
 }
 
 func addPrefix(prefix, name string) string {
@@ -29,11 +38,11 @@ func addPrefix(prefix, name string) string {
 }
 
 func NewJSONClient(url string) (*JSONClient, error) {
-	return &JSONClient{url: url, prefix: "Chain33"}, nil
+	return &JSONClient{url: url, prefix: "Chain33", token: authToken}, nil
 }
 
 func New(prefix, url string) (*JSONClient, error) {
-	return &JSONClient{url: url, prefix: prefix}, nil
+	return &JSONClient{url: url, prefix: prefix, token: authToken}, nil
 }
 
 type clientRequest struct {
@@ -58,7 +67,15 @@ func (client *JSONClient) Call(method string, params, resp interface{}) error {
 		return err
 	}
 	//println("request JsonStr", string(data), "")
-	postresp, err := http.Post(client.url, "application/json", bytes.NewBuffer(data))
+	httpreq, err := http.NewRequest("POST", client.url, bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+	httpreq.Header.Set("Content-Type", "application/json")
+	if client.token != "" {
+		httpreq.Header.Set("Authorization", "Bearer "+client.token)
+	}
+	postresp, err := http.DefaultClient.Do(httpreq)
 	if err != nil {
 		return err
 	}
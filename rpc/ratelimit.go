@@ -0,0 +1,114 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//超过这个时间没有访问的令牌桶已经是满的，可以删除
+const bucketIdleTime = time.Minute
+
+//tokenBucket 令牌桶，每秒补充rate个令牌，最多保存rate个令牌
+type tokenBucket struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate int64, now time.Time) *tokenBucket {
+	return &tokenBucket{rate: float64(rate), tokens: float64(rate), last: now}
+}
+
+func (b *tokenBucket) allow(now time.Time) bool {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.rate {
+		b.tokens = b.rate
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+//rateLimiter jsonrpc的访问频率限制，每个ip一个令牌桶，每个ip的每个受限方法再单独一个令牌桶
+type rateLimiter struct {
+	mu         sync.Mutex
+	ipRate     int64
+	methodRate map[string]int64
+	buckets    map[string]*tokenBucket
+	lastClean  time.Time
+}
+
+func newRateLimiter(ipRate int64, methodRate map[string]int64) *rateLimiter {
+	return &rateLimiter{
+		ipRate:     ipRate,
+		methodRate: methodRate,
+		buckets:    make(map[string]*tokenBucket),
+		lastClean:  time.Now(),
+	}
+}
+
+//parseMethodRateLimit 解析"方法名:每秒请求数"格式的配置
+func parseMethodRateLimit(limits []string) (map[string]int64, error) {
+	methodRate := make(map[string]int64)
+	for _, limit := range limits {
+		items := strings.Split(limit, ":")
+		if len(items) != 2 || items[0] == "" {
+			return nil, fmt.Errorf("methodRateLimit %s format should be method:rate", limit)
+		}
+		rate, err := strconv.ParseInt(items[1], 10, 64)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("methodRateLimit %s rate should be positive integer", limit)
+		}
+		methodRate[items[0]] = rate
+	}
+	return methodRate, nil
+}
+
+func (l *rateLimiter) take(key string, rate int64, now time.Time) bool {
+	b, ok := l.buckets[key]
+	if !ok {
+		b = newTokenBucket(rate, now)
+		l.buckets[key] = b
+	}
+	return b.allow(now)
+}
+
+//allow 检查ip访问funcName是否超过频率限制，ip的限制和方法的限制都要满足
+func (l *rateLimiter) allow(ip string, funcName string) bool {
+	if l == nil {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	l.clean(now)
+	if l.ipRate > 0 && !l.take(ip, l.ipRate, now) {
+		return false
+	}
+	if rate, ok := l.methodRate[funcName]; ok && !l.take(ip+"/"+funcName, rate, now) {
+		return false
+	}
+	return true
+}
+
+func (l *rateLimiter) clean(now time.Time) {
+	if now.Sub(l.lastClean) < bucketIdleTime {
+		return
+	}
+	l.lastClean = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > bucketIdleTime {
+			delete(l.buckets, key)
+		}
+	}
+}
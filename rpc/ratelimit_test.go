@@ -0,0 +1,92 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/33cn/chain33/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Now()
+	b := newTokenBucket(2, now)
+	assert.True(t, b.allow(now))
+	assert.True(t, b.allow(now))
+	assert.False(t, b.allow(now))
+	//半秒补充一个令牌
+	now = now.Add(500 * time.Millisecond)
+	assert.True(t, b.allow(now))
+	assert.False(t, b.allow(now))
+	//令牌最多补满rate个
+	now = now.Add(time.Hour)
+	assert.True(t, b.allow(now))
+	assert.True(t, b.allow(now))
+	assert.False(t, b.allow(now))
+}
+
+func TestParseMethodRateLimit(t *testing.T) {
+	methodRate, err := parseMethodRateLimit([]string{"GetTxByAddr:5", "QueryTransaction:100"})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int64{"GetTxByAddr": 5, "QueryTransaction": 100}, methodRate)
+	for _, limit := range []string{"GetTxByAddr", ":5", "GetTxByAddr:0", "GetTxByAddr:x", "GetTxByAddr:1:2"} {
+		_, err = parseMethodRateLimit([]string{limit})
+		assert.NotNil(t, err, limit)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	var nilLimiter *rateLimiter
+	assert.True(t, nilLimiter.allow("192.168.1.1", "GetTxByAddr"))
+
+	l := newRateLimiter(3, map[string]int64{"GetTxByAddr": 1})
+	assert.True(t, l.allow("192.168.1.1", "GetTxByAddr"))
+	assert.False(t, l.allow("192.168.1.1", "GetTxByAddr"))
+	//其他方法只受ip的限制
+	assert.True(t, l.allow("192.168.1.1", "GetBlocks"))
+	assert.False(t, l.allow("192.168.1.1", "GetBlocks"))
+	//不同ip单独计数
+	assert.True(t, l.allow("192.168.1.2", "GetTxByAddr"))
+	assert.Equal(t, 4, len(l.buckets))
+
+	//长时间没有访问的令牌桶被清理
+	l.lastClean = time.Now().Add(-2 * bucketIdleTime)
+	for _, b := range l.buckets {
+		b.last = time.Now().Add(-2 * bucketIdleTime)
+	}
+	assert.True(t, l.allow("192.168.1.3", "GetBlocks"))
+	assert.Equal(t, 1, len(l.buckets))
+}
+
+func TestCheckJrpcRequest(t *testing.T) {
+	cfg := &types.Rpc{
+		JrpcFuncWhitelist: []string{"*"},
+		JrpcFuncBlacklist: []string{"CloseQueue"},
+		MethodRateLimit:   []string{"GetTxByAddr:1"},
+	}
+	InitJrpcFuncWhitelist(cfg)
+	InitJrpcFuncBlacklist(cfg)
+	InitJrpcRateLimit(cfg)
+	defer InitJrpcRateLimit(&types.Rpc{})
+
+	status, err := checkJrpcRequest("192.168.1.1", nil, "Chain33.CloseQueue")
+	assert.Equal(t, 200, status)
+	assert.Equal(t, "The CloseQueue method is not authorized!", err.Error())
+	_, err = checkJrpcRequest("192.168.1.1", nil, "Chain33.GetTxByAddr")
+	assert.Nil(t, err)
+	status, err = checkJrpcRequest("192.168.1.1", nil, "Chain33.GetTxByAddr")
+	assert.Equal(t, http.StatusTooManyRequests, status)
+	assert.Equal(t, types.ErrRateLimit, err)
+	//本机访问不受限制
+	_, err = checkJrpcRequest("127.0.0.1", nil, "Chain33.GetTxByAddr")
+	assert.Nil(t, err)
+	_, err = checkJrpcRequest("127.0.0.1", nil, "Chain33.GetTxByAddr")
+	assert.Nil(t, err)
+
+	assert.Panics(t, func() { InitJrpcRateLimit(&types.Rpc{MethodRateLimit: []string{"GetTxByAddr"}}) })
+}
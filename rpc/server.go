@@ -27,6 +27,8 @@ var (
 	grpcFuncWhitelist = make(map[string]bool)
 	jrpcFuncBlacklist = make(map[string]bool)
 	grpcFuncBlacklist = make(map[string]bool)
+	jrpcLimiter       *rateLimiter
+	rpcAuth           *authenticator
 )
 
 type Chain33 struct {
@@ -48,6 +50,7 @@ type JSONRPCServer struct {
 	jrpc Chain33
 	s    *rpc.Server
 	l    net.Listener
+	ws   *wsHub
 	//addr string
 }
 
@@ -55,6 +58,9 @@ func (s *JSONRPCServer) Close() {
 	if s.l != nil {
 		s.l.Close()
 	}
+	if s.ws != nil {
+		s.ws.close()
+	}
 	s.jrpc.cli.Close()
 }
 
@@ -136,6 +142,14 @@ func NewGRpcServer(c queue.Client, api client.QueueProtocolAPI) *Grpcserver {
 		return handler(ctx, req)
 	}
 	opts = append(opts, grpc.UnaryInterceptor(interceptor))
+	//stream接口和普通接口使用相同的权限检查
+	streamInterceptor := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := auth(ss.Context(), &grpc.UnaryServerInfo{Server: srv, FullMethod: info.FullMethod}); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+	opts = append(opts, grpc.StreamInterceptor(streamInterceptor))
 	server := grpc.NewServer(opts...)
 	s.s = server
 	types.RegisterChain33Server(server, &s.grpc)
@@ -151,11 +165,17 @@ func NewJSONRPCServer(c queue.Client, api client.QueueProtocolAPI) *JSONRPCServe
 	return j
 }
 
+//SetWsQueueClient websocket订阅需要订阅rpc队列，使用单独的队列客户端
+func (j *JSONRPCServer) SetWsQueueClient(c queue.Client) {
+	j.ws = newWsHub(c)
+}
+
 type RPC struct {
 	cfg  *types.Rpc
 	gapi *Grpcserver
 	japi *JSONRPCServer
 	c    queue.Client
+	wsc  queue.Client
 	api  client.QueueProtocolAPI
 }
 
@@ -166,6 +186,8 @@ func InitCfg(cfg *types.Rpc) {
 	InitGrpcFuncWhitelist(cfg)
 	InitJrpcFuncBlacklist(cfg)
 	InitGrpcFuncBlacklist(cfg)
+	InitJrpcRateLimit(cfg)
+	InitAuth(cfg)
 }
 
 func New(cfg *types.Rpc) *RPC {
@@ -177,9 +199,17 @@ func (r *RPC) SetAPI(api client.QueueProtocolAPI) {
 	r.api = api
 }
 
+//SetWsQueueClient 设置websocket订阅使用的队列客户端，需要在SetQueueClient之前调用
+func (r *RPC) SetWsQueueClient(c queue.Client) {
+	r.wsc = c
+}
+
 func (r *RPC) SetQueueClient(c queue.Client) {
 	gapi := NewGRpcServer(c, r.api)
 	japi := NewJSONRPCServer(c, r.api)
+	if r.wsc != nil {
+		japi.SetWsQueueClient(r.wsc)
+	}
 	r.gapi = gapi
 	r.japi = japi
 	r.c = c
@@ -191,6 +221,9 @@ func (r *RPC) SetQueueClient(c queue.Client) {
 func (r *RPC) SetQueueClientNoListen(c queue.Client) {
 	gapi := NewGRpcServer(c, r.api)
 	japi := NewJSONRPCServer(c, r.api)
+	if r.wsc != nil {
+		japi.SetWsQueueClient(r.wsc)
+	}
 	r.gapi = gapi
 	r.japi = japi
 	r.c = c
@@ -299,6 +332,7 @@ func InitGrpcFuncWhitelist(cfg *types.Rpc) {
 func InitJrpcFuncBlacklist(cfg *types.Rpc) {
 	if len(cfg.JrpcFuncBlacklist) == 0 {
 		jrpcFuncBlacklist["CloseQueue"] = true
+		jrpcFuncBlacklist["ExportSnapshot"] = true
 		return
 	}
 	for _, funcName := range cfg.JrpcFuncBlacklist {
@@ -310,9 +344,30 @@ func InitJrpcFuncBlacklist(cfg *types.Rpc) {
 func InitGrpcFuncBlacklist(cfg *types.Rpc) {
 	if len(cfg.GrpcFuncBlacklist) == 0 {
 		grpcFuncBlacklist["CloseQueue"] = true
+		grpcFuncBlacklist["ExportSnapshot"] = true
 		return
 	}
 	for _, funcName := range cfg.GrpcFuncBlacklist {
 		grpcFuncBlacklist[funcName] = true
 	}
 }
+
+func InitJrpcRateLimit(cfg *types.Rpc) {
+	if cfg.IpRateLimit <= 0 && len(cfg.MethodRateLimit) == 0 {
+		jrpcLimiter = nil
+		return
+	}
+	methodRate, err := parseMethodRateLimit(cfg.MethodRateLimit)
+	if err != nil {
+		panic(err)
+	}
+	jrpcLimiter = newRateLimiter(cfg.IpRateLimit, methodRate)
+}
+
+func InitAuth(cfg *types.Rpc) {
+	auth, err := newAuthenticator(cfg)
+	if err != nil {
+		panic(err)
+	}
+	rpcAuth = auth
+}
@@ -5,7 +5,11 @@
 package rpc
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -118,6 +122,45 @@ func TestJSONClient_Call(t *testing.T) {
 	server.Close()
 	mock.AssertExpectationsForObjects(t, api)
 }
+func TestJSONRPCServer_Batch(t *testing.T) {
+	rpcCfg = new(types.Rpc)
+	rpcCfg.JrpcBindAddr = "127.0.0.1:0"
+	rpcCfg.JrpcFuncWhitelist = []string{"*"}
+	InitCfg(rpcCfg)
+	server := NewJSONRPCServer(&qmocks.Client{}, nil)
+	api := new(mocks.QueueProtocolAPI)
+	server.jrpc = *newTestChain33(api)
+	port, err := server.Listen()
+	assert.Nil(t, err)
+	defer server.l.Close()
+	api.On("IsSync").Return(&types.Reply{IsOk: true}, nil)
+
+	url := fmt.Sprintf("http://127.0.0.1:%d/", port)
+	body := `[{"jsonrpc":"2.0","id":1,"method":"Chain33.IsSync","params":[{}]},
+		{"jsonrpc":"2.0","id":2,"method":"Chain33.NotExist","params":[{}]},
+		"bad request"]`
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	assert.Nil(t, err)
+	var results []serverResponse
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&results))
+	resp.Body.Close()
+	assert.Equal(t, 3, len(results))
+	assert.Equal(t, uint64(1), results[0].Id)
+	assert.Equal(t, true, results[0].Result)
+	assert.Nil(t, results[0].Error)
+	assert.Equal(t, uint64(2), results[1].Id)
+	assert.NotNil(t, results[1].Error)
+	assert.NotNil(t, results[2].Error)
+
+	//空的批量请求
+	resp, err = http.Post(url, "application/json", strings.NewReader(" []"))
+	assert.Nil(t, err)
+	var result serverResponse
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&result))
+	resp.Body.Close()
+	assert.NotNil(t, result.Error)
+}
+
 func TestGrpc_Call(t *testing.T) {
 	rpcCfg = new(types.Rpc)
 	rpcCfg.GrpcBindAddr = "127.0.0.1:8101"
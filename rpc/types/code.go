@@ -25,7 +25,7 @@ func DecodeLog(execer []byte, rlog *ReceiptData) (*ReceiptDataResult, error) {
 	default:
 		rTy = "Unkown"
 	}
-	rd := &ReceiptDataResult{Ty: rlog.Ty, TyName: rTy}
+	rd := &ReceiptDataResult{Ty: rlog.Ty, TyName: rTy, GasUsed: rlog.GasUsed}
 	for _, l := range rlog.Logs {
 		var lTy string
 		var logIns json.RawMessage
@@ -76,6 +76,7 @@ func ConvertWalletTxDetailToJson(in *types.WalletTxDetails, out *WalletTxDetails
 			FromAddr:   tx.GetFromaddr(),
 			TxHash:     common.ToHex(tx.GetTxhash()),
 			ActionName: tx.GetActionName(),
+			WatchOnly:  tx.GetWatchOnly(),
 		})
 	}
 	return nil
@@ -119,6 +120,7 @@ func DecodeTx(tx *types.Transaction) (*Transaction, error) {
 		GroupCount: tx.GroupCount,
 		Header:     common.ToHex(tx.Header),
 		Next:       common.ToHex(tx.Next),
+		GasLimit:   tx.GasLimit,
 	}
 	if result.Amount != 0 {
 		result.AmountFmt = strconv.FormatFloat(float64(result.Amount)/float64(types.Coin), 'f', 4, 64)
@@ -74,6 +74,7 @@ type Transaction struct {
 	GroupCount int32           `json:"groupCount,omitempty"`
 	Header     string          `json:"header,omitempty"`
 	Next       string          `json:"next,omitempty"`
+	GasLimit   int64           `json:"gasLimit,omitempty"`
 }
 
 type ReceiptLog struct {
@@ -82,14 +83,16 @@ type ReceiptLog struct {
 }
 
 type ReceiptData struct {
-	Ty   int32         `json:"ty"`
-	Logs []*ReceiptLog `json:"logs"`
+	Ty      int32         `json:"ty"`
+	Logs    []*ReceiptLog `json:"logs"`
+	GasUsed int64         `json:"gasUsed,omitempty"`
 }
 
 type ReceiptDataResult struct {
-	Ty     int32               `json:"ty"`
-	TyName string              `json:"tyName"`
-	Logs   []*ReceiptLogResult `json:"logs"`
+	Ty      int32               `json:"ty"`
+	TyName  string              `json:"tyName"`
+	Logs    []*ReceiptLogResult `json:"logs"`
+	GasUsed int64               `json:"gasUsed,omitempty"`
 }
 
 type ReceiptLogResult struct {
@@ -168,6 +171,14 @@ type Peer struct {
 	MempoolSize int32   `json:"mempoolSize"`
 	Self        bool    `json:"self"`
 	Header      *Header `json:"header"`
+	Score       int64   `json:"score"`
+}
+
+type PeerBan struct {
+	Addr     string `json:"addr"`
+	Reason   string `json:"reason"`
+	Score    int64  `json:"score"`
+	Deadline int64  `json:"deadline"`
 }
 
 // Wallet Module
@@ -175,8 +186,9 @@ type WalletAccounts struct {
 	Wallets []*WalletAccount `json:"wallets"`
 }
 type WalletAccount struct {
-	Acc   *Account `json:"acc"`
-	Label string   `json:"label"`
+	Acc       *Account `json:"acc"`
+	Label     string   `json:"label"`
+	WatchOnly bool     `json:"watchOnly,omitempty"`
 }
 
 type Account struct {
@@ -226,6 +238,7 @@ type WalletTxDetail struct {
 	FromAddr   string             `json:"fromAddr"`
 	TxHash     string             `json:"txHash"`
 	ActionName string             `json:"actionName"`
+	WatchOnly  bool               `json:"watchOnly,omitempty"`
 }
 
 type BlockOverview struct {
@@ -238,6 +251,8 @@ type Query4Jrpc struct {
 	Execer   string          `json:"execer"`
 	FuncName string          `json:"funcName"`
 	Payload  json.RawMessage `json:"payload"`
+	//大于0时查询该高度的状态，只支持读取状态的查询，读取localdb的查询返回ErrHistoryLocalDB
+	Height int64 `json:"height,omitempty"`
 }
 
 type ChainExecutor struct {
@@ -248,10 +263,11 @@ type ChainExecutor struct {
 }
 
 type WalletStatus struct {
-	IsWalletLock bool `json:"isWalletLock"`
-	IsAutoMining bool `json:"isAutoMining"`
-	IsHasSeed    bool `json:"isHasSeed"`
-	IsTicketLock bool `json:"isTicketLock"`
+	IsWalletLock bool                      `json:"isWalletLock"`
+	IsAutoMining bool                      `json:"isAutoMining"`
+	IsHasSeed    bool                      `json:"isHasSeed"`
+	IsTicketLock bool                      `json:"isTicketLock"`
+	Rescan       *types.WalletRescanStatus `json:"rescan,omitempty"`
 }
 
 type NodeNetinfo struct {
@@ -305,3 +321,90 @@ type ExecAccount struct {
 type ExecNameParm struct {
 	ExecName string `json:"execname"`
 }
+
+type SimulateTxParam struct {
+	Data      string `json:"data"`
+	StateHash string `json:"stateHash"`
+	Pubkey    string `json:"pubkey"`
+	SignType  int32  `json:"signType"`
+}
+
+type SimulateKV struct {
+	Key     string `json:"key"`
+	Prev    string `json:"prev"`
+	Current string `json:"current"`
+}
+
+type SimulateTxResult struct {
+	Receipt   *ReceiptDataResult `json:"receipt"`
+	KV        []*SimulateKV      `json:"kv"`
+	Fee       int64              `json:"fee"`
+	GasUsed   int64              `json:"gasUsed"`
+	Height    int64              `json:"height"`
+	StateHash string             `json:"stateHash"`
+}
+
+type StateProofParam struct {
+	Key    string `json:"key"`
+	Height int64  `json:"height"`
+}
+
+type StateProofResult struct {
+	Value  string  `json:"value"`
+	Proof  string  `json:"proof"`
+	Header *Header `json:"header"`
+}
+
+type TxProofResult struct {
+	Tx     string   `json:"tx"`
+	TxHash string   `json:"txHash"`
+	Index  int64    `json:"index"`
+	Proofs []string `json:"proofs"`
+	TxRoot string   `json:"txRoot"`
+	Header *Header  `json:"header"`
+}
+
+type StorePruneStatus struct {
+	Enable    bool   `json:"enable"`
+	Pruning   bool   `json:"pruning"`
+	Height    int64  `json:"height"`
+	Retain    int64  `json:"retain"`
+	LastKey   string `json:"lastKey"`
+	LeafCount int64  `json:"leafCount"`
+	NodeCount int64  `json:"nodeCount"`
+	Finished  bool   `json:"finished"`
+}
+
+type SnapshotHeader struct {
+	Title      string `json:"title"`
+	Version    int32  `json:"version"`
+	Height     int64  `json:"height"`
+	BlockHash  string `json:"blockHash"`
+	StateHash  string `json:"stateHash"`
+	CreateTime int64  `json:"createTime"`
+}
+
+type SubscribeParam struct {
+	Type    string `json:"type"`
+	Execer  string `json:"execer,omitempty"`
+	LogType string `json:"logType,omitempty"`
+	Address string `json:"address,omitempty"`
+}
+
+type PendingTxResult struct {
+	TxHash string       `json:"txHash"`
+	Tx     *Transaction `json:"tx"`
+}
+
+type LogResult struct {
+	Height    int64           `json:"height"`
+	BlockHash string          `json:"blockHash"`
+	TxHash    string          `json:"txHash"`
+	TxIndex   int64           `json:"txIndex"`
+	Execer    string          `json:"execer"`
+	Ty        int32           `json:"ty"`
+	TyName    string          `json:"tyName"`
+	Log       json.RawMessage `json:"log"`
+	RawLog    string          `json:"rawLog"`
+	Removed   bool            `json:"removed"`
+}
@@ -0,0 +1,427 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/33cn/chain33/common"
+	"github.com/33cn/chain33/queue"
+	rpctypes "github.com/33cn/chain33/rpc/types"
+	"github.com/33cn/chain33/types"
+	"golang.org/x/net/websocket"
+)
+
+//websocket订阅的类型
+const (
+	SubTypeHeader    = "header"
+	SubTypeDelBlock  = "delBlock"
+	SubTypePendingTx = "pendingTx"
+	SubTypeLog       = "log"
+)
+
+const (
+	wsSendBuffer     = 256
+	wsMaxMessageSize = 1024 * 1024
+	wsWriteWait      = 10 * time.Second
+)
+
+type wsRequest struct {
+	Method string             `json:"method"`
+	Params [1]json.RawMessage `json:"params"`
+	Id     uint64             `json:"id"`
+}
+
+//wsNotify 推送给订阅者的消息
+type wsNotify struct {
+	Subscription string      `json:"subscription"`
+	Type         string      `json:"type"`
+	Result       interface{} `json:"result"`
+}
+
+type wsSubscription struct {
+	id     string
+	param  *rpctypes.SubscribeParam
+	client *wsClient
+}
+
+type wsClient struct {
+	hub  *wsHub
+	conn *websocket.Conn
+	ip   string
+	role *authRole
+	send chan []byte
+	quit chan struct{}
+	once sync.Once
+	//受hub.mu保护
+	subs map[string]*wsSubscription
+}
+
+//wsHub 管理所有websocket连接的订阅，订阅的数据来自blockchain和mempool推送到rpc队列的消息
+//hub使用单独的队列客户端，不和jsonrpc共用，关闭hub的时候关闭这个客户端
+type wsHub struct {
+	client  queue.Client
+	once    sync.Once
+	subID   int64
+	mu      sync.Mutex
+	clients map[*wsClient]bool
+}
+
+type wsLog struct {
+	result *rpctypes.LogResult
+	from   string
+	to     string
+}
+
+func newWsHub(client queue.Client) *wsHub {
+	return &wsHub{client: client, clients: make(map[*wsClient]bool)}
+}
+
+//start 订阅rpc队列，并通知blockchain和mempool开始推送
+func (h *wsHub) start() {
+	h.once.Do(func() {
+		h.client.Sub("rpc")
+		go h.procEvents()
+		for _, topic := range []string{"blockchain", "mempool"} {
+			msg := h.client.NewMessage(topic, types.EventSubscribePush, &types.ReqNil{})
+			err := h.client.Send(msg, false)
+			if err != nil {
+				log.Error("websocket subscribe push", "topic", topic, "err", err)
+			}
+		}
+	})
+}
+
+func (h *wsHub) close() {
+	h.mu.Lock()
+	clients := make([]*wsClient, 0, len(h.clients))
+	for c := range h.clients {
+		clients = append(clients, c)
+	}
+	h.mu.Unlock()
+	for _, c := range clients {
+		c.close()
+	}
+	h.client.Close()
+}
+
+func (h *wsHub) procEvents() {
+	for msg := range h.client.Recv() {
+		switch msg.Ty {
+		case types.EventAddBlock:
+			h.notifyBlock(msg.GetData().(*types.BlockDetail), false)
+		case types.EventDelBlock:
+			h.notifyBlock(msg.GetData().(*types.BlockDetail), true)
+		case types.EventTx:
+			h.notifyTx(msg.GetData().(*types.Transaction))
+		default:
+			log.Warn("websocket unknow msg", "msgtype", msg.Ty)
+		}
+	}
+}
+
+func (h *wsHub) serveWs(w http.ResponseWriter, r *http.Request, ip string, role *authRole) {
+	server := websocket.Server{
+		Handshake: checkWsOrigin,
+		Handler: func(conn *websocket.Conn) {
+			h.serveConn(conn, ip, role)
+		},
+	}
+	server.ServeHTTP(w, r)
+}
+
+//checkWsOrigin 浏览器发起的连接会带上Origin，只接受配置的websocketOrigins里的来源，防止跨站劫持
+//没有Origin的连接不是浏览器发起的，直接接受
+func checkWsOrigin(config *websocket.Config, r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	for _, allow := range rpcCfg.WebsocketOrigins {
+		if allow == "*" || strings.EqualFold(allow, origin) {
+			return nil
+		}
+	}
+	log.Error("websocket origin not allowed", "origin", origin, "RemoteAddr", r.RemoteAddr)
+	return types.ErrWebsocketOrigin
+}
+
+func (h *wsHub) serveConn(conn *websocket.Conn, ip string, role *authRole) {
+	conn.MaxPayloadBytes = wsMaxMessageSize
+	c := &wsClient{
+		hub:  h,
+		conn: conn,
+		ip:   ip,
+		role: role,
+		send: make(chan []byte, wsSendBuffer),
+		quit: make(chan struct{}),
+		subs: make(map[string]*wsSubscription),
+	}
+	h.mu.Lock()
+	h.clients[c] = true
+	h.mu.Unlock()
+	go c.writeLoop()
+	c.readLoop()
+}
+
+func (h *wsHub) subscribe(c *wsClient, param *rpctypes.SubscribeParam) (string, error) {
+	switch param.Type {
+	case SubTypeHeader, SubTypeDelBlock, SubTypePendingTx, SubTypeLog:
+	default:
+		return "", types.ErrSubscribeType
+	}
+	id := strconv.FormatInt(atomic.AddInt64(&h.subID, 1), 10)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	c.subs[id] = &wsSubscription{id: id, param: param, client: c}
+	return id, nil
+}
+
+func (h *wsHub) unsubscribe(c *wsClient, id string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := c.subs[id]; !ok {
+		return types.ErrSubscriptionNotFound
+	}
+	delete(c.subs, id)
+	return nil
+}
+
+func (h *wsHub) remove(c *wsClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.clients, c)
+	c.subs = make(map[string]*wsSubscription)
+}
+
+//subscriptions 返回订阅类型为subType的所有订阅，推送的时候不持有锁
+func (h *wsHub) subscriptions(subType string) []*wsSubscription {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var subs []*wsSubscription
+	for c := range h.clients {
+		for _, sub := range c.subs {
+			if sub.param.Type == subType {
+				subs = append(subs, sub)
+			}
+		}
+	}
+	return subs
+}
+
+func (h *wsHub) notifyBlock(detail *types.BlockDetail, removed bool) {
+	block := detail.GetBlock()
+	subType := SubTypeHeader
+	if removed {
+		subType = SubTypeDelBlock
+	}
+	if subs := h.subscriptions(subType); len(subs) > 0 {
+		header := block.GetHeader()
+		header.Hash = block.Hash()
+		result := convertHeader(header)
+		for _, sub := range subs {
+			sub.notify(result)
+		}
+	}
+	//回滚区块的日志也推送给订阅者，removed为true
+	subs := h.subscriptions(SubTypeLog)
+	if len(subs) == 0 {
+		return
+	}
+	logs := blockLogs(detail, removed)
+	for _, sub := range subs {
+		for _, l := range logs {
+			if sub.matchLog(l) {
+				sub.notify(l.result)
+			}
+		}
+	}
+}
+
+func (h *wsHub) notifyTx(tx *types.Transaction) {
+	subs := h.subscriptions(SubTypePendingTx)
+	if len(subs) == 0 {
+		return
+	}
+	tran, err := rpctypes.DecodeTx(tx)
+	if err != nil {
+		return
+	}
+	result := &rpctypes.PendingTxResult{TxHash: common.ToHex(tx.Hash()), Tx: tran}
+	for _, sub := range subs {
+		if sub.match(tran.Execer, tran.From, tran.To) {
+			sub.notify(result)
+		}
+	}
+}
+
+func blockLogs(detail *types.BlockDetail, removed bool) []*wsLog {
+	block := detail.GetBlock()
+	blockHash := common.ToHex(block.Hash())
+	var logs []*wsLog
+	for i, tx := range block.GetTxs() {
+		if i >= len(detail.GetReceipts()) {
+			break
+		}
+		from, to := tx.From(), tx.GetRealToAddr()
+		txHash := common.ToHex(tx.Hash())
+		for _, l := range detail.Receipts[i].GetLogs() {
+			result := &rpctypes.LogResult{
+				Height:    block.GetHeight(),
+				BlockHash: blockHash,
+				TxHash:    txHash,
+				TxIndex:   int64(i),
+				Execer:    string(tx.Execer),
+				Ty:        l.Ty,
+				RawLog:    common.ToHex(l.Log),
+				Removed:   removed,
+			}
+			logType := types.LoadLog(tx.Execer, int64(l.Ty))
+			if logType == nil {
+				result.TyName = "unkownType"
+			} else {
+				result.TyName = logType.Name()
+				result.Log, _ = logType.Json(l.Log)
+			}
+			logs = append(logs, &wsLog{result: result, from: from, to: to})
+		}
+	}
+	return logs
+}
+
+//match 执行器和地址的过滤条件，为空表示不过滤，执行器同时匹配平行链的真实执行器名称
+func (sub *wsSubscription) match(execer, from, to string) bool {
+	param := sub.param
+	if param.Execer != "" && param.Execer != execer &&
+		param.Execer != string(types.GetRealExecName([]byte(execer))) {
+		return false
+	}
+	if param.Address != "" && param.Address != from && param.Address != to {
+		return false
+	}
+	return true
+}
+
+func (sub *wsSubscription) matchLog(l *wsLog) bool {
+	if sub.param.LogType != "" && sub.param.LogType != l.result.TyName {
+		return false
+	}
+	return sub.match(l.result.Execer, l.from, l.to)
+}
+
+func (sub *wsSubscription) notify(result interface{}) {
+	data, err := json.Marshal(&wsNotify{Subscription: sub.id, Type: sub.param.Type, Result: result})
+	if err != nil {
+		log.Error("websocket notify", "err", err)
+		return
+	}
+	sub.client.queue(data)
+}
+
+//queue 不阻塞推送，客户端处理太慢导致缓存满了就断开连接
+func (c *wsClient) queue(data []byte) {
+	select {
+	case c.send <- data:
+	default:
+		log.Error("websocket client too slow, close it")
+		c.close()
+	}
+}
+
+func (c *wsClient) close() {
+	c.once.Do(func() {
+		c.hub.remove(c)
+		close(c.quit)
+		c.conn.Close()
+	})
+}
+
+func (c *wsClient) readLoop() {
+	defer c.close()
+	for {
+		var data []byte
+		err := websocket.Message.Receive(c.conn, &data)
+		if err != nil {
+			log.Debug("websocket read", "err", err)
+			return
+		}
+		resp, err := json.Marshal(c.handleRequest(data))
+		if err != nil {
+			log.Debug("json marshal error, nerver happen")
+			return
+		}
+		c.queue(resp)
+	}
+}
+
+func (c *wsClient) writeLoop() {
+	defer c.close()
+	for {
+		select {
+		case data := <-c.send:
+			err := c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err != nil {
+				return
+			}
+			if err := websocket.Message.Send(c.conn, string(data)); err != nil {
+				return
+			}
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+func (c *wsClient) handleRequest(data []byte) *serverResponse {
+	var req wsRequest
+	err := json.Unmarshal(data, &req)
+	if err != nil {
+		return &serverResponse{Error: fmt.Sprintf(`parse request err %s`, err.Error())}
+	}
+	_, err = checkJrpcRequest(c.ip, c.role, req.Method)
+	if err != nil {
+		return &serverResponse{Id: req.Id, Error: err.Error()}
+	}
+	funcName := req.Method[strings.LastIndex(req.Method, ".")+1:]
+	resp := &serverResponse{Id: req.Id}
+	switch funcName {
+	case "Subscribe":
+		var param rpctypes.SubscribeParam
+		err = json.Unmarshal(req.Params[0], &param)
+		if err != nil {
+			resp.Error = types.ErrInvalidParam.Error()
+			return resp
+		}
+		id, err := c.hub.subscribe(c, &param)
+		if err != nil {
+			resp.Error = err.Error()
+			return resp
+		}
+		resp.Result = id
+	case "Unsubscribe":
+		var param types.ReqString
+		err = json.Unmarshal(req.Params[0], &param)
+		if err != nil {
+			resp.Error = types.ErrInvalidParam.Error()
+			return resp
+		}
+		err = c.hub.unsubscribe(c, param.Data)
+		if err != nil {
+			resp.Error = err.Error()
+			return resp
+		}
+		resp.Result = true
+	default:
+		resp.Error = fmt.Sprintf(`The %s method is not supported by websocket!`, funcName)
+	}
+	return resp
+}
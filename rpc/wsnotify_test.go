@@ -0,0 +1,182 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/33cn/chain33/common"
+	"github.com/33cn/chain33/queue"
+	rpctypes "github.com/33cn/chain33/rpc/types"
+	"github.com/33cn/chain33/types"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+)
+
+const wsTestOrigin = "http://localhost"
+
+type testWsClient struct {
+	conn *websocket.Conn
+}
+
+func dialWs(t *testing.T, port int) *testWsClient {
+	conn, err := websocket.Dial(fmt.Sprintf("ws://127.0.0.1:%d/ws", port), "", wsTestOrigin)
+	assert.Nil(t, err)
+	return &testWsClient{conn: conn}
+}
+
+func (c *testWsClient) read(t *testing.T) []byte {
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var data []byte
+	err := websocket.Message.Receive(c.conn, &data)
+	assert.Nil(t, err)
+	return data
+}
+
+func (c *testWsClient) call(t *testing.T, method string, param interface{}) *serverResponse {
+	req, _ := json.Marshal(map[string]interface{}{"id": 1, "method": method, "params": []interface{}{param}})
+	assert.Nil(t, websocket.Message.Send(c.conn, string(req)))
+	var resp serverResponse
+	assert.Nil(t, json.Unmarshal(c.read(t), &resp))
+	return &resp
+}
+
+func (c *testWsClient) readNotify(t *testing.T, result interface{}) *wsNotify {
+	notify := &wsNotify{Result: result}
+	assert.Nil(t, json.Unmarshal(c.read(t), notify))
+	return notify
+}
+
+func newWsTestServer(t *testing.T, q queue.Queue) (*JSONRPCServer, int) {
+	rpcCfg = new(types.Rpc)
+	rpcCfg.JrpcBindAddr = "127.0.0.1:0"
+	rpcCfg.EnableWebsocket = true
+	rpcCfg.WebsocketOrigins = []string{wsTestOrigin}
+	InitCfg(rpcCfg)
+	server := NewJSONRPCServer(q.Client(), nil)
+	server.SetWsQueueClient(q.Client())
+	port, err := server.Listen()
+	assert.Nil(t, err)
+	return server, port
+}
+
+func newWsTestBlock(to string) (*types.BlockDetail, *types.Transaction) {
+	tx := &types.Transaction{Execer: []byte("coins"), Payload: []byte("payload"), Fee: 1e6, To: to}
+	block := &types.Block{Height: 10, BlockTime: 1, Txs: []*types.Transaction{tx}}
+	transfer := &types.ReceiptAccountTransfer{
+		Prev:    &types.Account{Balance: 10},
+		Current: &types.Account{Balance: 9},
+	}
+	receipt := &types.ReceiptData{Ty: types.ExecOk, Logs: []*types.ReceiptLog{
+		{Ty: types.TyLogFee, Log: types.Encode(transfer)},
+		{Ty: types.TyLogTransfer, Log: types.Encode(transfer)},
+	}}
+	return &types.BlockDetail{Block: block, Receipts: []*types.ReceiptData{receipt}}, tx
+}
+
+func TestWebsocketSubscribe(t *testing.T) {
+	q := queue.New("channel")
+	defer q.Close()
+	server, port := newWsTestServer(t, q)
+	defer server.l.Close()
+
+	//开启订阅之后通知blockchain推送区块
+	chain := q.Client()
+	chain.Sub("blockchain")
+	select {
+	case msg := <-chain.Recv():
+		assert.Equal(t, int64(types.EventSubscribePush), msg.Ty)
+	case <-time.After(5 * time.Second):
+		t.Error("blockchain not receive EventSubscribePush")
+	}
+
+	ws := dialWs(t, port)
+	defer ws.conn.Close()
+
+	resp := ws.call(t, "Chain33.Subscribe", &rpctypes.SubscribeParam{Type: "notexist"})
+	assert.Equal(t, types.ErrSubscribeType.Error(), resp.Error)
+	resp = ws.call(t, "Chain33.GetLastHeader", nil)
+	assert.NotNil(t, resp.Error)
+	resp = ws.call(t, "Chain33.Unsubscribe", &types.ReqString{Data: "100"})
+	assert.Equal(t, types.ErrSubscriptionNotFound.Error(), resp.Error)
+
+	to := "1JmFaA6unrCFYEWPGRi7uuXY1KthTJxJEP"
+	headerSub := ws.call(t, "Chain33.Subscribe", &rpctypes.SubscribeParam{Type: SubTypeHeader}).Result
+	delSub := ws.call(t, "Chain33.Subscribe", &rpctypes.SubscribeParam{Type: SubTypeDelBlock}).Result
+	logSub := ws.call(t, "Chain33.Subscribe", &rpctypes.SubscribeParam{Type: SubTypeLog, Execer: "coins", LogType: "LogTransfer", Address: to}).Result
+	//过滤条件不满足的订阅不会收到推送
+	ws.call(t, "Chain33.Subscribe", &rpctypes.SubscribeParam{Type: SubTypeLog, Execer: "token"})
+	ws.call(t, "Chain33.Subscribe", &rpctypes.SubscribeParam{Type: SubTypePendingTx, Address: "notexist"})
+	txSub := ws.call(t, "Chain33.Subscribe", &rpctypes.SubscribeParam{Type: SubTypePendingTx, Execer: "coins"}).Result
+	assert.NotNil(t, headerSub)
+	assert.NotEqual(t, headerSub, delSub)
+
+	detail, tx := newWsTestBlock(to)
+	client := q.Client()
+	client.Send(client.NewMessage("rpc", types.EventAddBlock, detail), false)
+	var header rpctypes.Header
+	notify := ws.readNotify(t, &header)
+	assert.Equal(t, headerSub, notify.Subscription)
+	assert.Equal(t, SubTypeHeader, notify.Type)
+	assert.Equal(t, int64(10), header.Height)
+	assert.Equal(t, common.ToHex(detail.Block.Hash()), header.Hash)
+	var l rpctypes.LogResult
+	notify = ws.readNotify(t, &l)
+	assert.Equal(t, logSub, notify.Subscription)
+	assert.Equal(t, "LogTransfer", l.TyName)
+	assert.Equal(t, common.ToHex(tx.Hash()), l.TxHash)
+	assert.False(t, l.Removed)
+
+	client.Send(client.NewMessage("rpc", types.EventTx, tx), false)
+	var pending rpctypes.PendingTxResult
+	notify = ws.readNotify(t, &pending)
+	assert.Equal(t, txSub, notify.Subscription)
+	assert.Equal(t, common.ToHex(tx.Hash()), pending.TxHash)
+	assert.Equal(t, to, pending.Tx.To)
+
+	//取消订阅之后回滚区块只收到回滚通知
+	resp = ws.call(t, "Chain33.Unsubscribe", &types.ReqString{Data: logSub.(string)})
+	assert.Nil(t, resp.Error)
+	client.Send(client.NewMessage("rpc", types.EventDelBlock, detail), false)
+	notify = ws.readNotify(t, &header)
+	assert.Equal(t, delSub, notify.Subscription)
+	assert.Equal(t, int64(10), header.Height)
+
+	resp = ws.call(t, "Chain33.Unsubscribe", &types.ReqString{Data: delSub.(string)})
+	assert.Equal(t, true, resp.Result)
+}
+
+func TestWebsocketHandshake(t *testing.T) {
+	q := queue.New("channel")
+	defer q.Close()
+	server, port := newWsTestServer(t, q)
+	defer server.l.Close()
+
+	//普通的http请求不能升级
+	resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/ws", port))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp.Body.Close()
+
+	//不在websocketOrigins里的浏览器来源被拒绝
+	_, err = websocket.Dial(fmt.Sprintf("ws://127.0.0.1:%d/ws", port), "", "http://evil.com")
+	assert.NotNil(t, err)
+
+	//不带Origin的非浏览器连接可以升级
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	assert.Nil(t, err)
+	defer conn.Close()
+	fmt.Fprintf(conn, "GET /ws HTTP/1.1\r\nHost: 127.0.0.1\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+	resp, err = http.ReadResponse(bufio.NewReader(conn), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+}
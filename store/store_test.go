@@ -190,6 +190,34 @@ func TestGetAndSetCommitAndRollback(t *testing.T) {
 	s.Close()
 }
 
+func TestGetStateNotExist(t *testing.T) {
+	q, s := initEnv()
+	client := q.Client()
+	var stateHash [32]byte
+	key := []byte("hello" + randstr())
+	value := []byte("world")
+
+	hash, err := setmem(client, stateHash[:], key, value)
+	assert.Nil(t, err)
+	query := &types.StoreGet{StateHash: hash, Keys: [][]byte{key}}
+	msg := client.NewMessage("store", types.EventStoreGetState, query)
+	client.Send(msg, true)
+	msg, err = client.Wait(msg)
+	assert.Nil(t, err)
+	assert.Equal(t, value, msg.GetData().(*types.StoreReplyValue).GetValues()[0])
+
+	//回滚之后状态已经不存在，EventStoreGet返回空值，EventStoreGetState返回错误
+	rollback(client, hash)
+	value2, err := get(client, hash, key)
+	assert.Nil(t, err)
+	assert.Nil(t, value2)
+	msg = client.NewMessage("store", types.EventStoreGetState, query)
+	client.Send(msg, true)
+	_, err = client.Wait(msg)
+	assert.Equal(t, types.ErrStateNotExist, err)
+	s.Close()
+}
+
 func BenchmarkGetKey(b *testing.B) {
 	q, s := initEnv()
 	client := q.Client()
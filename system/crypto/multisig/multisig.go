@@ -0,0 +1,333 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//多重签名：M-of-N 的签名策略
+//公钥是编码后的策略(门限和N个成员的签名类型和公钥)，地址由策略的hash得到
+//签名包含策略和成员的部分签名，每个成员单独签名，有效的部分签名达到门限时验证通过
+package multisig
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/33cn/chain33/common/crypto"
+	"github.com/33cn/chain33/system/crypto/ed25519"
+	"github.com/33cn/chain33/system/crypto/secp256k1"
+	"github.com/33cn/chain33/system/crypto/sm2"
+)
+
+//MaxKeys 策略中最多的成员数
+const MaxKeys = 20
+
+//memberTypes 成员只能使用普通的单签名类型，不能嵌套多重签名，也不能用隐私和证书的签名
+var memberTypes = map[string]bool{
+	secp256k1.Name: true,
+	ed25519.Name:   true,
+	sm2.Name:       true,
+}
+
+var (
+	ErrNoPrivKey    = errors.New("ErrMultiSigNoPrivKey")
+	ErrThreshold    = errors.New("ErrMultiSigThreshold")
+	ErrTooManyKeys  = errors.New("ErrMultiSigTooManyKeys")
+	ErrDupKey       = errors.New("ErrMultiSigDupKey")
+	ErrKeyType      = errors.New("ErrMultiSigKeyType")
+	ErrDecode       = errors.New("ErrMultiSigDecode")
+	ErrNotMember    = errors.New("ErrMultiSigNotMember")
+	ErrPolicyChange = errors.New("ErrMultiSigPolicyChange")
+)
+
+type Driver struct{}
+
+//GenKey 多重签名没有私钥，成员用自己的私钥签名
+func (d Driver) GenKey() (crypto.PrivKey, error) {
+	return nil, ErrNoPrivKey
+}
+
+func (d Driver) PrivKeyFromBytes(b []byte) (privKey crypto.PrivKey, err error) {
+	return nil, ErrNoPrivKey
+}
+
+func (d Driver) PubKeyFromBytes(b []byte) (pubKey crypto.PubKey, err error) {
+	return decodePubKey(b)
+}
+
+func (d Driver) SignatureFromBytes(b []byte) (sig crypto.Signature, err error) {
+	return decodeSignature(b)
+}
+
+//PubKeyMultiSig 多重签名的策略
+type PubKeyMultiSig struct {
+	Threshold int
+	Types     []int32
+	Keys      []crypto.PubKey
+}
+
+//NewPubKey 用门限和成员的签名类型及公钥创建策略
+func NewPubKey(threshold int, types []int32, keys [][]byte) (*PubKeyMultiSig, error) {
+	if len(types) != len(keys) {
+		return nil, ErrKeyType
+	}
+	if len(keys) > MaxKeys {
+		return nil, ErrTooManyKeys
+	}
+	if threshold <= 0 || threshold > len(keys) {
+		return nil, ErrThreshold
+	}
+	pub := &PubKeyMultiSig{Threshold: threshold, Types: types}
+	seen := make(map[string]bool)
+	for i, key := range keys {
+		name := crypto.GetName(int(types[i]))
+		if !memberTypes[name] {
+			return nil, ErrKeyType
+		}
+		c, err := crypto.New(name)
+		if err != nil {
+			return nil, ErrKeyType
+		}
+		pk, err := c.PubKeyFromBytes(key)
+		if err != nil {
+			return nil, err
+		}
+		if seen[string(key)] {
+			return nil, ErrDupKey
+		}
+		seen[string(key)] = true
+		pub.Keys = append(pub.Keys, pk)
+	}
+	return pub, nil
+}
+
+func (pubKey *PubKeyMultiSig) Bytes() []byte {
+	var buf bytes.Buffer
+	putUvarint(&buf, uint64(pubKey.Threshold))
+	putUvarint(&buf, uint64(len(pubKey.Keys)))
+	for i, key := range pubKey.Keys {
+		putUvarint(&buf, uint64(pubKey.Types[i]))
+		putBytes(&buf, key.Bytes())
+	}
+	return buf.Bytes()
+}
+
+//Index 成员公钥在策略中的位置，不是成员返回-1
+func (pubKey *PubKeyMultiSig) Index(key crypto.PubKey) int {
+	for i, k := range pubKey.Keys {
+		if k.Equals(key) {
+			return i
+		}
+	}
+	return -1
+}
+
+//VerifyBytes 签名中的策略必须和公钥一致，不同成员的有效签名数达到门限
+func (pubKey *PubKeyMultiSig) VerifyBytes(msg []byte, sig crypto.Signature) bool {
+	multi, ok := sig.(*SignatureMultiSig)
+	if !ok {
+		return false
+	}
+	if !bytes.Equal(multi.Policy.Bytes(), pubKey.Bytes()) {
+		return false
+	}
+	signed := make(map[int]bool)
+	for _, part := range multi.Sigs {
+		if part.Index < 0 || part.Index >= len(pubKey.Keys) || signed[part.Index] {
+			return false
+		}
+		c, err := crypto.New(crypto.GetName(int(pubKey.Types[part.Index])))
+		if err != nil {
+			return false
+		}
+		s, err := c.SignatureFromBytes(part.Signature)
+		if err != nil || !pubKey.Keys[part.Index].VerifyBytes(msg, s) {
+			return false
+		}
+		signed[part.Index] = true
+	}
+	return len(signed) >= pubKey.Threshold
+}
+
+func (pubKey *PubKeyMultiSig) String() string {
+	return fmt.Sprintf("PubKeyMultiSig{%d/%d %X}", pubKey.Threshold, len(pubKey.Keys), pubKey.Bytes())
+}
+
+func (pubKey *PubKeyMultiSig) KeyString() string {
+	return fmt.Sprintf("%X", pubKey.Bytes())
+}
+
+func (pubKey *PubKeyMultiSig) Equals(other crypto.PubKey) bool {
+	if otherMulti, ok := other.(*PubKeyMultiSig); ok {
+		return bytes.Equal(pubKey.Bytes(), otherMulti.Bytes())
+	}
+	return false
+}
+
+//PartialSig 一个成员的签名，Index是成员在策略中的位置
+type PartialSig struct {
+	Index     int
+	Signature []byte
+}
+
+//SignatureMultiSig 策略和按成员位置排序的部分签名
+type SignatureMultiSig struct {
+	Policy *PubKeyMultiSig
+	Sigs   []PartialSig
+}
+
+//NewSignature 创建没有部分签名的多重签名
+func NewSignature(policy *PubKeyMultiSig) *SignatureMultiSig {
+	return &SignatureMultiSig{Policy: policy}
+}
+
+//AddSignature 用成员的私钥签名msg，同一个成员再次签名时替换原来的签名
+func (sig *SignatureMultiSig) AddSignature(priv crypto.PrivKey, msg []byte) error {
+	index := sig.Policy.Index(priv.PubKey())
+	if index < 0 {
+		return ErrNotMember
+	}
+	part := PartialSig{Index: index, Signature: priv.Sign(msg).Bytes()}
+	i := sort.Search(len(sig.Sigs), func(i int) bool { return sig.Sigs[i].Index >= index })
+	if i < len(sig.Sigs) && sig.Sigs[i].Index == index {
+		sig.Sigs[i] = part
+		return nil
+	}
+	sig.Sigs = append(sig.Sigs, PartialSig{})
+	copy(sig.Sigs[i+1:], sig.Sigs[i:])
+	sig.Sigs[i] = part
+	return nil
+}
+
+func (sig *SignatureMultiSig) Bytes() []byte {
+	var buf bytes.Buffer
+	putBytes(&buf, sig.Policy.Bytes())
+	putUvarint(&buf, uint64(len(sig.Sigs)))
+	for _, part := range sig.Sigs {
+		putUvarint(&buf, uint64(part.Index))
+		putBytes(&buf, part.Signature)
+	}
+	return buf.Bytes()
+}
+
+func (sig *SignatureMultiSig) IsZero() bool { return len(sig.Sigs) == 0 }
+
+func (sig *SignatureMultiSig) String() string {
+	return fmt.Sprintf("SignatureMultiSig{%d/%d}", len(sig.Sigs), sig.Policy.Threshold)
+}
+
+func (sig *SignatureMultiSig) Equals(other crypto.Signature) bool {
+	if otherMulti, ok := other.(*SignatureMultiSig); ok {
+		return bytes.Equal(sig.Bytes(), otherMulti.Bytes())
+	}
+	return false
+}
+
+//decodePubKey 只接受规范的编码，同一个策略只对应一个地址
+func decodePubKey(b []byte) (*PubKeyMultiSig, error) {
+	r := bytes.NewReader(b)
+	pub, err := readPubKey(r)
+	if err != nil {
+		return nil, err
+	}
+	if r.Len() != 0 || !bytes.Equal(pub.Bytes(), b) {
+		return nil, ErrDecode
+	}
+	return pub, nil
+}
+
+func readPubKey(r *bytes.Reader) (*PubKeyMultiSig, error) {
+	threshold, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, ErrDecode
+	}
+	count, err := binary.ReadUvarint(r)
+	if err != nil || count > MaxKeys {
+		return nil, ErrDecode
+	}
+	types := make([]int32, count)
+	keys := make([][]byte, count)
+	for i := range keys {
+		ty, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, ErrDecode
+		}
+		types[i] = int32(ty)
+		keys[i], err = readBytes(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return NewPubKey(int(threshold), types, keys)
+}
+
+//decodeSignature 部分签名必须按成员位置排序并且不重复，保证同一个签名只有一种编码
+func decodeSignature(b []byte) (*SignatureMultiSig, error) {
+	r := bytes.NewReader(b)
+	policy, err := readBytes(r)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := decodePubKey(policy)
+	if err != nil {
+		return nil, err
+	}
+	count, err := binary.ReadUvarint(r)
+	if err != nil || count > uint64(len(pub.Keys)) {
+		return nil, ErrDecode
+	}
+	sig := NewSignature(pub)
+	for i := 0; i < int(count); i++ {
+		index, err := binary.ReadUvarint(r)
+		if err != nil || index >= uint64(len(pub.Keys)) {
+			return nil, ErrDecode
+		}
+		if i > 0 && int(index) <= sig.Sigs[i-1].Index {
+			return nil, ErrDecode
+		}
+		signature, err := readBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		sig.Sigs = append(sig.Sigs, PartialSig{Index: int(index), Signature: signature})
+	}
+	if r.Len() != 0 || !bytes.Equal(sig.Bytes(), b) {
+		return nil, ErrDecode
+	}
+	return sig, nil
+}
+
+func putUvarint(buf *bytes.Buffer, v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	buf.Write(b[:n])
+}
+
+func putBytes(buf *bytes.Buffer, data []byte) {
+	putUvarint(buf, uint64(len(data)))
+	buf.Write(data)
+}
+
+func readBytes(r *bytes.Reader) ([]byte, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil || size > uint64(r.Len()) {
+		return nil, ErrDecode
+	}
+	data := make([]byte, size)
+	_, err = r.Read(data)
+	if err != nil && size > 0 {
+		return nil, ErrDecode
+	}
+	return data, nil
+}
+
+const Name = "multisig"
+
+//ID 4和5被隐私合约的签名类型使用
+const ID = 6
+
+func init() {
+	crypto.Register(Name, &Driver{})
+	crypto.RegisterType(Name, ID)
+}
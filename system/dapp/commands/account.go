@@ -5,13 +5,17 @@
 package commands
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
+	"github.com/33cn/chain33/common"
 	"github.com/33cn/chain33/common/address"
 	"github.com/33cn/chain33/rpc/jsonclient"
 	rpctypes "github.com/33cn/chain33/rpc/types"
+	"github.com/33cn/chain33/system/crypto/multisig"
 	. "github.com/33cn/chain33/system/dapp/commands/types"
 	"github.com/33cn/chain33/types"
 	"github.com/spf13/cobra"
@@ -26,11 +30,14 @@ func AccountCmd() *cobra.Command {
 
 	cmd.AddCommand(
 		DumpKeyCmd(),
+		ExportXpubCmd(),
 		GetAccountListCmd(),
 		GetBalanceCmd(),
 		ImportKeyCmd(),
+		ImportWatchCmd(),
 		NewAccountCmd(),
 		SetLabelCmd(),
+		MultiSigCmd(),
 	)
 
 	return cmd
@@ -63,6 +70,23 @@ func dumpKey(cmd *cobra.Command, args []string) {
 	ctx.Run()
 }
 
+// export extended public key for watch-only wallet
+func ExportXpubCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export_xpub",
+		Short: "Export BIP32 extended public key of the wallet, import it with import_watch on a watch-only node",
+		Run:   exportXpub,
+	}
+	return cmd
+}
+
+func exportXpub(cmd *cobra.Command, args []string) {
+	rpcLaddr, _ := cmd.Flags().GetString("rpc_laddr")
+	var res types.ReplyString
+	ctx := jsonclient.NewRpcCtx(rpcLaddr, "Chain33.ExportXpub", nil, &res)
+	ctx.Run()
+}
+
 // get accounts of the wallet
 func GetAccountListCmd() *cobra.Command {
 	cmd := &cobra.Command{
@@ -93,7 +117,7 @@ func parseListAccountRes(arg interface{}) (interface{}, error) {
 			Balance:  balanceResult,
 			Frozen:   frozenResult,
 		}
-		result.Wallets = append(result.Wallets, &WalletResult{Acc: accResult, Label: r.Label})
+		result.Wallets = append(result.Wallets, &WalletResult{Acc: accResult, Label: r.Label, WatchOnly: r.WatchOnly})
 	}
 	return result, nil
 }
@@ -140,17 +164,11 @@ func balance(cmd *cobra.Command, args []string) {
 		fmt.Fprintln(os.Stderr, types.ErrInvalidAddress)
 		return
 	}
-	if execer == "" {
-		req := types.ReqAddr{Addr: addr}
-		var res rpctypes.AllExecBalance
-		ctx := jsonclient.NewRpcCtx(rpcLaddr, "Chain33.GetAllExecBalance", req, &res)
-		ctx.SetResultCb(parseGetAllBalanceRes)
-		ctx.Run()
-		return
-	}
-	if ok := types.IsAllowExecName([]byte(execer), []byte(execer)); !ok {
-		fmt.Fprintln(os.Stderr, types.ErrExecNameNotAllow)
-		return
+	if execer != "" {
+		if ok := types.IsAllowExecName([]byte(execer), []byte(execer)); !ok {
+			fmt.Fprintln(os.Stderr, types.ErrExecNameNotAllow)
+			return
+		}
 	}
 	stateHash := ""
 	if height >= 0 {
@@ -169,6 +187,14 @@ func balance(cmd *cobra.Command, args []string) {
 		h := res.Items[0]
 		stateHash = h.StateHash
 	}
+	if execer == "" {
+		req := types.ReqAddr{Addr: addr, StateHash: stateHash}
+		var res rpctypes.AllExecBalance
+		ctx := jsonclient.NewRpcCtx(rpcLaddr, "Chain33.GetAllExecBalance", req, &res)
+		ctx.SetResultCb(parseGetAllBalanceRes)
+		ctx.Run()
+		return
+	}
 
 	var addrs []string
 	addrs = append(addrs, addr)
@@ -230,15 +256,23 @@ func addImportKeyFlags(cmd *cobra.Command) {
 
 	cmd.Flags().StringP("label", "l", "", "label for private key")
 	cmd.MarkFlagRequired("label")
+
+	cmd.Flags().StringP("sign_type", "t", "", "sign type of private key: secp256k1, ed25519 or sm2, default wallet sign type (optional)")
 }
 
 func importKey(cmd *cobra.Command, args []string) {
 	rpcLaddr, _ := cmd.Flags().GetString("rpc_laddr")
 	key, _ := cmd.Flags().GetString("key")
 	label, _ := cmd.Flags().GetString("label")
+	signType, err := getSignTypeFlag(cmd)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
 	params := types.ReqWalletImportPrivkey{
-		Privkey: key,
-		Label:   label,
+		Privkey:  key,
+		Label:    label,
+		SignType: signType,
 	}
 	var res types.WalletAccount
 	ctx := jsonclient.NewRpcCtx(rpcLaddr, "Chain33.ImportPrivkey", params, &res)
@@ -256,6 +290,57 @@ func parseImportKeyRes(arg interface{}) (interface{}, error) {
 	return result, nil
 }
 
+// sign type by name, 0 for wallet default
+func getSignTypeFlag(cmd *cobra.Command) (int32, error) {
+	name, _ := cmd.Flags().GetString("sign_type")
+	if name == "" {
+		return 0, nil
+	}
+	signType := types.GetSignType("", name)
+	if signType == 0 {
+		return 0, types.ErrNotSupport
+	}
+	return int32(signType), nil
+}
+
+// import watch-only address or extended public key
+func ImportWatchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import_watch",
+		Short: "Import watch-only address or BIP32 extended public key with label",
+		Run:   importWatch,
+	}
+	addImportWatchFlags(cmd)
+	return cmd
+}
+
+func addImportWatchFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("addr", "a", "", "watch-only address")
+	cmd.Flags().StringP("xpub", "x", "", "BIP32 extended public key, addresses are derived from xpub/0/index")
+
+	cmd.Flags().StringP("label", "l", "", "label for watch-only account")
+	cmd.MarkFlagRequired("label")
+
+	cmd.Flags().Int32P("gap", "g", 0, "gap limit of unused xpub addresses, default 20 (optional)")
+}
+
+func importWatch(cmd *cobra.Command, args []string) {
+	rpcLaddr, _ := cmd.Flags().GetString("rpc_laddr")
+	addr, _ := cmd.Flags().GetString("addr")
+	xpub, _ := cmd.Flags().GetString("xpub")
+	label, _ := cmd.Flags().GetString("label")
+	gap, _ := cmd.Flags().GetInt32("gap")
+	params := types.ReqWalletImportWatch{
+		Addr:     addr,
+		Xpub:     xpub,
+		Label:    label,
+		GapLimit: gap,
+	}
+	var res types.WalletAccounts
+	ctx := jsonclient.NewRpcCtx(rpcLaddr, "Chain33.ImportWatch", params, &res)
+	ctx.Run()
+}
+
 // create an account
 func NewAccountCmd() *cobra.Command {
 	cmd := &cobra.Command{
@@ -270,13 +355,21 @@ func NewAccountCmd() *cobra.Command {
 func addCreateAccountFlags(cmd *cobra.Command) {
 	cmd.Flags().StringP("label", "l", "", "account label")
 	cmd.MarkFlagRequired("label")
+
+	cmd.Flags().StringP("sign_type", "t", "", "sign type of account: secp256k1, ed25519 or sm2, default wallet sign type (optional)")
 }
 
 func createAccount(cmd *cobra.Command, args []string) {
 	rpcLaddr, _ := cmd.Flags().GetString("rpc_laddr")
 	label, _ := cmd.Flags().GetString("label")
+	signType, err := getSignTypeFlag(cmd)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
 	params := types.ReqNewAccount{
-		Label: label,
+		Label:    label,
+		SignType: signType,
 	}
 	var res types.WalletAccount
 	ctx := jsonclient.NewRpcCtx(rpcLaddr, "Chain33.NewAccount", params, &res)
@@ -336,3 +429,69 @@ func parseSetLabelRes(arg interface{}) (interface{}, error) {
 	}
 	return result, nil
 }
+
+// create multisig policy
+func MultiSigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "multisig",
+		Short: "Create M-of-N multisig policy and address",
+		Run:   multiSigPolicy,
+	}
+	addMultiSigFlags(cmd)
+	return cmd
+}
+
+func addMultiSigFlags(cmd *cobra.Command) {
+	cmd.Flags().Int32P("threshold", "m", 0, "number of signatures required")
+	cmd.MarkFlagRequired("threshold")
+
+	cmd.Flags().StringP("pubkeys", "k", "", "member public keys in hex, separated by \",\"")
+	cmd.MarkFlagRequired("pubkeys")
+
+	cmd.Flags().StringP("types", "t", "", "member sign types separated by \",\", default secp256k1 for all (optional)")
+}
+
+func multiSigPolicy(cmd *cobra.Command, args []string) {
+	threshold, _ := cmd.Flags().GetInt32("threshold")
+	pubkeys, _ := cmd.Flags().GetString("pubkeys")
+	signTypes, _ := cmd.Flags().GetString("types")
+	keys := strings.Split(pubkeys, ",")
+	tys := make([]int32, len(keys))
+	keyBytes := make([][]byte, len(keys))
+	var names []string
+	if signTypes != "" {
+		names = strings.Split(signTypes, ",")
+		if len(names) != len(keys) {
+			fmt.Fprintln(os.Stderr, "the number of types and pubkeys does not match")
+			return
+		}
+	}
+	for i, key := range keys {
+		var err error
+		keyBytes[i], err = common.FromHex(key)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+		tys[i] = types.SECP256K1
+		if names != nil {
+			tys[i] = int32(types.GetSignType("", names[i]))
+		}
+	}
+	pub, err := multisig.NewPubKey(int(threshold), tys, keyBytes)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	policy := pub.Bytes()
+	result := struct {
+		Policy string `json:"policy"`
+		Addr   string `json:"addr"`
+	}{common.ToHex(policy), address.PubKeyToAddress(policy).String()}
+	data, err := json.MarshalIndent(result, "", "    ")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	fmt.Println(string(data))
+}
@@ -52,6 +52,21 @@ func OneStepSend(args []string) {
 			}
 		}
 	}
+	var rpcToken string
+	size = len(params)
+	for i, v := range params {
+		if v == "--rpc_token" {
+			if i < size-1 {
+				rpcToken = params[i+1]
+				params = append(params[:i], params[i+2:]...)
+			}
+			break
+		}
+	}
+	var tokenParams []string
+	if rpcToken != "" {
+		tokenParams = []string{"--rpc_token", rpcToken}
+	}
 	var isAddr bool
 	err := address.CheckAddress(key)
 	if err != nil {
@@ -60,7 +75,7 @@ func OneStepSend(args []string) {
 		isAddr = true
 	}
 
-	cmdCreate := exec.Command(name, params...)
+	cmdCreate := exec.Command(name, append(params, tokenParams...)...)
 	var outCreate bytes.Buffer
 	var errCreate bytes.Buffer
 	cmdCreate.Stdout = &outCreate
@@ -87,6 +102,7 @@ func OneStepSend(args []string) {
 	if hasAddr {
 		cParams = append(cParams, "--rpc_laddr", rpcAddr)
 	}
+	cParams = append(cParams, tokenParams...)
 	cmdSign := exec.Command(name, cParams...)
 	var outSign bytes.Buffer
 	var errSign bytes.Buffer
@@ -107,6 +123,7 @@ func OneStepSend(args []string) {
 	if hasAddr {
 		cParams = append(cParams, "--rpc_laddr", rpcAddr)
 	}
+	cParams = append(cParams, tokenParams...)
 	cmdSend := exec.Command(name, cParams...)
 	var outSend bytes.Buffer
 	var errSend bytes.Buffer
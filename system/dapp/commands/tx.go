@@ -13,6 +13,7 @@ import (
 	"strings"
 
 	"github.com/33cn/chain33/common"
+	"github.com/33cn/chain33/common/merkle"
 	"github.com/33cn/chain33/rpc/jsonclient"
 	rpctypes "github.com/33cn/chain33/rpc/types"
 	. "github.com/33cn/chain33/system/dapp/commands/types"
@@ -34,6 +35,7 @@ func TxCmd() *cobra.Command {
 		GetRawTxCmd(),
 		DecodeTxCmd(),
 		GetAddrOverviewCmd(),
+		VerifyTxProofCmd(),
 	)
 
 	return cmd
@@ -302,3 +304,92 @@ func parseAddrOverview(view interface{}) (interface{}, error) {
 	}
 	return addrOverview, nil
 }
+
+// verify tx merkle proof
+func VerifyTxProofCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify_proof",
+		Short: "Verify transaction merkle proof against block tx root",
+		Run:   verifyTxProof,
+	}
+	addVerifyTxProofFlags(cmd)
+	return cmd
+}
+
+func addVerifyTxProofFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("hash", "s", "", "transaction hash")
+	cmd.MarkFlagRequired("hash")
+	cmd.Flags().StringP("root", "r", "", "trusted tx root(txHash of block header), use the root from rpc if empty")
+	cmd.Flags().StringP("proofs", "p", "", "merkle branch separated by \",\", verify offline if set")
+	cmd.Flags().Int64P("index", "i", 0, "transaction index in block, used with proofs")
+}
+
+func verifyTxProof(cmd *cobra.Command, args []string) {
+	rpcLaddr, _ := cmd.Flags().GetString("rpc_laddr")
+	hash, _ := cmd.Flags().GetString("hash")
+	root, _ := cmd.Flags().GetString("root")
+	proofs, _ := cmd.Flags().GetString("proofs")
+	index, _ := cmd.Flags().GetInt64("index")
+
+	result := &TxProofVerifyResult{TxHash: hash, Index: index, TxRoot: root}
+	var branch []string
+	if proofs != "" {
+		//离线验证需要提供可信的root
+		if root == "" {
+			fmt.Fprintln(os.Stderr, "root is required when verify offline")
+			return
+		}
+		branch = strings.Split(proofs, ",")
+	} else {
+		var res rpctypes.TxProofResult
+		ctx := jsonclient.NewRpcCtx(rpcLaddr, "Chain33.GetTxProof", rpctypes.QueryParm{Hash: hash}, &res)
+		_, err := ctx.RunResult()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+		if res.TxHash != hash {
+			fmt.Fprintln(os.Stderr, "tx hash mismatch")
+			return
+		}
+		branch = res.Proofs
+		result.Index = res.Index
+		result.Height = res.Header.Height
+		if root == "" {
+			result.TxRoot = res.TxRoot
+		}
+	}
+	verified, err := verifyMerkleBranch(branch, result.TxHash, result.Index, result.TxRoot)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	result.Verified = verified
+
+	data, err := json.MarshalIndent(result, "", "    ")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	fmt.Println(string(data))
+}
+
+func verifyMerkleBranch(proofs []string, txHash string, index int64, root string) (bool, error) {
+	leaf, err := common.FromHex(txHash)
+	if err != nil {
+		return false, err
+	}
+	roothash, err := common.FromHex(root)
+	if err != nil {
+		return false, err
+	}
+	var branch [][]byte
+	for _, proof := range proofs {
+		b, err := common.FromHex(proof)
+		if err != nil {
+			return false, err
+		}
+		branch = append(branch, b)
+	}
+	return merkle.VerifyMerkleBranch(branch, leaf, uint32(index), roothash), nil
+}
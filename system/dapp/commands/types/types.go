@@ -14,8 +14,9 @@ type AccountsResult struct {
 }
 
 type WalletResult struct {
-	Acc   *AccountResult `json:"acc,omitempty"`
-	Label string         `json:"label,omitempty"`
+	Acc       *AccountResult `json:"acc,omitempty"`
+	Label     string         `json:"label,omitempty"`
+	WatchOnly bool           `json:"watchOnly,omitempty"`
 }
 
 type AccountResult struct {
@@ -114,6 +115,7 @@ type WalletTxDetailResult struct {
 	Fromaddr   string                      `json:"fromaddr"`
 	Txhash     string                      `json:"txhash"`
 	ActionName string                      `json:"actionname"`
+	WatchOnly  bool                        `json:"watchOnly,omitempty"`
 }
 
 type AddrOverviewResult struct {
@@ -266,3 +268,11 @@ type PrivacyOutput struct {
 	RpubKeytx string       `protobuf:"bytes,1,opt,name=RpubKeytx,proto3" json:"RpubKeytx,omitempty"`
 	Keyoutput []*KeyOutput `protobuf:"bytes,2,rep,name=keyoutput" json:"keyoutput,omitempty"`
 }
+
+type TxProofVerifyResult struct {
+	TxHash   string `json:"txHash"`
+	Index    int64  `json:"index"`
+	Height   int64  `json:"height,omitempty"`
+	TxRoot   string `json:"txRoot"`
+	Verified bool   `json:"verified"`
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"errors"
@@ -38,6 +39,7 @@ func WalletCmd() *cobra.Command {
 		NoBalanceCmd(),
 		SetFeeCmd(),
 		SendTxCmd(),
+		RescanCmd(),
 	)
 
 	return cmd
@@ -206,6 +208,7 @@ func parseWalletTxListRes(arg interface{}) (interface{}, error) {
 			Fromaddr:   v.FromAddr,
 			Txhash:     v.TxHash,
 			ActionName: v.ActionName,
+			WatchOnly:  v.WatchOnly,
 		}
 		result.TxDetails = append(result.TxDetails, wtxd)
 	}
@@ -272,6 +275,44 @@ func autoMine(cmd *cobra.Command, args []string) {
 	ctx.Run()
 }
 
+// rescan history blocks
+func RescanCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rescan",
+		Short: "Rescan history blocks for the transactions of wallet addresses, see wallet status for progress",
+		Run:   rescan,
+	}
+	addRescanFlags(cmd)
+	return cmd
+}
+
+func addRescanFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("addrs", "a", "", "addresses separated by ',', all wallet addresses if empty (optional)")
+	cmd.Flags().Int64P("start", "s", 0, "block height to start from")
+	cmd.Flags().Int32P("flag", "f", 0, `rescan(0: start, 1: cancel, 2: resume)`)
+}
+
+func rescan(cmd *cobra.Command, args []string) {
+	rpcLaddr, _ := cmd.Flags().GetString("rpc_laddr")
+	addrs, _ := cmd.Flags().GetString("addrs")
+	start, _ := cmd.Flags().GetInt64("start")
+	flag, _ := cmd.Flags().GetInt32("flag")
+	if flag < 0 || flag > 2 {
+		cmd.UsageFunc()(cmd)
+		return
+	}
+	params := types.ReqWalletRescan{
+		FromHeight: start,
+		Flag:       flag,
+	}
+	if addrs != "" {
+		params.Addrs = strings.Split(addrs, ",")
+	}
+	var res types.WalletRescanStatus
+	ctx := jsonclient.NewRpcCtx(rpcLaddr, "Chain33.RescanWallet", params, &res)
+	ctx.Run()
+}
+
 // sign raw tx
 func NoBalanceCmd() *cobra.Command {
 	cmd := &cobra.Command{
@@ -310,6 +351,7 @@ func addSignRawTxFlags(cmd *cobra.Command) {
 	cmd.Flags().StringP("key", "k", "", "private key (optional)")
 	cmd.Flags().StringP("addr", "a", "", "account address (optional)")
 	cmd.Flags().StringP("expire", "e", "120s", "transaction expire time")
+	cmd.Flags().StringP("multisig", "m", "", "multisig policy hex, required by the first signer (optional)")
 	// A duration string is a possibly signed sequence of
 	// decimal numbers, each with optional fraction and a unit suffix,
 	// such as "300ms", "-1.5h" or "2h45m".
@@ -400,6 +442,7 @@ func signRawTx(cmd *cobra.Command, args []string) {
 	addr, _ := cmd.Flags().GetString("addr")
 	index, _ := cmd.Flags().GetInt32("index")
 	expire, _ := cmd.Flags().GetString("expire")
+	multiSig, _ := cmd.Flags().GetString("multisig")
 	expire, err := parseExpireOpt(expire)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
@@ -407,11 +450,12 @@ func signRawTx(cmd *cobra.Command, args []string) {
 	}
 
 	params := types.ReqSignRawTx{
-		Addr:    addr,
-		Privkey: key,
-		TxHex:   data,
-		Expire:  expire,
-		Index:   index,
+		Addr:     addr,
+		Privkey:  key,
+		TxHex:    data,
+		Expire:   expire,
+		Index:    index,
+		MultiSig: multiSig,
 	}
 	ctx := jsonclient.NewRpcCtx(rpcLaddr, "Chain33.SignRawTx", params, nil)
 	ctx.RunWithoutMarshal()
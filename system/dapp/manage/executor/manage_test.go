@@ -192,7 +192,7 @@ func genEventDelBlockMsg(client queue.Client, block *types.Block) queue.Message
 
 //"coins", "GetTxsByAddr",
 func genEventBlockChainQueryMsg(client queue.Client, param []byte, strDriver string, strFunName string) queue.Message {
-	blockChainQue := &types.ChainExecutor{strDriver, strFunName, zeroHash[:], param, nil}
+	blockChainQue := &types.ChainExecutor{Driver: strDriver, FuncName: strFunName, StateHash: zeroHash[:], Param: param}
 	msg := client.NewMessage("execs", types.EventBlockChainQuery, blockChainQue)
 	return msg
 }
@@ -203,7 +203,7 @@ func storeProcess(q queue.Queue) {
 		client.Sub("store")
 		for msg := range client.Recv() {
 			switch msg.Ty {
-			case types.EventStoreGet:
+			case types.EventStoreGet, types.EventStoreGetState:
 				datas := msg.GetData().(*types.StoreGet)
 				//fmt.Println("EventStoreGet Keys[0] = %s", string(datas.Keys[0]))
 
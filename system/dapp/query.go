@@ -34,7 +34,7 @@ func (d *DriverBase) GetTxsByAddr(addr *types.ReqAddr) (types.Message, error) {
 			return nil, err
 		}
 		if len(txinfos) == 0 {
-			return nil, errors.New("tx does not exist")
+			return nil, types.ErrTxNotExist
 		}
 	} else { //翻页查找指定的txhash列表
 		heightstr := HeightIndexStr(addr.GetHeight(), addr.GetIndex())
@@ -50,7 +50,7 @@ func (d *DriverBase) GetTxsByAddr(addr *types.ReqAddr) (types.Message, error) {
 			return nil, err
 		}
 		if len(txinfos) == 0 {
-			return nil, errors.New("tx does not exist")
+			return nil, types.ErrTxNotExist
 		}
 	}
 	var replyTxInfos types.ReplyTxInfos
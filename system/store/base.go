@@ -23,6 +23,9 @@ import (
 //批量读
 2. EventStoreGet(stateHash, k1,k2,k3)
 
+//批量读，状态不存在或者已经被裁剪时返回ErrStateNotExist
+3. EventStoreGetState(stateHash, k1,k2,k3)
+
 */
 
 var slog = log.New("module", "store")
@@ -47,6 +50,11 @@ type SubStore interface {
 	ProcEvent(msg queue.Message)
 }
 
+//StateGetter 读取状态时能够返回错误的SubStore可以实现这个接口，比如状态已经被裁剪
+type StateGetter interface {
+	GetState(datas *types.StoreGet) ([][]byte, error)
+}
+
 type BaseStore struct {
 	db      dbm.DB
 	qclient queue.Client
@@ -92,6 +100,20 @@ func (store *BaseStore) processMessage(msg queue.Message) {
 	} else if msg.Ty == types.EventStoreGet {
 		datas := msg.GetData().(*types.StoreGet)
 		values := store.child.Get(datas)
+		msg.Reply(client.NewMessage("", types.EventStoreGetReply, &types.StoreReplyValue{Values: values}))
+	} else if msg.Ty == types.EventStoreGetState {
+		datas := msg.GetData().(*types.StoreGet)
+		var values [][]byte
+		if getter, ok := store.child.(StateGetter); ok {
+			var err error
+			values, err = getter.GetState(datas)
+			if err != nil {
+				msg.Reply(client.NewMessage("", types.EventStoreGetReply, err))
+				return
+			}
+		} else {
+			values = store.child.Get(datas)
+		}
 		msg.Reply(client.NewMessage("", types.EventStoreGetReply, &types.StoreReplyValue{values}))
 	} else if msg.Ty == types.EventStoreMemSet { //只是在内存中set 一下，并不改变状态
 		datas := msg.GetData().(*types.StoreSetWithSync)
@@ -6,4 +6,5 @@ package init
 
 import (
 	_ "github.com/33cn/chain33/system/store/mavl"
+	_ "github.com/33cn/chain33/system/store/mvccdb"
 )
@@ -66,20 +66,7 @@ func ReadProof(roothash []byte, leafhash []byte, data []byte) (*Proof, error) {
 
 //计算inner节点的hash
 func InnerNodeProofHash(childHash []byte, branch *types.InnerNode) []byte {
-	var innernode types.InnerNode
-
-	innernode.Height = branch.Height
-	innernode.Size = branch.Size
-
-	// left is nil
-	if len(branch.LeftHash) == 0 {
-		innernode.LeftHash = childHash
-		innernode.RightHash = branch.RightHash
-	} else {
-		innernode.LeftHash = branch.LeftHash
-		innernode.RightHash = childHash
-	}
-	return innernode.Hash()
+	return branch.ProofHash(childHash)
 }
 
 func (node *Node) constructProof(t *Tree, key []byte, valuePtr *[]byte, proof *Proof) (exists bool) {
@@ -21,6 +21,7 @@ import (
 
 const (
 	leafKeyCountPrefix = "..mk.."
+	pruneStatusKey     = "..mp..status"
 	delMapPoolPrefix   = "_..md.._"
 	blockHeightStrLen  = 10
 	pruningStateStart  = 1
@@ -29,6 +30,8 @@ const (
 	delNodeCacheSize = 256 + 1
 	//每个del Pool下存放默认4096个hash
 	perDelNodePoolSize = 4096
+	//每次处理的叶子节点数，处理完保存进度
+	onceScanCount = 10000
 )
 
 var (
@@ -36,6 +39,10 @@ var (
 	enablePrune bool
 	// 每个10000裁剪一次
 	pruneHeight int = 10000
+	// 保留最近多少个高度的状态，为0时和pruneHeight相同
+	pruneRetain int64
+	// 每批裁剪之间的休眠时间
+	pruneInterval = 10 * time.Millisecond
 	// 裁剪状态
 	pruningState int32
 	delPoolCache *lru.Cache
@@ -80,6 +87,10 @@ func SetPruneHeight(height int) {
 	pruneHeight = height
 }
 
+func SetPruneRetain(retain int64) {
+	pruneRetain = retain
+}
+
 func ClosePrune() {
 	quit = true
 	wg.Wait()
@@ -127,103 +138,169 @@ func setPruning(state int32) {
 	atomic.StoreInt32(&pruningState, state)
 }
 
+func newPruneStatus(curHeight int64) *types.StorePruneStatus {
+	status := &types.StorePruneStatus{Height: curHeight, Retain: pruneRetain}
+	if pruneRetain <= 0 {
+		status.Retain = int64(pruneHeight)
+	}
+	return status
+}
+
 func pruningTree(db dbm.DB, curHeight int64) {
 	wg.Add(1)
 	defer wg.Add(-1)
+	runPruning(db, newPruneStatus(curHeight))
+}
+
+//startPruning 在后台裁剪，启动协程之前增加wg，保证ClosePrune可以等待裁剪退出
+func startPruning(db dbm.DB, status *types.StorePruneStatus) {
 	setPruning(pruningStateStart)
-	treelog.Info("pruningTree", "start curHeight:", curHeight)
+	wg.Add(1)
+	go func() {
+		defer wg.Add(-1)
+		runPruning(db, status)
+	}()
+}
+
+func runPruning(db dbm.DB, status *types.StorePruneStatus) {
+	setPruning(pruningStateStart)
+	treelog.Info("pruningTree", "start curHeight:", status.Height, "retain", status.Retain)
 	start := time.Now()
-	pruningTreeLeafNode(db, curHeight)
+	pruningTreeLeafNode(db, status)
 	end := time.Now()
-	treelog.Info("pruningTree", "curHeight:", curHeight, "pruning leafNode cost time:", end.Sub(start))
+	treelog.Info("pruningTree", "curHeight:", status.Height, "pruning leafNode cost time:", end.Sub(start),
+		"leafCount", status.LeafCount, "nodeCount", status.NodeCount, "finished", status.Finished)
 	setPruning(pruningStateEnd)
 }
 
-func pruningTreeLeafNode(db dbm.DB, curHeight int64) {
+//pruningTreeLeafNode 按照key升序遍历叶子节点计数，同一个key的叶子节点按照高度升序排列，
+//某个版本被更新的高度(也就是下一个版本的高度)在保留的高度之前，这个版本就不会再被访问，可以删除。
+//每处理onceScanCount个叶子节点保存一次进度，然后休眠一段时间，避免影响节点的正常运行
+func pruningTreeLeafNode(db dbm.DB, status *types.StorePruneStatus) {
 	prefix := []byte(leafKeyCountPrefix)
-	it := db.Iterator(prefix, nil, true)
+	it := db.Iterator(prefix, nil, false)
 	defer it.Close()
 
-	const onceScanCount = 100000
-	mp := make(map[string][]hashData)
+	var prev *leafCountData
+	it.Rewind()
+	if status.LastKey != nil {
+		//从上次保存的位置继续，lastKey对应的叶子节点作为下一个叶子节点的前一个版本
+		it.Seek(status.LastKey)
+	}
+	delMp := make(map[string]bool)
+	batch := db.NewBatch(true)
 	count := 0
-	for it.Rewind(); it.Valid(); it.Next() {
+	for ; it.Valid(); it.Next() {
 		if quit {
-			//该处退出
+			//该处退出，进度已经保存，下次启动时继续
 			return
 		}
-		//copy key
-		hashK := make([]byte, len(it.Key()))
-		copy(hashK, it.Key())
-
-		value := it.Value()
-		var pData types.PruneData
-		err := proto.Unmarshal(value, &pData)
+		cur, err := newLeafCountData(it.Key(), it.Value())
 		if err != nil {
-			panic("Unmarshal mavl leafCountKey fail")
+			continue
 		}
-		hashLen := int(pData.Lenth)
-		key, err := getKeyFromLeafCountKey(hashK, hashLen)
-		if err == nil {
-			data := hashData{
-				height: pData.Height,
-				hash:   hashK[len(hashK)-hashLen:],
-			}
-			mp[string(key)] = append(mp[string(key)], data)
-			count++
-			if count >= onceScanCount {
-				deleteNode(db, mp, curHeight, key)
-				count = 0
+		if prev != nil && bytes.Equal(prev.key, cur.key) && prev.height != cur.height { //防止相同高度时候出现的误删除
+			if status.Height-status.Retain >= cur.height {
+				batch.Delete(prev.countKey)
+				delMp[string(prev.hash)] = true
 			}
 		}
+		prev = cur
+		count++
+		if count >= onceScanCount {
+			flushPruning(db, batch, delMp, status, prev.countKey)
+			delMp = make(map[string]bool)
+			batch = db.NewBatch(true)
+			count = 0
+			time.Sleep(pruneInterval)
+		}
 	}
-	if count > 0 {
-		deleteNode(db, mp, curHeight, nil)
+	flushPruning(db, batch, delMp, status, nil)
+	status.Finished = true
+	savePruneStatus(db, status)
+}
+
+func flushPruning(db dbm.DB, batch dbm.Batch, delMp map[string]bool, status *types.StorePruneStatus, lastKey []byte) {
+	batch.Write()
+	status.LeafCount += int64(len(delMp))
+	//裁剪hashNode
+	status.NodeCount += int64(pruningHashNode(db, delMp))
+	status.LastKey = lastKey
+	savePruneStatus(db, status)
+}
+
+type leafCountData struct {
+	countKey []byte
+	key      []byte
+	hash     []byte
+	height   int64
+}
+
+func newLeafCountData(countKey, value []byte) (*leafCountData, error) {
+	var pData types.PruneData
+	err := proto.Unmarshal(value, &pData)
+	if err != nil {
+		panic("Unmarshal mavl leafCountKey fail")
 	}
+	hashLen := int(pData.Lenth)
+	key, err := getKeyFromLeafCountKey(countKey, hashLen)
+	if err != nil {
+		return nil, err
+	}
+	//copy key
+	data := &leafCountData{countKey: make([]byte, len(countKey)), height: pData.Height}
+	copy(data.countKey, countKey)
+	data.key = data.countKey[len(leafKeyCountPrefix) : len(leafKeyCountPrefix)+len(key)]
+	data.hash = data.countKey[len(data.countKey)-hashLen:]
+	return data, nil
 }
 
-func deleteNode(db dbm.DB, mp map[string][]hashData, curHeight int64, lastKey []byte) {
-	if len(mp) == 0 {
-		return
+func savePruneStatus(db dbm.DB, status *types.StorePruneStatus) {
+	err := db.SetSync([]byte(pruneStatusKey), types.Encode(status))
+	if err != nil {
+		treelog.Error("savePruneStatus", "err", err)
 	}
-	var tmp []hashData
-	//del
-	if lastKey != nil {
-		if _, ok := mp[string(lastKey)]; ok {
-			tmp = mp[string(lastKey)]
-			delete(mp, string(lastKey))
-		}
+}
+
+func loadPruneStatus(db dbm.DB) *types.StorePruneStatus {
+	status := &types.StorePruneStatus{}
+	value, err := db.Get([]byte(pruneStatusKey))
+	if err != nil || len(value) == 0 {
+		return status
 	}
-	delMp := make(map[string]bool)
-	batch := db.NewBatch(true)
-	for key, vals := range mp {
-		if len(vals) > 1 {
-			if vals[1].height != vals[0].height { //防止相同高度时候出现的误删除
-				for _, val := range vals[1:] { //从第二个开始判断
-					if curHeight >= val.height+int64(pruneHeight) {
-						//batch.Delete(val.hash) //叶子节点hash值的删除放入pruningHashNode中
-						batch.Delete(genLeafCountKey([]byte(key), val.hash, val.height))
-						delMp[string(val.hash)] = true
-					}
-				}
-			}
-		}
-		delete(mp, key)
+	err = types.Decode(value, status)
+	if err != nil {
+		treelog.Error("loadPruneStatus", "err", err)
+		return &types.StorePruneStatus{}
 	}
-	batch.Write()
-	//add
-	if lastKey != nil {
-		if _, ok := mp[string(lastKey)]; ok {
-			mp[string(lastKey)] = tmp
-		}
+	return status
+}
+
+//ResumePrune 启动时调用，上一次的裁剪没有完成时从保存的位置继续裁剪
+func ResumePrune(db dbm.DB) {
+	quit = false
+	setPruning(pruningStateEnd)
+	if !enablePrune {
+		return
 	}
-	//裁剪hashNode
-	pruningHashNode(db, delMp)
+	status := loadPruneStatus(db)
+	if status.Height == 0 || status.Finished {
+		return
+	}
+	startPruning(db, status)
 }
 
-func pruningHashNode(db dbm.DB, mp map[string]bool) {
+//GetPruneStatus 获取裁剪的进度
+func GetPruneStatus(db dbm.DB) *types.StorePruneStatus {
+	status := loadPruneStatus(db)
+	status.Enable = enablePrune
+	status.Pruning = isPruning()
+	return status
+}
+
+func pruningHashNode(db dbm.DB, mp map[string]bool) int {
 	if len(mp) == 0 {
-		return
+		return 0
 	}
 	ndb := newMarkNodeDB(db, 1024*10)
 	var delNodeStrs []string
@@ -268,6 +345,7 @@ func pruningHashNode(db dbm.DB, mp map[string]bool) {
 	batch.Write()
 	//fmt.Printf("pruningHashNode ndb.count %d delete %d \n", ndb.count, count1)
 	treelog.Info("pruningHashNode ", "delNodeStrs", count1, "delete node mp count", count)
+	return count
 }
 
 //获取要删除的hash节点
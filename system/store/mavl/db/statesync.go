@@ -0,0 +1,105 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mavl
+
+import (
+	"bytes"
+
+	dbm "github.com/33cn/chain33/common/db"
+	"github.com/33cn/chain33/types"
+	"github.com/golang/protobuf/proto"
+)
+
+//GetStateNodes 获取快速同步需要的状态树节点，节点不存在时为空
+//开启mvcc时叶子节点不保存value，无法校验节点的hash，不提供节点
+func GetStateNodes(db dbm.DB, hashes [][]byte) [][]byte {
+	nodes := make([][]byte, len(hashes))
+	if enableMvcc {
+		return nodes
+	}
+	for i, hash := range hashes {
+		buf, err := db.Get(hash)
+		if err == nil && len(buf) > 0 {
+			nodes[i] = buf
+		}
+	}
+	return nodes
+}
+
+//SaveStateNodes 校验节点和hash一致之后保存，返回内部节点的子节点hash
+//从根节点开始逐层获取节点，所有的节点都可以通过区块头中的StateHash校验
+func SaveStateNodes(db dbm.DB, hashes [][]byte, nodes [][]byte) ([][]byte, error) {
+	if enableMvcc {
+		return nil, types.ErrNotSupport
+	}
+	if len(hashes) != len(nodes) {
+		return nil, types.ErrInvalidParam
+	}
+	var children [][]byte
+	batch := db.NewBatch(true)
+	for i, hash := range hashes {
+		if len(nodes[i]) == 0 {
+			return nil, types.ErrStateNodeNotExist
+		}
+		var storeNode types.StoreNode
+		err := proto.Unmarshal(nodes[i], &storeNode)
+		if err != nil {
+			return nil, types.ErrStateNodeHash
+		}
+		if !checkStateNodeHash(hash, &storeNode) {
+			return nil, types.ErrStateNodeHash
+		}
+		//parentHash只用于裁剪，不在hash的计算中，不能信任
+		storeNode.ParentHash = nil
+		batch.Set(hash, types.Encode(&storeNode))
+		if storeNode.Height > 0 {
+			children = append(children, storeNode.LeftHash, storeNode.RightHash)
+		}
+	}
+	err := batch.Write()
+	if err != nil {
+		return nil, err
+	}
+	return children, nil
+}
+
+//GetStateNodeChildren 返回内部节点的左右子节点hash，叶子节点没有子节点
+func GetStateNodeChildren(node []byte) ([][]byte, error) {
+	var storeNode types.StoreNode
+	err := proto.Unmarshal(node, &storeNode)
+	if err != nil {
+		return nil, err
+	}
+	if storeNode.Height == 0 {
+		return nil, nil
+	}
+	return [][]byte{storeNode.LeftHash, storeNode.RightHash}, nil
+}
+
+//checkStateNodeHash 开启mavl前缀时，除了根节点之外的hash带有高度前缀
+func checkStateNodeHash(hash []byte, storeNode *types.StoreNode) bool {
+	var nodeHash []byte
+	if storeNode.Height == 0 {
+		if storeNode.Size != 1 {
+			return false
+		}
+		leafnode := &types.LeafNode{Height: storeNode.Height, Key: storeNode.Key, Size: storeNode.Size, Value: storeNode.Value}
+		nodeHash = leafnode.Hash()
+	} else {
+		if storeNode.Height < 0 || len(storeNode.LeftHash) == 0 || len(storeNode.RightHash) == 0 {
+			return false
+		}
+		innernode := &types.InnerNode{Height: storeNode.Height, Size: storeNode.Size, LeftHash: storeNode.LeftHash, RightHash: storeNode.RightHash}
+		nodeHash = innernode.Hash()
+	}
+	if bytes.Equal(hash, nodeHash) {
+		return true
+	}
+	prefix := []byte(hashNodePrefix)
+	if storeNode.Height == 0 {
+		prefix = []byte(leafNodePrefix)
+	}
+	return bytes.HasPrefix(hash, prefix) && bytes.HasSuffix(hash, nodeHash)
+}
@@ -136,10 +136,10 @@ func (t *Tree) Save() []byte {
 			return nil
 		}
 		// 该线程应只允许一个
-		if enablePrune && !isPruning() &&
+		if enablePrune && !isPruning() && pruneHeight > 0 &&
 			t.blockHeight%int64(pruneHeight) == 0 &&
 			t.blockHeight/int64(pruneHeight) > 1 {
-			go pruningTree(t.ndb.db, t.blockHeight)
+			startPruning(t.ndb.db, newPruneStatus(t.blockHeight))
 		}
 	}
 	return t.root.hash
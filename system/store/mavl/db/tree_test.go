@@ -834,6 +834,135 @@ func TestPruningTree(t *testing.T) {
 	PruningTreePrintDB(db, []byte(leafNodePrefix))
 }
 
+//每个高度都更新相同的key，裁剪之后保留高度内的状态可以读取，之前的状态被裁剪
+func TestPruningRetain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "datastore")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	db := db.NewDB("test", "leveldb", dir, 100)
+	defer db.Close()
+
+	EnablePrune(true)
+	defer EnablePrune(false)
+	defer EnableMavlPrefix(false)
+	//不在保存时触发裁剪
+	defer SetPruneHeight(pruneHeight)
+	SetPruneHeight(10000)
+
+	const keyN = 5
+	const blockN = 100
+	const retain = 20
+	hashes := saveSameKeyBlocks(t, db, keyN, blockN)
+	status := &types.StorePruneStatus{Height: blockN - 1, Retain: retain}
+	runPruning(db, status)
+	assert.True(t, status.Finished)
+	//每个key在高度0到78的版本被更新的高度不大于79，可以删除
+	assert.Equal(t, int64(keyN*(blockN-1-retain)), status.LeafCount)
+	assert.True(t, status.NodeCount >= status.LeafCount)
+
+	for h := blockN - 1 - retain; h < blockN; h++ {
+		values, ok := getSameKeyValues(db, hashes[h], keyN)
+		assert.True(t, ok, "height %d", h)
+		assert.Equal(t, []byte(fmt.Sprintf("v_%d", h)), values[0])
+	}
+	_, ok := getSameKeyValues(db, hashes[blockN-2-retain], keyN)
+	assert.False(t, ok)
+
+	saved := GetPruneStatus(db)
+	assert.True(t, saved.Enable)
+	assert.False(t, saved.Pruning)
+	assert.True(t, saved.Finished)
+	assert.Equal(t, int64(blockN-1), saved.Height)
+	assert.Equal(t, status.LeafCount, saved.LeafCount)
+}
+
+//从保存的进度继续裁剪
+func TestPruningResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "datastore")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	db := db.NewDB("test", "leveldb", dir, 100)
+	defer db.Close()
+
+	EnablePrune(true)
+	defer EnablePrune(false)
+	defer EnableMavlPrefix(false)
+	//不在保存时触发裁剪
+	defer SetPruneHeight(pruneHeight)
+	SetPruneHeight(10000)
+
+	const keyN = 4
+	const blockN = 10
+	saveSameKeyBlocks(t, db, keyN, blockN)
+	//模拟上次裁剪到k2的第一个版本时退出
+	var lastKey []byte
+	it := db.Iterator([]byte(leafKeyCountPrefix+"k2"), nil, false)
+	if it.Rewind() {
+		lastKey = append(lastKey, it.Key()...)
+	}
+	it.Close()
+	require.NotNil(t, lastKey)
+	savePruneStatus(db, &types.StorePruneStatus{Height: blockN - 1, Retain: 1, LastKey: lastKey})
+
+	ResumePrune(db)
+	wg.Wait()
+	status := GetPruneStatus(db)
+	assert.True(t, status.Finished)
+	assert.False(t, status.Pruning)
+	//只裁剪了k2和k3
+	assert.Equal(t, int64(2*(blockN-2)), status.LeafCount)
+	assert.Equal(t, blockN, countLeafKey(db, "k0"))
+	assert.Equal(t, 2, countLeafKey(db, "k2"))
+
+	//已经完成的裁剪不会再继续
+	ResumePrune(db)
+	assert.False(t, isPruning())
+}
+
+func saveSameKeyBlocks(t *testing.T, db db.DB, keyN, blockN int) (hashes [][]byte) {
+	prevHash := make([]byte, 32)
+	for h := 0; h < blockN; h++ {
+		tree := NewTree(db, true)
+		require.NoError(t, tree.Load(prevHash))
+		tree.SetBlockHeight(int64(h))
+		for i := 0; i < keyN; i++ {
+			tree.Set([]byte(fmt.Sprintf("k%d", i)), []byte(fmt.Sprintf("v_%d", h)))
+		}
+		prevHash = tree.Save()
+		hashes = append(hashes, prevHash)
+	}
+	return hashes
+}
+
+func getSameKeyValues(db db.DB, hash []byte, keyN int) (values [][]byte, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			ok = false
+		}
+	}()
+	tree := NewTree(db, true)
+	if err := tree.Load(hash); err != nil {
+		return nil, false
+	}
+	for i := 0; i < keyN; i++ {
+		_, value, exist := tree.Get([]byte(fmt.Sprintf("k%d", i)))
+		if !exist {
+			return nil, false
+		}
+		values = append(values, value)
+	}
+	return values, true
+}
+
+func countLeafKey(db db.DB, key string) (count int) {
+	it := db.Iterator([]byte(leafKeyCountPrefix+key), nil, false)
+	defer it.Close()
+	for it.Rewind(); it.Valid(); it.Next() {
+		count++
+	}
+	return count
+}
+
 func genUpdateKV(height int64, txN int64, vIndex int) (kvs []*types.KeyValue) {
 	for i := int64(0); i < txN; i++ {
 		n := height*txN + i
@@ -1148,3 +1277,49 @@ func saveBlock(dbm db.DB, height int64, hash []byte, txN int64, mvcc bool) (newH
 	}
 	return newHash, nil
 }
+
+//从根节点开始逐层复制状态树的节点到新的数据库，复制之后可以读取所有的key
+func TestStateNodesSync(t *testing.T) {
+	for _, prefix := range []bool{false, true} {
+		EnableMavlPrefix(prefix)
+		dir, err := ioutil.TempDir("", "datastore")
+		require.NoError(t, err)
+		src := db.NewDB("src", "leveldb", dir, 100)
+		dst := db.NewDB("dst", "leveldb", dir, 100)
+		var hash []byte
+		for i := int64(0); i < 10; i++ {
+			hash, err = saveBlock(src, i, hash, 20, false)
+			require.NoError(t, err)
+		}
+
+		var count int
+		hashes := [][]byte{hash}
+		for len(hashes) > 0 {
+			nodes := GetStateNodes(src, hashes)
+			children, err := SaveStateNodes(dst, hashes, nodes)
+			require.NoError(t, err)
+			count += len(hashes)
+			hashes = children
+		}
+		assert.True(t, count > 200)
+
+		tree := NewTree(dst, true)
+		require.NoError(t, tree.Load(hash))
+		for _, kv := range genKV(9, 20) {
+			_, value, exist := tree.Get(kv.Key)
+			assert.True(t, exist)
+			assert.Equal(t, kv.Value, value)
+		}
+
+		//节点不存在或者和hash不一致
+		_, err = SaveStateNodes(dst, [][]byte{[]byte("notexist")}, GetStateNodes(src, [][]byte{[]byte("notexist")}))
+		assert.Equal(t, types.ErrStateNodeNotExist, err)
+		nodes := GetStateNodes(src, [][]byte{hash})
+		_, err = SaveStateNodes(dst, [][]byte{[]byte(RandStr(32))}, nodes)
+		assert.Equal(t, types.ErrStateNodeHash, err)
+		src.Close()
+		dst.Close()
+		os.RemoveAll(dir)
+	}
+	EnableMavlPrefix(false)
+}
@@ -33,6 +33,7 @@ type Store struct {
 	enableMVCC       bool
 	enableMavlPrune  bool
 	pruneHeight      int32
+	pruneRetain      int64
 }
 
 func init() {
@@ -44,6 +45,7 @@ type subConfig struct {
 	EnableMVCC       bool  `json:"enableMVCC"`
 	EnableMavlPrune  bool  `json:"enableMavlPrune"`
 	PruneHeight      int32 `json:"pruneHeight"`
+	PruneRetain      int64 `json:"pruneRetain"`
 }
 
 func New(cfg *types.Store, sub []byte) queue.Module {
@@ -52,7 +54,7 @@ func New(cfg *types.Store, sub []byte) queue.Module {
 	if sub != nil {
 		types.MustDecode(sub, &subcfg)
 	}
-	mavls := &Store{bs, make(map[string]*mavl.Tree), nil, subcfg.EnableMavlPrefix, subcfg.EnableMVCC, subcfg.EnableMavlPrune, subcfg.PruneHeight}
+	mavls := &Store{bs, make(map[string]*mavl.Tree), nil, subcfg.EnableMavlPrefix, subcfg.EnableMVCC, subcfg.EnableMavlPrune, subcfg.PruneHeight, subcfg.PruneRetain}
 	mavls.cache, _ = lru.New(10)
 	//使能前缀mavl以及MVCC
 
@@ -60,11 +62,15 @@ func New(cfg *types.Store, sub []byte) queue.Module {
 	mavls.enableMVCC = subcfg.EnableMVCC
 	mavls.enableMavlPrune = subcfg.EnableMavlPrune
 	mavls.pruneHeight = subcfg.PruneHeight
+	mavls.pruneRetain = subcfg.PruneRetain
 	mavl.EnableMavlPrefix(mavls.enableMavlPrefix)
 	mavl.EnableMVCC(mavls.enableMVCC)
 	mavl.EnablePrune(mavls.enableMavlPrune)
 	mavl.SetPruneHeight(int(mavls.pruneHeight))
+	mavl.SetPruneRetain(mavls.pruneRetain)
 	bs.SetChild(mavls)
+	//继续上次没有完成的裁剪
+	mavl.ResumePrune(mavls.GetDB())
 	return mavls
 }
 
@@ -79,33 +85,89 @@ func (mavls *Store) Set(datas *types.StoreSet, sync bool) ([]byte, error) {
 }
 
 func (mavls *Store) Get(datas *types.StoreGet) [][]byte {
-	var tree *mavl.Tree
-	var err error
-	values := make([][]byte, len(datas.Keys))
-	search := string(datas.StateHash)
-	if data, ok := mavls.cache.Get(search); ok {
-		tree = data.(*mavl.Tree)
-	} else if data, ok := mavls.trees[search]; ok {
-		tree = data
-	} else {
-		tree = mavl.NewTree(mavls.GetDB(), true)
-		//get接口也应该传入高度
-		//tree.SetBlockHeight(datas.Height)
-		err = tree.Load(datas.StateHash)
-		if err == nil {
-			mavls.cache.Add(search, tree)
+	values, err := mavls.GetState(datas)
+	if err != nil {
+		mlog.Debug("store mavl get", "err", err, "StateHash", common.ToHex(datas.StateHash))
+	}
+	return values
+}
+
+//GetState 和Get相同，但是状态不存在或者已经被裁剪时返回ErrStateNotExist
+func (mavls *Store) GetState(datas *types.StoreGet) (values [][]byte, err error) {
+	values = make([][]byte, len(datas.Keys))
+	tree, err := mavls.getTree(datas.StateHash)
+	if err != nil {
+		return values, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			values, err = make([][]byte, len(datas.Keys)), recoverPruned(r)
 		}
-		mlog.Debug("store mavl get tree", "err", err, "StateHash", common.ToHex(datas.StateHash))
-	}
-	if err == nil {
-		for i := 0; i < len(datas.Keys); i++ {
-			_, value, exit := tree.Get(datas.Keys[i])
-			if exit {
-				values[i] = value
-			}
+	}()
+	for i := 0; i < len(datas.Keys); i++ {
+		_, value, exit := tree.Get(datas.Keys[i])
+		if exit {
+			values[i] = value
 		}
 	}
-	return values
+	return values, nil
+}
+
+//GetProof 获取key在状态中的mavl proof，key不存在时返回ErrNotFound
+//开启mavl前缀时，路径上节点的hash带有写入高度的前缀，proof中没有这些信息，不能生成proof
+func (mavls *Store) GetProof(req *types.StoreGetProof) (reply *types.StoreReplyProof, err error) {
+	if mavls.enableMavlPrefix {
+		return nil, types.ErrNotSupport
+	}
+	tree, err := mavls.getTree(req.StateHash)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			reply, err = nil, recoverPruned(r)
+		}
+	}()
+	value, proof := tree.ConstructProof(req.Key)
+	if proof == nil {
+		return nil, types.ErrNotFound
+	}
+	reply = &types.StoreReplyProof{
+		Value: value,
+		Proof: &types.MAVLProof{LeafHash: proof.LeafHash, InnerNodes: proof.InnerNodes, RootHash: proof.RootHash},
+	}
+	return reply, nil
+}
+
+func (mavls *Store) getTree(stateHash []byte) (*mavl.Tree, error) {
+	search := string(stateHash)
+	if data, ok := mavls.cache.Get(search); ok {
+		return data.(*mavl.Tree), nil
+	}
+	if data, ok := mavls.trees[search]; ok {
+		return data, nil
+	}
+	tree := mavl.NewTree(mavls.GetDB(), true)
+	//get接口也应该传入高度
+	//tree.SetBlockHeight(datas.Height)
+	err := tree.Load(stateHash)
+	mlog.Debug("store mavl get tree", "err", err, "StateHash", common.ToHex(stateHash))
+	if err == mavl.ErrNodeNotExist {
+		return nil, types.ErrStateNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	mavls.cache.Add(search, tree)
+	return tree, nil
+}
+
+//recoverPruned 裁剪只删除了部分节点时，在查找的过程中才会发现节点不存在
+func recoverPruned(r interface{}) error {
+	if r != mavl.ErrNodeNotExist {
+		panic(r)
+	}
+	return types.ErrStateNotExist
 }
 
 func (mavls *Store) MemSet(datas *types.StoreSet, sync bool) ([]byte, error) {
@@ -168,6 +230,35 @@ func (mavls *Store) IterateRangeByStateHash(statehash []byte, start []byte, end
 }
 
 func (mavls *Store) ProcEvent(msg queue.Message) {
+	if msg.Ty == types.EventStoreGetProof {
+		reply, err := mavls.GetProof(msg.GetData().(*types.StoreGetProof))
+		if err != nil {
+			msg.Reply(mavls.GetQueueClient().NewMessage("", types.EventStoreGetProofReply, err))
+			return
+		}
+		msg.Reply(mavls.GetQueueClient().NewMessage("", types.EventStoreGetProofReply, reply))
+		return
+	}
+	if msg.Ty == types.EventStoreGetPruneStatus {
+		msg.Reply(mavls.GetQueueClient().NewMessage("", types.EventStoreGetPruneStatusReply, mavl.GetPruneStatus(mavls.GetDB())))
+		return
+	}
+	if msg.Ty == types.EventStoreGetStateNodes {
+		req := msg.GetData().(*types.ReqStateNodes)
+		nodes := mavl.GetStateNodes(mavls.GetDB(), req.Hashes)
+		msg.Reply(mavls.GetQueueClient().NewMessage("", types.EventStateNodes, &types.StateNodes{Hashes: req.Hashes, Nodes: nodes}))
+		return
+	}
+	if msg.Ty == types.EventStoreSaveStateNodes {
+		req := msg.GetData().(*types.StateNodes)
+		children, err := mavl.SaveStateNodes(mavls.GetDB(), req.Hashes, req.Nodes)
+		if err != nil {
+			msg.Reply(mavls.GetQueueClient().NewMessage("", types.EventStateNodes, err))
+			return
+		}
+		msg.Reply(mavls.GetQueueClient().NewMessage("", types.EventStateNodes, &types.StateNodes{Hashes: children}))
+		return
+	}
 	msg.ReplyErr("Store", types.ErrActionNotSupport)
 }
 
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/33cn/chain33/common"
+	dbm "github.com/33cn/chain33/common/db"
 	drivers "github.com/33cn/chain33/system/store"
 	mavldb "github.com/33cn/chain33/system/store/mavl/db"
 	"github.com/33cn/chain33/types"
@@ -115,6 +116,117 @@ func TestKvdbMemSet(t *testing.T) {
 	assert.Nil(t, notExistHash)
 }
 
+func TestKvdbGetState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "example")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir) // clean up
+	os.RemoveAll(dir)       //删除已存在目录
+	var store_cfg = newStoreCfg(dir)
+	store := New(store_cfg, nil).(*Store)
+	assert.NotNil(t, store)
+
+	var kv []*types.KeyValue
+	var keys [][]byte
+	for i := 0; i < 10; i++ {
+		key := []byte(fmt.Sprintf("k%d", i))
+		keys = append(keys, key)
+		kv = append(kv, &types.KeyValue{Key: key, Value: []byte(fmt.Sprintf("v%d", i))})
+	}
+	hash, err := store.Set(&types.StoreSet{StateHash: drivers.EmptyRoot[:], KV: kv}, true)
+	assert.Nil(t, err)
+	values, err := store.GetState(&types.StoreGet{StateHash: hash, Keys: keys})
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("v9"), values[9])
+
+	//状态不存在
+	_, err = store.GetState(&types.StoreGet{StateHash: common.Sha256([]byte("notexist")), Keys: keys})
+	assert.Equal(t, types.ErrStateNotExist, err)
+	store.Close()
+
+	//模拟裁剪：只保留根节点
+	db := dbm.NewDB("store", store_cfg.Driver, store_cfg.DbPath, store_cfg.DbCache)
+	it := db.Iterator(nil, nil, false)
+	var nodes [][]byte
+	for it.Rewind(); it.Valid(); it.Next() {
+		if string(it.Key()) != string(hash) {
+			nodes = append(nodes, it.Key())
+		}
+	}
+	it.Close()
+	for _, node := range nodes {
+		db.Delete(node)
+	}
+	db.Close()
+
+	store = New(store_cfg, nil).(*Store)
+	defer store.Close()
+	values, err = store.GetState(&types.StoreGet{StateHash: hash, Keys: keys})
+	assert.Equal(t, types.ErrStateNotExist, err)
+	assert.Len(t, values, len(keys))
+	//Get 接口保持原来的行为
+	values = store.Get(&types.StoreGet{StateHash: hash, Keys: keys})
+	assert.Nil(t, values[0])
+}
+
+func TestKvdbGetProof(t *testing.T) {
+	dir, err := ioutil.TempDir("", "example")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir) // clean up
+	os.RemoveAll(dir)       //删除已存在目录
+	var store_cfg = newStoreCfg(dir)
+	store := New(store_cfg, nil).(*Store)
+	assert.NotNil(t, store)
+	defer store.Close()
+
+	var kv []*types.KeyValue
+	for i := 0; i < 10; i++ {
+		kv = append(kv, &types.KeyValue{Key: []byte(fmt.Sprintf("k%d", i)), Value: []byte(fmt.Sprintf("v%d", i))})
+	}
+	hash, err := store.Set(&types.StoreSet{StateHash: drivers.EmptyRoot[:], KV: kv}, true)
+	assert.Nil(t, err)
+	header := &types.Header{StateHash: hash}
+	for i := 0; i < 10; i++ {
+		reply, err := store.GetProof(&types.StoreGetProof{StateHash: hash, Key: kv[i].Key})
+		assert.Nil(t, err)
+		assert.Equal(t, kv[i].Value, reply.Value)
+		assert.Nil(t, types.VerifyStateProof(header, kv[i].Key, reply.Value, reply.Proof))
+		//篡改value
+		assert.Equal(t, types.ErrStateProofVerify, types.VerifyStateProof(header, kv[i].Key, []byte("bad"), reply.Proof))
+	}
+	//错误的区块头
+	reply, err := store.GetProof(&types.StoreGetProof{StateHash: hash, Key: kv[0].Key})
+	assert.Nil(t, err)
+	badHeader := &types.Header{StateHash: common.Sha256([]byte("bad"))}
+	assert.Equal(t, types.ErrStateProofVerify, types.VerifyStateProof(badHeader, kv[0].Key, kv[0].Value, reply.Proof))
+
+	_, err = store.GetProof(&types.StoreGetProof{StateHash: hash, Key: []byte("notexist")})
+	assert.Equal(t, types.ErrNotFound, err)
+	_, err = store.GetProof(&types.StoreGetProof{StateHash: common.Sha256([]byte("notexist")), Key: kv[0].Key})
+	assert.Equal(t, types.ErrStateNotExist, err)
+
+	//带前缀的proof不能验证
+	prefixProof := &types.MAVLProof{LeafHash: append([]byte("_mb_-0000000001-"), reply.Proof.LeafHash...), InnerNodes: reply.Proof.InnerNodes, RootHash: hash}
+	assert.Equal(t, types.ErrNotSupport, types.VerifyStateProof(header, kv[0].Key, kv[0].Value, prefixProof))
+}
+
+func TestKvdbGetProofWithPrefix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "example")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir) // clean up
+	os.RemoveAll(dir)       //删除已存在目录
+	var store_cfg = newStoreCfg(dir)
+	store := New(store_cfg, []byte(`{"enableMavlPrefix":true}`)).(*Store)
+	assert.NotNil(t, store)
+	defer store.Close()
+	defer mavldb.EnableMavlPrefix(false)
+
+	kv := []*types.KeyValue{{Key: []byte("k1"), Value: []byte("v1")}, {Key: []byte("k2"), Value: []byte("v2")}}
+	hash, err := store.Set(&types.StoreSet{StateHash: drivers.EmptyRoot[:], KV: kv}, true)
+	assert.Nil(t, err)
+	_, err = store.GetProof(&types.StoreGetProof{StateHash: hash, Key: kv[0].Key})
+	assert.Equal(t, types.ErrNotSupport, err)
+}
+
 func TestKvdbRollback(t *testing.T) {
 	dir, err := ioutil.TempDir("", "example")
 	assert.Nil(t, err)
@@ -0,0 +1,371 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mvccdb
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/33cn/chain33/common"
+	dbm "github.com/33cn/chain33/common/db"
+	clog "github.com/33cn/chain33/common/log"
+	log "github.com/33cn/chain33/common/log/log15"
+	"github.com/33cn/chain33/queue"
+	drivers "github.com/33cn/chain33/system/store"
+	"github.com/33cn/chain33/types"
+)
+
+var mlog = log.New("module", "mvccdb")
+
+//版本号固定为20位数字，见common/db.GetKey
+const versionLen = 20
+
+func SetLogLevel(level string) {
+	clog.SetLogLevel(level)
+}
+
+func DisableLog() {
+	mlog.SetHandler(log.DiscardHandler())
+}
+
+//Store 基于MVCC的扁平kv状态存储，每个区块只写入修改过的key，没有mavl树节点的写放大
+//状态hash = sha256(前一个状态hash，版本号，本区块排序去重后的kv)，是一个增量的累加器，
+//和mavl的状态hash不兼容，需要从创世区块开始使用。只保存主链的历史状态，分叉的状态在提交时被删除
+//状态hash不是默克尔树的根，不能生成key的证明，也不能按照状态hash下载状态节点，
+//所以不支持GetStateProof，也不支持基于checkpoint的快速同步
+type Store struct {
+	*drivers.BaseStore
+	mvcc    *dbm.MVCCHelper
+	pending map[string]*pendingSet
+}
+
+//pendingSet MemSet之后还没有Commit的状态
+type pendingSet struct {
+	prevHash []byte
+	version  int64
+	sync     bool
+	kvs      []*types.KeyValue
+	kvmap    map[string][]byte
+}
+
+func init() {
+	drivers.Reg("mvccdb", New)
+}
+
+func New(cfg *types.Store, sub []byte) queue.Module {
+	bs := drivers.NewBaseStore(cfg)
+	mvccs := &Store{BaseStore: bs, mvcc: dbm.NewMVCC(bs.GetDB()), pending: make(map[string]*pendingSet)}
+	bs.SetChild(mvccs)
+	return mvccs
+}
+
+func (mvccs *Store) Close() {
+	mvccs.BaseStore.Close()
+	mlog.Info("store mvccdb closed")
+}
+
+func (mvccs *Store) Set(datas *types.StoreSet, sync bool) ([]byte, error) {
+	hash, err := mvccs.MemSet(datas, sync)
+	if err != nil {
+		return nil, err
+	}
+	return mvccs.Commit(&types.ReqHash{Hash: hash})
+}
+
+func (mvccs *Store) Get(datas *types.StoreGet) [][]byte {
+	values, err := mvccs.GetState(datas)
+	if err != nil {
+		mlog.Debug("store mvccdb get", "err", err, "StateHash", common.ToHex(datas.StateHash))
+	}
+	return values
+}
+
+//GetState 和Get相同，但是状态不存在或者已经被删除时返回ErrStateNotExist
+func (mvccs *Store) GetState(datas *types.StoreGet) ([][]byte, error) {
+	values := make([][]byte, len(datas.Keys))
+	hash := datas.StateHash
+	var overlays []*pendingSet
+	for {
+		p, ok := mvccs.pending[string(hash)]
+		if !ok {
+			break
+		}
+		overlays = append(overlays, p)
+		hash = p.prevHash
+	}
+	version := int64(-1)
+	if !isEmptyRoot(hash) {
+		var err error
+		version, err = mvccs.getVersion(hash)
+		if err != nil {
+			return values, err
+		}
+	}
+	for i, key := range datas.Keys {
+		found := false
+		for _, p := range overlays {
+			if value, ok := p.kvmap[string(key)]; ok {
+				values[i], found = value, true
+				break
+			}
+		}
+		if found || version < 0 {
+			continue
+		}
+		value, err := mvccs.getV(key, version)
+		if err == types.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return make([][]byte, len(datas.Keys)), err
+		}
+		if len(value) > 0 {
+			values[i] = value
+		}
+	}
+	return values, nil
+}
+
+func (mvccs *Store) MemSet(datas *types.StoreSet, sync bool) ([]byte, error) {
+	version := int64(0)
+	if !isEmptyRoot(datas.StateHash) {
+		prev, err := mvccs.getVersion(datas.StateHash)
+		if err != nil {
+			return nil, err
+		}
+		version = prev + 1
+	}
+	p := &pendingSet{prevHash: datas.StateHash, version: version, sync: sync, kvmap: make(map[string][]byte)}
+	for _, kv := range datas.KV {
+		p.kvmap[string(kv.Key)] = kv.Value
+	}
+	for key, value := range p.kvmap {
+		p.kvs = append(p.kvs, &types.KeyValue{Key: []byte(key), Value: value})
+	}
+	sort.Slice(p.kvs, func(i, j int) bool {
+		return bytes.Compare(p.kvs[i].Key, p.kvs[j].Key) < 0
+	})
+	hash := calcStateHash(datas.StateHash, version, p.kvs)
+	mvccs.pending[string(hash)] = p
+	if len(mvccs.pending) > 1000 {
+		mlog.Error("too many pending state in cache")
+	}
+	return hash, nil
+}
+
+func (mvccs *Store) Commit(req *types.ReqHash) ([]byte, error) {
+	p, ok := mvccs.pending[string(req.Hash)]
+	if !ok {
+		mlog.Error("store mvccdb commit", "err", types.ErrHashNotFound)
+		return nil, types.ErrHashNotFound
+	}
+	err := mvccs.save(req.Hash, p)
+	if err != nil {
+		mlog.Error("store mvccdb commit", "err", err)
+		return nil, err
+	}
+	delete(mvccs.pending, string(req.Hash))
+	return req.Hash, nil
+}
+
+func (mvccs *Store) Rollback(req *types.ReqHash) ([]byte, error) {
+	_, ok := mvccs.pending[string(req.Hash)]
+	if !ok {
+		mlog.Error("store mvccdb rollback", "err", types.ErrHashNotFound)
+		return nil, types.ErrHashNotFound
+	}
+	delete(mvccs.pending, string(req.Hash))
+	return req.Hash, nil
+}
+
+//IterateRangeByStateHash 遍历[start, end)之间的key在statehash版本的值
+func (mvccs *Store) IterateRangeByStateHash(statehash []byte, start []byte, end []byte, ascending bool, fn func(key, value []byte) bool) {
+	version, err := mvccs.getVersion(statehash)
+	if err != nil {
+		mlog.Error("store mvccdb IterateRangeByStateHash", "err", err, "StateHash", common.ToHex(statehash))
+		return
+	}
+	prefix := dataPrefix()
+	//end为空时遍历到所有数据的结尾
+	itend := append(append([]byte{}, prefix[:len(prefix)-1]...), prefix[len(prefix)-1]+1)
+	if end != nil {
+		itend = append(append([]byte{}, prefix...), end...)
+	}
+	it := mvccs.GetDB().Iterator(append(append([]byte{}, prefix...), start...), itend, !ascending)
+	defer it.Close()
+	var curKey, curValue []byte
+	curVersion := int64(-1)
+	flush := func() bool {
+		if curVersion < 0 || len(curValue) == 0 {
+			return false
+		}
+		return fn(curKey, curValue)
+	}
+	for it.Rewind(); it.Valid(); it.Next() {
+		if it.Error() != nil {
+			mlog.Error("store mvccdb IterateRangeByStateHash", "err", it.Error())
+			return
+		}
+		key, v, ok := splitDataKey(it.Key()[len(prefix):])
+		if !ok {
+			continue
+		}
+		if !bytes.Equal(key, curKey) {
+			if flush() {
+				return
+			}
+			curKey, curValue, curVersion = append([]byte{}, key...), nil, -1
+		}
+		if v <= version && v > curVersion {
+			curValue, curVersion = it.ValueCopy(), v
+		}
+	}
+	flush()
+}
+
+//ProcEvent 状态证明和状态节点的事件都不支持，见Store的说明
+func (mvccs *Store) ProcEvent(msg queue.Message) {
+	msg.ReplyErr("Store", types.ErrActionNotSupport)
+}
+
+func (mvccs *Store) Del(req *types.StoreDel) ([]byte, error) {
+	version, err := mvccs.getVersion(req.StateHash)
+	if err != nil {
+		return nil, err
+	}
+	err = mvccs.delVersionFrom(version)
+	if err != nil {
+		return nil, err
+	}
+	return req.StateHash, nil
+}
+
+//getV 获取key在version以及之前最新版本的值
+//MVCCHelper.GetV依赖Seek指向第一个大于等于key的值，memdb的Seek只能精确匹配，这里反向遍历
+func (mvccs *Store) getV(key []byte, version int64) ([]byte, error) {
+	end, err := dbm.GetKey(key, version)
+	if err != nil {
+		return nil, err
+	}
+	//leveldb不包含end，memdb包含end，加上0之后两者都包含key.version
+	end = append(end, 0)
+	prefix := dataPrefix()
+	it := mvccs.GetDB().Iterator(dbm.GetKeyPerfix(key), end, true)
+	defer it.Close()
+	for it.Rewind(); it.Valid(); it.Next() {
+		if it.Error() != nil {
+			return nil, it.Error()
+		}
+		//key.version的前缀也可能是其他key，比如k.0.version
+		k, _, ok := splitDataKey(it.Key()[len(prefix):])
+		if ok && bytes.Equal(k, key) {
+			return it.ValueCopy(), nil
+		}
+	}
+	return nil, types.ErrNotFound
+}
+
+func (mvccs *Store) getVersion(hash []byte) (int64, error) {
+	if p, ok := mvccs.pending[string(hash)]; ok {
+		return p.version, nil
+	}
+	version, err := mvccs.mvcc.GetVersion(hash)
+	if err == types.ErrNotFound {
+		return 0, types.ErrStateNotExist
+	}
+	return version, err
+}
+
+func (mvccs *Store) save(hash []byte, p *pendingSet) error {
+	oldHash, err := mvccs.mvcc.GetVersionHash(p.version)
+	if err == nil {
+		if bytes.Equal(oldHash, hash) {
+			return nil
+		}
+		//分叉：这个版本已经保存了其他区块的状态，删除这个版本以及之后的版本
+		err = mvccs.delVersionFrom(p.version)
+		if err != nil {
+			return err
+		}
+	} else if err != types.ErrNotFound {
+		return err
+	}
+	kvlist, err := mvccs.mvcc.AddMVCC(p.kvs, hash, p.prevHash, p.version)
+	if err != nil {
+		return err
+	}
+	batch := mvccs.GetDB().NewBatch(p.sync)
+	for _, kv := range kvlist {
+		value := kv.Value
+		//被删除的key也要写入这个版本，否则会读到以前版本的值
+		if value == nil {
+			value = []byte{}
+		}
+		batch.Set(kv.Key, value)
+	}
+	err = batch.Write()
+	if err != nil {
+		mlog.Error("store mvccdb save", "err", err)
+		return types.ErrDataBaseDamage
+	}
+	return nil
+}
+
+//delVersionFrom 从最新的版本开始，删除version以及之后的版本
+func (mvccs *Store) delVersionFrom(version int64) error {
+	maxVersion, err := mvccs.mvcc.GetMaxVersion()
+	if err != nil {
+		return err
+	}
+	for v := maxVersion; v >= version; v-- {
+		hash, err := mvccs.mvcc.GetVersionHash(v)
+		if err != nil {
+			return err
+		}
+		kvlist, err := mvccs.mvcc.DelMVCC(hash, v, true)
+		if err != nil {
+			return err
+		}
+		batch := mvccs.GetDB().NewBatch(true)
+		for _, kv := range kvlist {
+			batch.Delete(kv.Key)
+		}
+		err = batch.Write()
+		if err != nil {
+			mlog.Error("store mvccdb delete version", "version", v, "err", err)
+			return types.ErrDataBaseDamage
+		}
+	}
+	return nil
+}
+
+func calcStateHash(prevHash []byte, version int64, kvs []*types.KeyValue) []byte {
+	set := &types.StoreSet{StateHash: prevHash, KV: kvs, Height: version}
+	return common.Sha256(types.Encode(set))
+}
+
+func isEmptyRoot(hash []byte) bool {
+	return len(hash) == 0 || bytes.Equal(hash, drivers.EmptyRoot[:])
+}
+
+func dataPrefix() []byte {
+	prefix := dbm.GetKeyPerfix(nil)
+	return prefix[:len(prefix)-1]
+}
+
+//splitDataKey 把key.version拆分成key和version
+func splitDataKey(data []byte) ([]byte, int64, bool) {
+	if len(data) < versionLen+1 || data[len(data)-versionLen-1] != '.' {
+		return nil, 0, false
+	}
+	var version int64
+	for _, c := range data[len(data)-versionLen:] {
+		if c < '0' || c > '9' {
+			return nil, 0, false
+		}
+		version = version*10 + int64(c-'0')
+	}
+	return data[:len(data)-versionLen-1], version, true
+}
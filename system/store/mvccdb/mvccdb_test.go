@@ -0,0 +1,223 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mvccdb
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/33cn/chain33/common"
+	drivers "github.com/33cn/chain33/system/store"
+	"github.com/33cn/chain33/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newStoreCfg(dir string) *types.Store {
+	return &types.Store{Name: "mvccdb_test", Driver: "leveldb", DbPath: dir, DbCache: 100}
+}
+
+func newStore(t *testing.T) (*Store, string) {
+	dir, err := ioutil.TempDir("", "example")
+	assert.Nil(t, err)
+	store := New(newStoreCfg(dir), nil).(*Store)
+	assert.NotNil(t, store)
+	return store, dir
+}
+
+func kvlist(prefix string, n int, value string) (kv []*types.KeyValue) {
+	for i := 0; i < n; i++ {
+		kv = append(kv, &types.KeyValue{Key: []byte(fmt.Sprintf("%s%d", prefix, i)), Value: []byte(fmt.Sprintf("%s%d", value, i))})
+	}
+	return kv
+}
+
+func TestMvccdbSetGet(t *testing.T) {
+	store, dir := newStore(t)
+	defer os.RemoveAll(dir)
+	defer store.Close()
+
+	kv := kvlist("k", 3, "v")
+	hash1, err := store.Set(&types.StoreSet{StateHash: drivers.EmptyRoot[:], KV: kv}, true)
+	assert.Nil(t, err)
+	keys := [][]byte{kv[0].Key, kv[1].Key, kv[2].Key, []byte("notexist")}
+	values, err := store.GetState(&types.StoreGet{StateHash: hash1, Keys: keys})
+	assert.Nil(t, err)
+	assert.Equal(t, [][]byte{kv[0].Value, kv[1].Value, kv[2].Value, nil}, values)
+
+	//修改一个key，删除一个key
+	hash2, err := store.Set(&types.StoreSet{StateHash: hash1, KV: []*types.KeyValue{
+		{Key: kv[0].Key, Value: []byte("new")},
+		{Key: kv[1].Key, Value: nil},
+	}, Height: 1}, true)
+	assert.Nil(t, err)
+	assert.NotEqual(t, hash1, hash2)
+	values = store.Get(&types.StoreGet{StateHash: hash2, Keys: keys})
+	assert.Equal(t, [][]byte{[]byte("new"), nil, kv[2].Value, nil}, values)
+	//历史版本不变
+	values = store.Get(&types.StoreGet{StateHash: hash1, Keys: keys})
+	assert.Equal(t, [][]byte{kv[0].Value, kv[1].Value, kv[2].Value, nil}, values)
+
+	_, err = store.GetState(&types.StoreGet{StateHash: common.Sha256([]byte("notexist")), Keys: keys})
+	assert.Equal(t, types.ErrStateNotExist, err)
+	_, err = store.Set(&types.StoreSet{StateHash: common.Sha256([]byte("notexist")), KV: kv}, true)
+	assert.Equal(t, types.ErrStateNotExist, err)
+}
+
+func TestMvccdbStateHash(t *testing.T) {
+	store, dir := newStore(t)
+	defer os.RemoveAll(dir)
+	defer store.Close()
+
+	//状态hash和kv的顺序无关，重复的key以最后一个为准
+	kv := kvlist("k", 3, "v")
+	hash1, err := store.MemSet(&types.StoreSet{StateHash: drivers.EmptyRoot[:], KV: kv}, true)
+	assert.Nil(t, err)
+	reorder := []*types.KeyValue{{Key: kv[2].Key, Value: []byte("old")}, kv[2], kv[1], kv[0]}
+	hash2, err := store.MemSet(&types.StoreSet{StateHash: drivers.EmptyRoot[:], KV: reorder}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, hash1, hash2)
+
+	//没有修改的区块状态hash也会变化
+	hash3, err := store.MemSet(&types.StoreSet{StateHash: hash1}, true)
+	assert.Nil(t, err)
+	assert.NotEqual(t, hash1, hash3)
+	//未提交的状态可以读取，也可以继续MemSet
+	values := store.Get(&types.StoreGet{StateHash: hash3, Keys: [][]byte{kv[0].Key}})
+	assert.Equal(t, kv[0].Value, values[0])
+
+	_, err = store.Commit(&types.ReqHash{Hash: hash1})
+	assert.Nil(t, err)
+	_, err = store.Commit(&types.ReqHash{Hash: hash3})
+	assert.Nil(t, err)
+	_, err = store.Commit(&types.ReqHash{Hash: hash3})
+	assert.Equal(t, types.ErrHashNotFound, err)
+
+	hash4, err := store.MemSet(&types.StoreSet{StateHash: hash3, KV: kvlist("k", 1, "x")}, true)
+	assert.Nil(t, err)
+	_, err = store.Rollback(&types.ReqHash{Hash: hash4})
+	assert.Nil(t, err)
+	_, err = store.Rollback(&types.ReqHash{Hash: hash4})
+	assert.Equal(t, types.ErrHashNotFound, err)
+	_, err = store.GetState(&types.StoreGet{StateHash: hash4, Keys: [][]byte{kv[0].Key}})
+	assert.Equal(t, types.ErrStateNotExist, err)
+}
+
+func TestMvccdbFork(t *testing.T) {
+	store, dir := newStore(t)
+	defer os.RemoveAll(dir)
+	defer store.Close()
+
+	hash0, err := store.Set(&types.StoreSet{StateHash: drivers.EmptyRoot[:], KV: kvlist("k", 2, "v")}, true)
+	assert.Nil(t, err)
+	hash1, err := store.Set(&types.StoreSet{StateHash: hash0, KV: kvlist("a", 2, "a")}, true)
+	assert.Nil(t, err)
+	hash2, err := store.Set(&types.StoreSet{StateHash: hash1, KV: kvlist("k", 1, "x")}, true)
+	assert.Nil(t, err)
+
+	//在hash0上分叉，旧的版本1和版本2被删除
+	fork1, err := store.Set(&types.StoreSet{StateHash: hash0, KV: kvlist("b", 1, "b")}, true)
+	assert.Nil(t, err)
+	_, err = store.GetState(&types.StoreGet{StateHash: hash1, Keys: [][]byte{[]byte("a0")}})
+	assert.Equal(t, types.ErrStateNotExist, err)
+	_, err = store.GetState(&types.StoreGet{StateHash: hash2, Keys: [][]byte{[]byte("a0")}})
+	assert.Equal(t, types.ErrStateNotExist, err)
+
+	values, err := store.GetState(&types.StoreGet{StateHash: fork1, Keys: [][]byte{[]byte("a0"), []byte("b0"), []byte("k0")}})
+	assert.Nil(t, err)
+	assert.Equal(t, [][]byte{nil, []byte("b0"), []byte("v0")}, values)
+	version, err := store.mvcc.GetMaxVersion()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), version)
+
+	//重复提交相同的状态
+	same, err := store.Set(&types.StoreSet{StateHash: hash0, KV: kvlist("b", 1, "b")}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, fork1, same)
+
+	_, err = store.Del(&types.StoreDel{StateHash: fork1})
+	assert.Nil(t, err)
+	_, err = store.GetState(&types.StoreGet{StateHash: fork1, Keys: [][]byte{[]byte("b0")}})
+	assert.Equal(t, types.ErrStateNotExist, err)
+	values, err = store.GetState(&types.StoreGet{StateHash: hash0, Keys: [][]byte{[]byte("k0")}})
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("v0"), values[0])
+}
+
+func TestMvccdbIterateRange(t *testing.T) {
+	store, dir := newStore(t)
+	defer os.RemoveAll(dir)
+	defer store.Close()
+
+	hash0, err := store.Set(&types.StoreSet{StateHash: drivers.EmptyRoot[:], KV: kvlist("k", 5, "v")}, true)
+	assert.Nil(t, err)
+	hash1, err := store.Set(&types.StoreSet{StateHash: hash0, KV: []*types.KeyValue{
+		{Key: []byte("k1"), Value: []byte("x1")},
+		{Key: []byte("k2"), Value: nil},
+		{Key: []byte("z0"), Value: []byte("z0")},
+	}}, true)
+	assert.Nil(t, err)
+
+	collect := func(hash, start, end []byte, ascending bool, limit int) (keys []string, values []string) {
+		store.IterateRangeByStateHash(hash, start, end, ascending, func(key, value []byte) bool {
+			keys = append(keys, string(key))
+			values = append(values, string(value))
+			return len(keys) >= limit
+		})
+		return
+	}
+	keys, values := collect(hash0, []byte("k"), nil, true, 100)
+	assert.Equal(t, []string{"k0", "k1", "k2", "k3", "k4"}, keys)
+	assert.Equal(t, []string{"v0", "v1", "v2", "v3", "v4"}, values)
+
+	keys, values = collect(hash1, []byte("k"), nil, true, 100)
+	assert.Equal(t, []string{"k0", "k1", "k3", "k4", "z0"}, keys)
+	assert.Equal(t, []string{"v0", "x1", "v3", "v4", "z0"}, values)
+
+	keys, _ = collect(hash1, []byte("k1"), []byte("k4"), true, 100)
+	assert.Equal(t, []string{"k1", "k3"}, keys)
+
+	keys, values = collect(hash1, []byte("k"), []byte("l"), false, 2)
+	assert.Equal(t, []string{"k4", "k3"}, keys)
+	assert.Equal(t, []string{"v4", "v3"}, values)
+
+	keys, _ = collect(common.Sha256([]byte("notexist")), []byte("k"), nil, true, 100)
+	assert.Nil(t, keys)
+}
+
+func TestMvccdbGetVersion(t *testing.T) {
+	for _, driver := range []string{"leveldb", "memdb"} {
+		dir, err := ioutil.TempDir("", "example")
+		assert.Nil(t, err)
+		store := New(&types.Store{Name: "mvccdb_test", Driver: driver, DbPath: dir, DbCache: 100}, nil).(*Store)
+		//k.0的版本key和k的版本key有相同的前缀
+		hash0, err := store.Set(&types.StoreSet{StateHash: drivers.EmptyRoot[:], KV: []*types.KeyValue{
+			{Key: []byte("k.0"), Value: []byte("x0")},
+		}}, true)
+		assert.Nil(t, err)
+		hash1, err := store.Set(&types.StoreSet{StateHash: hash0, KV: []*types.KeyValue{
+			{Key: []byte("k"), Value: []byte("v1")},
+		}}, true)
+		assert.Nil(t, err)
+		hash2, err := store.Set(&types.StoreSet{StateHash: hash1, KV: []*types.KeyValue{
+			{Key: []byte("k.0"), Value: []byte("x2")},
+		}}, true)
+		assert.Nil(t, err)
+		keys := [][]byte{[]byte("k"), []byte("k.0")}
+		values, err := store.GetState(&types.StoreGet{StateHash: hash0, Keys: keys})
+		assert.Nil(t, err, driver)
+		assert.Equal(t, [][]byte{nil, []byte("x0")}, values, driver)
+		values, err = store.GetState(&types.StoreGet{StateHash: hash1, Keys: keys})
+		assert.Nil(t, err, driver)
+		assert.Equal(t, [][]byte{[]byte("v1"), []byte("x0")}, values, driver)
+		//版本2没有修改k，读取版本1的值
+		values, err = store.GetState(&types.StoreGet{StateHash: hash2, Keys: keys})
+		assert.Nil(t, err, driver)
+		assert.Equal(t, [][]byte{[]byte("v1"), []byte("x2")}, values, driver)
+		store.Close()
+		os.RemoveAll(dir)
+	}
+}
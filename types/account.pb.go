@@ -344,6 +344,8 @@ type ReqBalance struct {
 	// 执行器名称
 	Execer    string `protobuf:"bytes,2,opt,name=execer" json:"execer,omitempty"`
 	StateHash string `protobuf:"bytes,3,opt,name=stateHash" json:"stateHash,omitempty"`
+	// 大于0时查询该高度的状态
+	Height int64 `protobuf:"varint,4,opt,name=height" json:"height,omitempty"`
 }
 
 func (m *ReqBalance) Reset()                    { *m = ReqBalance{} }
@@ -372,6 +374,13 @@ func (m *ReqBalance) GetStateHash() string {
 	return ""
 }
 
+func (m *ReqBalance) GetHeight() int64 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
 // Account 的列表
 type Accounts struct {
 	Acc []*Account `protobuf:"bytes,1,rep,name=acc" json:"acc,omitempty"`
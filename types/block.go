@@ -40,6 +40,27 @@ func (block *Block) GetHeader() *Header {
 	return head
 }
 
+//CalcHash 根据区块头计算区块hash，和Block.Hash的计算方式一致，ForkBlockHash之后hash中包含StateHash
+func (header *Header) CalcHash() []byte {
+	head := &Header{
+		Version:    header.Version,
+		ParentHash: header.ParentHash,
+		TxHash:     header.TxHash,
+		BlockTime:  header.BlockTime,
+		Height:     header.Height,
+	}
+	if IsFork(header.Height, "ForkBlockHash") {
+		head.Difficulty = header.Difficulty
+		head.StateHash = header.StateHash
+		head.TxCount = header.TxCount
+	}
+	data, err := proto.Marshal(head)
+	if err != nil {
+		panic(err)
+	}
+	return common.Sha256(data)
+}
+
 func (block *Block) CheckSign() bool {
 	//检查区块的签名
 	if block.Signature != nil {
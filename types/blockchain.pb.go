@@ -666,6 +666,9 @@ type ChainExecutor struct {
 	Param     []byte `protobuf:"bytes,4,opt,name=param,proto3" json:"param,omitempty"`
 	// 扩展字段，用于额外的用途
 	Extra []byte `protobuf:"bytes,5,opt,name=extra,proto3" json:"extra,omitempty"`
+	// 大于0并且没有指定stateHash时，查询该高度的状态
+	// localdb只保存最新的数据，这时读取localdb的查询返回ErrHistoryLocalDB
+	Height int64 `protobuf:"varint,6,opt,name=height" json:"height,omitempty"`
 }
 
 func (m *ChainExecutor) Reset()                    { *m = ChainExecutor{} }
@@ -708,6 +711,13 @@ func (m *ChainExecutor) GetExtra() []byte {
 	return nil
 }
 
+func (m *ChainExecutor) GetHeight() int64 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
 //  通过block hash记录block的操作类型及add/del：1/2
 type BlockSequence struct {
 	Hash []byte `protobuf:"bytes,1,opt,name=Hash,proto3" json:"Hash,omitempty"`
@@ -777,6 +787,150 @@ func (m *ParaChainBlockDetail) GetSequence() int64 {
 	return 0
 }
 
+// 获取状态数据的proof，height大于0时获取该高度的状态，否则获取最新的状态
+type ReqStateProof struct {
+	Key    []byte `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Height int64  `protobuf:"varint,2,opt,name=height" json:"height,omitempty"`
+}
+
+func (m *ReqStateProof) Reset()         { *m = ReqStateProof{} }
+func (m *ReqStateProof) String() string { return proto.CompactTextString(m) }
+func (*ReqStateProof) ProtoMessage()    {}
+
+func (m *ReqStateProof) GetKey() []byte {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
+func (m *ReqStateProof) GetHeight() int64 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
+// 轻节点可以用types.VerifyStateProof验证value在header.stateHash中
+type ReplyStateProof struct {
+	Value  []byte     `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	Proof  *MAVLProof `protobuf:"bytes,2,opt,name=proof" json:"proof,omitempty"`
+	Header *Header    `protobuf:"bytes,3,opt,name=header" json:"header,omitempty"`
+}
+
+func (m *ReplyStateProof) Reset()         { *m = ReplyStateProof{} }
+func (m *ReplyStateProof) String() string { return proto.CompactTextString(m) }
+func (*ReplyStateProof) ProtoMessage()    {}
+
+func (m *ReplyStateProof) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (m *ReplyStateProof) GetProof() *MAVLProof {
+	if m != nil {
+		return m.Proof
+	}
+	return nil
+}
+
+func (m *ReplyStateProof) GetHeader() *Header {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+// 交易的merkle proof，可以用merkle.VerifyMerkleBranch验证tx在header.txHash中
+type ReplyTxProof struct {
+	Tx     *Transaction `protobuf:"bytes,1,opt,name=tx" json:"tx,omitempty"`
+	Index  int64        `protobuf:"varint,2,opt,name=index" json:"index,omitempty"`
+	Proofs [][]byte     `protobuf:"bytes,3,rep,name=proofs,proto3" json:"proofs,omitempty"`
+	Header *Header      `protobuf:"bytes,4,opt,name=header" json:"header,omitempty"`
+}
+
+func (m *ReplyTxProof) Reset()         { *m = ReplyTxProof{} }
+func (m *ReplyTxProof) String() string { return proto.CompactTextString(m) }
+func (*ReplyTxProof) ProtoMessage()    {}
+
+func (m *ReplyTxProof) GetTx() *Transaction {
+	if m != nil {
+		return m.Tx
+	}
+	return nil
+}
+
+func (m *ReplyTxProof) GetIndex() int64 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func (m *ReplyTxProof) GetProofs() [][]byte {
+	if m != nil {
+		return m.Proofs
+	}
+	return nil
+}
+
+func (m *ReplyTxProof) GetHeader() *Header {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+// 从fromSeq开始订阅区块序列
+type ReqBlockSeqStream struct {
+	FromSeq int64 `protobuf:"varint,1,opt,name=fromSeq" json:"fromSeq,omitempty"`
+}
+
+func (m *ReqBlockSeqStream) Reset()         { *m = ReqBlockSeqStream{} }
+func (m *ReqBlockSeqStream) String() string { return proto.CompactTextString(m) }
+func (*ReqBlockSeqStream) ProtoMessage()    {}
+
+func (m *ReqBlockSeqStream) GetFromSeq() int64 {
+	if m != nil {
+		return m.FromSeq
+	}
+	return 0
+}
+
+// 区块序列号对应的区块详情，seq.type为1表示添加区块，2表示回滚区块
+type BlockSeq struct {
+	Num    int64          `protobuf:"varint,1,opt,name=num" json:"num,omitempty"`
+	Seq    *BlockSequence `protobuf:"bytes,2,opt,name=seq" json:"seq,omitempty"`
+	Detail *BlockDetail   `protobuf:"bytes,3,opt,name=detail" json:"detail,omitempty"`
+}
+
+func (m *BlockSeq) Reset()         { *m = BlockSeq{} }
+func (m *BlockSeq) String() string { return proto.CompactTextString(m) }
+func (*BlockSeq) ProtoMessage()    {}
+
+func (m *BlockSeq) GetNum() int64 {
+	if m != nil {
+		return m.Num
+	}
+	return 0
+}
+
+func (m *BlockSeq) GetSeq() *BlockSequence {
+	if m != nil {
+		return m.Seq
+	}
+	return nil
+}
+
+func (m *BlockSeq) GetDetail() *BlockDetail {
+	if m != nil {
+		return m.Detail
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*Header)(nil), "types.Header")
 	proto.RegisterType((*Block)(nil), "types.Block")
@@ -803,6 +957,11 @@ func init() {
 	proto.RegisterType((*BlockSequence)(nil), "types.BlockSequence")
 	proto.RegisterType((*BlockSequences)(nil), "types.BlockSequences")
 	proto.RegisterType((*ParaChainBlockDetail)(nil), "types.ParaChainBlockDetail")
+	proto.RegisterType((*ReqStateProof)(nil), "types.ReqStateProof")
+	proto.RegisterType((*ReplyStateProof)(nil), "types.ReplyStateProof")
+	proto.RegisterType((*ReplyTxProof)(nil), "types.ReplyTxProof")
+	proto.RegisterType((*ReqBlockSeqStream)(nil), "types.ReqBlockSeqStream")
+	proto.RegisterType((*BlockSeq)(nil), "types.BlockSeq")
 }
 
 func init() { proto.RegisterFile("blockchain.proto", fileDescriptor1) }
@@ -99,6 +99,19 @@ type BlockChain struct {
 	IsRecordBlockSequence bool   `protobuf:"varint,11,opt,name=isRecordBlockSequence" json:"isRecordBlockSequence,omitempty"`
 	IsParaChain           bool   `protobuf:"varint,12,opt,name=isParaChain" json:"isParaChain,omitempty"`
 	EnableTxQuickIndex    bool   `protobuf:"varint,13,opt,name=enableTxQuickIndex" json:"enableTxQuickIndex,omitempty"`
+	//快速同步的检查点高度和区块hash，新节点只下载检查点之前的区块头、交易索引和检查点的状态，只执行检查点之后的区块
+	FastSyncCheckpointHeight int64  `protobuf:"varint,14,opt,name=fastSyncCheckpointHeight" json:"fastSyncCheckpointHeight,omitempty"`
+	FastSyncCheckpointHash   string `protobuf:"bytes,15,opt,name=fastSyncCheckpointHash" json:"fastSyncCheckpointHash,omitempty"`
+	//最大的回滚区块数，为0时不限制
+	MaxReorgDepth int64 `protobuf:"varint,16,opt,name=maxReorgDepth" json:"maxReorgDepth,omitempty"`
+	//最终确认的检查点，格式为height:hash，检查点及之前的区块不会被回滚
+	FinalizedCheckpoints []string `protobuf:"bytes,17,rep,name=finalizedCheckpoints" json:"finalizedCheckpoints,omitempty"`
+	//数据库中保留区块体和交易结果的最近区块数，更早的区块体和交易结果移到归档数据库，为0时不归档
+	ArchiveBlockNum int64 `protobuf:"varint,18,opt,name=archiveBlockNum" json:"archiveBlockNum,omitempty"`
+	//归档数据库的目录，为空时和dbPath相同
+	ArchiveDbPath string `protobuf:"bytes,19,opt,name=archiveDbPath" json:"archiveDbPath,omitempty"`
+	//rpc导出状态快照的目录，导出的文件名不能包含路径，为空时不能通过rpc导出
+	SnapshotDir string `protobuf:"bytes,20,opt,name=snapshotDir" json:"snapshotDir,omitempty"`
 }
 
 type P2P struct {
@@ -118,6 +131,14 @@ type P2P struct {
 	InnerSeedEnable bool     `protobuf:"varint,14,opt,name=innerSeedEnable" json:"innerSeedEnable,omitempty"`
 	InnerBounds     int32    `protobuf:"varint,15,opt,name=innerBounds" json:"innerBounds,omitempty"`
 	UseGithub       bool     `protobuf:"varint,16,opt,name=useGithub" json:"useGithub,omitempty"`
+	// 节点的不良行为累计的分数达到banScore之后按ip禁止连接，为0时使用默认值
+	BanScore int64 `protobuf:"varint,17,opt,name=banScore" json:"banScore,omitempty"`
+	// 禁止连接的秒数，为0时使用默认值
+	BanSeconds int64 `protobuf:"varint,18,opt,name=banSeconds" json:"banSeconds,omitempty"`
+	// 分数衰减一半需要的秒数，为0时使用默认值
+	ScoreHalfLife int64 `protobuf:"varint,19,opt,name=scoreHalfLife" json:"scoreHalfLife,omitempty"`
+	// 每个节点每秒最多广播的新交易数，超过时按不良行为扣分，为0时使用默认值
+	MaxTxPerSecond int64 `protobuf:"varint,20,opt,name=maxTxPerSecond" json:"maxTxPerSecond,omitempty"`
 }
 
 type Rpc struct {
@@ -130,6 +151,21 @@ type Rpc struct {
 	JrpcFuncBlacklist []string `protobuf:"bytes,7,rep,name=jrpcFuncBlacklist" json:"jrpcFuncBlacklist,omitempty"`
 	GrpcFuncBlacklist []string `protobuf:"bytes,8,rep,name=grpcFuncBlacklist" json:"grpcFuncBlacklist,omitempty"`
 	MainnetJrpcAddr   string   `protobuf:"bytes,9,opt,name=mainnetJrpcAddr" json:"mainnetJrpcAddr,omitempty"`
+	// 开启后jsonrpc服务在/ws路径提供websocket订阅，推送新区块、回滚区块、新交易和执行日志
+	EnableWebsocket bool `protobuf:"varint,10,opt,name=enableWebsocket" json:"enableWebsocket,omitempty"`
+	// 每个ip每秒最多的jsonrpc请求数，批量请求中的每个请求单独计数，为0不限制，本机访问不限制
+	IpRateLimit int64 `protobuf:"varint,11,opt,name=ipRateLimit" json:"ipRateLimit,omitempty"`
+	// 每个ip访问单个jsonrpc方法每秒最多的请求数，格式为"方法名:每秒请求数"
+	MethodRateLimit []string `protobuf:"bytes,12,rep,name=methodRateLimit" json:"methodRateLimit,omitempty"`
+	// 配置了apiKeys或者jwtSecret之后，所有的rpc请求都要带上Authorization: Bearer <token>，本机访问也不例外
+	// api key的格式为"key:角色名"
+	ApiKeys []string `protobuf:"bytes,13,rep,name=apiKeys" json:"apiKeys,omitempty"`
+	// HS256签名的jwt的密钥，jwt的role字段是角色名，exp字段是过期时间
+	JwtSecret string `protobuf:"bytes,14,opt,name=jwtSecret" json:"jwtSecret,omitempty"`
+	// 角色可以访问的方法，格式为"角色名:方法1,方法2"，方法为*表示可以访问所有方法
+	AuthRoles []string `protobuf:"bytes,15,rep,name=authRoles" json:"authRoles,omitempty"`
+	// 允许发起websocket连接的浏览器来源，格式为"http://host:port"，*表示允许所有来源，不带Origin的非浏览器连接不受限制
+	WebsocketOrigins []string `protobuf:"bytes,16,rep,name=websocketOrigins" json:"websocketOrigins,omitempty"`
 }
 
 type Exec struct {
@@ -140,6 +176,10 @@ type Exec struct {
 	DisableAddrIndex bool     `protobuf:"varint,7,opt,name=disableAddrIndex" json:"disableAddrIndex,omitempty"`
 	Alias            []string `protobuf:"bytes,5,rep,name=alias" json:"alias,omitempty"`
 	SaveTokenTxList  bool     `protobuf:"varint,6,opt,name=saveTokenTxList" json:"saveTokenTxList,omitempty"`
+	// 开启后交易的nonce必须按账户连续递增，链上所有节点的配置必须一致
+	EnableSequentialNonce bool `protobuf:"varint,8,opt,name=enableSequentialNonce" json:"enableSequentialNonce,omitempty"`
+	// 并行执行区块交易的goroutine数量，小于等于1时串行执行
+	ParallelExec int32 `protobuf:"varint,9,opt,name=parallelExec" json:"parallelExec,omitempty"`
 }
 
 type Pprof struct {
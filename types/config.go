@@ -44,6 +44,7 @@ const (
 
 func init() {
 	S("TestNet", false)
+	S("sequentialNonce", false)
 	SetMinFee(1e5)
 	for key, cfg := range chaincfg.LoadAll() {
 		S("cfg."+key, cfg)
@@ -90,6 +91,16 @@ func GetP(height int64) *ChainParam {
 	return c
 }
 
+//GetGasLimit 返回高度为height的区块中每笔交易和整个区块最多可以消耗的gas，为0时不限制
+//ForkTxGas之前不计量gas
+func GetGasLimit(height int64) (maxTxGas, maxBlockGas int64) {
+	if !IsFork(height, "ForkTxGas") {
+		return 0, 0
+	}
+	conf := Conf("mver.exec")
+	return conf.MGInt("maxTxGas", height), conf.MGInt("maxBlockGas", height)
+}
+
 func GetFundAddr() string {
 	return MGStr("mver.consensus.fundKeyAddr", 0)
 }
@@ -234,6 +245,8 @@ func Init(t string, cfg *Config) {
 		}
 		setMinFee(cfg.Exec.MinExecFee)
 		setChainConfig("FixTime", cfg.FixTime)
+		setChainConfig("sequentialNonce", cfg.Exec.EnableSequentialNonce)
+		setChainConfig("addrIndex", !cfg.Exec.DisableAddrIndex)
 	}
 	//local 只用于单元测试
 	if isLocal() {
@@ -213,6 +213,9 @@ ForkResetTx0= 200000
 ForkWithdraw= 200000
 ForkExecRollback= 450000
 ForkCheckBlockTime=1200000
+ForkSequentialNonce= -1
+ForkTxGas= -1
+ForkMultiSig= -1
 ForkTxHeight= -1
 ForkTxGroupPara= -1
 ForkChainParamV2= -1
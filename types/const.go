@@ -56,6 +56,7 @@ var (
 //ty = 3 -> sm2
 //ty = 4 -> onetimeed25519
 //ty = 5 -> RingBaseonED25519
+//ty = 6 -> multisig
 //ty = 1+offset(1<<8) ->auth_ecdsa
 //ty = 2+offset(1<<8) -> auth_sm2
 const (
@@ -63,6 +64,7 @@ const (
 	SECP256K1 = 1
 	ED25519   = 2
 	SM2       = 3
+	MULTISIG  = 6
 )
 
 // 创建隐私交易的类型定义
@@ -112,6 +114,17 @@ const (
 	ExecOk   = 2
 )
 
+//gas 计量标准：执行器每次读写数据库消耗的gas
+const (
+	GasStateGet int64 = 200
+	GasStateSet int64 = 5000
+	GasLocalGet int64 = 100
+	//LocalDB List和PrefixCount 需要遍历数据库
+	GasLocalList int64 = 1000
+	//读写的key和value每个字节消耗的gas
+	GasPerByte int64 = 10
+)
+
 func init() {
 	S("TxHeight", false)
 }
@@ -444,6 +444,246 @@ func (m *StoreValuePool) GetValues() [][]byte {
 	return nil
 }
 
+// 获取key在stateHash对应状态中的mavl proof
+type StoreGetProof struct {
+	StateHash []byte `protobuf:"bytes,1,opt,name=stateHash,proto3" json:"stateHash,omitempty"`
+	Key       []byte `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+}
+
+func (m *StoreGetProof) Reset()         { *m = StoreGetProof{} }
+func (m *StoreGetProof) String() string { return proto.CompactTextString(m) }
+func (*StoreGetProof) ProtoMessage()    {}
+
+func (m *StoreGetProof) GetStateHash() []byte {
+	if m != nil {
+		return m.StateHash
+	}
+	return nil
+}
+
+func (m *StoreGetProof) GetKey() []byte {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
+type StoreReplyProof struct {
+	Value []byte     `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	Proof *MAVLProof `protobuf:"bytes,2,opt,name=proof" json:"proof,omitempty"`
+}
+
+func (m *StoreReplyProof) Reset()         { *m = StoreReplyProof{} }
+func (m *StoreReplyProof) String() string { return proto.CompactTextString(m) }
+func (*StoreReplyProof) ProtoMessage()    {}
+
+func (m *StoreReplyProof) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (m *StoreReplyProof) GetProof() *MAVLProof {
+	if m != nil {
+		return m.Proof
+	}
+	return nil
+}
+
+// mavl裁剪的进度，保存在数据库中，重启之后从lastKey继续裁剪
+type StorePruneStatus struct {
+	// 是否开启裁剪
+	Enable    bool   `protobuf:"varint,1,opt,name=enable" json:"enable,omitempty"`
+	// 是否正在裁剪
+	Pruning   bool   `protobuf:"varint,2,opt,name=pruning" json:"pruning,omitempty"`
+	// 当前或者上一次裁剪时的区块高度
+	Height    int64  `protobuf:"varint,3,opt,name=height" json:"height,omitempty"`
+	// 保留最近多少个高度的状态
+	Retain    int64  `protobuf:"varint,4,opt,name=retain" json:"retain,omitempty"`
+	// 已经处理到的叶子节点计数key
+	LastKey   []byte `protobuf:"bytes,5,opt,name=lastKey,proto3" json:"lastKey,omitempty"`
+	// 本次已经裁剪的叶子节点数
+	LeafCount int64  `protobuf:"varint,6,opt,name=leafCount" json:"leafCount,omitempty"`
+	// 本次已经裁剪的节点数
+	NodeCount int64  `protobuf:"varint,7,opt,name=nodeCount" json:"nodeCount,omitempty"`
+	// 本次裁剪是否已经完成
+	Finished  bool   `protobuf:"varint,8,opt,name=finished" json:"finished,omitempty"`
+}
+
+func (m *StorePruneStatus) Reset()         { *m = StorePruneStatus{} }
+func (m *StorePruneStatus) String() string { return proto.CompactTextString(m) }
+func (*StorePruneStatus) ProtoMessage()    {}
+
+func (m *StorePruneStatus) GetEnable() bool {
+	if m != nil {
+		return m.Enable
+	}
+	return false
+}
+
+func (m *StorePruneStatus) GetPruning() bool {
+	if m != nil {
+		return m.Pruning
+	}
+	return false
+}
+
+func (m *StorePruneStatus) GetHeight() int64 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
+func (m *StorePruneStatus) GetRetain() int64 {
+	if m != nil {
+		return m.Retain
+	}
+	return 0
+}
+
+func (m *StorePruneStatus) GetLastKey() []byte {
+	if m != nil {
+		return m.LastKey
+	}
+	return nil
+}
+
+func (m *StorePruneStatus) GetLeafCount() int64 {
+	if m != nil {
+		return m.LeafCount
+	}
+	return 0
+}
+
+func (m *StorePruneStatus) GetNodeCount() int64 {
+	if m != nil {
+		return m.NodeCount
+	}
+	return 0
+}
+
+func (m *StorePruneStatus) GetFinished() bool {
+	if m != nil {
+		return m.Finished
+	}
+	return false
+}
+
+// 状态快照文件的头部信息
+type SnapshotHeader struct {
+	// 链的title
+	Title      string `protobuf:"bytes,1,opt,name=title" json:"title,omitempty"`
+	// 快照格式的版本
+	Version    int32  `protobuf:"varint,2,opt,name=version" json:"version,omitempty"`
+	// 快照对应的区块高度
+	Height     int64  `protobuf:"varint,3,opt,name=height" json:"height,omitempty"`
+	// 快照对应的区块hash
+	BlockHash  []byte `protobuf:"bytes,4,opt,name=blockHash,proto3" json:"blockHash,omitempty"`
+	// 快照对应的状态树根hash
+	StateHash  []byte `protobuf:"bytes,5,opt,name=stateHash,proto3" json:"stateHash,omitempty"`
+	// 创建时间
+	CreateTime int64  `protobuf:"varint,6,opt,name=createTime" json:"createTime,omitempty"`
+}
+
+func (m *SnapshotHeader) Reset()         { *m = SnapshotHeader{} }
+func (m *SnapshotHeader) String() string { return proto.CompactTextString(m) }
+func (*SnapshotHeader) ProtoMessage()    {}
+
+func (m *SnapshotHeader) GetTitle() string {
+	if m != nil {
+		return m.Title
+	}
+	return ""
+}
+
+func (m *SnapshotHeader) GetVersion() int32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *SnapshotHeader) GetHeight() int64 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
+func (m *SnapshotHeader) GetBlockHash() []byte {
+	if m != nil {
+		return m.BlockHash
+	}
+	return nil
+}
+
+func (m *SnapshotHeader) GetStateHash() []byte {
+	if m != nil {
+		return m.StateHash
+	}
+	return nil
+}
+
+func (m *SnapshotHeader) GetCreateTime() int64 {
+	if m != nil {
+		return m.CreateTime
+	}
+	return 0
+}
+
+// 快照数据块，data为gzip压缩之后的LocalDBSet，checksum为data的sha256
+type SnapshotChunk struct {
+	// 数据类型：state，blockchain，end
+	Section  string `protobuf:"bytes,1,opt,name=section" json:"section,omitempty"`
+	// 数据块的序号，从0开始
+	Index    int64  `protobuf:"varint,2,opt,name=index" json:"index,omitempty"`
+	// 数据块中kv的个数，end数据块中为kv的总数
+	Count    int64  `protobuf:"varint,3,opt,name=count" json:"count,omitempty"`
+	Data     []byte `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	Checksum []byte `protobuf:"bytes,5,opt,name=checksum,proto3" json:"checksum,omitempty"`
+}
+
+func (m *SnapshotChunk) Reset()         { *m = SnapshotChunk{} }
+func (m *SnapshotChunk) String() string { return proto.CompactTextString(m) }
+func (*SnapshotChunk) ProtoMessage()    {}
+
+func (m *SnapshotChunk) GetSection() string {
+	if m != nil {
+		return m.Section
+	}
+	return ""
+}
+
+func (m *SnapshotChunk) GetIndex() int64 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func (m *SnapshotChunk) GetCount() int64 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *SnapshotChunk) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+func (m *SnapshotChunk) GetChecksum() []byte {
+	if m != nil {
+		return m.Checksum
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*LeafNode)(nil), "types.LeafNode")
 	proto.RegisterType((*InnerNode)(nil), "types.InnerNode")
@@ -460,6 +700,11 @@ func init() {
 	proto.RegisterType((*StoreReplyValue)(nil), "types.StoreReplyValue")
 	proto.RegisterType((*PruneData)(nil), "types.PruneData")
 	proto.RegisterType((*StoreValuePool)(nil), "types.StoreValuePool")
+	proto.RegisterType((*StoreGetProof)(nil), "types.StoreGetProof")
+	proto.RegisterType((*StoreReplyProof)(nil), "types.StoreReplyProof")
+	proto.RegisterType((*StorePruneStatus)(nil), "types.StorePruneStatus")
+	proto.RegisterType((*SnapshotHeader)(nil), "types.SnapshotHeader")
+	proto.RegisterType((*SnapshotChunk)(nil), "types.SnapshotChunk")
 }
 
 func init() { proto.RegisterFile("db.proto", fileDescriptor3) }
@@ -63,11 +63,23 @@ var (
 	ErrManyTx                     = errors.New("ErrManyTx")
 	ErrDupTx                      = errors.New("ErrDupTx")
 	ErrMemFull                    = errors.New("ErrMemFull")
+	ErrTxFeeTooLowToReplace       = errors.New("ErrTxFeeTooLowToReplace")
+	ErrNonceTooLow                = errors.New("ErrNonceTooLow")
+	ErrNonceNotMatch              = errors.New("ErrNonceNotMatch")
+	ErrOutOfGas                   = errors.New("ErrOutOfGas")
+	ErrBlockOutOfGas              = errors.New("ErrBlockOutOfGas")
+	ErrTxGasLimitTooBig           = errors.New("ErrTxGasLimitTooBig")
+	ErrStateNotExist              = errors.New("ErrStateNotExist")
+	ErrHistoryLocalDB             = errors.New("ErrHistoryLocalDB")
+	ErrStateProofVerify           = errors.New("ErrStateProofVerify")
+	ErrStateNodeNotExist          = errors.New("ErrStateNodeNotExist")
+	ErrStateNodeHash              = errors.New("ErrStateNodeHash")
 	ErrNoBalance                  = errors.New("ErrNoBalance")
 	ErrBalanceLessThanTenTimesFee = errors.New("ErrBalanceLessThanTenTimesFee")
 	ErrTxExpire                   = errors.New("ErrTxExpire")
 	ErrHeaderNotSet               = errors.New("ErrHeaderNotSet")
 	ErrSign                       = errors.New("ErrSign")
+	ErrMultiSigNotEnable          = errors.New("ErrMultiSigNotEnable")
 	ErrFeeTooLow                  = errors.New("ErrFeeTooLow")
 	ErrEmptyTx                    = errors.New("ErrEmptyTx")
 	ErrTxFeeTooLow                = errors.New("ErrTxFeeTooLow")
@@ -85,6 +97,7 @@ var (
 	ErrAddrNotExist           = errors.New("ErrAddrNotExist")
 	ErrStartHeight            = errors.New("ErrStartHeight")
 	ErrEndLessThanStartHeight = errors.New("ErrEndLessThanStartHeight")
+	ErrRecordBlockSequence    = errors.New("ErrRecordBlockSequence")
 	ErrClientNotBindQueue     = errors.New("ErrClientNotBindQueue")
 	ErrContinueBack           = errors.New("ErrContinueBack")
 	ErrUnmarshal              = errors.New("ErrUnmarshal")
@@ -98,6 +111,18 @@ var (
 	ErrDecode                 = errors.New("ErrDecode")
 	ErrNotRollBack            = errors.New("ErrNotRollBack")
 	ErrPeerInfoIsNil          = errors.New("ErrPeerInfoIsNil")
+	ErrFastSyncHeaders        = errors.New("ErrFastSyncHeaders")
+	ErrFastSyncCheckpoint     = errors.New("ErrFastSyncCheckpoint")
+	ErrFastSyncing            = errors.New("ErrFastSyncing")
+	ErrSnapshotFormat         = errors.New("ErrSnapshotFormat")
+	ErrSnapshotChecksum       = errors.New("ErrSnapshotChecksum")
+	ErrSnapshotState          = errors.New("ErrSnapshotState")
+	ErrSnapshotExist          = errors.New("ErrSnapshotExist")
+	ErrSnapshotPath           = errors.New("ErrSnapshotPath")
+	ErrReorgTooDeep           = errors.New("ErrReorgTooDeep")
+	ErrReorgFinalized         = errors.New("ErrReorgFinalized")
+	ErrCheckpointMismatch     = errors.New("ErrCheckpointMismatch")
+	ErrFinalizedCheckpoint    = errors.New("ErrFinalizedCheckpoint")
 	//wallet
 	ErrWalletIsLocked       = errors.New("ErrWalletIsLocked")
 	ErrSaveSeedFirst        = errors.New("ErrSaveSeedFirst")
@@ -131,6 +156,14 @@ var (
 	ErrFromHex            = errors.New("ErrFromHex")
 	ErrPrivKeyFromBytes   = errors.New("ErrFromHex")
 	ErrParentHash         = errors.New("ErrParentHash")
+	ErrKeyStoreNotExist   = errors.New("ErrKeyStoreNotExist")
+	ErrKeyStoreVersion    = errors.New("ErrKeyStoreVersion")
+	ErrDecrypt            = errors.New("ErrDecrypt")
+	ErrAddrExist          = errors.New("ErrAddrExist")
+	ErrXpub               = errors.New("ErrXpub")
+	ErrWatchOnly          = errors.New("ErrWatchOnly")
+	ErrWalletRescanning   = errors.New("ErrWalletRescanning")
+	ErrRescanNotExist     = errors.New("ErrRescanNotExist")
 
 	//p2p
 	ErrPing       = errors.New("ErrPingSignature")
@@ -161,6 +194,13 @@ var (
 
 	//rpc
 	ErrInvalidMainnetRpcAddr = errors.New("ErrInvalidMainnetRpcAddr")
+	ErrSubscribeType         = errors.New("ErrSubscribeType")
+	ErrSubscriptionNotFound  = errors.New("ErrSubscriptionNotFound")
+	ErrWebsocketOrigin       = errors.New("ErrWebsocketOrigin")
+	ErrRateLimit             = errors.New("ErrRateLimit")
+	ErrAuthRequired          = errors.New("ErrAuthRequired")
+	ErrInvalidToken          = errors.New("ErrInvalidToken")
+	ErrTokenExpired          = errors.New("ErrTokenExpired")
 
 	ErrDBFlag      = errors.New("ErrDBFlag")
 	ErrLocalPrefix = errors.New("ErrLocalPrefix")
@@ -103,38 +103,60 @@ const (
 	EventIsSync              = 96
 	EventReplyIsSync         = 97
 
-	EventCloseTickets            = 98
-	EventGetAddrTxs              = 99
-	EventReplyAddrTxs            = 100
-	EventIsNtpClockSync          = 101
-	EventReplyIsNtpClockSync     = 102
-	EventDelTxList               = 103
-	EventStoreGetTotalCoins      = 104
-	EventGetTotalCoinsReply      = 105
-	EventQueryTotalFee           = 106
-	EventSignRawTx               = 107
-	EventReplySignRawTx          = 108
-	EventSyncBlock               = 109
-	EventGetNetInfo              = 110
-	EventReplyNetInfo            = 111
-	EventErrToFront              = 112
-	EventFatalFailure            = 113
-	EventReplyFatalFailure       = 114
-	EventBindMiner               = 115
-	EventReplyBindMiner          = 116
-	EventDecodeRawTx             = 117
-	EventReplyDecodeRawTx        = 118
-	EventGetLastBlockSequence    = 119
-	EventReplyLastBlockSequence  = 120
-	EventGetBlockSequences       = 121
-	EventReplyBlockSequences     = 122
-	EventGetBlockByHashes        = 123
-	EventReplyBlockDetailsBySeqs = 124
-	EventDelParaChainBlockDetail = 125
-	EventAddParaChainBlockDetail = 126
-	EventGetSeqByHash            = 127
-	EventLocalPrefixCount        = 128
-	EventWalletCreateTx          = 129
+	EventCloseTickets             = 98
+	EventGetAddrTxs               = 99
+	EventReplyAddrTxs             = 100
+	EventIsNtpClockSync           = 101
+	EventReplyIsNtpClockSync      = 102
+	EventDelTxList                = 103
+	EventStoreGetTotalCoins       = 104
+	EventGetTotalCoinsReply       = 105
+	EventQueryTotalFee            = 106
+	EventSignRawTx                = 107
+	EventReplySignRawTx           = 108
+	EventSyncBlock                = 109
+	EventGetNetInfo               = 110
+	EventReplyNetInfo             = 111
+	EventErrToFront               = 112
+	EventFatalFailure             = 113
+	EventReplyFatalFailure        = 114
+	EventBindMiner                = 115
+	EventReplyBindMiner           = 116
+	EventDecodeRawTx              = 117
+	EventReplyDecodeRawTx         = 118
+	EventGetLastBlockSequence     = 119
+	EventReplyLastBlockSequence   = 120
+	EventGetBlockSequences        = 121
+	EventReplyBlockSequences      = 122
+	EventGetBlockByHashes         = 123
+	EventReplyBlockDetailsBySeqs  = 124
+	EventDelParaChainBlockDetail  = 125
+	EventAddParaChainBlockDetail  = 126
+	EventGetSeqByHash             = 127
+	EventLocalPrefixCount         = 128
+	EventWalletCreateTx           = 129
+	EventSimulateTx               = 130
+	EventReplySimulateTx          = 131
+	EventStoreGetProof            = 132
+	EventStoreGetProofReply       = 133
+	EventStoreGetPruneStatus      = 134
+	EventStoreGetPruneStatusReply = 135
+	EventSubscribePush            = 136
+	EventFetchStateNodes          = 137
+	EventStoreGetStateNodes       = 138
+	EventStoreSaveStateNodes      = 139
+	EventStateNodes               = 140
+	EventExportSnapshot           = 141
+	EventReplyExportSnapshot      = 142
+	EventReportPeer               = 143
+	EventListPeerBans             = 144
+	EventReplyPeerBans            = 145
+	EventUnbanPeer                = 146
+	EventWalletImportWatch        = 147
+	EventWalletRescan             = 148
+	EventGetAccountNonce          = 149
+	EventStoreGetState            = 150
+	EventWalletExportXpub         = 151
 	//exec
 	EventBlockChainQuery = 212
 	EventConsensusQuery  = 213
@@ -265,6 +287,28 @@ var eventName = map[int]string{
 	126: "EventAddParaChainBlockDetail",
 	127: "EventGetSeqByHash",
 	128: "EventLocalPrefixCount",
+	130: "EventSimulateTx",
+	131: "EventReplySimulateTx",
+	132: "EventStoreGetProof",
+	133: "EventStoreGetProofReply",
+	134: "EventStoreGetPruneStatus",
+	135: "EventStoreGetPruneStatusReply",
+	136: "EventSubscribePush",
+	137: "EventFetchStateNodes",
+	138: "EventStoreGetStateNodes",
+	139: "EventStoreSaveStateNodes",
+	140: "EventStateNodes",
+	141: "EventExportSnapshot",
+	142: "EventReplyExportSnapshot",
+	143: "EventReportPeer",
+	144: "EventListPeerBans",
+	145: "EventReplyPeerBans",
+	146: "EventUnbanPeer",
+	147: "EventWalletImportWatch",
+	148: "EventWalletRescan",
+	149: "EventGetAccountNonce",
+	150: "EventStoreGetState",
+	151: "EventWalletExportXpub",
 	//todo: 这个可能后面会删除
 	EventWalletCreateTx: "EventWalletCreateTx",
 	// Token
@@ -191,6 +191,9 @@ func SetTestNetFork() {
 	systemFork.SetFork("chain33", "ForkTxHeight", 806578)
 	systemFork.SetFork("chain33", "ForkTxGroupPara", 806578)
 	systemFork.SetFork("chain33", "ForkCheckBlockTime", 1200000)
+	systemFork.SetFork("chain33", "ForkSequentialNonce", MaxHeight)
+	systemFork.SetFork("chain33", "ForkTxGas", MaxHeight)
+	systemFork.SetFork("chain33", "ForkMultiSig", MaxHeight)
 }
 
 func setLocalFork() {
@@ -225,6 +225,66 @@ func (_m *Chain33Client) ExecWallet(ctx context.Context, in *types.ChainExecutor
 	return r0, r1
 }
 
+// ExportSnapshot provides a mock function with given fields: ctx, in, opts
+func (_m *Chain33Client) ExportSnapshot(ctx context.Context, in *types.ReqString, opts ...grpc.CallOption) (*types.SnapshotHeader, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *types.SnapshotHeader
+	if rf, ok := ret.Get(0).(func(context.Context, *types.ReqString, ...grpc.CallOption) *types.SnapshotHeader); ok {
+		r0 = rf(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.SnapshotHeader)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *types.ReqString, ...grpc.CallOption) error); ok {
+		r1 = rf(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ExportXpub provides a mock function with given fields: ctx, in, opts
+func (_m *Chain33Client) ExportXpub(ctx context.Context, in *types.ReqNil, opts ...grpc.CallOption) (*types.ReplyString, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *types.ReplyString
+	if rf, ok := ret.Get(0).(func(context.Context, *types.ReqNil, ...grpc.CallOption) *types.ReplyString); ok {
+		r0 = rf(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.ReplyString)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *types.ReqNil, ...grpc.CallOption) error); ok {
+		r1 = rf(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GenSeed provides a mock function with given fields: ctx, in, opts
 func (_m *Chain33Client) GenSeed(ctx context.Context, in *types.GenSeedLang, opts ...grpc.CallOption) (*types.ReplySeed, error) {
 	_va := make([]interface{}, len(opts))
@@ -255,6 +315,36 @@ func (_m *Chain33Client) GenSeed(ctx context.Context, in *types.GenSeedLang, opt
 	return r0, r1
 }
 
+// GetAccountNonce provides a mock function with given fields: ctx, in, opts
+func (_m *Chain33Client) GetAccountNonce(ctx context.Context, in *types.ReqString, opts ...grpc.CallOption) (*types.Int64, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *types.Int64
+	if rf, ok := ret.Get(0).(func(context.Context, *types.ReqString, ...grpc.CallOption) *types.Int64); ok {
+		r0 = rf(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.Int64)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *types.ReqString, ...grpc.CallOption) error); ok {
+		r1 = rf(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetAccounts provides a mock function with given fields: ctx, in, opts
 func (_m *Chain33Client) GetAccounts(ctx context.Context, in *types.ReqNil, opts ...grpc.CallOption) (*types.WalletAccounts, error) {
 	_va := make([]interface{}, len(opts))
@@ -765,6 +855,66 @@ func (_m *Chain33Client) GetSeed(ctx context.Context, in *types.GetSeedByPw, opt
 	return r0, r1
 }
 
+// GetStateProof provides a mock function with given fields: ctx, in, opts
+func (_m *Chain33Client) GetStateProof(ctx context.Context, in *types.ReqStateProof, opts ...grpc.CallOption) (*types.ReplyStateProof, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *types.ReplyStateProof
+	if rf, ok := ret.Get(0).(func(context.Context, *types.ReqStateProof, ...grpc.CallOption) *types.ReplyStateProof); ok {
+		r0 = rf(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.ReplyStateProof)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *types.ReqStateProof, ...grpc.CallOption) error); ok {
+		r1 = rf(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetStorePruneStatus provides a mock function with given fields: ctx, in, opts
+func (_m *Chain33Client) GetStorePruneStatus(ctx context.Context, in *types.ReqNil, opts ...grpc.CallOption) (*types.StorePruneStatus, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *types.StorePruneStatus
+	if rf, ok := ret.Get(0).(func(context.Context, *types.ReqNil, ...grpc.CallOption) *types.StorePruneStatus); ok {
+		r0 = rf(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.StorePruneStatus)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *types.ReqNil, ...grpc.CallOption) error); ok {
+		r1 = rf(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetTransactionByAddr provides a mock function with given fields: ctx, in, opts
 func (_m *Chain33Client) GetTransactionByAddr(ctx context.Context, in *types.ReqAddr, opts ...grpc.CallOption) (*types.ReplyTxInfos, error) {
 	_va := make([]interface{}, len(opts))
@@ -825,6 +975,36 @@ func (_m *Chain33Client) GetTransactionByHashes(ctx context.Context, in *types.R
 	return r0, r1
 }
 
+// GetTxProof provides a mock function with given fields: ctx, in, opts
+func (_m *Chain33Client) GetTxProof(ctx context.Context, in *types.ReqHash, opts ...grpc.CallOption) (*types.ReplyTxProof, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *types.ReplyTxProof
+	if rf, ok := ret.Get(0).(func(context.Context, *types.ReqHash, ...grpc.CallOption) *types.ReplyTxProof); ok {
+		r0 = rf(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.ReplyTxProof)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *types.ReqHash, ...grpc.CallOption) error); ok {
+		r1 = rf(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetWalletStatus provides a mock function with given fields: ctx, in, opts
 func (_m *Chain33Client) GetWalletStatus(ctx context.Context, in *types.ReqNil, opts ...grpc.CallOption) (*types.WalletStatus, error) {
 	_va := make([]interface{}, len(opts))
@@ -885,6 +1065,36 @@ func (_m *Chain33Client) ImportPrivkey(ctx context.Context, in *types.ReqWalletI
 	return r0, r1
 }
 
+// ImportWatch provides a mock function with given fields: ctx, in, opts
+func (_m *Chain33Client) ImportWatch(ctx context.Context, in *types.ReqWalletImportWatch, opts ...grpc.CallOption) (*types.WalletAccounts, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *types.WalletAccounts
+	if rf, ok := ret.Get(0).(func(context.Context, *types.ReqWalletImportWatch, ...grpc.CallOption) *types.WalletAccounts); ok {
+		r0 = rf(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.WalletAccounts)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *types.ReqWalletImportWatch, ...grpc.CallOption) error); ok {
+		r1 = rf(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // IsNtpClockSync provides a mock function with given fields: ctx, in, opts
 func (_m *Chain33Client) IsNtpClockSync(ctx context.Context, in *types.ReqNil, opts ...grpc.CallOption) (*types.Reply, error) {
 	_va := make([]interface{}, len(opts))
@@ -945,6 +1155,36 @@ func (_m *Chain33Client) IsSync(ctx context.Context, in *types.ReqNil, opts ...g
 	return r0, r1
 }
 
+// ListPeerBans provides a mock function with given fields: ctx, in, opts
+func (_m *Chain33Client) ListPeerBans(ctx context.Context, in *types.ReqNil, opts ...grpc.CallOption) (*types.PeerBans, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *types.PeerBans
+	if rf, ok := ret.Get(0).(func(context.Context, *types.ReqNil, ...grpc.CallOption) *types.PeerBans); ok {
+		r0 = rf(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.PeerBans)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *types.ReqNil, ...grpc.CallOption) error); ok {
+		r1 = rf(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Lock provides a mock function with given fields: ctx, in, opts
 func (_m *Chain33Client) Lock(ctx context.Context, in *types.ReqNil, opts ...grpc.CallOption) (*types.Reply, error) {
 	_va := make([]interface{}, len(opts))
@@ -1155,6 +1395,36 @@ func (_m *Chain33Client) QueryTransaction(ctx context.Context, in *types.ReqHash
 	return r0, r1
 }
 
+// RescanWallet provides a mock function with given fields: ctx, in, opts
+func (_m *Chain33Client) RescanWallet(ctx context.Context, in *types.ReqWalletRescan, opts ...grpc.CallOption) (*types.WalletRescanStatus, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *types.WalletRescanStatus
+	if rf, ok := ret.Get(0).(func(context.Context, *types.ReqWalletRescan, ...grpc.CallOption) *types.WalletRescanStatus); ok {
+		r0 = rf(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.WalletRescanStatus)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *types.ReqWalletRescan, ...grpc.CallOption) error); ok {
+		r1 = rf(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // SaveSeed provides a mock function with given fields: ctx, in, opts
 func (_m *Chain33Client) SaveSeed(ctx context.Context, in *types.SaveSeedByPw, opts ...grpc.CallOption) (*types.Reply, error) {
 	_va := make([]interface{}, len(opts))
@@ -1395,6 +1665,66 @@ func (_m *Chain33Client) SignRawTx(ctx context.Context, in *types.ReqSignRawTx,
 	return r0, r1
 }
 
+// SimulateTransaction provides a mock function with given fields: ctx, in, opts
+func (_m *Chain33Client) SimulateTransaction(ctx context.Context, in *types.ReqSimulateTx, opts ...grpc.CallOption) (*types.ReplySimulateTx, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *types.ReplySimulateTx
+	if rf, ok := ret.Get(0).(func(context.Context, *types.ReqSimulateTx, ...grpc.CallOption) *types.ReplySimulateTx); ok {
+		r0 = rf(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.ReplySimulateTx)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *types.ReqSimulateTx, ...grpc.CallOption) error); ok {
+		r1 = rf(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// StreamBlockSequences provides a mock function with given fields: ctx, in, opts
+func (_m *Chain33Client) StreamBlockSequences(ctx context.Context, in *types.ReqBlockSeqStream, opts ...grpc.CallOption) (types.Chain33_StreamBlockSequencesClient, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 types.Chain33_StreamBlockSequencesClient
+	if rf, ok := ret.Get(0).(func(context.Context, *types.ReqBlockSeqStream, ...grpc.CallOption) types.Chain33_StreamBlockSequencesClient); ok {
+		r0 = rf(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(types.Chain33_StreamBlockSequencesClient)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *types.ReqBlockSeqStream, ...grpc.CallOption) error); ok {
+		r1 = rf(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // UnLock provides a mock function with given fields: ctx, in, opts
 func (_m *Chain33Client) UnLock(ctx context.Context, in *types.WalletUnLock, opts ...grpc.CallOption) (*types.Reply, error) {
 	_va := make([]interface{}, len(opts))
@@ -1425,6 +1755,36 @@ func (_m *Chain33Client) UnLock(ctx context.Context, in *types.WalletUnLock, opt
 	return r0, r1
 }
 
+// UnbanPeer provides a mock function with given fields: ctx, in, opts
+func (_m *Chain33Client) UnbanPeer(ctx context.Context, in *types.ReqString, opts ...grpc.CallOption) (*types.Reply, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, in)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *types.Reply
+	if rf, ok := ret.Get(0).(func(context.Context, *types.ReqString, ...grpc.CallOption) *types.Reply); ok {
+		r0 = rf(ctx, in, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.Reply)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *types.ReqString, ...grpc.CallOption) error); ok {
+		r1 = rf(ctx, in, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Version provides a mock function with given fields: ctx, in, opts
 func (_m *Chain33Client) Version(ctx context.Context, in *types.ReqNil, opts ...grpc.CallOption) (*types.Reply, error) {
 	_va := make([]interface{}, len(opts))
@@ -969,6 +969,8 @@ type Peer struct {
 	Self        bool    `protobuf:"varint,4,opt,name=self" json:"self,omitempty"`
 	MempoolSize int32   `protobuf:"varint,5,opt,name=mempoolSize" json:"mempoolSize,omitempty"`
 	Header      *Header `protobuf:"bytes,6,opt,name=header" json:"header,omitempty"`
+	// 不良行为的分数
+	Score int64 `protobuf:"varint,7,opt,name=score" json:"score,omitempty"`
 }
 
 func (m *Peer) Reset()                    { *m = Peer{} }
@@ -1018,6 +1020,13 @@ func (m *Peer) GetHeader() *Header {
 	return nil
 }
 
+func (m *Peer) GetScore() int64 {
+	if m != nil {
+		return m.Score
+	}
+	return 0
+}
+
 // *
 // peer 列表
 type PeerList struct {
@@ -1150,6 +1159,160 @@ func (m *PeersInfo) GetP2Pversion() int32 {
 	return 0
 }
 
+// 从peer获取状态树的节点，用于快速同步
+type P2PGetStateNodes struct {
+	Version int32    `protobuf:"varint,1,opt,name=version" json:"version,omitempty"`
+	Hashes  [][]byte `protobuf:"bytes,2,rep,name=hashes,proto3" json:"hashes,omitempty"`
+}
+
+func (m *P2PGetStateNodes) Reset()         { *m = P2PGetStateNodes{} }
+func (m *P2PGetStateNodes) String() string { return proto.CompactTextString(m) }
+func (*P2PGetStateNodes) ProtoMessage()    {}
+
+func (m *P2PGetStateNodes) GetVersion() int32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *P2PGetStateNodes) GetHashes() [][]byte {
+	if m != nil {
+		return m.Hashes
+	}
+	return nil
+}
+
+// blockchain请求p2p从指定的peer获取状态树的节点
+type ReqStateNodes struct {
+	Pid    string   `protobuf:"bytes,1,opt,name=pid" json:"pid,omitempty"`
+	Hashes [][]byte `protobuf:"bytes,2,rep,name=hashes,proto3" json:"hashes,omitempty"`
+}
+
+func (m *ReqStateNodes) Reset()         { *m = ReqStateNodes{} }
+func (m *ReqStateNodes) String() string { return proto.CompactTextString(m) }
+func (*ReqStateNodes) ProtoMessage()    {}
+
+func (m *ReqStateNodes) GetPid() string {
+	if m != nil {
+		return m.Pid
+	}
+	return ""
+}
+
+func (m *ReqStateNodes) GetHashes() [][]byte {
+	if m != nil {
+		return m.Hashes
+	}
+	return nil
+}
+
+// 状态树的节点，nodes和hashes一一对应，节点不存在时为空
+type StateNodes struct {
+	Hashes [][]byte `protobuf:"bytes,1,rep,name=hashes,proto3" json:"hashes,omitempty"`
+	Nodes  [][]byte `protobuf:"bytes,2,rep,name=nodes,proto3" json:"nodes,omitempty"`
+}
+
+func (m *StateNodes) Reset()         { *m = StateNodes{} }
+func (m *StateNodes) String() string { return proto.CompactTextString(m) }
+func (*StateNodes) ProtoMessage()    {}
+
+func (m *StateNodes) GetHashes() [][]byte {
+	if m != nil {
+		return m.Hashes
+	}
+	return nil
+}
+
+func (m *StateNodes) GetNodes() [][]byte {
+	if m != nil {
+		return m.Nodes
+	}
+	return nil
+}
+
+// 因为不良行为被禁止连接的节点
+type PeerBan struct {
+	// 节点的ip
+	Addr   string `protobuf:"bytes,1,opt,name=addr" json:"addr,omitempty"`
+	Reason string `protobuf:"bytes,2,opt,name=reason" json:"reason,omitempty"`
+	// 禁止时的分数
+	Score int64 `protobuf:"varint,3,opt,name=score" json:"score,omitempty"`
+	// 禁止到期的时间
+	Deadline int64 `protobuf:"varint,4,opt,name=deadline" json:"deadline,omitempty"`
+}
+
+func (m *PeerBan) Reset()         { *m = PeerBan{} }
+func (m *PeerBan) String() string { return proto.CompactTextString(m) }
+func (*PeerBan) ProtoMessage()    {}
+
+func (m *PeerBan) GetAddr() string {
+	if m != nil {
+		return m.Addr
+	}
+	return ""
+}
+
+func (m *PeerBan) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+func (m *PeerBan) GetScore() int64 {
+	if m != nil {
+		return m.Score
+	}
+	return 0
+}
+
+func (m *PeerBan) GetDeadline() int64 {
+	if m != nil {
+		return m.Deadline
+	}
+	return 0
+}
+
+type PeerBans struct {
+	Items []*PeerBan `protobuf:"bytes,1,rep,name=items" json:"items,omitempty"`
+}
+
+func (m *PeerBans) Reset()         { *m = PeerBans{} }
+func (m *PeerBans) String() string { return proto.CompactTextString(m) }
+func (*PeerBans) ProtoMessage()    {}
+
+func (m *PeerBans) GetItems() []*PeerBan {
+	if m != nil {
+		return m.Items
+	}
+	return nil
+}
+
+// blockchain报告发送了错误区块的节点
+type ReportPeer struct {
+	Pid    string `protobuf:"bytes,1,opt,name=pid" json:"pid,omitempty"`
+	Reason string `protobuf:"bytes,2,opt,name=reason" json:"reason,omitempty"`
+}
+
+func (m *ReportPeer) Reset()         { *m = ReportPeer{} }
+func (m *ReportPeer) String() string { return proto.CompactTextString(m) }
+func (*ReportPeer) ProtoMessage()    {}
+
+func (m *ReportPeer) GetPid() string {
+	if m != nil {
+		return m.Pid
+	}
+	return ""
+}
+
+func (m *ReportPeer) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*P2PGetPeerInfo)(nil), "types.P2PGetPeerInfo")
 	proto.RegisterType((*P2PPeerInfo)(nil), "types.P2PPeerInfo")
@@ -1179,6 +1342,12 @@ func init() {
 	proto.RegisterType((*NodeNetInfo)(nil), "types.NodeNetInfo")
 	proto.RegisterType((*PeersReply)(nil), "types.PeersReply")
 	proto.RegisterType((*PeersInfo)(nil), "types.PeersInfo")
+	proto.RegisterType((*P2PGetStateNodes)(nil), "types.P2PGetStateNodes")
+	proto.RegisterType((*ReqStateNodes)(nil), "types.ReqStateNodes")
+	proto.RegisterType((*StateNodes)(nil), "types.StateNodes")
+	proto.RegisterType((*PeerBan)(nil), "types.PeerBan")
+	proto.RegisterType((*PeerBans)(nil), "types.PeerBans")
+	proto.RegisterType((*ReportPeer)(nil), "types.ReportPeer")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -1215,6 +1384,8 @@ type P2PgserviceClient interface {
 	GetData(ctx context.Context, in *P2PGetData, opts ...grpc.CallOption) (P2Pgservice_GetDataClient, error)
 	// 获取头部
 	GetHeaders(ctx context.Context, in *P2PGetHeaders, opts ...grpc.CallOption) (*P2PHeaders, error)
+	// 获取状态树的节点
+	GetStateNodes(ctx context.Context, in *P2PGetStateNodes, opts ...grpc.CallOption) (*StateNodes, error)
 	// 获取 peerinfo
 	GetPeerInfo(ctx context.Context, in *P2PGetPeerInfo, opts ...grpc.CallOption) (*P2PPeerInfo, error)
 	// grpc server 读客户端发送来的数据
@@ -1365,6 +1536,15 @@ func (c *p2PgserviceClient) GetHeaders(ctx context.Context, in *P2PGetHeaders, o
 	return out, nil
 }
 
+func (c *p2PgserviceClient) GetStateNodes(ctx context.Context, in *P2PGetStateNodes, opts ...grpc.CallOption) (*StateNodes, error) {
+	out := new(StateNodes)
+	err := grpc.Invoke(ctx, "/types.p2pgservice/GetStateNodes", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *p2PgserviceClient) GetPeerInfo(ctx context.Context, in *P2PGetPeerInfo, opts ...grpc.CallOption) (*P2PPeerInfo, error) {
 	out := new(P2PPeerInfo)
 	err := grpc.Invoke(ctx, "/types.p2pgservice/GetPeerInfo", in, out, c.cc, opts...)
@@ -1484,6 +1664,8 @@ type P2PgserviceServer interface {
 	GetData(*P2PGetData, P2Pgservice_GetDataServer) error
 	// 获取头部
 	GetHeaders(context.Context, *P2PGetHeaders) (*P2PHeaders, error)
+	// 获取状态树的节点
+	GetStateNodes(context.Context, *P2PGetStateNodes) (*StateNodes, error)
 	// 获取 peerinfo
 	GetPeerInfo(context.Context, *P2PGetPeerInfo) (*P2PPeerInfo, error)
 	// grpc server 读客户端发送来的数据
@@ -1718,6 +1900,24 @@ func _P2Pgservice_GetHeaders_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _P2Pgservice_GetStateNodes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(P2PGetStateNodes)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(P2PgserviceServer).GetStateNodes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.p2pgservice/GetStateNodes",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(P2PgserviceServer).GetStateNodes(ctx, req.(*P2PGetStateNodes))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _P2Pgservice_GetPeerInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(P2PGetPeerInfo)
 	if err := dec(in); err != nil {
@@ -1867,6 +2067,10 @@ var _P2Pgservice_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetHeaders",
 			Handler:    _P2Pgservice_GetHeaders_Handler,
 		},
+		{
+			MethodName: "GetStateNodes",
+			Handler:    _P2Pgservice_GetStateNodes_Handler,
+		},
 		{
 			MethodName: "GetPeerInfo",
 			Handler:    _P2Pgservice_GetPeerInfo_Handler,
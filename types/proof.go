@@ -0,0 +1,38 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package types
+
+import (
+	"bytes"
+
+	"github.com/33cn/chain33/common"
+)
+
+//VerifyStateProof 验证key对应的value在区块头的状态中，只依赖types包，轻节点可以直接使用
+//header需要调用者自己确认是可信的，比如和已知的区块hash做比较
+func VerifyStateProof(header *Header, key, value []byte, proof *MAVLProof) error {
+	if header == nil || proof == nil || len(key) == 0 {
+		return ErrInvalidParam
+	}
+	if len(proof.RootHash) != 0 && !bytes.Equal(proof.RootHash, header.StateHash) {
+		return ErrStateProofVerify
+	}
+	//开启mavl前缀时节点的hash带有写入高度的前缀，只用proof中的信息不能验证
+	if len(proof.LeafHash) > len(common.Hash{}) {
+		return ErrNotSupport
+	}
+	leafnode := &LeafNode{Key: key, Value: value, Height: 0, Size: 1}
+	hash := leafnode.Hash()
+	if len(proof.LeafHash) != 0 && !bytes.Equal(hash, proof.LeafHash) {
+		return ErrStateProofVerify
+	}
+	for _, branch := range proof.InnerNodes {
+		hash = branch.ProofHash(hash)
+	}
+	if !bytes.Equal(hash, header.StateHash) {
+		return ErrStateProofVerify
+	}
+	return nil
+}
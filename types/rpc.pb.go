@@ -126,6 +126,30 @@ type Chain33Client interface {
 	// 签名交易
 	SignRawTx(ctx context.Context, in *ReqSignRawTx, opts ...grpc.CallOption) (*ReplySignRawTx, error)
 	CreateNoBalanceTransaction(ctx context.Context, in *NoBalanceTx, opts ...grpc.CallOption) (*ReplySignRawTx, error)
+	// 模拟执行交易，不会修改区块链的状态
+	SimulateTransaction(ctx context.Context, in *ReqSimulateTx, opts ...grpc.CallOption) (*ReplySimulateTx, error)
+	// 获取状态数据的mavl proof
+	GetStateProof(ctx context.Context, in *ReqStateProof, opts ...grpc.CallOption) (*ReplyStateProof, error)
+	// 获取交易的merkle proof和区块头
+	GetTxProof(ctx context.Context, in *ReqHash, opts ...grpc.CallOption) (*ReplyTxProof, error)
+	// 获取mavl裁剪的进度
+	GetStorePruneStatus(ctx context.Context, in *ReqNil, opts ...grpc.CallOption) (*StorePruneStatus, error)
+	// 从指定的序列号开始推送区块序列
+	StreamBlockSequences(ctx context.Context, in *ReqBlockSeqStream, opts ...grpc.CallOption) (Chain33_StreamBlockSequencesClient, error)
+	// 导出状态快照到节点本地的文件
+	ExportSnapshot(ctx context.Context, in *ReqString, opts ...grpc.CallOption) (*SnapshotHeader, error)
+	// 因为不良行为被禁止连接的节点
+	ListPeerBans(ctx context.Context, in *ReqNil, opts ...grpc.CallOption) (*PeerBans, error)
+	// 解除对节点ip的禁止
+	UnbanPeer(ctx context.Context, in *ReqString, opts ...grpc.CallOption) (*Reply, error)
+	// 导入只读的地址或者扩展公钥
+	ImportWatch(ctx context.Context, in *ReqWalletImportWatch, opts ...grpc.CallOption) (*WalletAccounts, error)
+	// 导出钱包的扩展公钥
+	ExportXpub(ctx context.Context, in *ReqNil, opts ...grpc.CallOption) (*ReplyString, error)
+	// 后台扫描历史区块恢复地址的交易记录
+	RescanWallet(ctx context.Context, in *ReqWalletRescan, opts ...grpc.CallOption) (*WalletRescanStatus, error)
+	// 获取账户下一笔交易的nonce
+	GetAccountNonce(ctx context.Context, in *ReqString, opts ...grpc.CallOption) (*Int64, error)
 }
 
 type chain33Client struct {
@@ -577,6 +601,137 @@ func (c *chain33Client) CreateNoBalanceTransaction(ctx context.Context, in *NoBa
 	return out, nil
 }
 
+func (c *chain33Client) SimulateTransaction(ctx context.Context, in *ReqSimulateTx, opts ...grpc.CallOption) (*ReplySimulateTx, error) {
+	out := new(ReplySimulateTx)
+	err := grpc.Invoke(ctx, "/types.chain33/SimulateTransaction", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chain33Client) GetStateProof(ctx context.Context, in *ReqStateProof, opts ...grpc.CallOption) (*ReplyStateProof, error) {
+	out := new(ReplyStateProof)
+	err := grpc.Invoke(ctx, "/types.chain33/GetStateProof", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chain33Client) GetTxProof(ctx context.Context, in *ReqHash, opts ...grpc.CallOption) (*ReplyTxProof, error) {
+	out := new(ReplyTxProof)
+	err := grpc.Invoke(ctx, "/types.chain33/GetTxProof", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chain33Client) GetStorePruneStatus(ctx context.Context, in *ReqNil, opts ...grpc.CallOption) (*StorePruneStatus, error) {
+	out := new(StorePruneStatus)
+	err := grpc.Invoke(ctx, "/types.chain33/GetStorePruneStatus", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chain33Client) StreamBlockSequences(ctx context.Context, in *ReqBlockSeqStream, opts ...grpc.CallOption) (Chain33_StreamBlockSequencesClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Chain33_serviceDesc.Streams[0], c.cc, "/types.chain33/StreamBlockSequences", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &chain33StreamBlockSequencesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Chain33_StreamBlockSequencesClient interface {
+	Recv() (*BlockSeq, error)
+	grpc.ClientStream
+}
+
+type chain33StreamBlockSequencesClient struct {
+	grpc.ClientStream
+}
+
+func (x *chain33StreamBlockSequencesClient) Recv() (*BlockSeq, error) {
+	m := new(BlockSeq)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *chain33Client) ExportSnapshot(ctx context.Context, in *ReqString, opts ...grpc.CallOption) (*SnapshotHeader, error) {
+	out := new(SnapshotHeader)
+	err := grpc.Invoke(ctx, "/types.chain33/ExportSnapshot", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chain33Client) ListPeerBans(ctx context.Context, in *ReqNil, opts ...grpc.CallOption) (*PeerBans, error) {
+	out := new(PeerBans)
+	err := grpc.Invoke(ctx, "/types.chain33/ListPeerBans", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chain33Client) UnbanPeer(ctx context.Context, in *ReqString, opts ...grpc.CallOption) (*Reply, error) {
+	out := new(Reply)
+	err := grpc.Invoke(ctx, "/types.chain33/UnbanPeer", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chain33Client) ImportWatch(ctx context.Context, in *ReqWalletImportWatch, opts ...grpc.CallOption) (*WalletAccounts, error) {
+	out := new(WalletAccounts)
+	err := grpc.Invoke(ctx, "/types.chain33/ImportWatch", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chain33Client) ExportXpub(ctx context.Context, in *ReqNil, opts ...grpc.CallOption) (*ReplyString, error) {
+	out := new(ReplyString)
+	err := grpc.Invoke(ctx, "/types.chain33/ExportXpub", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chain33Client) RescanWallet(ctx context.Context, in *ReqWalletRescan, opts ...grpc.CallOption) (*WalletRescanStatus, error) {
+	out := new(WalletRescanStatus)
+	err := grpc.Invoke(ctx, "/types.chain33/RescanWallet", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chain33Client) GetAccountNonce(ctx context.Context, in *ReqString, opts ...grpc.CallOption) (*Int64, error) {
+	out := new(Int64)
+	err := grpc.Invoke(ctx, "/types.chain33/GetAccountNonce", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for Chain33 service
 
 type Chain33Server interface {
@@ -674,6 +829,30 @@ type Chain33Server interface {
 	// 签名交易
 	SignRawTx(context.Context, *ReqSignRawTx) (*ReplySignRawTx, error)
 	CreateNoBalanceTransaction(context.Context, *NoBalanceTx) (*ReplySignRawTx, error)
+	// 模拟执行交易，不会修改区块链的状态
+	SimulateTransaction(context.Context, *ReqSimulateTx) (*ReplySimulateTx, error)
+	// 获取状态数据的mavl proof
+	GetStateProof(context.Context, *ReqStateProof) (*ReplyStateProof, error)
+	// 获取交易的merkle proof和区块头
+	GetTxProof(context.Context, *ReqHash) (*ReplyTxProof, error)
+	// 获取mavl裁剪的进度
+	GetStorePruneStatus(context.Context, *ReqNil) (*StorePruneStatus, error)
+	// 从指定的序列号开始推送区块序列
+	StreamBlockSequences(*ReqBlockSeqStream, Chain33_StreamBlockSequencesServer) error
+	// 导出状态快照到节点本地的文件
+	ExportSnapshot(context.Context, *ReqString) (*SnapshotHeader, error)
+	// 因为不良行为被禁止连接的节点
+	ListPeerBans(context.Context, *ReqNil) (*PeerBans, error)
+	// 解除对节点ip的禁止
+	UnbanPeer(context.Context, *ReqString) (*Reply, error)
+	// 导入只读的地址或者扩展公钥
+	ImportWatch(context.Context, *ReqWalletImportWatch) (*WalletAccounts, error)
+	// 导出钱包的扩展公钥
+	ExportXpub(context.Context, *ReqNil) (*ReplyString, error)
+	// 后台扫描历史区块恢复地址的交易记录
+	RescanWallet(context.Context, *ReqWalletRescan) (*WalletRescanStatus, error)
+	// 获取账户下一笔交易的nonce
+	GetAccountNonce(context.Context, *ReqString) (*Int64, error)
 }
 
 func RegisterChain33Server(s *grpc.Server, srv Chain33Server) {
@@ -1562,6 +1741,225 @@ func _Chain33_CreateNoBalanceTransaction_Handler(srv interface{}, ctx context.Co
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Chain33_SimulateTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReqSimulateTx)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Chain33Server).SimulateTransaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.chain33/SimulateTransaction",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(Chain33Server).SimulateTransaction(ctx, req.(*ReqSimulateTx))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chain33_GetStateProof_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReqStateProof)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Chain33Server).GetStateProof(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.chain33/GetStateProof",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(Chain33Server).GetStateProof(ctx, req.(*ReqStateProof))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chain33_GetTxProof_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReqHash)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Chain33Server).GetTxProof(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.chain33/GetTxProof",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(Chain33Server).GetTxProof(ctx, req.(*ReqHash))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chain33_GetStorePruneStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReqNil)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Chain33Server).GetStorePruneStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.chain33/GetStorePruneStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(Chain33Server).GetStorePruneStatus(ctx, req.(*ReqNil))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chain33_StreamBlockSequences_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ReqBlockSeqStream)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(Chain33Server).StreamBlockSequences(m, &chain33StreamBlockSequencesServer{stream})
+}
+
+type Chain33_StreamBlockSequencesServer interface {
+	Send(*BlockSeq) error
+	grpc.ServerStream
+}
+
+type chain33StreamBlockSequencesServer struct {
+	grpc.ServerStream
+}
+
+func (x *chain33StreamBlockSequencesServer) Send(m *BlockSeq) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Chain33_ExportSnapshot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReqString)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Chain33Server).ExportSnapshot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.chain33/ExportSnapshot",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(Chain33Server).ExportSnapshot(ctx, req.(*ReqString))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chain33_ListPeerBans_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReqNil)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Chain33Server).ListPeerBans(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.chain33/ListPeerBans",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(Chain33Server).ListPeerBans(ctx, req.(*ReqNil))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chain33_UnbanPeer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReqString)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Chain33Server).UnbanPeer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.chain33/UnbanPeer",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(Chain33Server).UnbanPeer(ctx, req.(*ReqString))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chain33_ImportWatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReqWalletImportWatch)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Chain33Server).ImportWatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.chain33/ImportWatch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(Chain33Server).ImportWatch(ctx, req.(*ReqWalletImportWatch))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chain33_ExportXpub_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReqNil)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Chain33Server).ExportXpub(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.chain33/ExportXpub",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(Chain33Server).ExportXpub(ctx, req.(*ReqNil))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chain33_RescanWallet_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReqWalletRescan)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Chain33Server).RescanWallet(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.chain33/RescanWallet",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(Chain33Server).RescanWallet(ctx, req.(*ReqWalletRescan))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chain33_GetAccountNonce_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReqString)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Chain33Server).GetAccountNonce(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/types.chain33/GetAccountNonce",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(Chain33Server).GetAccountNonce(ctx, req.(*ReqString))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Chain33_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "types.chain33",
 	HandlerType: (*Chain33Server)(nil),
@@ -1762,8 +2160,58 @@ var _Chain33_serviceDesc = grpc.ServiceDesc{
 			MethodName: "CreateNoBalanceTransaction",
 			Handler:    _Chain33_CreateNoBalanceTransaction_Handler,
 		},
+		{
+			MethodName: "SimulateTransaction",
+			Handler:    _Chain33_SimulateTransaction_Handler,
+		},
+		{
+			MethodName: "GetStateProof",
+			Handler:    _Chain33_GetStateProof_Handler,
+		},
+		{
+			MethodName: "GetTxProof",
+			Handler:    _Chain33_GetTxProof_Handler,
+		},
+		{
+			MethodName: "GetStorePruneStatus",
+			Handler:    _Chain33_GetStorePruneStatus_Handler,
+		},
+		{
+			MethodName: "ExportSnapshot",
+			Handler:    _Chain33_ExportSnapshot_Handler,
+		},
+		{
+			MethodName: "ListPeerBans",
+			Handler:    _Chain33_ListPeerBans_Handler,
+		},
+		{
+			MethodName: "UnbanPeer",
+			Handler:    _Chain33_UnbanPeer_Handler,
+		},
+		{
+			MethodName: "ImportWatch",
+			Handler:    _Chain33_ImportWatch_Handler,
+		},
+		{
+			MethodName: "ExportXpub",
+			Handler:    _Chain33_ExportXpub_Handler,
+		},
+		{
+			MethodName: "RescanWallet",
+			Handler:    _Chain33_RescanWallet_Handler,
+		},
+		{
+			MethodName: "GetAccountNonce",
+			Handler:    _Chain33_GetAccountNonce_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamBlockSequences",
+			Handler:       _Chain33_StreamBlockSequences_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "rpc.proto",
 }
 
@@ -218,6 +218,7 @@ type CreateTx struct {
 	IsToken     bool   `protobuf:"varint,6,opt,name=isToken" json:"isToken,omitempty"`
 	TokenSymbol string `protobuf:"bytes,7,opt,name=tokenSymbol" json:"tokenSymbol,omitempty"`
 	ExecName    string `protobuf:"bytes,8,opt,name=execName" json:"execName,omitempty"`
+	From        string `protobuf:"bytes,9,opt,name=from" json:"from,omitempty"`
 }
 
 func (m *CreateTx) Reset()                    { *m = CreateTx{} }
@@ -281,6 +282,13 @@ func (m *CreateTx) GetExecName() string {
 	return ""
 }
 
+func (m *CreateTx) GetFrom() string {
+	if m != nil {
+		return m.From
+	}
+	return ""
+}
+
 type CreateTransactionGroup struct {
 	Txs []string `protobuf:"bytes,1,rep,name=txs" json:"txs,omitempty"`
 }
@@ -407,6 +415,8 @@ type Transaction struct {
 	GroupCount int32  `protobuf:"varint,8,opt,name=groupCount" json:"groupCount,omitempty"`
 	Header     []byte `protobuf:"bytes,9,opt,name=header,proto3" json:"header,omitempty"`
 	Next       []byte `protobuf:"bytes,10,opt,name=next,proto3" json:"next,omitempty"`
+	// 交易执行最多可以消耗的gas，为0时使用链上配置的最大值
+	GasLimit int64 `protobuf:"varint,11,opt,name=gasLimit" json:"gasLimit,omitempty"`
 }
 
 func (m *Transaction) Reset()                    { *m = Transaction{} }
@@ -484,6 +494,13 @@ func (m *Transaction) GetNext() []byte {
 	return nil
 }
 
+func (m *Transaction) GetGasLimit() int64 {
+	if m != nil {
+		return m.GasLimit
+	}
+	return 0
+}
+
 type Transactions struct {
 	Txs []*Transaction `protobuf:"bytes,1,rep,name=txs" json:"txs,omitempty"`
 }
@@ -623,8 +640,11 @@ type ReqAddr struct {
 	Flag      int32 `protobuf:"varint,2,opt,name=flag" json:"flag,omitempty"`
 	Count     int32 `protobuf:"varint,3,opt,name=count" json:"count,omitempty"`
 	Direction int32 `protobuf:"varint,4,opt,name=direction" json:"direction,omitempty"`
-	Height    int64 `protobuf:"varint,5,opt,name=height" json:"height,omitempty"`
-	Index     int64 `protobuf:"varint,6,opt,name=index" json:"index,omitempty"`
+	// GetAllExecBalance时查询该高度的余额，stateHash优先
+	Height int64 `protobuf:"varint,5,opt,name=height" json:"height,omitempty"`
+	Index  int64 `protobuf:"varint,6,opt,name=index" json:"index,omitempty"`
+	// GetAllExecBalance时查询该状态的余额，为空时查询最新的余额
+	StateHash string `protobuf:"bytes,7,opt,name=stateHash" json:"stateHash,omitempty"`
 }
 
 func (m *ReqAddr) Reset()                    { *m = ReqAddr{} }
@@ -674,6 +694,13 @@ func (m *ReqAddr) GetIndex() int64 {
 	return 0
 }
 
+func (m *ReqAddr) GetStateHash() string {
+	if m != nil {
+		return m.StateHash
+	}
+	return ""
+}
+
 type ReqPrivacy struct {
 	Count     int32 `protobuf:"varint,1,opt,name=count" json:"count,omitempty"`
 	Direction int32 `protobuf:"varint,2,opt,name=direction" json:"direction,omitempty"`
@@ -870,9 +897,10 @@ func (m *ReceiptLog) GetLog() []byte {
 // ty = 1 -> CutFee //cut fee ,bug exec not ok
 // ty = 2 -> exec ok
 type Receipt struct {
-	Ty   int32         `protobuf:"varint,1,opt,name=ty" json:"ty,omitempty"`
-	KV   []*KeyValue   `protobuf:"bytes,2,rep,name=KV" json:"KV,omitempty"`
-	Logs []*ReceiptLog `protobuf:"bytes,3,rep,name=logs" json:"logs,omitempty"`
+	Ty      int32         `protobuf:"varint,1,opt,name=ty" json:"ty,omitempty"`
+	KV      []*KeyValue   `protobuf:"bytes,2,rep,name=KV" json:"KV,omitempty"`
+	Logs    []*ReceiptLog `protobuf:"bytes,3,rep,name=logs" json:"logs,omitempty"`
+	GasUsed int64         `protobuf:"varint,4,opt,name=gasUsed" json:"gasUsed,omitempty"`
 }
 
 func (m *Receipt) Reset()                    { *m = Receipt{} }
@@ -901,9 +929,17 @@ func (m *Receipt) GetLogs() []*ReceiptLog {
 	return nil
 }
 
+func (m *Receipt) GetGasUsed() int64 {
+	if m != nil {
+		return m.GasUsed
+	}
+	return 0
+}
+
 type ReceiptData struct {
-	Ty   int32         `protobuf:"varint,1,opt,name=ty" json:"ty,omitempty"`
-	Logs []*ReceiptLog `protobuf:"bytes,3,rep,name=logs" json:"logs,omitempty"`
+	Ty      int32         `protobuf:"varint,1,opt,name=ty" json:"ty,omitempty"`
+	Logs    []*ReceiptLog `protobuf:"bytes,3,rep,name=logs" json:"logs,omitempty"`
+	GasUsed int64         `protobuf:"varint,4,opt,name=gasUsed" json:"gasUsed,omitempty"`
 }
 
 func (m *ReceiptData) Reset()                    { *m = ReceiptData{} }
@@ -925,6 +961,134 @@ func (m *ReceiptData) GetLogs() []*ReceiptLog {
 	return nil
 }
 
+func (m *ReceiptData) GetGasUsed() int64 {
+	if m != nil {
+		return m.GasUsed
+	}
+	return 0
+}
+
+// 模拟执行交易，交易没有签名时需要指定发送交易的公钥
+type ReqSimulateTx struct {
+	Tx *Transaction `protobuf:"bytes,1,opt,name=tx" json:"tx,omitempty"`
+	// 为空时在最新区块的状态上执行
+	StateHash []byte `protobuf:"bytes,2,opt,name=stateHash,proto3" json:"stateHash,omitempty"`
+	Pubkey    []byte `protobuf:"bytes,3,opt,name=pubkey,proto3" json:"pubkey,omitempty"`
+	// pubkey的签名类型，为0时使用secp256k1
+	SignType int32 `protobuf:"varint,4,opt,name=signType" json:"signType,omitempty"`
+}
+
+func (m *ReqSimulateTx) Reset()         { *m = ReqSimulateTx{} }
+func (m *ReqSimulateTx) String() string { return proto.CompactTextString(m) }
+func (*ReqSimulateTx) ProtoMessage()    {}
+
+func (m *ReqSimulateTx) GetTx() *Transaction {
+	if m != nil {
+		return m.Tx
+	}
+	return nil
+}
+
+func (m *ReqSimulateTx) GetStateHash() []byte {
+	if m != nil {
+		return m.StateHash
+	}
+	return nil
+}
+
+func (m *ReqSimulateTx) GetPubkey() []byte {
+	if m != nil {
+		return m.Pubkey
+	}
+	return nil
+}
+
+func (m *ReqSimulateTx) GetSignType() int32 {
+	if m != nil {
+		return m.SignType
+	}
+	return 0
+}
+
+// 交易执行修改的状态数据，prev为空表示新增的key
+type SimulateKV struct {
+	Key     []byte `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Prev    []byte `protobuf:"bytes,2,opt,name=prev,proto3" json:"prev,omitempty"`
+	Current []byte `protobuf:"bytes,3,opt,name=current,proto3" json:"current,omitempty"`
+}
+
+func (m *SimulateKV) Reset()         { *m = SimulateKV{} }
+func (m *SimulateKV) String() string { return proto.CompactTextString(m) }
+func (*SimulateKV) ProtoMessage()    {}
+
+func (m *SimulateKV) GetKey() []byte {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
+func (m *SimulateKV) GetPrev() []byte {
+	if m != nil {
+		return m.Prev
+	}
+	return nil
+}
+
+func (m *SimulateKV) GetCurrent() []byte {
+	if m != nil {
+		return m.Current
+	}
+	return nil
+}
+
+type ReplySimulateTx struct {
+	Receipt   *ReceiptData  `protobuf:"bytes,1,opt,name=receipt" json:"receipt,omitempty"`
+	KV        []*SimulateKV `protobuf:"bytes,2,rep,name=KV" json:"KV,omitempty"`
+	Fee       int64         `protobuf:"varint,3,opt,name=fee" json:"fee,omitempty"`
+	Height    int64         `protobuf:"varint,4,opt,name=height" json:"height,omitempty"`
+	StateHash []byte        `protobuf:"bytes,5,opt,name=stateHash,proto3" json:"stateHash,omitempty"`
+}
+
+func (m *ReplySimulateTx) Reset()         { *m = ReplySimulateTx{} }
+func (m *ReplySimulateTx) String() string { return proto.CompactTextString(m) }
+func (*ReplySimulateTx) ProtoMessage()    {}
+
+func (m *ReplySimulateTx) GetReceipt() *ReceiptData {
+	if m != nil {
+		return m.Receipt
+	}
+	return nil
+}
+
+func (m *ReplySimulateTx) GetKV() []*SimulateKV {
+	if m != nil {
+		return m.KV
+	}
+	return nil
+}
+
+func (m *ReplySimulateTx) GetFee() int64 {
+	if m != nil {
+		return m.Fee
+	}
+	return 0
+}
+
+func (m *ReplySimulateTx) GetHeight() int64 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
+func (m *ReplySimulateTx) GetStateHash() []byte {
+	if m != nil {
+		return m.StateHash
+	}
+	return nil
+}
+
 type TxResult struct {
 	Height      int64        `protobuf:"varint,1,opt,name=height" json:"height,omitempty"`
 	Index       int32        `protobuf:"varint,2,opt,name=index" json:"index,omitempty"`
@@ -1201,6 +1365,9 @@ func init() {
 	proto.RegisterType((*ReceiptLog)(nil), "types.ReceiptLog")
 	proto.RegisterType((*Receipt)(nil), "types.Receipt")
 	proto.RegisterType((*ReceiptData)(nil), "types.ReceiptData")
+	proto.RegisterType((*ReqSimulateTx)(nil), "types.ReqSimulateTx")
+	proto.RegisterType((*SimulateKV)(nil), "types.SimulateKV")
+	proto.RegisterType((*ReplySimulateTx)(nil), "types.ReplySimulateTx")
 	proto.RegisterType((*TxResult)(nil), "types.TxResult")
 	proto.RegisterType((*TransactionDetail)(nil), "types.TransactionDetail")
 	proto.RegisterType((*TransactionDetails)(nil), "types.TransactionDetails")
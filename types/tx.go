@@ -113,7 +113,7 @@ func (txgroup *Transactions) Check(height int64, minfee int64) error {
 		if txs[i] == nil {
 			return ErrTxGroupEmpty
 		}
-		err := txs[i].check(0)
+		err := txs[i].check(height, 0)
 		if err != nil {
 			return err
 		}
@@ -220,7 +220,7 @@ func (tx *TransactionCache) Check(height, minfee int64) error {
 			return err
 		}
 		if txs == nil {
-			tx.checkok = tx.check(minfee)
+			tx.checkok = tx.check(height, minfee)
 		} else {
 			tx.checkok = txs.Check(height, minfee)
 		}
@@ -354,16 +354,24 @@ func (tx *Transaction) Check(height, minfee int64) error {
 		return err
 	}
 	if group == nil {
-		return tx.check(minfee)
+		return tx.check(height, minfee)
 	}
 	return group.Check(height, minfee)
 }
 
-func (tx *Transaction) check(minfee int64) error {
+func (tx *Transaction) check(height, minfee int64) error {
 	txSize := Size(tx)
 	if txSize > int(MaxTxSize) {
 		return ErrTxMsgSizeTooBig
 	}
+	//gasLimit不能超过链上配置的每笔交易最大gas
+	if maxgas, _ := GetGasLimit(height); tx.GasLimit < 0 || (maxgas > 0 && tx.GasLimit > maxgas) {
+		return ErrTxGasLimitTooBig
+	}
+	//ForkMultiSig之前不接受多重签名的交易
+	if tx.GetSignature().GetTy() == MULTISIG && !IsFork(height, "ForkMultiSig") {
+		return ErrMultiSigNotEnable
+	}
 	if minfee == 0 {
 		return nil
 	}
@@ -485,6 +493,7 @@ func (tx *Transaction) Json() string {
 		GroupCount int32  `json:"groupCount,omitempty"`
 		Header     string `json:"header,omitempty"`
 		Next       string `json:"next,omitempty"`
+		GasLimit   int64  `json:"gasLimit,omitempty"`
 	}
 
 	newtx := &transaction{}
@@ -499,6 +508,7 @@ func (tx *Transaction) Json() string {
 	newtx.GroupCount = tx.GroupCount
 	newtx.Header = hex.EncodeToString(tx.Header)
 	newtx.Next = hex.EncodeToString(tx.Next)
+	newtx.GasLimit = tx.GasLimit
 	data, err := json.MarshalIndent(newtx, "", "\t")
 	if err != nil {
 		return err.Error()
@@ -230,10 +230,26 @@ func (innernode *InnerNode) Hash() []byte {
 	return common.Sha256(data)
 }
 
+//InnerNode.ProofHash 用proof中的inner节点和子节点的hash计算出inner节点的hash
+func (innernode *InnerNode) ProofHash(childHash []byte) []byte {
+	var node InnerNode
+	node.Height = innernode.Height
+	node.Size = innernode.Size
+	// left is nil
+	if len(innernode.LeftHash) == 0 {
+		node.LeftHash = childHash
+		node.RightHash = innernode.RightHash
+	} else {
+		node.LeftHash = innernode.LeftHash
+		node.RightHash = childHash
+	}
+	return node.Hash()
+}
+
 func NewErrReceipt(err error) *Receipt {
 	berr := err.Error()
 	errlog := &ReceiptLog{TyLogErr, []byte(berr)}
-	return &Receipt{ExecErr, nil, []*ReceiptLog{errlog}}
+	return &Receipt{ExecErr, nil, []*ReceiptLog{errlog}, 0}
 }
 
 func CheckAmount(amount int64) bool {
@@ -38,6 +38,7 @@ type WalletTxDetail struct {
 	Txhash     []byte       `protobuf:"bytes,8,opt,name=txhash,proto3" json:"txhash,omitempty"`
 	ActionName string       `protobuf:"bytes,9,opt,name=actionName" json:"actionName,omitempty"`
 	Payload    []byte       `protobuf:"bytes,10,opt,name=payload,proto3" json:"payload,omitempty"`
+	WatchOnly  bool         `protobuf:"varint,11,opt,name=watchOnly" json:"watchOnly,omitempty"`
 }
 
 func (m *WalletTxDetail) Reset()                    { *m = WalletTxDetail{} }
@@ -115,6 +116,13 @@ func (m *WalletTxDetail) GetPayload() []byte {
 	return nil
 }
 
+func (m *WalletTxDetail) GetWatchOnly() bool {
+	if m != nil {
+		return m.WatchOnly
+	}
+	return false
+}
+
 type WalletTxDetails struct {
 	TxDetails []*WalletTxDetail `protobuf:"bytes,1,rep,name=txDetails" json:"txDetails,omitempty"`
 }
@@ -141,6 +149,7 @@ type WalletAccountStore struct {
 	Label     string `protobuf:"bytes,2,opt,name=label" json:"label,omitempty"`
 	Addr      string `protobuf:"bytes,3,opt,name=addr" json:"addr,omitempty"`
 	TimeStamp string `protobuf:"bytes,4,opt,name=timeStamp" json:"timeStamp,omitempty"`
+	SignType  int32  `protobuf:"varint,5,opt,name=signType" json:"signType,omitempty"`
 }
 
 func (m *WalletAccountStore) Reset()                    { *m = WalletAccountStore{} }
@@ -176,6 +185,13 @@ func (m *WalletAccountStore) GetTimeStamp() string {
 	return ""
 }
 
+func (m *WalletAccountStore) GetSignType() int32 {
+	if m != nil {
+		return m.SignType
+	}
+	return 0
+}
+
 // 钱包模块通过一个随机值对钱包密码加密
 // 	 pwHash : 对钱包密码和一个随机值组合进行哈希计算
 // 	 randstr :对钱包密码加密的一个随机值
@@ -212,7 +228,8 @@ type WalletStatus struct {
 	IsWalletLock bool `protobuf:"varint,1,opt,name=isWalletLock" json:"isWalletLock,omitempty"`
 	IsAutoMining bool `protobuf:"varint,2,opt,name=isAutoMining" json:"isAutoMining,omitempty"`
 	IsHasSeed    bool `protobuf:"varint,3,opt,name=isHasSeed" json:"isHasSeed,omitempty"`
-	IsTicketLock bool `protobuf:"varint,4,opt,name=isTicketLock" json:"isTicketLock,omitempty"`
+	IsTicketLock bool                `protobuf:"varint,4,opt,name=isTicketLock" json:"isTicketLock,omitempty"`
+	Rescan       *WalletRescanStatus `protobuf:"bytes,5,opt,name=rescan" json:"rescan,omitempty"`
 }
 
 func (m *WalletStatus) Reset()                    { *m = WalletStatus{} }
@@ -248,6 +265,13 @@ func (m *WalletStatus) GetIsTicketLock() bool {
 	return false
 }
 
+func (m *WalletStatus) GetRescan() *WalletRescanStatus {
+	if m != nil {
+		return m.Rescan
+	}
+	return nil
+}
+
 type WalletAccounts struct {
 	Wallets []*WalletAccount `protobuf:"bytes,1,rep,name=wallets" json:"wallets,omitempty"`
 }
@@ -265,8 +289,9 @@ func (m *WalletAccounts) GetWallets() []*WalletAccount {
 }
 
 type WalletAccount struct {
-	Acc   *Account `protobuf:"bytes,1,opt,name=acc" json:"acc,omitempty"`
-	Label string   `protobuf:"bytes,2,opt,name=label" json:"label,omitempty"`
+	Acc       *Account `protobuf:"bytes,1,opt,name=acc" json:"acc,omitempty"`
+	Label     string   `protobuf:"bytes,2,opt,name=label" json:"label,omitempty"`
+	WatchOnly bool     `protobuf:"varint,3,opt,name=watchOnly" json:"watchOnly,omitempty"`
 }
 
 func (m *WalletAccount) Reset()                    { *m = WalletAccount{} }
@@ -288,6 +313,13 @@ func (m *WalletAccount) GetLabel() string {
 	return ""
 }
 
+func (m *WalletAccount) GetWatchOnly() bool {
+	if m != nil {
+		return m.WatchOnly
+	}
+	return false
+}
+
 // 钱包解锁
 // 	 passwd : 钱包密码
 // 	 timeout :钱包解锁时间，0，一直解锁，非0值，超时之后继续锁定
@@ -424,7 +456,8 @@ func (m *ReqWalletSetPasswd) GetNewPass() string {
 }
 
 type ReqNewAccount struct {
-	Label string `protobuf:"bytes,1,opt,name=label" json:"label,omitempty"`
+	Label    string `protobuf:"bytes,1,opt,name=label" json:"label,omitempty"`
+	SignType int32  `protobuf:"varint,2,opt,name=signType" json:"signType,omitempty"`
 }
 
 func (m *ReqNewAccount) Reset()                    { *m = ReqNewAccount{} }
@@ -439,6 +472,13 @@ func (m *ReqNewAccount) GetLabel() string {
 	return ""
 }
 
+func (m *ReqNewAccount) GetSignType() int32 {
+	if m != nil {
+		return m.SignType
+	}
+	return 0
+}
+
 // 获取钱包交易的详细信息
 // 	 fromTx : []byte( Sprintf("%018d", height*100000 + index)，
 // 				表示从高度 height 中的 index 开始获取交易列表；
@@ -479,8 +519,9 @@ func (m *ReqWalletTransactionList) GetDirection() int32 {
 
 type ReqWalletImportPrivkey struct {
 	// bitcoin 的私钥格式
-	Privkey string `protobuf:"bytes,1,opt,name=privkey" json:"privkey,omitempty"`
-	Label   string `protobuf:"bytes,2,opt,name=label" json:"label,omitempty"`
+	Privkey  string `protobuf:"bytes,1,opt,name=privkey" json:"privkey,omitempty"`
+	Label    string `protobuf:"bytes,2,opt,name=label" json:"label,omitempty"`
+	SignType int32  `protobuf:"varint,3,opt,name=signType" json:"signType,omitempty"`
 }
 
 func (m *ReqWalletImportPrivkey) Reset()                    { *m = ReqWalletImportPrivkey{} }
@@ -502,6 +543,13 @@ func (m *ReqWalletImportPrivkey) GetLabel() string {
 	return ""
 }
 
+func (m *ReqWalletImportPrivkey) GetSignType() int32 {
+	if m != nil {
+		return m.SignType
+	}
+	return 0
+}
+
 // 发送交易
 // 	 from : 打出地址
 // 	 to :接受地址
@@ -798,6 +846,8 @@ type ReqSignRawTx struct {
 	// 1：隐私交易
 	// int32  mode  = 6;
 	Token string `protobuf:"bytes,7,opt,name=token" json:"token,omitempty"`
+	// 多重签名的策略(hex)，第一个成员签名时需要，之后从交易已有的签名中获取
+	MultiSig string `protobuf:"bytes,8,opt,name=multiSig" json:"multiSig,omitempty"`
 }
 
 func (m *ReqSignRawTx) Reset()                    { *m = ReqSignRawTx{} }
@@ -847,6 +897,13 @@ func (m *ReqSignRawTx) GetToken() string {
 	return ""
 }
 
+func (m *ReqSignRawTx) GetMultiSig() string {
+	if m != nil {
+		return m.MultiSig
+	}
+	return ""
+}
+
 type ReplySignRawTx struct {
 	TxHex string `protobuf:"bytes,1,opt,name=txHex" json:"txHex,omitempty"`
 }
@@ -1016,6 +1073,267 @@ func (m *ReqAccountList) GetWithoutBalance() bool {
 	return false
 }
 
+// 只读账户，只有地址没有私钥，通过扩展公钥导入时记录xpub和地址的索引
+type WalletWatchStore struct {
+	Addr      string `protobuf:"bytes,1,opt,name=addr" json:"addr,omitempty"`
+	Label     string `protobuf:"bytes,2,opt,name=label" json:"label,omitempty"`
+	Xpub      string `protobuf:"bytes,3,opt,name=xpub" json:"xpub,omitempty"`
+	Index     uint32 `protobuf:"varint,4,opt,name=index" json:"index,omitempty"`
+	TimeStamp string `protobuf:"bytes,5,opt,name=timeStamp" json:"timeStamp,omitempty"`
+}
+
+func (m *WalletWatchStore) Reset()         { *m = WalletWatchStore{} }
+func (m *WalletWatchStore) String() string { return proto.CompactTextString(m) }
+func (*WalletWatchStore) ProtoMessage()    {}
+
+func (m *WalletWatchStore) GetAddr() string {
+	if m != nil {
+		return m.Addr
+	}
+	return ""
+}
+
+func (m *WalletWatchStore) GetLabel() string {
+	if m != nil {
+		return m.Label
+	}
+	return ""
+}
+
+func (m *WalletWatchStore) GetXpub() string {
+	if m != nil {
+		return m.Xpub
+	}
+	return ""
+}
+
+func (m *WalletWatchStore) GetIndex() uint32 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func (m *WalletWatchStore) GetTimeStamp() string {
+	if m != nil {
+		return m.TimeStamp
+	}
+	return ""
+}
+
+// 通过扩展公钥导入的只读账户，next是已经生成的地址数，used是最后一个使用过的地址的索引加一
+type WalletXpubStore struct {
+	Xpub      string `protobuf:"bytes,1,opt,name=xpub" json:"xpub,omitempty"`
+	Label     string `protobuf:"bytes,2,opt,name=label" json:"label,omitempty"`
+	GapLimit  int32  `protobuf:"varint,3,opt,name=gapLimit" json:"gapLimit,omitempty"`
+	Next      uint32 `protobuf:"varint,4,opt,name=next" json:"next,omitempty"`
+	Used      uint32 `protobuf:"varint,5,opt,name=used" json:"used,omitempty"`
+	TimeStamp string `protobuf:"bytes,6,opt,name=timeStamp" json:"timeStamp,omitempty"`
+}
+
+func (m *WalletXpubStore) Reset()         { *m = WalletXpubStore{} }
+func (m *WalletXpubStore) String() string { return proto.CompactTextString(m) }
+func (*WalletXpubStore) ProtoMessage()    {}
+
+func (m *WalletXpubStore) GetXpub() string {
+	if m != nil {
+		return m.Xpub
+	}
+	return ""
+}
+
+func (m *WalletXpubStore) GetLabel() string {
+	if m != nil {
+		return m.Label
+	}
+	return ""
+}
+
+func (m *WalletXpubStore) GetGapLimit() int32 {
+	if m != nil {
+		return m.GapLimit
+	}
+	return 0
+}
+
+func (m *WalletXpubStore) GetNext() uint32 {
+	if m != nil {
+		return m.Next
+	}
+	return 0
+}
+
+func (m *WalletXpubStore) GetUsed() uint32 {
+	if m != nil {
+		return m.Used
+	}
+	return 0
+}
+
+func (m *WalletXpubStore) GetTimeStamp() string {
+	if m != nil {
+		return m.TimeStamp
+	}
+	return ""
+}
+
+// 导入只读的地址或者BIP32扩展公钥，gapLimit为0时使用默认值
+type ReqWalletImportWatch struct {
+	Addr     string `protobuf:"bytes,1,opt,name=addr" json:"addr,omitempty"`
+	Xpub     string `protobuf:"bytes,2,opt,name=xpub" json:"xpub,omitempty"`
+	Label    string `protobuf:"bytes,3,opt,name=label" json:"label,omitempty"`
+	GapLimit int32  `protobuf:"varint,4,opt,name=gapLimit" json:"gapLimit,omitempty"`
+}
+
+func (m *ReqWalletImportWatch) Reset()         { *m = ReqWalletImportWatch{} }
+func (m *ReqWalletImportWatch) String() string { return proto.CompactTextString(m) }
+func (*ReqWalletImportWatch) ProtoMessage()    {}
+
+func (m *ReqWalletImportWatch) GetAddr() string {
+	if m != nil {
+		return m.Addr
+	}
+	return ""
+}
+
+func (m *ReqWalletImportWatch) GetXpub() string {
+	if m != nil {
+		return m.Xpub
+	}
+	return ""
+}
+
+func (m *ReqWalletImportWatch) GetLabel() string {
+	if m != nil {
+		return m.Label
+	}
+	return ""
+}
+
+func (m *ReqWalletImportWatch) GetGapLimit() int32 {
+	if m != nil {
+		return m.GapLimit
+	}
+	return 0
+}
+
+// 重新扫描历史区块，恢复地址的交易记录
+// 	 addrs : 为空时扫描钱包中所有的地址和只读地址
+// 	 flag : 0:开始新的扫描 1:取消 2:继续取消或者中断的扫描
+type ReqWalletRescan struct {
+	Addrs      []string `protobuf:"bytes,1,rep,name=addrs" json:"addrs,omitempty"`
+	FromHeight int64    `protobuf:"varint,2,opt,name=fromHeight" json:"fromHeight,omitempty"`
+	Flag       int32    `protobuf:"varint,3,opt,name=flag" json:"flag,omitempty"`
+}
+
+func (m *ReqWalletRescan) Reset()         { *m = ReqWalletRescan{} }
+func (m *ReqWalletRescan) String() string { return proto.CompactTextString(m) }
+func (*ReqWalletRescan) ProtoMessage()    {}
+
+func (m *ReqWalletRescan) GetAddrs() []string {
+	if m != nil {
+		return m.Addrs
+	}
+	return nil
+}
+
+func (m *ReqWalletRescan) GetFromHeight() int64 {
+	if m != nil {
+		return m.FromHeight
+	}
+	return 0
+}
+
+func (m *ReqWalletRescan) GetFlag() int32 {
+	if m != nil {
+		return m.Flag
+	}
+	return 0
+}
+
+// 扫描的进度
+// 	 status : 1:扫描中 2:已取消 3:完成 4:失败
+// 	 useAddrIndex : 通过地址索引按地址扫描，addrDone是已经完成的地址数，height和index是当前地址最后处理的交易，height为-1时当前地址还没有开始
+// 	 否则按区块扫描，height是下一个扫描的区块高度
+type WalletRescanStatus struct {
+	Addrs        []string `protobuf:"bytes,1,rep,name=addrs" json:"addrs,omitempty"`
+	FromHeight   int64    `protobuf:"varint,2,opt,name=fromHeight" json:"fromHeight,omitempty"`
+	EndHeight    int64    `protobuf:"varint,3,opt,name=endHeight" json:"endHeight,omitempty"`
+	Height       int64    `protobuf:"varint,4,opt,name=height" json:"height,omitempty"`
+	Status       int32    `protobuf:"varint,5,opt,name=status" json:"status,omitempty"`
+	UseAddrIndex bool     `protobuf:"varint,6,opt,name=useAddrIndex" json:"useAddrIndex,omitempty"`
+	AddrDone     int32    `protobuf:"varint,7,opt,name=addrDone" json:"addrDone,omitempty"`
+	Index        int64    `protobuf:"varint,8,opt,name=index" json:"index,omitempty"`
+	TxCount      int64    `protobuf:"varint,9,opt,name=txCount" json:"txCount,omitempty"`
+}
+
+func (m *WalletRescanStatus) Reset()         { *m = WalletRescanStatus{} }
+func (m *WalletRescanStatus) String() string { return proto.CompactTextString(m) }
+func (*WalletRescanStatus) ProtoMessage()    {}
+
+func (m *WalletRescanStatus) GetAddrs() []string {
+	if m != nil {
+		return m.Addrs
+	}
+	return nil
+}
+
+func (m *WalletRescanStatus) GetFromHeight() int64 {
+	if m != nil {
+		return m.FromHeight
+	}
+	return 0
+}
+
+func (m *WalletRescanStatus) GetEndHeight() int64 {
+	if m != nil {
+		return m.EndHeight
+	}
+	return 0
+}
+
+func (m *WalletRescanStatus) GetHeight() int64 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
+func (m *WalletRescanStatus) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+func (m *WalletRescanStatus) GetUseAddrIndex() bool {
+	if m != nil {
+		return m.UseAddrIndex
+	}
+	return false
+}
+
+func (m *WalletRescanStatus) GetAddrDone() int32 {
+	if m != nil {
+		return m.AddrDone
+	}
+	return 0
+}
+
+func (m *WalletRescanStatus) GetIndex() int64 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func (m *WalletRescanStatus) GetTxCount() int64 {
+	if m != nil {
+		return m.TxCount
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*WalletTxDetail)(nil), "types.WalletTxDetail")
 	proto.RegisterType((*WalletTxDetails)(nil), "types.WalletTxDetails")
@@ -1047,6 +1365,11 @@ func init() {
 	proto.RegisterType((*Int32)(nil), "types.Int32")
 	proto.RegisterType((*ReqCreateTransaction)(nil), "types.ReqCreateTransaction")
 	proto.RegisterType((*ReqAccountList)(nil), "types.ReqAccountList")
+	proto.RegisterType((*WalletWatchStore)(nil), "types.WalletWatchStore")
+	proto.RegisterType((*WalletXpubStore)(nil), "types.WalletXpubStore")
+	proto.RegisterType((*ReqWalletImportWatch)(nil), "types.ReqWalletImportWatch")
+	proto.RegisterType((*ReqWalletRescan)(nil), "types.ReqWalletRescan")
+	proto.RegisterType((*WalletRescanStatus)(nil), "types.WalletRescanStatus")
 }
 
 func init() { proto.RegisterFile("wallet.proto", fileDescriptor10) }
@@ -166,6 +166,7 @@ func RunChain33(name string) {
 	}
 	//jsonrpc, grpc, channel 三种模式
 	rpcapi := rpc.New(cfg.Rpc)
+	rpcapi.SetWsQueueClient(q.Client())
 	rpcapi.SetQueueClient(q.Client())
 
 	log.Info("loading wallet module")
@@ -67,6 +67,11 @@ func Run(RPCAddr, ParaName string) {
 	types.S("ParaName", ParaName)
 	rootCmd.PersistentFlags().String("rpc_laddr", types.GStr("RPCAddr"), "http url")
 	rootCmd.PersistentFlags().String("paraName", types.GStr("ParaName"), "parachain")
+	rootCmd.PersistentFlags().String("rpc_token", "", "api key or jwt for rpc authentication")
+	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		token, _ := cmd.Flags().GetString("rpc_token")
+		jsonclient.SetAuthToken(token)
+	}
 	if len(os.Args) > 1 {
 		if os.Args[1] == "send" {
 			commands.OneStepSend(os.Args)
@@ -151,6 +151,11 @@ enableStat=false
 enableMVCC=false
 alias=["token1:token","token2:token","token3:token"]
 
+#每笔交易和每个区块最多可以消耗的gas，ForkTxGas之后生效，为0时不限制
+[mver.exec]
+maxTxGas=0
+maxBlockGas=0
+
 [exec.sub.token]
 saveTokenTxList=true
 tokenApprs = [
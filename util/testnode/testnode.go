@@ -70,6 +70,11 @@ func GetDefaultConfig() (*types.Config, *types.ConfigSubModule) {
 	return types.InitCfgString(cfgstring)
 }
 
+//GetDefaultConfigString 返回默认配置的字符串，单元测试可以修改后通过types.InitCfgString使用
+func GetDefaultConfigString() string {
+	return cfgstring
+}
+
 func NewWithConfig(cfg *types.Config, sub *types.ConfigSubModule, mockapi client.QueueProtocolAPI) *Chain33Mock {
 	return newWithConfig(cfg, sub, mockapi)
 }
@@ -124,6 +129,7 @@ func newWithConfig(cfg *types.Config, sub *types.ConfigSubModule, mockapi client
 	mock.api = mockapi
 	server := rpc.New(cfg.Rpc)
 	server.SetAPI(mock.api)
+	server.SetWsQueueClient(q.Client())
 	server.SetQueueClientNoListen(q.Client())
 	mock.rpc = server
 	return mock
@@ -204,7 +210,7 @@ func newWalletRealize(qApi client.QueueProtocolAPI) {
 		panic(err)
 	}
 	for i, priv := range util.TestPrivkeyHex {
-		privkey := &types.ReqWalletImportPrivkey{priv, fmt.Sprintf("label%d", i)}
+		privkey := &types.ReqWalletImportPrivkey{Privkey: priv, Label: fmt.Sprintf("label%d", i)}
 		acc, err := qApi.WalletImportprivkey(privkey)
 		if err != nil {
 			panic(err)
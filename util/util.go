@@ -228,7 +228,7 @@ func ExecBlock(client queue.Client, prevStateRoot []byte, block *types.Block, er
 			deltxlist[i] = true
 			continue
 		}
-		rdata = append(rdata, &types.ReceiptData{receipt.Ty, receipt.Logs})
+		rdata = append(rdata, &types.ReceiptData{receipt.Ty, receipt.Logs, receipt.GasUsed})
 		//处理KV
 		kvs := receipt.KV
 		for _, kv := range kvs {
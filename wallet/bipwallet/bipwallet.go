@@ -132,7 +132,12 @@ type HDWallet struct {
 
 // 通过索引生成新的秘钥对
 func (w *HDWallet) NewKeyPair(index uint32) (priv, pub []byte, err error) {
-	key, err := bip44.NewKeyFromMasterKey(w.MasterKey, w.CoinType, bip32.FirstHardenedChild, 0, index)
+	return w.NewKeyPairByAccount(0, index)
+}
+
+// 通过bip44路径 m/44'/coin'/account'/0/index 生成秘钥对
+func (w *HDWallet) NewKeyPairByAccount(account, index uint32) (priv, pub []byte, err error) {
+	key, err := bip44.NewKeyFromMasterKey(w.MasterKey, w.CoinType, bip32.FirstHardenedChild+account, 0, index)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -229,3 +234,48 @@ func NewWalletFromSeed(coinType uint32, seed []byte) (wallet *HDWallet, err erro
 		RootSeed:  seed,
 		MasterKey: masterKey}, nil
 }
+
+// 导出account层 m/44'/coin'/account' 的扩展公钥，用于只读钱包生成地址
+func (w *HDWallet) Xpub(account uint32) (string, error) {
+	key, err := w.MasterKey.NewChildKey(bip44.Purpose)
+	if err != nil {
+		return "", err
+	}
+	for _, index := range []uint32{w.CoinType, bip32.FirstHardenedChild + account} {
+		key, err = key.NewChildKey(index)
+		if err != nil {
+			return "", err
+		}
+	}
+	return key.PublicKey().String(), nil
+}
+
+// 只有扩展公钥的钱包，只能生成公钥不能签名
+type PubWallet struct {
+	Key *bip32.Key
+}
+
+// 通过BIP32扩展公钥生成只读的钱包对象
+func NewWalletFromXpub(xpub string) (*PubWallet, error) {
+	key, err := bip32.B58Deserialize(xpub)
+	if err != nil {
+		return nil, err
+	}
+	if key.IsPrivate {
+		return nil, errors.New("extended key is not a public key")
+	}
+	return &PubWallet{Key: key}, nil
+}
+
+// 通过索引生成外部链上的公钥 xpub/0/index
+func (w *PubWallet) NewPubKey(index uint32) ([]byte, error) {
+	key, err := w.Key.NewChildKey(0)
+	if err != nil {
+		return nil, err
+	}
+	key, err = key.NewChildKey(index)
+	if err != nil {
+		return nil, err
+	}
+	return key.Key, nil
+}
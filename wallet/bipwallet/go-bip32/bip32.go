@@ -175,6 +175,48 @@ func (key *Key) String() string {
 	return string(base58Encode(key.Serialize()))
 }
 
+// Deserialize a 82 byte slice into a Key, the checksum is verified
+func Deserialize(data []byte) (*Key, error) {
+	if len(data) != 82 {
+		return nil, errors.New("Serialized keys should by exactly 82 bytes")
+	}
+	if !bytes.Equal(checksum(data[:78]), data[78:]) {
+		return nil, errors.New("Checksum doesn't match")
+	}
+
+	key := &Key{
+		Version:     data[0:4],
+		Depth:       data[4],
+		FingerPrint: data[5:9],
+		ChildNumber: data[9:13],
+		ChainCode:   data[13:45],
+	}
+	if data[45] == 0x0 {
+		key.IsPrivate = true
+		key.Key = data[46:78]
+		if err := validatePrivateKey(key.Key); err != nil {
+			return nil, err
+		}
+	} else {
+		key.IsPrivate = false
+		key.Key = data[45:78]
+		if err := validatePublicKey(key.Key); err != nil {
+			return nil, err
+		}
+	}
+
+	return key, nil
+}
+
+// Deserialize a Key encoded in the standard Bitcoin base58 encoding
+func B58Deserialize(data string) (*Key, error) {
+	b, err := BitcoinBase58Encoding.DecodeString(data)
+	if err != nil {
+		return nil, err
+	}
+	return Deserialize(b)
+}
+
 // Cryptographically secure seed
 func NewSeed() ([]byte, error) {
 	// Well that easy, just make go read 256 random bytes into a slice
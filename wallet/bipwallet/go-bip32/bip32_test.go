@@ -123,5 +123,29 @@ func testVectorKeyPairs(t *testing.T, vector testMasterKey) {
 		// Assert correctness
 		assert.Equal(t, testChildKey.privKey, privKey.String())
 		assert.Equal(t, testChildKey.pubKey, pubKey.String())
+
+		// Serialized keys deserialize to the same key
+		key, err := bip32.B58Deserialize(testChildKey.privKey)
+		assert.NoError(t, err)
+		assert.Equal(t, privKey, key)
+		key, err = bip32.B58Deserialize(testChildKey.pubKey)
+		assert.NoError(t, err)
+		assert.Equal(t, pubKey, key)
 	}
 }
+
+func TestB58DeserializeInvalid(t *testing.T) {
+	valid := "xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8"
+	_, err := bip32.B58Deserialize(valid)
+	assert.NoError(t, err)
+
+	// wrong checksum
+	_, err = bip32.B58Deserialize(valid[:len(valid)-1] + "9")
+	assert.Error(t, err)
+	// wrong length
+	_, err = bip32.B58Deserialize(valid[:len(valid)-4])
+	assert.Error(t, err)
+	// invalid character
+	_, err = bip32.B58Deserialize("0" + valid[1:])
+	assert.Error(t, err)
+}
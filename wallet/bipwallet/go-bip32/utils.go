@@ -148,6 +148,18 @@ func validateChildPublicKey(key []byte) error {
 	return nil
 }
 
+func validatePublicKey(key []byte) error {
+	if len(key) != PublicKeyCompressedLength || (key[0] != 0x2 && key[0] != 0x3) {
+		return errors.New("Invalid public key")
+	}
+	x, y := expandPublicKey(key)
+	if !curve.IsOnCurve(x, y) {
+		return errors.New("Invalid public key")
+	}
+
+	return nil
+}
+
 //
 // Numerical
 //
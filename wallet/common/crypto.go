@@ -7,8 +7,110 @@ package common
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/rand"
+
+	"github.com/33cn/chain33/types"
+	"golang.org/x/crypto/scrypt"
+)
+
+//KeyStoreVersion 当前的密钥版本，加密后的数据以版本号开头
+const KeyStoreVersion = 1
+
+const (
+	scryptN  = 1 << 15
+	scryptR  = 8
+	scryptP  = 1
+	keyLen   = 32
+	saltLen  = 16
+	nonceLen = 12
 )
 
+//KeyStore 钱包的密钥：用scrypt从钱包密码和随机的salt派生aes密钥，私钥和seed使用aes-gcm加密
+//派生参数和版本号一起保存在钱包数据库中，以后修改默认参数时已有的钱包仍然可以解密
+type KeyStore struct {
+	Version int32  `json:"version"`
+	Salt    []byte `json:"salt"`
+	N       int    `json:"n"`
+	R       int    `json:"r"`
+	P       int    `json:"p"`
+	key     []byte
+}
+
+//NewKeyStore 用新的随机salt创建密钥
+func NewKeyStore(password []byte) (*KeyStore, error) {
+	ks := &KeyStore{Version: KeyStoreVersion, Salt: make([]byte, saltLen), N: scryptN, R: scryptR, P: scryptP}
+	if _, err := rand.Read(ks.Salt); err != nil {
+		return nil, err
+	}
+	if err := ks.Unlock(password); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+//Unlock 用密码派生aes密钥，密码是否正确由解密时的认证来判断
+func (ks *KeyStore) Unlock(password []byte) error {
+	if ks.Version != KeyStoreVersion {
+		return types.ErrKeyStoreVersion
+	}
+	key, err := scrypt.Key(password, ks.Salt, ks.N, ks.R, ks.P, keyLen)
+	if err != nil {
+		return err
+	}
+	ks.key = key
+	return nil
+}
+
+//Lock 清除内存中的密钥，之后需要重新Unlock
+func (ks *KeyStore) Lock() {
+	for i := range ks.key {
+		ks.key[i] = 0
+	}
+	ks.key = nil
+}
+
+//Encrypt 加密后的格式: version(1) | nonce(12) | aes-gcm密文
+func (ks *KeyStore) Encrypt(plain []byte) ([]byte, error) {
+	aesgcm, err := ks.aead()
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, 1+nonceLen, 1+nonceLen+len(plain)+aesgcm.Overhead())
+	data[0] = byte(ks.Version)
+	if _, err := rand.Read(data[1 : 1+nonceLen]); err != nil {
+		return nil, err
+	}
+	return aesgcm.Seal(data, data[1:1+nonceLen], plain, data[:1]), nil
+}
+
+//Decrypt 版本号作为附加数据参与认证，密码错误或者数据被修改时返回ErrDecrypt
+func (ks *KeyStore) Decrypt(data []byte) ([]byte, error) {
+	if len(data) < 1+nonceLen || data[0] != byte(ks.Version) {
+		return nil, types.ErrKeyStoreVersion
+	}
+	aesgcm, err := ks.aead()
+	if err != nil {
+		return nil, err
+	}
+	plain, err := aesgcm.Open(nil, data[1:1+nonceLen], data[1+nonceLen:], data[:1])
+	if err != nil {
+		return nil, types.ErrDecrypt
+	}
+	return plain, nil
+}
+
+func (ks *KeyStore) aead() (cipher.AEAD, error) {
+	if len(ks.key) == 0 {
+		return nil, types.ErrWalletIsLocked
+	}
+	block, err := aes.NewCipher(ks.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+//旧版本的钱包使用的加密方式，只用于升级之前的私钥的解密
 //使用钱包的password对私钥进行aes cbc加密,返回加密后的privkey
 func CBCEncrypterPrivkey(password []byte, privkey []byte) []byte {
 	key := make([]byte, 32)
@@ -15,6 +15,10 @@ const (
 	keyEncryptionCompFlag = "EncryptionFlag" // 中间有一段时间运行了一个错误的密码版本，导致有部分用户信息发生错误，需要兼容下
 	keyPasswordHash       = "PasswordHash"
 	keyWalletSeed         = "walletseed"
+	keyKeyStore           = "KeyStore"
+	keyWatch              = "Watch"
+	keyXpub               = "Xpub"
+	keyRescan             = "RescanStatus"
 )
 
 //用于所有Account账户的输出list，需要安装时间排序
@@ -53,3 +57,31 @@ func CalcPasswordHash() []byte {
 func CalcWalletSeed() []byte {
 	return []byte(keyWalletSeed)
 }
+
+//版本化的钱包密钥派生参数
+func CalcKeyStore() []byte {
+	return []byte(keyKeyStore)
+}
+
+//只读账户，通过addr查询
+func CalcWatchKey(addr string) []byte {
+	return []byte(fmt.Sprintf("%s:%s", keyWatch, addr))
+}
+
+func CalcWatchPrefix() []byte {
+	return []byte(keyWatch + ":")
+}
+
+//通过扩展公钥导入的只读账户
+func CalcXpubKey(xpub string) []byte {
+	return []byte(fmt.Sprintf("%s:%s", keyXpub, xpub))
+}
+
+func CalcXpubPrefix() []byte {
+	return []byte(keyXpub + ":")
+}
+
+//历史区块扫描的进度
+func CalcRescanKey() []byte {
+	return []byte(keyRescan)
+}
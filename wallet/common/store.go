@@ -325,3 +325,124 @@ func (store *Store) HasSeed() (bool, error) {
 	}
 	return true, nil
 }
+
+//SetKeyStore 保存密钥的派生参数，和重新加密的私钥在同一个batch中写入
+func (store *Store) SetKeyStore(ks *KeyStore, batch db.Batch) error {
+	data, err := json.Marshal(ks)
+	if err != nil {
+		storelog.Error("SetKeyStore marshal", "err", err)
+		return types.ErrMarshal
+	}
+	batch.Set(CalcKeyStore(), data)
+	return nil
+}
+
+//GetKeyStore 升级之前的钱包没有保存密钥，返回ErrKeyStoreNotExist
+func (store *Store) GetKeyStore() (*KeyStore, error) {
+	data, err := store.Get(CalcKeyStore())
+	if len(data) == 0 || err != nil {
+		return nil, types.ErrKeyStoreNotExist
+	}
+	var ks KeyStore
+	err = json.Unmarshal(data, &ks)
+	if err != nil {
+		storelog.Error("GetKeyStore unmarshal", "err", err)
+		return nil, types.ErrUnmarshal
+	}
+	return &ks, nil
+}
+
+//SetWatchAccount 保存只读账户
+func (store *Store) SetWatchAccount(account *types.WalletWatchStore, batch db.Batch) {
+	batch.Set(CalcWatchKey(account.Addr), types.Encode(account))
+}
+
+//GetWatchAccount 只读账户不存在时返回ErrAddrNotExist
+func (store *Store) GetWatchAccount(addr string) (*types.WalletWatchStore, error) {
+	if len(addr) == 0 {
+		return nil, types.ErrInvalidParam
+	}
+	data, err := store.Get(CalcWatchKey(addr))
+	if len(data) == 0 || err != nil {
+		return nil, types.ErrAddrNotExist
+	}
+	var account types.WalletWatchStore
+	err = types.Decode(data, &account)
+	if err != nil {
+		storelog.Error("GetWatchAccount", "Decode err", err)
+		return nil, types.ErrUnmarshal
+	}
+	return &account, nil
+}
+
+//GetWatchAccounts 所有的只读账户
+func (store *Store) GetWatchAccounts() ([]*types.WalletWatchStore, error) {
+	values := store.NewListHelper().PrefixScan(CalcWatchPrefix())
+	accounts := make([]*types.WalletWatchStore, len(values))
+	for i, value := range values {
+		var account types.WalletWatchStore
+		err := types.Decode(value, &account)
+		if err != nil {
+			storelog.Error("GetWatchAccounts", "Decode err", err)
+			return nil, types.ErrUnmarshal
+		}
+		accounts[i] = &account
+	}
+	return accounts, nil
+}
+
+//SetXpub 保存扩展公钥和已经生成的地址数
+func (store *Store) SetXpub(xpub *types.WalletXpubStore, batch db.Batch) {
+	batch.Set(CalcXpubKey(xpub.Xpub), types.Encode(xpub))
+}
+
+//GetXpub 扩展公钥不存在时返回ErrNotFound
+func (store *Store) GetXpub(xpub string) (*types.WalletXpubStore, error) {
+	data, err := store.Get(CalcXpubKey(xpub))
+	if len(data) == 0 || err != nil {
+		return nil, types.ErrNotFound
+	}
+	var x types.WalletXpubStore
+	err = types.Decode(data, &x)
+	if err != nil {
+		storelog.Error("GetXpub", "Decode err", err)
+		return nil, types.ErrUnmarshal
+	}
+	return &x, nil
+}
+
+//GetXpubs 所有导入的扩展公钥
+func (store *Store) GetXpubs() ([]*types.WalletXpubStore, error) {
+	values := store.NewListHelper().PrefixScan(CalcXpubPrefix())
+	xpubs := make([]*types.WalletXpubStore, len(values))
+	for i, value := range values {
+		var x types.WalletXpubStore
+		err := types.Decode(value, &x)
+		if err != nil {
+			storelog.Error("GetXpubs", "Decode err", err)
+			return nil, types.ErrUnmarshal
+		}
+		xpubs[i] = &x
+	}
+	return xpubs, nil
+}
+
+//SetRescanStatus 扫描的进度和扫描到的交易在同一个batch中写入
+func (store *Store) SetRescanStatus(status *types.WalletRescanStatus, batch db.Batch) {
+	batch.Set(CalcRescanKey(), types.Encode(status))
+}
+
+//GetRescanStatus 没有扫描过时返回ErrRescanNotExist
+func (store *Store) GetRescanStatus() (*types.WalletRescanStatus, error) {
+	data, err := store.Get(CalcRescanKey())
+	if len(data) == 0 || err != nil {
+		return nil, types.ErrRescanNotExist
+	}
+	var status types.WalletRescanStatus
+	err = types.Decode(data, &status)
+	if err != nil {
+		storelog.Error("GetRescanStatus", "Decode err", err)
+		return nil, types.ErrUnmarshal
+	}
+	return &status, nil
+}
@@ -43,7 +43,8 @@ type WalletOperate interface {
 	GetMutex() *sync.Mutex
 	GetDBStore() db.DB
 	GetSignType() int
-	GetPassword() string
+	Encrypt(plain []byte) ([]byte, error)
+	Decrypt(data []byte) ([]byte, error)
 	GetBlockHeight() int64
 	GetRandom() *rand.Rand
 	GetWalletDone() chan struct{}
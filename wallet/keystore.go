@@ -0,0 +1,168 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wallet
+
+import (
+	"github.com/33cn/chain33/common"
+	dbm "github.com/33cn/chain33/common/db"
+	"github.com/33cn/chain33/types"
+	wcom "github.com/33cn/chain33/wallet/common"
+)
+
+//私钥和seed使用版本化的密钥加密，见wcom.KeyStore
+//升级之前的钱包在下一次解锁时自动迁移到新的加密方式
+
+//Encrypt 使用钱包的密钥加密数据，钱包锁定之后返回ErrWalletIsLocked
+func (wallet *Wallet) Encrypt(plain []byte) ([]byte, error) {
+	if wallet.keystore == nil {
+		return nil, types.ErrWalletIsLocked
+	}
+	return wallet.keystore.Encrypt(plain)
+}
+
+//Decrypt 解密Encrypt加密的数据
+func (wallet *Wallet) Decrypt(data []byte) ([]byte, error) {
+	if wallet.keystore == nil {
+		return nil, types.ErrWalletIsLocked
+	}
+	return wallet.keystore.Decrypt(data)
+}
+
+//encryptPrivkey 使用钱包的密钥加密私钥，返回hex字符串
+func (wallet *Wallet) encryptPrivkey(privkey []byte) (string, error) {
+	encrypted, err := wallet.Encrypt(privkey)
+	if err != nil {
+		return "", err
+	}
+	return common.ToHex(encrypted), nil
+}
+
+//decryptPrivkey 解密WalletAccountStore中保存的私钥
+func (wallet *Wallet) decryptPrivkey(store string) ([]byte, error) {
+	if wallet.keystore == nil {
+		return nil, types.ErrWalletIsLocked
+	}
+	encrypted, err := common.FromHex(store)
+	if err != nil || len(encrypted) == 0 {
+		return nil, types.ErrFromHex
+	}
+	return wallet.Decrypt(encrypted)
+}
+
+//lockKeyStore 锁定钱包时清除内存中的密钥和密码，解锁时重新从密码派生
+func (wallet *Wallet) lockKeyStore() {
+	wallet.mtx.Lock()
+	defer wallet.mtx.Unlock()
+	if wallet.keystore != nil {
+		wallet.keystore.Lock()
+		wallet.keystore = nil
+	}
+	wallet.Password = ""
+}
+
+//openKeyStore 用密码派生密钥，并通过解密seed验证密码
+func (wallet *Wallet) openKeyStore(password string) (*wcom.KeyStore, error) {
+	if wallet.keystore != nil && password == wallet.Password {
+		return wallet.keystore, nil
+	}
+	ks, err := wallet.walletStore.GetKeyStore()
+	if err != nil {
+		return nil, err
+	}
+	err = ks.Unlock([]byte(password))
+	if err != nil {
+		return nil, err
+	}
+	_, err = GetSeedByKeyStore(wallet.walletStore.GetDB(), ks)
+	if err == types.ErrDecrypt {
+		return nil, types.ErrInputPassword
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+//unlockKeyStore 解锁时加载密钥，升级之前的钱包先迁移
+func (wallet *Wallet) unlockKeyStore(password string) error {
+	ks, err := wallet.openKeyStore(password)
+	if err == types.ErrKeyStoreNotExist {
+		ks, err = wallet.migrateKeyStore(password)
+	}
+	if err != nil {
+		return err
+	}
+	wallet.keystore = ks
+	return nil
+}
+
+//migrateKeyStore 用旧的加密方式解密seed和所有的私钥，使用新的密钥重新加密之后在一个batch中写入
+func (wallet *Wallet) migrateKeyStore(password string) (*wcom.KeyStore, error) {
+	db := wallet.walletStore.GetDB()
+	seed, err := getLegacySeed(db, password)
+	if err != nil {
+		walletlog.Error("migrateKeyStore", "getLegacySeed err", err)
+		return nil, types.ErrInputPassword
+	}
+	ks, err := wcom.NewKeyStore([]byte(password))
+	if err != nil {
+		return nil, err
+	}
+	batch := wallet.walletStore.NewBatch(true)
+	ok, err := SaveSeedInBatch(db, seed, ks, batch)
+	if !ok {
+		return nil, err
+	}
+	legacy := func(encrypted []byte) ([]byte, error) {
+		return wcom.CBCDecrypterPrivkey([]byte(password), encrypted), nil
+	}
+	err = wallet.reencryptAccounts(legacy, ks, batch)
+	if err != nil {
+		return nil, err
+	}
+	err = wallet.walletStore.SetKeyStore(ks, batch)
+	if err != nil {
+		return nil, err
+	}
+	err = batch.Write()
+	if err != nil {
+		return nil, err
+	}
+	walletlog.Info("migrateKeyStore", "version", ks.Version)
+	return ks, nil
+}
+
+//reencryptAccounts 使用decrypt解密所有账户的私钥，再用ks重新加密写入batch，任何一个失败时都不写入
+func (wallet *Wallet) reencryptAccounts(decrypt func([]byte) ([]byte, error), ks *wcom.KeyStore, batch dbm.Batch) error {
+	accStores, err := wallet.walletStore.GetAccountByPrefix("Account")
+	if err == types.ErrAccountNotExist {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, accStore := range accStores {
+		encrypted, err := common.FromHex(accStore.GetPrivkey())
+		if err != nil || len(encrypted) == 0 {
+			walletlog.Error("reencryptAccounts", "addr", accStore.Addr, "FromHex err", err)
+			return types.ErrFromHex
+		}
+		privkey, err := decrypt(encrypted)
+		if err != nil {
+			walletlog.Error("reencryptAccounts", "addr", accStore.Addr, "decrypt err", err)
+			return err
+		}
+		encrypted, err = ks.Encrypt(privkey)
+		if err != nil {
+			return err
+		}
+		accStore.Privkey = common.ToHex(encrypted)
+		err = wallet.walletStore.SetWalletAccountInBatch(true, accStore.Addr, accStore, batch)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
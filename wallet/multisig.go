@@ -0,0 +1,175 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wallet
+
+import (
+	"bytes"
+
+	"github.com/33cn/chain33/common"
+	"github.com/33cn/chain33/common/crypto"
+	"github.com/33cn/chain33/system/crypto/multisig"
+	"github.com/33cn/chain33/types"
+)
+
+//hasMultiSig 交易或者交易组中是否已经有多重签名的部分签名
+func hasMultiSig(tx *types.Transaction, group *types.Transactions) bool {
+	if group == nil {
+		return tx.GetSignature().GetTy() == multisig.ID
+	}
+	for _, gtx := range group.GetTxs() {
+		if gtx.GetSignature().GetTy() == multisig.ID {
+			return true
+		}
+	}
+	return false
+}
+
+//maxPartialSigSize 成员部分签名的最大长度，secp256k1和sm2的DER编码签名最长72字节
+const maxPartialSigSize = 72
+
+//multiSigOf 交易已有的多重签名，或者用请求中的policy(hex)创建的多重签名，不是多重签名时返回nil
+func multiSigOf(tx *types.Transaction, policy string) (*multisig.SignatureMultiSig, error) {
+	var sig *multisig.SignatureMultiSig
+	if tx.GetSignature().GetTy() == multisig.ID {
+		c, err := crypto.New(multisig.Name)
+		if err != nil {
+			return nil, err
+		}
+		s, err := c.SignatureFromBytes(tx.GetSignature().GetSignature())
+		if err != nil {
+			return nil, err
+		}
+		sig = s.(*multisig.SignatureMultiSig)
+	}
+	if policy == "" {
+		return sig, nil
+	}
+	policyByte, err := common.FromHex(policy)
+	if err != nil {
+		return nil, err
+	}
+	if sig != nil {
+		if !bytes.Equal(policyByte, sig.Policy.Bytes()) {
+			return nil, multisig.ErrPolicyChange
+		}
+		return sig, nil
+	}
+	c, err := crypto.New(multisig.Name)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := c.PubKeyFromBytes(policyByte)
+	if err != nil {
+		return nil, err
+	}
+	return multisig.NewSignature(pub.(*multisig.PubKeyMultiSig)), nil
+}
+
+//signTx 没有多重签名时按账户的签名类型signType签名
+//否则加入一个成员的部分签名，策略来自交易已有的签名或者请求中的policy(hex)
+func signTx(tx *types.Transaction, key crypto.PrivKey, signType int32, policy string) error {
+	sig, err := multiSigOf(tx, policy)
+	if err != nil {
+		return err
+	}
+	if sig == nil {
+		tx.Sign(signType, key)
+		return nil
+	}
+	copytx := *tx
+	copytx.Signature = nil
+	err = sig.AddSignature(key, types.Encode(&copytx))
+	if err != nil {
+		return err
+	}
+	tx.Signature = &types.Signature{
+		Ty:        multisig.ID,
+		Pubkey:    sig.Policy.Bytes(),
+		Signature: sig.Bytes(),
+	}
+	return nil
+}
+
+//multiSigFee 达到门限的成员都签名之后交易需要的手续费
+func multiSigFee(tx *types.Transaction, sig *multisig.SignatureMultiSig) (int64, error) {
+	full := multisig.NewSignature(sig.Policy)
+	for i := 0; i < sig.Policy.Threshold; i++ {
+		full.Sigs = append(full.Sigs, multisig.PartialSig{Index: i, Signature: make([]byte, maxPartialSigSize)})
+	}
+	copytx := *tx
+	copytx.Signature = &types.Signature{
+		Ty:        multisig.ID,
+		Pubkey:    sig.Policy.Bytes(),
+		Signature: full.Bytes(),
+	}
+	return copytx.GetRealFee(minFee)
+}
+
+//setMultiSigFee 部分签名会让交易变大，第一个成员签名前按签名完成后的大小重新估算手续费
+//手续费也在签名的数据中，已经有部分签名之后手续费不够只能重新签名
+func setMultiSigFee(tx *types.Transaction, policy string) error {
+	sig, err := multiSigOf(tx, policy)
+	if err != nil || sig == nil {
+		return err
+	}
+	fee, err := multiSigFee(tx, sig)
+	if err != nil {
+		return err
+	}
+	if tx.Fee >= fee {
+		return nil
+	}
+	if !sig.IsZero() {
+		return types.ErrTxFeeTooLow
+	}
+	tx.Fee = fee
+	return nil
+}
+
+//setGroupMultiSigFee 交易组中有多重签名时按签名完成后的大小重新估算第一笔交易中的总手续费
+//修改手续费会改变交易组的header，只有交易组还没有任何签名时才能修改
+//index 和 ReqSignRawTx 一样，小于等于0 表示签名所有的交易
+func setGroupMultiSigFee(group *types.Transactions, index int32, policy string) error {
+	txs := group.GetTxs()
+	totalfee := int64(0)
+	hasMulti := false
+	signed := false
+	for i, tx := range txs {
+		txPolicy := ""
+		if index <= 0 || int(index-1) == i {
+			txPolicy = policy
+		}
+		sig, err := multiSigOf(tx, txPolicy)
+		if err != nil {
+			return err
+		}
+		var fee int64
+		if sig != nil {
+			hasMulti = true
+			fee, err = multiSigFee(tx, sig)
+		} else {
+			fee, err = tx.GetRealFee(minFee)
+		}
+		if err != nil {
+			return err
+		}
+		totalfee += fee
+		if tx.GetSignature() != nil {
+			signed = true
+		}
+	}
+	if !hasMulti || txs[0].Fee >= totalfee {
+		return nil
+	}
+	if signed {
+		return types.ErrTxFeeTooLow
+	}
+	txs[0].Fee = totalfee
+	header := txs[0].Hash()
+	for _, tx := range txs {
+		tx.Header = header
+	}
+	return nil
+}
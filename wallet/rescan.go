@@ -0,0 +1,380 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wallet
+
+import (
+	"errors"
+
+	"github.com/33cn/chain33/common/address"
+	dbm "github.com/33cn/chain33/common/db"
+	"github.com/33cn/chain33/types"
+)
+
+//导入私钥或者只读地址之后，钱包只能看到之后的区块，通过rescan恢复地址历史的交易记录
+//blockchain开启地址索引(exec.disableAddrIndex=false)时按地址查询交易，否则从fromHeight开始遍历区块
+//扫描在后台进行，每处理一批区块或者交易，扫描的进度和交易记录在同一个batch中写入
+//钱包关闭时中断的扫描在下次启动时自动继续，取消或者失败的扫描可以通过flag=2继续
+
+const (
+	rescanStart  = 0
+	rescanCancel = 1
+	rescanResume = 2
+)
+
+const (
+	rescanScanning  = 1
+	rescanCancelled = 2
+	rescanDone      = 3
+	rescanFailed    = 4
+)
+
+const (
+	rescanBlockBatch = 100
+	rescanTxBatch    = 100
+)
+
+var errRescanStopped = errors.New("rescan stopped")
+
+//rescanJob 正在运行的扫描，扫描退出时关闭done
+type rescanJob struct {
+	cancel chan struct{}
+	done   chan struct{}
+}
+
+func (job *rescanJob) running() bool {
+	if job == nil {
+		return false
+	}
+	select {
+	case <-job.done:
+		return false
+	default:
+		return true
+	}
+}
+
+//ProcWalletRescan 开始，取消或者继续扫描，返回当前的进度
+func (wallet *Wallet) ProcWalletRescan(req *types.ReqWalletRescan) (*types.WalletRescanStatus, error) {
+	wallet.mtx.Lock()
+	defer wallet.mtx.Unlock()
+
+	if req == nil {
+		return nil, types.ErrInvalidParam
+	}
+	switch req.GetFlag() {
+	case rescanStart:
+		if wallet.rescanJob.running() {
+			return nil, types.ErrWalletRescanning
+		}
+		status, err := wallet.newRescanStatus(req)
+		if err != nil {
+			return nil, err
+		}
+		return wallet.startRescan(status)
+	case rescanCancel:
+		if !wallet.rescanJob.running() {
+			return nil, types.ErrRescanNotExist
+		}
+		return wallet.cancelRescan()
+	case rescanResume:
+		if wallet.rescanJob.running() {
+			return nil, types.ErrWalletRescanning
+		}
+		status, err := wallet.walletStore.GetRescanStatus()
+		if err != nil {
+			return nil, err
+		}
+		if status.Status == rescanDone {
+			return nil, types.ErrRescanNotExist
+		}
+		return wallet.startRescan(status)
+	}
+	return nil, types.ErrInvalidParam
+}
+
+//newRescanStatus 检查需要扫描的地址，addrs为空时扫描钱包中所有的地址和只读地址
+func (wallet *Wallet) newRescanStatus(req *types.ReqWalletRescan) (*types.WalletRescanStatus, error) {
+	if req.GetFromHeight() < 0 {
+		return nil, types.ErrInvalidParam
+	}
+	header, err := wallet.api.GetLastHeader()
+	if err != nil {
+		walletlog.Error("newRescanStatus", "GetLastHeader err", err)
+		return nil, err
+	}
+	if req.GetFromHeight() > header.GetHeight() {
+		return nil, types.ErrStartHeight
+	}
+	addrs := req.GetAddrs()
+	if len(addrs) == 0 {
+		addrs, err = wallet.rescanAddrs()
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, addr := range addrs {
+		if !wallet.AddrInWallet(addr) && wallet.getWatchAccount(addr) == nil {
+			walletlog.Error("newRescanStatus", "addr", addr)
+			return nil, types.ErrAddrNotExist
+		}
+	}
+	status := &types.WalletRescanStatus{
+		Addrs:        addrs,
+		FromHeight:   req.GetFromHeight(),
+		EndHeight:    header.GetHeight(),
+		Height:       req.GetFromHeight(),
+		UseAddrIndex: types.IsEnable("addrIndex"),
+	}
+	if status.UseAddrIndex {
+		status.Height = -1
+	}
+	return status, nil
+}
+
+func (wallet *Wallet) rescanAddrs() ([]string, error) {
+	var addrs []string
+	accStores, err := wallet.walletStore.GetAccountByPrefix("Account")
+	if err != nil && err != types.ErrAccountNotExist {
+		return nil, err
+	}
+	for _, acc := range accStores {
+		addrs = append(addrs, acc.Addr)
+	}
+	watches, err := wallet.walletStore.GetWatchAccounts()
+	if err != nil {
+		return nil, err
+	}
+	for _, watch := range watches {
+		addrs = append(addrs, watch.Addr)
+	}
+	if len(addrs) == 0 {
+		return nil, types.ErrAccountNotExist
+	}
+	return addrs, nil
+}
+
+//startRescan 保存进度之后在后台扫描，调用者持有wallet.mtx
+//扫描修改的是status的副本，返回的status不会被并发修改
+func (wallet *Wallet) startRescan(status *types.WalletRescanStatus) (*types.WalletRescanStatus, error) {
+	status.Status = rescanScanning
+	batch := wallet.walletStore.NewBatch(true)
+	wallet.walletStore.SetRescanStatus(status, batch)
+	err := batch.Write()
+	if err != nil {
+		return nil, err
+	}
+	job := &rescanJob{cancel: make(chan struct{}), done: make(chan struct{})}
+	wallet.rescanJob = job
+	scan := *status
+	wallet.wg.Add(1)
+	go wallet.rescan(&scan, job)
+	return status, nil
+}
+
+//cancelRescan 等待扫描退出之后保存取消的状态，已经完成或者失败的扫描保持原来的状态
+func (wallet *Wallet) cancelRescan() (*types.WalletRescanStatus, error) {
+	close(wallet.rescanJob.cancel)
+	<-wallet.rescanJob.done
+	status, err := wallet.walletStore.GetRescanStatus()
+	if err != nil {
+		return nil, err
+	}
+	if status.Status != rescanScanning {
+		return status, nil
+	}
+	status.Status = rescanCancelled
+	batch := wallet.walletStore.NewBatch(true)
+	wallet.walletStore.SetRescanStatus(status, batch)
+	return status, batch.Write()
+}
+
+//resumeRescan 钱包启动时继续上次关闭时中断的扫描
+func (wallet *Wallet) resumeRescan() {
+	wallet.mtx.Lock()
+	defer wallet.mtx.Unlock()
+
+	status, err := wallet.walletStore.GetRescanStatus()
+	if err != nil || status.Status != rescanScanning {
+		return
+	}
+	walletlog.Info("resumeRescan", "height", status.Height, "addrDone", status.AddrDone)
+	_, err = wallet.startRescan(status)
+	if err != nil {
+		walletlog.Error("resumeRescan", "err", err)
+	}
+}
+
+//rescan 不能获取wallet.mtx，取消扫描时持有wallet.mtx等待扫描退出
+func (wallet *Wallet) rescan(status *types.WalletRescanStatus, job *rescanJob) {
+	defer wallet.wg.Done()
+	defer close(job.done)
+
+	var err error
+	if status.UseAddrIndex {
+		err = wallet.rescanByAddrIndex(status, job.cancel)
+	} else {
+		err = wallet.rescanBlocks(status, job.cancel)
+	}
+	//取消或者钱包关闭时保持扫描中的状态，由取消的调用者或者下次启动时处理
+	//钱包关闭时查询可能先于取消检查返回错误，这时也不能记录为失败
+	if err == errRescanStopped || (err != nil && wallet.rescanStopped(job.cancel)) {
+		return
+	}
+	status.Status = rescanDone
+	if err != nil {
+		walletlog.Error("rescan", "height", status.Height, "err", err)
+		status.Status = rescanFailed
+	}
+	batch := wallet.walletStore.NewBatch(true)
+	wallet.walletStore.SetRescanStatus(status, batch)
+	err = batch.Write()
+	if err != nil {
+		walletlog.Error("rescan", "batch.Write err", err)
+	}
+	walletlog.Info("rescan", "status", status.Status, "txCount", status.TxCount)
+}
+
+func (wallet *Wallet) rescanStopped(cancel chan struct{}) bool {
+	select {
+	case <-cancel:
+		return true
+	case <-wallet.done:
+		return true
+	default:
+		return false
+	}
+}
+
+//rescanBlocks 从status.Height开始遍历区块，每批区块的交易记录和进度一起写入
+func (wallet *Wallet) rescanBlocks(status *types.WalletRescanStatus, cancel chan struct{}) error {
+	addrs := make(map[string]bool)
+	for _, addr := range status.Addrs {
+		addrs[addr] = true
+	}
+	for status.Height <= status.EndHeight {
+		if wallet.rescanStopped(cancel) {
+			return errRescanStopped
+		}
+		end := status.Height + rescanBlockBatch - 1
+		if end > status.EndHeight {
+			end = status.EndHeight
+		}
+		blocks, err := wallet.api.GetBlocks(&types.ReqBlocks{Start: status.Height, End: end, IsDetail: true})
+		if err != nil {
+			return err
+		}
+		batch := wallet.walletStore.NewBatch(true)
+		for _, block := range blocks.GetItems() {
+			status.TxCount += int64(wallet.rescanBlock(block, addrs, batch))
+		}
+		status.Height = end + 1
+		wallet.walletStore.SetRescanStatus(status, batch)
+		err = batch.Write()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//rescanBlock 和ProcWalletAddBlock一样生成from或者to是扫描地址的交易记录，返回交易数
+func (wallet *Wallet) rescanBlock(block *types.BlockDetail, addrs map[string]bool, batch dbm.Batch) int {
+	count := 0
+	for index, tx := range block.GetBlock().GetTxs() {
+		fromaddr := address.PubKeyToAddress(tx.GetSignature().GetPubkey()).String()
+		toaddr := tx.GetTo()
+		if !addrs[fromaddr] && !addrs[toaddr] {
+			continue
+		}
+		param := &buildStoreWalletTxDetailParam{
+			block:        block,
+			tx:           tx,
+			index:        index,
+			newbatch:     batch,
+			senderRecver: fromaddr,
+			addDelType:   AddTx,
+			sendRecvFlag: recvTx,
+			watchOnly:    wallet.isWatchOnlyTx(fromaddr, toaddr),
+		}
+		if addrs[fromaddr] {
+			param.sendRecvFlag = sendTx
+		}
+		wallet.buildAndStoreWalletTxDetail(param)
+		count++
+	}
+	return count
+}
+
+//rescanByAddrIndex 通过地址索引按地址扫描，跳过fromHeight之前的交易
+//ListHelper从不存在的key开始翻页时会跳过第一个交易，所以每个地址从第一个交易开始查询(height为-1)
+//之后从最后处理的交易继续翻页
+func (wallet *Wallet) rescanByAddrIndex(status *types.WalletRescanStatus, cancel chan struct{}) error {
+	for int(status.AddrDone) < len(status.Addrs) {
+		addr := status.Addrs[status.AddrDone]
+		for {
+			if wallet.rescanStopped(cancel) {
+				return errRescanStopped
+			}
+			req := &types.ReqAddr{Addr: addr, Count: rescanTxBatch, Direction: dbm.ListASC, Height: status.Height, Index: status.Index}
+			infos, err := wallet.api.GetTransactionByAddr(req)
+			//没有更多交易时返回ErrTxNotExist，见system/dapp/query.go
+			if err != nil && err != types.ErrTxNotExist {
+				return err
+			}
+			finished, err := wallet.rescanTxInfos(status, infos.GetTxInfos())
+			if err != nil {
+				return err
+			}
+			if finished {
+				break
+			}
+		}
+		status.AddrDone++
+		status.Height, status.Index = -1, 0
+		batch := wallet.walletStore.NewBatch(true)
+		wallet.walletStore.SetRescanStatus(status, batch)
+		err := batch.Write()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//rescanTxInfos 查询一页交易的详细信息并保存，返回当前地址是否已经扫描完成
+func (wallet *Wallet) rescanTxInfos(status *types.WalletRescanStatus, infos []*types.ReplyTxInfo) (bool, error) {
+	//之后的区块中的交易由ProcWalletAddBlock处理
+	finished := len(infos) < rescanTxBatch
+	hashes := &types.ReqHashes{}
+	for _, info := range infos {
+		if info.GetHeight() > status.EndHeight {
+			finished = true
+			break
+		}
+		status.Height, status.Index = info.GetHeight(), info.GetIndex()
+		if info.GetHeight() >= status.FromHeight {
+			hashes.Hashes = append(hashes.Hashes, info.GetHash())
+		}
+	}
+	batch := wallet.walletStore.NewBatch(true)
+	if len(hashes.Hashes) > 0 {
+		details, err := wallet.api.GetTransactionByHash(hashes)
+		if err != nil {
+			return false, err
+		}
+		err = wallet.setTxDetails(details.GetTxs(), batch)
+		if err != nil {
+			return false, err
+		}
+		status.TxCount += int64(len(details.GetTxs()))
+	}
+	wallet.walletStore.SetRescanStatus(status, batch)
+	return finished, batch.Write()
+}
+
+//isWatchOnlyTx from和to都不是钱包中的地址时是只读账户的交易
+func (wallet *Wallet) isWatchOnlyTx(fromaddr, toaddr string) bool {
+	return !wallet.AddrInWallet(fromaddr) && !wallet.AddrInWallet(toaddr)
+}
@@ -15,7 +15,7 @@ import (
 	"encoding/json"
 	"fmt"
 
-	//	"math/big"
+	"math/big"
 	"strings"
 
 	log "github.com/33cn/chain33/common/log/log15"
@@ -26,6 +26,8 @@ import (
 	"github.com/33cn/chain33/common"
 	dbm "github.com/33cn/chain33/common/db"
 	"github.com/33cn/chain33/types"
+	wcom "github.com/33cn/chain33/wallet/common"
+	"github.com/tjfoc/gmsm/sm2"
 )
 
 var (
@@ -81,44 +83,82 @@ func VerifySeed(seed string) (bool, error) {
 	return true, nil
 }
 
-//使用password加密seed存储到db中
+//使用password加密seed存储到db中，同时保存密钥的派生参数
 func SaveSeed(db dbm.DB, seed string, password string) (bool, error) {
 	if len(seed) == 0 || len(password) == 0 {
 		return false, types.ErrInvalidParam
 	}
-
-	Encrypted, err := AesgcmEncrypter([]byte(password), []byte(seed))
+	ks, err := wcom.NewKeyStore([]byte(password))
 	if err != nil {
-		seedlog.Error("SaveSeed", "AesgcmEncrypter err", err)
+		seedlog.Error("SaveSeed", "NewKeyStore err", err)
+		return false, err
+	}
+	batch := db.NewBatch(true)
+	ok, err := SaveSeedInBatch(db, seed, ks, batch)
+	if !ok {
 		return false, err
 	}
-	err = db.SetSync(WalletSeed, Encrypted)
+	err = wcom.NewStore(db).SetKeyStore(ks, batch)
+	if err != nil {
+		return false, err
+	}
+	err = batch.Write()
 	if err != nil {
 		return false, err
 	}
 	return true, nil
 }
 
-func SaveSeedInBatch(db dbm.DB, seed string, password string, batch dbm.Batch) (bool, error) {
-	if len(seed) == 0 || len(password) == 0 {
+func SaveSeedInBatch(db dbm.DB, seed string, ks *wcom.KeyStore, batch dbm.Batch) (bool, error) {
+	if len(seed) == 0 || ks == nil {
 		return false, types.ErrInvalidParam
 	}
 
-	Encrypted, err := AesgcmEncrypter([]byte(password), []byte(seed))
+	Encrypted, err := ks.Encrypt([]byte(seed))
 	if err != nil {
-		seedlog.Error("SaveSeed", "AesgcmEncrypter err", err)
+		seedlog.Error("SaveSeed", "Encrypt err", err)
 		return false, err
 	}
 	batch.Set(WalletSeed, Encrypted)
-	//seedlog.Info("SaveSeed ok", "Encryptedseed", Encryptedseed)
 	return true, nil
 }
 
-//使用password解密seed上报给上层
+//使用password解密seed上报给上层，升级之前的钱包没有保存密钥，使用旧的加密方式
 func GetSeed(db dbm.DB, password string) (string, error) {
 	if len(password) == 0 {
 		return "", types.ErrInvalidParam
 	}
+	ks, err := wcom.NewStore(db).GetKeyStore()
+	if err == types.ErrKeyStoreNotExist {
+		return getLegacySeed(db, password)
+	}
+	if err != nil {
+		return "", err
+	}
+	err = ks.Unlock([]byte(password))
+	if err != nil {
+		return "", err
+	}
+	return GetSeedByKeyStore(db, ks)
+}
+
+//使用已经派生的密钥解密seed
+func GetSeedByKeyStore(db dbm.DB, ks *wcom.KeyStore) (string, error) {
+	Encryptedseed, err := db.Get(WalletSeed)
+	if err != nil {
+		return "", err
+	}
+	if len(Encryptedseed) == 0 {
+		return "", types.ErrSeedNotExist
+	}
+	seed, err := ks.Decrypt(Encryptedseed)
+	if err != nil {
+		return "", err
+	}
+	return string(seed), nil
+}
+
+func getLegacySeed(db dbm.DB, password string) (string, error) {
 	Encryptedseed, err := db.Get(WalletSeed)
 	if err != nil {
 		return "", err
@@ -133,14 +173,44 @@ func GetSeed(db dbm.DB, password string) (string, error) {
 	return string(seed), nil
 }
 
-//通过seed生成子私钥十六进制字符串
-func GetPrivkeyBySeed(db dbm.DB, seed string) (string, error) {
+//每种签名类型使用单独的索引，钱包配置的签名类型沿用升级之前的BACKUPKEYINDEX
+func calcBackupKeyIndex(signType int) []byte {
+	if signType == SignType {
+		return []byte(BACKUPKEYINDEX)
+	}
+	return []byte(fmt.Sprintf("%s-%d", BACKUPKEYINDEX, signType))
+}
+
+//newHDWallet 助记词形式的seed按bip39生成HD钱包，不是助记词时直接作为seed
+func newHDWallet(seed string) (*bipwallet.HDWallet, error) {
+	wallet, err := bipwallet.NewWalletFromMnemonic(bipwallet.TypeBty, seed)
+	if err != nil {
+		seedlog.Error("newHDWallet NewWalletFromMnemonic", "err", err)
+		wallet, err = bipwallet.NewWalletFromSeed(bipwallet.TypeBty, []byte(seed))
+		if err != nil {
+			seedlog.Error("newHDWallet NewWalletFromSeed", "err", err)
+			return nil, types.ErrNewWalletFromSeed
+		}
+	}
+	return wallet, nil
+}
+
+//validSM2Key sm2的私钥必须在[1, n-2]之间，bip44按secp256k1的阶生成的私钥可能超出sm2的范围
+func validSM2Key(priv []byte) bool {
+	d := new(big.Int).SetBytes(priv)
+	max := new(big.Int).Sub(sm2.P256Sm2().Params().N, big.NewInt(1))
+	return d.Sign() > 0 && d.Cmp(max) < 0
+}
+
+//通过seed生成signType类型的子私钥十六进制字符串
+//secp256k1使用bip44路径m/44'/coin'/0'/0/index，sm2使用m/44'/coin'/2'/0/index，同一个seed生成的私钥互不相同
+func GetPrivkeyBySeed(db dbm.DB, seed string, signType int) (string, error) {
 	var backupindex uint32
 	var Hexsubprivkey string
 	var err error
 	var index uint32
 	//通过主私钥随机生成child私钥十六进制字符串
-	backuppubkeyindex, err := db.Get([]byte(BACKUPKEYINDEX))
+	backuppubkeyindex, err := db.Get(calcBackupKeyIndex(signType))
 	if backuppubkeyindex == nil || err != nil {
 		index = 0
 	} else {
@@ -150,39 +220,44 @@ func GetPrivkeyBySeed(db dbm.DB, seed string) (string, error) {
 		index = backupindex + 1
 	}
 
-	//secp256k1
-	if SignType == 1 {
+	if signType == types.SECP256K1 || signType == types.SM2 {
 
-		wallet, err := bipwallet.NewWalletFromMnemonic(bipwallet.TypeBty, seed)
+		wallet, err := newHDWallet(seed)
 		if err != nil {
-			seedlog.Error("GetPrivkeyBySeed NewWalletFromMnemonic", "err", err)
-			wallet, err = bipwallet.NewWalletFromSeed(bipwallet.TypeBty, []byte(seed))
-			if err != nil {
-				seedlog.Error("GetPrivkeyBySeed NewWalletFromSeed", "err", err)
-				return "", types.ErrNewWalletFromSeed
-			}
+			return "", err
 		}
 
-		//通过索引生成Key pair
-		priv, pub, err := wallet.NewKeyPair(index)
-		if err != nil {
-			seedlog.Error("GetPrivkeyBySeed NewKeyPair", "err", err)
-			return "", types.ErrNewKeyPair
+		//通过索引生成Key pair，sm2跳过私钥超出范围的索引
+		var priv, pub []byte
+		for {
+			priv, pub, err = wallet.NewKeyPairByAccount(uint32(signType-1), index)
+			if err != nil {
+				seedlog.Error("GetPrivkeyBySeed NewKeyPair", "err", err)
+				return "", types.ErrNewKeyPair
+			}
+			if signType != types.SM2 || validSM2Key(priv) {
+				break
+			}
+			seedlog.Info("GetPrivkeyBySeed skip invalid sm2 key", "index", index)
+			index++
 		}
 
 		Hexsubprivkey = hex.EncodeToString(priv)
 
-		public, err := bipwallet.PrivkeyToPub(bipwallet.TypeBty, priv)
-		if err != nil {
-			seedlog.Error("GetPrivkeyBySeed PrivkeyToPub", "err", err)
-			return "", types.ErrPrivkeyToPub
-		}
-		if !bytes.Equal(pub, public) {
-			seedlog.Error("GetPrivkeyBySeed NewKeyPair pub  != PrivkeyToPub", "err", err)
-			return "", types.ErrSubPubKeyVerifyFail
+		//bip44生成的公钥是secp256k1的公钥，sm2的公钥由私钥重新计算
+		if signType == types.SECP256K1 {
+			public, err := bipwallet.PrivkeyToPub(bipwallet.TypeBty, priv)
+			if err != nil {
+				seedlog.Error("GetPrivkeyBySeed PrivkeyToPub", "err", err)
+				return "", types.ErrPrivkeyToPub
+			}
+			if !bytes.Equal(pub, public) {
+				seedlog.Error("GetPrivkeyBySeed NewKeyPair pub  != PrivkeyToPub", "err", err)
+				return "", types.ErrSubPubKeyVerifyFail
+			}
 		}
 
-	} else if SignType == 2 { //ed25519
+	} else if signType == types.ED25519 {
 
 		//通过助记词形式的seed生成私钥和公钥,一个seed根据不同的index可以生成许多组密钥
 		//字符串形式的助记词(英语单词)通过计算一次hash转成字节形式的seed
@@ -197,8 +272,6 @@ func GetPrivkeyBySeed(db dbm.DB, seed string) (string, error) {
 		//seedlog.Error("GetPrivkeyBySeed", "index", index, "secretKey", secretKey, "publicKey", publicKey)
 
 		Hexsubprivkey = secretKey
-	} else if SignType == 3 { //sm2
-		return "", types.ErrNotSupport
 	} else {
 		return "", types.ErrNotSupport
 	}
@@ -210,11 +283,12 @@ func GetPrivkeyBySeed(db dbm.DB, seed string) (string, error) {
 		return "", types.ErrMarshal
 	}
 
-	db.SetSync([]byte(BACKUPKEYINDEX), pubkeyindex)
+	db.SetSync(calcBackupKeyIndex(signType), pubkeyindex)
 	//seedlog.Info("GetPrivkeyBySeed", "Hexsubprivkey", Hexsubprivkey, "index", index)
 	return Hexsubprivkey, nil
 }
 
+//旧版本的钱包使用的加密方式，只用于升级之前的seed的解密
 //使用钱包的password对seed进行aesgcm加密,返回加密后的seed
 func AesgcmEncrypter(password []byte, seed []byte) ([]byte, error) {
 	key := make([]byte, 32)
@@ -51,7 +51,7 @@ func (wallet *Wallet) getAllPrivKeys() ([]crypto.PrivKey, error) {
 	}
 	var privs []crypto.PrivKey
 	for _, acc := range accounts {
-		priv, err := wallet.getPrivKeyByAddr(acc.Addr)
+		priv, _, err := wallet.getPrivKeyByAddr(acc.Addr)
 		if err != nil {
 			return nil, err
 		}
@@ -73,7 +73,7 @@ func (wallet *Wallet) GetHeight() int64 {
 }
 
 func (wallet *Wallet) sendTransactionWait(payload types.Message, execer []byte, priv crypto.PrivKey, to string) (err error) {
-	hash, err := wallet.sendTransaction(payload, execer, priv, to)
+	hash, err := wallet.sendTransaction(payload, execer, priv, wallet.keySignType(priv), to)
 	if err != nil {
 		return err
 	}
@@ -85,21 +85,24 @@ func (wallet *Wallet) sendTransactionWait(payload types.Message, execer []byte,
 }
 
 func (wallet *Wallet) SendTransaction(payload types.Message, execer []byte, priv crypto.PrivKey, to string) (hash []byte, err error) {
-	return wallet.sendTransaction(payload, execer, priv, to)
+	return wallet.sendTransaction(payload, execer, priv, wallet.keySignType(priv), to)
 }
 
-func (wallet *Wallet) sendTransaction(payload types.Message, execer []byte, priv crypto.PrivKey, to string) (hash []byte, err error) {
+func (wallet *Wallet) sendTransaction(payload types.Message, execer []byte, priv crypto.PrivKey, signType int32, to string) (hash []byte, err error) {
 	if to == "" {
 		to = address.ExecAddress(string(execer))
 	}
 	tx := &types.Transaction{Execer: execer, Payload: types.Encode(payload), Fee: minFee, To: to}
-	tx.Nonce = rand.Int63()
+	tx.Nonce, err = wallet.getTxNonce(address.PubKeyToAddress(priv.PubKey().Bytes()).String())
+	if err != nil {
+		return nil, err
+	}
 	tx.Fee, err = tx.GetRealFee(wallet.getFee())
 	if err != nil {
 		return nil, err
 	}
 	tx.SetExpire(time.Second * 120)
-	tx.Sign(int32(SignType), priv)
+	tx.Sign(signType, priv)
 	reply, err := wallet.sendTx(tx)
 	if err != nil {
 		return nil, err
@@ -169,7 +172,7 @@ func (wallet *Wallet) queryTx(hash []byte) (*types.TransactionDetail, error) {
 	return resp.Data.(*types.TransactionDetail), nil
 }
 func (wallet *Wallet) SendToAddress(priv crypto.PrivKey, addrto string, amount int64, note string, Istoken bool, tokenSymbol string) (*types.ReplyHash, error) {
-	return wallet.sendToAddress(priv, addrto, amount, note, Istoken, tokenSymbol)
+	return wallet.sendToAddress(priv, wallet.keySignType(priv), addrto, amount, note, Istoken, tokenSymbol)
 }
 
 func (wallet *Wallet) createSendToAddress(addrto string, amount int64, note string, Istoken bool, tokenSymbol string) (*types.Transaction, error) {
@@ -218,12 +221,16 @@ func (wallet *Wallet) createSendToAddress(addrto string, amount int64, note stri
 	return tx, nil
 }
 
-func (wallet *Wallet) sendToAddress(priv crypto.PrivKey, addrto string, amount int64, note string, Istoken bool, tokenSymbol string) (*types.ReplyHash, error) {
+func (wallet *Wallet) sendToAddress(priv crypto.PrivKey, signType int32, addrto string, amount int64, note string, Istoken bool, tokenSymbol string) (*types.ReplyHash, error) {
 	tx, err := wallet.createSendToAddress(addrto, amount, note, Istoken, tokenSymbol)
 	if err != nil {
 		return nil, err
 	}
-	tx.Sign(int32(SignType), priv)
+	tx.Nonce, err = wallet.getTxNonce(address.PubKeyToAddress(priv.PubKey().Bytes()).String())
+	if err != nil {
+		return nil, err
+	}
+	tx.Sign(signType, priv)
 
 	reply, err := wallet.api.SendTx(tx)
 	if err != nil {
@@ -237,6 +244,18 @@ func (wallet *Wallet) sendToAddress(priv crypto.PrivKey, addrto string, amount i
 	return &hash, nil
 }
 
+//getTxNonce 开启sequentialNonce时从Mempool获取账户下一笔交易的nonce，否则nonce只用于去重
+func (wallet *Wallet) getTxNonce(addr string) (int64, error) {
+	if !types.IsEnable("sequentialNonce") {
+		return rand.Int63(), nil
+	}
+	reply, err := wallet.api.GetAccountNonce(&types.ReqString{Data: addr})
+	if err != nil {
+		return 0, err
+	}
+	return reply.Data, nil
+}
+
 func (wallet *Wallet) queryBalance(in *types.ReqBalance) ([]*types.Account, error) {
 
 	switch in.GetExecer() {
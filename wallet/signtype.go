@@ -0,0 +1,71 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wallet
+
+import (
+	"github.com/33cn/chain33/common/address"
+	"github.com/33cn/chain33/common/crypto"
+	"github.com/33cn/chain33/types"
+)
+
+//每个账户保存自己的签名类型，同一个钱包可以同时有secp256k1, ed25519和sm2的账户
+//升级之前的账户没有保存签名类型，使用钱包配置的SignType
+
+var accountSignTypes = []int{types.SECP256K1, types.ED25519, types.SM2}
+
+//checkSignType 请求中的签名类型，0表示钱包配置的签名类型
+func checkSignType(signType int32) (int, error) {
+	if signType == 0 {
+		return SignType, nil
+	}
+	for _, ty := range accountSignTypes {
+		if int(signType) == ty {
+			return ty, nil
+		}
+	}
+	return 0, types.ErrNotSupport
+}
+
+//accountSignType 账户的签名类型
+func accountSignType(acc *types.WalletAccountStore) int {
+	if acc.GetSignType() == 0 {
+		return SignType
+	}
+	return int(acc.GetSignType())
+}
+
+//keySignType 插件传入的私钥没有签名类型，使用私钥所属账户的签名类型，不是钱包中的账户时使用SignType
+func (wallet *Wallet) keySignType(priv crypto.PrivKey) int32 {
+	addr := address.PubKeyToAddress(priv.PubKey().Bytes()).String()
+	acc, err := wallet.walletStore.GetAccountByAddr(addr)
+	if err != nil {
+		return int32(SignType)
+	}
+	return int32(accountSignType(acc))
+}
+
+//privKeyFromBytes 按签名类型生成私钥对象和对应的地址
+func privKeyFromBytes(signType int, privkey []byte) (crypto.PrivKey, string, error) {
+	cr, err := crypto.New(types.GetSignName("", signType))
+	if err != nil {
+		return nil, "", err
+	}
+	priv, err := cr.PrivKeyFromBytes(privkey)
+	if err != nil {
+		walletlog.Error("privKeyFromBytes", "signType", signType, "err", err)
+		return nil, "", types.ErrPrivkeyToPub
+	}
+	return priv, address.PubKeyToAddress(priv.PubKey().Bytes()).String(), nil
+}
+
+//accountPrivKey 解密账户的私钥，按账户的签名类型生成私钥对象
+func (wallet *Wallet) accountPrivKey(acc *types.WalletAccountStore) (crypto.PrivKey, error) {
+	privkey, err := wallet.decryptPrivkey(acc.GetPrivkey())
+	if err != nil {
+		return nil, err
+	}
+	priv, _, err := privKeyFromBytes(accountSignType(acc), privkey)
+	return priv, err
+}
@@ -14,7 +14,6 @@ import (
 
 	"github.com/33cn/chain33/account"
 	"github.com/33cn/chain33/client"
-	"github.com/33cn/chain33/common"
 	"github.com/33cn/chain33/common/address"
 	"github.com/33cn/chain33/common/crypto"
 	dbm "github.com/33cn/chain33/common/db"
@@ -60,6 +59,7 @@ type Wallet struct {
 	isWalletLocked     int32
 	fatalFailureFlag   int32
 	Password           string
+	keystore           *wcom.KeyStore
 	FeeAmount          int64
 	EncryptFlag        int64
 	wg                 *sync.WaitGroup
@@ -68,6 +68,7 @@ type Wallet struct {
 	cfg                *types.Wallet
 	done               chan struct{}
 	rescanwg           *sync.WaitGroup
+	rescanJob          *rescanJob
 	lastHeader         *types.Header
 }
 
@@ -141,10 +142,6 @@ func (wallet *Wallet) GetSignType() int {
 	return SignType
 }
 
-func (wallet *Wallet) GetPassword() string {
-	return wallet.Password
-}
-
 func (wallet *Wallet) Nonce() int64 {
 	return wallet.random.Int63()
 }
@@ -241,6 +238,7 @@ func (wallet *Wallet) SetQueueClient(cli queue.Client) {
 	for _, policy := range wcom.PolicyContainer {
 		policy.OnSetQueueClient()
 	}
+	wallet.resumeRescan()
 }
 
 func (wallet *Wallet) GetAccountByAddr(addr string) (*types.WalletAccountStore, error) {
@@ -252,37 +250,30 @@ func (wallet *Wallet) SetWalletAccount(update bool, addr string, account *types.
 }
 
 func (wallet *Wallet) GetPrivKeyByAddr(addr string) (crypto.PrivKey, error) {
-	return wallet.getPrivKeyByAddr(addr)
+	priv, _, err := wallet.getPrivKeyByAddr(addr)
+	return priv, err
 }
 
-func (wallet *Wallet) getPrivKeyByAddr(addr string) (crypto.PrivKey, error) {
+//getPrivKeyByAddr 返回账户的私钥和签名类型
+func (wallet *Wallet) getPrivKeyByAddr(addr string) (crypto.PrivKey, int32, error) {
 	//获取指定地址在钱包里的账户信息
 	Accountstor, err := wallet.walletStore.GetAccountByAddr(addr)
 	if err != nil {
 		walletlog.Error("ProcSendToAddress", "GetAccountByAddr err:", err)
-		return nil, err
-	}
-
-	//通过password解密存储的私钥
-	prikeybyte, err := common.FromHex(Accountstor.GetPrivkey())
-	if err != nil || len(prikeybyte) == 0 {
-		walletlog.Error("ProcSendToAddress", "FromHex err", err)
-		return nil, err
+		//只读账户没有私钥，交易需要在冷钱包中签名
+		if wallet.getWatchAccount(addr) != nil {
+			return nil, 0, types.ErrWatchOnly
+		}
+		return nil, 0, err
 	}
 
-	privkey := wcom.CBCDecrypterPrivkey([]byte(wallet.Password), prikeybyte)
-	//通过privkey生成一个pubkey然后换算成对应的addr
-	cr, err := crypto.New(types.GetSignName("", SignType))
+	//通过钱包的密钥解密存储的私钥，按账户的签名类型生成私钥
+	priv, err := wallet.accountPrivKey(Accountstor)
 	if err != nil {
-		walletlog.Error("ProcSendToAddress", "err", err)
-		return nil, err
-	}
-	priv, err := cr.PrivKeyFromBytes(privkey)
-	if err != nil {
-		walletlog.Error("ProcSendToAddress", "PrivKeyFromBytes err", err)
-		return nil, err
+		walletlog.Error("ProcSendToAddress", "accountPrivKey err", err)
+		return nil, 0, err
 	}
-	return priv, nil
+	return priv, int32(accountSignType(Accountstor)), nil
 }
 
 //外部已经加了lock
@@ -359,6 +350,7 @@ func (wallet *Wallet) GetWalletStatus() *types.WalletStatus {
 	s.IsHasSeed, _ = wallet.walletStore.HasSeed()
 	s.IsAutoMining = wallet.isAutoMinning()
 	s.IsTicketLock = wallet.isTicketLocked()
+	s.Rescan, _ = wallet.walletStore.GetRescanStatus()
 
 	walletlog.Debug("GetWalletStatus", "walletstatus", s)
 	return s
@@ -58,6 +58,30 @@ func (wallet *Wallet) On_WalletImportPrivkey(req *types.ReqWalletImportPrivkey)
 	return reply, err
 }
 
+func (wallet *Wallet) On_WalletImportWatch(req *types.ReqWalletImportWatch) (types.Message, error) {
+	reply, err := wallet.ProcImportWatch(req)
+	if err != nil {
+		walletlog.Error("ProcImportWatch", "err", err.Error())
+	}
+	return reply, err
+}
+
+func (wallet *Wallet) On_WalletExportXpub(req *types.ReqNil) (types.Message, error) {
+	reply, err := wallet.ProcExportXpub()
+	if err != nil {
+		walletlog.Error("ProcExportXpub", "err", err.Error())
+	}
+	return reply, err
+}
+
+func (wallet *Wallet) On_WalletRescan(req *types.ReqWalletRescan) (types.Message, error) {
+	reply, err := wallet.ProcWalletRescan(req)
+	if err != nil {
+		walletlog.Error("ProcWalletRescan", "err", err.Error())
+	}
+	return reply, err
+}
+
 func (wallet *Wallet) On_WalletSendToAddress(req *types.ReqWalletSendToAddress) (types.Message, error) {
 	reply, err := wallet.ProcSendToAddress(req)
 	if err != nil {
@@ -5,6 +5,7 @@
 package wallet
 
 import (
+	"bytes"
 	"encoding/hex"
 	"fmt"
 	"strings"
@@ -21,7 +22,6 @@ import (
 	dbm "github.com/33cn/chain33/common/db"
 	cty "github.com/33cn/chain33/system/dapp/coins/types"
 	"github.com/33cn/chain33/types"
-	"github.com/33cn/chain33/wallet/bipwallet"
 	wcom "github.com/33cn/chain33/wallet/common"
 	"github.com/golang/protobuf/proto"
 )
@@ -77,12 +77,13 @@ func (wallet *Wallet) ProcSignRawTx(unsigned *types.ReqSignRawTx) (string, error
 	}
 
 	var key crypto.PrivKey
+	signType := int32(SignType)
 	if unsigned.GetAddr() != "" {
 		ok, err := wallet.CheckWalletStatus()
 		if !ok {
 			return "", err
 		}
-		key, err = wallet.getPrivKeyByAddr(unsigned.GetAddr())
+		key, signType, err = wallet.getPrivKeyByAddr(unsigned.GetAddr())
 		if err != nil {
 			return "", err
 		}
@@ -112,11 +113,18 @@ func (wallet *Wallet) ProcSignRawTx(unsigned *types.ReqSignRawTx) (string, error
 	if err != nil {
 		return "", err
 	}
-	expire, err := wallet.parseExpire(unsigned.GetExpire())
+	group, err := tx.GetTxGroup()
 	if err != nil {
 		return "", err
 	}
-	tx.SetExpire(time.Duration(expire))
+	//已经有多重签名的部分签名时不能再修改过期时间，否则之前的签名失效
+	if !hasMultiSig(&tx, group) {
+		expire, err := wallet.parseExpire(unsigned.GetExpire())
+		if err != nil {
+			return "", err
+		}
+		tx.SetExpire(time.Duration(expire))
+	}
 	if policy, ok := wcom.PolicyContainer[string(tx.Execer)]; ok {
 		// 尝试让策略自己去完成签名
 		needSysSign, signtx, err := policy.SignTransaction(key, unsigned)
@@ -125,12 +133,15 @@ func (wallet *Wallet) ProcSignRawTx(unsigned *types.ReqSignRawTx) (string, error
 		}
 	}
 
-	group, err := tx.GetTxGroup()
-	if err != nil {
-		return "", err
-	}
 	if group == nil {
-		tx.Sign(int32(SignType), key)
+		err = setMultiSigFee(&tx, unsigned.GetMultiSig())
+		if err != nil {
+			return "", err
+		}
+		err = signTx(&tx, key, signType, unsigned.GetMultiSig())
+		if err != nil {
+			return "", err
+		}
 		txHex := types.Encode(&tx)
 		signedTx := hex.EncodeToString(txHex)
 		return signedTx, nil
@@ -138,9 +149,16 @@ func (wallet *Wallet) ProcSignRawTx(unsigned *types.ReqSignRawTx) (string, error
 	if int(index) > len(group.GetTxs()) {
 		return "", types.ErrIndex
 	}
+	err = setGroupMultiSigFee(group, index, unsigned.GetMultiSig())
+	if err != nil {
+		return "", err
+	}
 	if index <= 0 {
 		for i := range group.Txs {
-			group.SignN(i, int32(SignType), key)
+			err = signTx(group.Txs[i], key, signType, unsigned.GetMultiSig())
+			if err != nil {
+				return "", err
+			}
 		}
 		grouptx := group.Tx()
 		txHex := types.Encode(grouptx)
@@ -148,7 +166,10 @@ func (wallet *Wallet) ProcSignRawTx(unsigned *types.ReqSignRawTx) (string, error
 		return signedTx, nil
 	}
 	index--
-	group.SignN(int(index), int32(SignType), key)
+	err = signTx(group.Txs[index], key, signType, unsigned.GetMultiSig())
+	if err != nil {
+		return "", err
+	}
 	grouptx := group.Tx()
 	txHex := types.Encode(grouptx)
 	signedTx := hex.EncodeToString(txHex)
@@ -168,12 +189,28 @@ func (wallet *Wallet) ProcGetAccountList(req *types.ReqAccountList) (*types.Wall
 
 	//通过Account前缀查找获取钱包中的所有账户信息
 	WalletAccStores, err := wallet.walletStore.GetAccountByPrefix("Account")
-	if err != nil || len(WalletAccStores) == 0 {
+	if err != nil && err != types.ErrAccountNotExist {
+		walletlog.Info("ProcGetAccountList", "GetAccountByPrefix:err", err)
+		return nil, err
+	}
+	//只读账户排在后面，已经导入私钥的地址不再作为只读账户
+	watches, werr := wallet.walletStore.GetWatchAccounts()
+	if werr != nil {
+		walletlog.Error("ProcGetAccountList", "GetWatchAccounts:err", werr)
+		return nil, werr
+	}
+	watchIndex := len(WalletAccStores)
+	for _, watch := range watches {
+		if !wallet.AddrInWallet(watch.Addr) {
+			WalletAccStores = append(WalletAccStores, &types.WalletAccountStore{Addr: watch.Addr, Label: watch.Label})
+		}
+	}
+	if len(WalletAccStores) == 0 {
 		walletlog.Info("ProcGetAccountList", "GetAccountByPrefix:err", err)
 		return nil, err
 	}
 	if req.WithoutBalance {
-		return makeAccountWithoutBalance(WalletAccStores)
+		return makeAccountWithoutBalance(WalletAccStores, watchIndex)
 	}
 
 	addrs := make([]string, len(WalletAccStores))
@@ -205,12 +242,14 @@ func (wallet *Wallet) ProcGetAccountList(req *types.ReqAccountList) (*types.Wall
 		}
 		WalletAccount.Acc = Account
 		WalletAccount.Label = WalletAccStores[index].GetLabel()
+		WalletAccount.WatchOnly = index >= watchIndex
 		WalletAccounts.Wallets[index] = &WalletAccount
 	}
 	return &WalletAccounts, nil
 }
 
-func makeAccountWithoutBalance(accountStores []*types.WalletAccountStore) (*types.WalletAccounts, error) {
+//watchIndex之后的是只读账户
+func makeAccountWithoutBalance(accountStores []*types.WalletAccountStore, watchIndex int) (*types.WalletAccounts, error) {
 	var WalletAccounts types.WalletAccounts
 	WalletAccounts.Wallets = make([]*types.WalletAccount, len(accountStores))
 
@@ -222,6 +261,7 @@ func makeAccountWithoutBalance(accountStores []*types.WalletAccountStore) (*type
 		}
 		WalletAccount.Acc = &types.Account{Addr: account.Addr}
 		WalletAccount.Label = account.GetLabel()
+		WalletAccount.WatchOnly = index >= watchIndex
 		WalletAccounts.Wallets[index] = &WalletAccount
 	}
 	return &WalletAccounts, nil
@@ -264,16 +304,13 @@ func (wallet *Wallet) ProcCreateNewAccount(Label *types.ReqNewAccount) (*types.W
 	var Account types.Account
 	var walletAccount types.WalletAccount
 	var WalletAccStore types.WalletAccountStore
-	var cointype uint32
 	var addr string
 	var privkeybyte []byte
 
-	if SignType == 1 {
-		cointype = bipwallet.TypeBty
-	} else if SignType == 2 {
-		cointype = bipwallet.TypeYcc
-	} else {
-		cointype = bipwallet.TypeBty
+	signType, err := checkSignType(Label.GetSignType())
+	if err != nil {
+		walletlog.Error("ProcCreateNewAccount", "signType", Label.GetSignType(), "err", err)
+		return nil, err
 	}
 
 	//通过seed获取私钥, 首先通过钱包密码解锁seed然后通过seed生成私钥
@@ -284,7 +321,7 @@ func (wallet *Wallet) ProcCreateNewAccount(Label *types.ReqNewAccount) (*types.W
 	}
 
 	for {
-		privkeyhex, err := GetPrivkeyBySeed(wallet.walletStore.GetDB(), seed)
+		privkeyhex, err := GetPrivkeyBySeed(wallet.walletStore.GetDB(), seed, signType)
 		if err != nil {
 			walletlog.Error("ProcCreateNewAccount", "GetPrivkeyBySeed err", err)
 			return nil, err
@@ -295,15 +332,10 @@ func (wallet *Wallet) ProcCreateNewAccount(Label *types.ReqNewAccount) (*types.W
 			return nil, err
 		}
 
-		pub, err := bipwallet.PrivkeyToPub(cointype, privkeybyte)
-		if err != nil {
-			seedlog.Error("ProcCreateNewAccount PrivkeyToPub", "err", err)
-			return nil, types.ErrPrivkeyToPub
-		}
-		addr, err = bipwallet.PubToAddress(cointype, pub)
+		_, addr, err = privKeyFromBytes(signType, privkeybyte)
 		if err != nil {
-			seedlog.Error("ProcCreateNewAccount PubToAddress", "err", err)
-			return nil, types.ErrPrivkeyToPub
+			seedlog.Error("ProcCreateNewAccount privKeyFromBytes", "err", err)
+			return nil, err
 		}
 		//通过新生成的账户地址查询钱包数据库，如果查询返回的账户信息是空，
 		//说明新生成的账户没有被使用，否则继续使用下一个index生成私钥对
@@ -321,11 +353,15 @@ func (wallet *Wallet) ProcCreateNewAccount(Label *types.ReqNewAccount) (*types.W
 	walletAccount.Acc = &Account
 	walletAccount.Label = Label.GetLabel()
 
-	//使用钱包的password对私钥加密 aes cbc
-	Encrypted := wcom.CBCEncrypterPrivkey([]byte(wallet.Password), privkeybyte)
-	WalletAccStore.Privkey = common.ToHex(Encrypted)
+	//使用钱包的密钥对私钥加密
+	WalletAccStore.Privkey, err = wallet.encryptPrivkey(privkeybyte)
+	if err != nil {
+		walletlog.Error("ProcCreateNewAccount", "encryptPrivkey err", err)
+		return nil, err
+	}
 	WalletAccStore.Label = Label.GetLabel()
 	WalletAccStore.Addr = addr
+	WalletAccStore.SignType = int32(signType)
 
 	//存储账户信息到wallet数据库中
 	err = wallet.walletStore.SetWalletAccount(false, Account.Addr, &WalletAccStore)
@@ -418,13 +454,10 @@ func (wallet *Wallet) ProcImportPrivKey(PrivKey *types.ReqWalletImportPrivkey) (
 		return nil, types.ErrLabelHasUsed
 	}
 
-	var cointype uint32
-	if SignType == 1 {
-		cointype = bipwallet.TypeBty
-	} else if SignType == 2 {
-		cointype = bipwallet.TypeYcc
-	} else {
-		cointype = bipwallet.TypeBty
+	signType, err := checkSignType(PrivKey.GetSignType())
+	if err != nil {
+		walletlog.Error("ProcImportPrivKey", "signType", PrivKey.GetSignType(), "err", err)
+		return nil, err
 	}
 
 	privkeybyte, err := common.FromHex(PrivKey.Privkey)
@@ -433,24 +466,24 @@ func (wallet *Wallet) ProcImportPrivKey(PrivKey *types.ReqWalletImportPrivkey) (
 		return nil, types.ErrFromHex
 	}
 
-	pub, err := bipwallet.PrivkeyToPub(cointype, privkeybyte)
+	_, addr, err := privKeyFromBytes(signType, privkeybyte)
 	if err != nil {
-		seedlog.Error("ProcImportPrivKey PrivkeyToPub", "err", err)
-		return nil, types.ErrPrivkeyToPub
-	}
-	addr, err := bipwallet.PubToAddress(cointype, pub)
-	if err != nil {
-		seedlog.Error("ProcImportPrivKey PrivkeyToPub", "err", err)
-		return nil, types.ErrPrivkeyToPub
+		seedlog.Error("ProcImportPrivKey privKeyFromBytes", "err", err)
+		return nil, err
 	}
 
 	//对私钥加密
-	Encryptered := wcom.CBCEncrypterPrivkey([]byte(wallet.Password), privkeybyte)
-	Encrypteredstr := common.ToHex(Encryptered)
+	Encrypteredstr, err := wallet.encryptPrivkey(privkeybyte)
+	if err != nil {
+		walletlog.Error("ProcImportPrivKey", "encryptPrivkey err", err)
+		return nil, err
+	}
 	//校验PrivKey对应的addr是否已经存在钱包中
 	Account, err = wallet.walletStore.GetAccountByAddr(addr)
 	if Account != nil {
-		if Account.Privkey == Encrypteredstr {
+		//每次加密的nonce不同，需要比较解密之后的私钥
+		storekey, err := wallet.decryptPrivkey(Account.Privkey)
+		if err == nil && bytes.Equal(storekey, privkeybyte) {
 			walletlog.Error("ProcImportPrivKey Privkey is exist in wallet!")
 			return nil, types.ErrPrivkeyExist
 		} else {
@@ -464,6 +497,7 @@ func (wallet *Wallet) ProcImportPrivKey(PrivKey *types.ReqWalletImportPrivkey) (
 	WalletAccStore.Privkey = Encrypteredstr //存储加密后的私钥
 	WalletAccStore.Label = PrivKey.GetLabel()
 	WalletAccStore.Addr = addr
+	WalletAccStore.SignType = int32(signType)
 	//存储Addr:label+privkey+addr到数据库
 	err = wallet.walletStore.SetWalletAccount(false, addr, &WalletAccStore)
 	if err != nil {
@@ -562,11 +596,11 @@ func (wallet *Wallet) ProcSendToAddress(SendToAddress *types.ReqWalletSendToAddr
 	}
 	addrto := SendToAddress.GetTo()
 	note := SendToAddress.GetNote()
-	priv, err := wallet.getPrivKeyByAddr(addrs[0])
+	priv, signType, err := wallet.getPrivKeyByAddr(addrs[0])
 	if err != nil {
 		return nil, err
 	}
-	return wallet.sendToAddress(priv, addrto, amount, note, SendToAddress.IsToken, SendToAddress.TokenSymbol)
+	return wallet.sendToAddress(priv, signType, addrto, amount, note, SendToAddress.IsToken, SendToAddress.TokenSymbol)
 }
 
 //type ReqWalletSetFee struct {
@@ -682,31 +716,16 @@ func (wallet *Wallet) ProcMergeBalance(MergeBalance *types.ReqWalletMergeBalance
 	if len(WalletAccStores) != len(accounts) {
 		walletlog.Error("ProcMergeBalance", "AccStores", len(WalletAccStores), "accounts", len(accounts))
 	}
-	//通过privkey生成一个pubkey然后换算成对应的addr
-	cr, err := crypto.New(types.GetSignName("", SignType))
-	if err != nil {
-		walletlog.Error("ProcMergeBalance", "err", err)
-		return nil, err
-	}
-
 	addrto := MergeBalance.GetTo()
 	note := "MergeBalance"
 
 	var ReplyHashes types.ReplyHashes
 
 	for index, Account := range accounts {
-		Privkey := WalletAccStores[index].Privkey
-		//解密存储的私钥
-		prikeybyte, err := common.FromHex(Privkey)
-		if err != nil || len(prikeybyte) == 0 {
-			walletlog.Error("ProcMergeBalance", "FromHex err", err, "index", index)
-			continue
-		}
-
-		privkey := wcom.CBCDecrypterPrivkey([]byte(wallet.Password), prikeybyte)
-		priv, err := cr.PrivKeyFromBytes(privkey)
+		//解密存储的私钥，每个账户按自己的签名类型签名
+		priv, err := wallet.accountPrivKey(WalletAccStores[index])
 		if err != nil {
-			walletlog.Error("ProcMergeBalance", "PrivKeyFromBytes err", err, "index", index)
+			walletlog.Error("ProcMergeBalance", "accountPrivKey err", err, "index", index)
 			continue
 		}
 		//过滤掉to地址
@@ -721,10 +740,14 @@ func (wallet *Wallet) ProcMergeBalance(MergeBalance *types.ReqWalletMergeBalance
 		amount = amount - wallet.FeeAmount
 		v := &cty.CoinsAction_Transfer{&types.AssetsTransfer{Amount: amount, Note: note}}
 		transfer := &cty.CoinsAction{Value: v, Ty: cty.CoinsActionTransfer}
-		//初始化随机数
-		tx := &types.Transaction{Execer: []byte("coins"), Payload: types.Encode(transfer), Fee: wallet.FeeAmount, To: addrto, Nonce: wallet.random.Int63()}
+		nonce, err := wallet.getTxNonce(Account.Addr)
+		if err != nil {
+			walletlog.Error("ProcMergeBalance", "getTxNonce err", err, "index", index)
+			continue
+		}
+		tx := &types.Transaction{Execer: []byte("coins"), Payload: types.Encode(transfer), Fee: wallet.FeeAmount, To: addrto, Nonce: nonce}
 		tx.SetExpire(time.Second * 120)
-		tx.Sign(int32(SignType), priv)
+		tx.Sign(int32(accountSignType(WalletAccStores[index])), priv)
 		//walletlog.Info("ProcMergeBalance", "tx.Nonce", tx.Nonce, "tx", tx, "index", index)
 
 		//发送交易信息给mempool模块
@@ -796,44 +819,51 @@ func (wallet *Wallet) ProcWalletSetPasswd(Passwd *types.ReqWalletSetPasswd) erro
 		walletlog.Error("ProcWalletSetPasswd", "SetEncryptionFlag err", err)
 		return err
 	}
-	//使用old密码解密seed然后用新的钱包密码重新加密seed
-	seed, err := wallet.getSeed(Passwd.OldPass)
+	//使用old密码解密seed和所有的私钥，然后用新的密钥重新加密，和密码一起在一个batch中写入
+	var seed string
+	decrypt := func(encrypted []byte) ([]byte, error) {
+		return wcom.CBCDecrypterPrivkey([]byte(Passwd.OldPass), encrypted), nil
+	}
+	oldks, err := wallet.openKeyStore(Passwd.OldPass)
+	if err == nil {
+		decrypt = oldks.Decrypt
+		seed, err = GetSeedByKeyStore(wallet.walletStore.GetDB(), oldks)
+	} else if err == types.ErrKeyStoreNotExist {
+		seed, err = getLegacySeed(wallet.walletStore.GetDB(), Passwd.OldPass)
+	}
 	if err != nil {
 		walletlog.Error("ProcWalletSetPasswd", "getSeed err", err)
 		return err
 	}
-	ok, err := SaveSeedInBatch(wallet.walletStore.GetDB(), seed, Passwd.NewPass, newBatch)
+	ks, err := wcom.NewKeyStore([]byte(Passwd.NewPass))
+	if err != nil {
+		walletlog.Error("ProcWalletSetPasswd", "NewKeyStore err", err)
+		return err
+	}
+	ok, err := SaveSeedInBatch(wallet.walletStore.GetDB(), seed, ks, newBatch)
 	if !ok {
 		walletlog.Error("ProcWalletSetPasswd", "SaveSeed err", err)
 		return err
 	}
-
-	//对所有存储的私钥重新使用新的密码加密,通过Account前缀查找获取钱包中的所有账户信息
-	WalletAccStores, err := wallet.walletStore.GetAccountByPrefix("Account")
-	if err != nil || len(WalletAccStores) == 0 {
-		walletlog.Error("ProcWalletSetPasswd", "GetAccountByPrefix:err", err)
+	err = wallet.reencryptAccounts(decrypt, ks, newBatch)
+	if err != nil {
+		walletlog.Error("ProcWalletSetPasswd", "reencryptAccounts err", err)
+		return err
 	}
-
-	for _, AccStore := range WalletAccStores {
-		//使用old Password解密存储的私钥
-		storekey, err := common.FromHex(AccStore.GetPrivkey())
-		if err != nil || len(storekey) == 0 {
-			walletlog.Info("ProcWalletSetPasswd", "addr", AccStore.Addr, "FromHex err", err)
-			continue
-		}
-		Decrypter := wcom.CBCDecrypterPrivkey([]byte(Passwd.OldPass), storekey)
-
-		//使用新的密码重新加密私钥
-		Encrypter := wcom.CBCEncrypterPrivkey([]byte(Passwd.NewPass), Decrypter)
-		AccStore.Privkey = common.ToHex(Encrypter)
-		err = wallet.walletStore.SetWalletAccountInBatch(true, AccStore.Addr, AccStore, newBatch)
-		if err != nil {
-			walletlog.Info("ProcWalletSetPasswd", "addr", AccStore.Addr, "SetWalletAccount err", err)
-		}
+	err = wallet.walletStore.SetKeyStore(ks, newBatch)
+	if err != nil {
+		return err
+	}
+	err = newBatch.Write()
+	if err != nil {
+		walletlog.Error("ProcWalletSetPasswd", "batch write err", err)
+		return err
+	}
+	if wallet.keystore != nil {
+		wallet.keystore.Lock()
 	}
-
-	newBatch.Write()
 	wallet.Password = Passwd.NewPass
+	wallet.keystore = ks
 	wallet.EncryptFlag = 1
 	return nil
 }
@@ -847,6 +877,7 @@ func (wallet *Wallet) ProcWalletLock() error {
 	}
 
 	atomic.CompareAndSwapInt32(&wallet.isWalletLocked, 0, 1)
+	wallet.lockKeyStore()
 	for _, policy := range wcom.PolicyContainer {
 		policy.OnWalletLocked()
 	}
@@ -876,6 +907,12 @@ func (wallet *Wallet) ProcWalletUnLock(WalletUnLock *types.WalletUnLock) error {
 	if len(wallet.Password) != 0 && WalletUnLock.Passwd != wallet.Password {
 		return types.ErrInputPassword
 	}
+	//加载钱包的密钥，升级之前的钱包在这里迁移到新的加密方式
+	err := wallet.unlockKeyStore(WalletUnLock.Passwd)
+	if err != nil {
+		walletlog.Error("ProcWalletUnLock", "unlockKeyStore err", err)
+		return err
+	}
 	//本钱包没有设置密码加密过,只需要解锁不需要记录解锁密码
 	wallet.Password = WalletUnLock.Passwd
 	//只解锁挖矿转账
@@ -917,13 +954,28 @@ func (wallet *Wallet) ProcWalletAddBlock(block *types.BlockDetail) {
 	for index := 0; index < txlen; index++ {
 		tx := block.Block.Txs[index]
 		execer := string(tx.Execer)
+		//获取from地址
+		pubkey := block.Block.Txs[index].Signature.GetPubkey()
+		fromaddress := address.PubKeyToAddress(pubkey).String()
+		toaddr := tx.GetTo()
+		//扩展公钥生成的地址在区块中出现时继续生成地址，不管交易是否已经被钱包的账户或者业务策略记录
+		fromWatch, toWatch := wallet.getWatchAccount(fromaddress), wallet.getWatchAccount(toaddr)
+		wallet.useXpubAddr(fromWatch)
+		wallet.useXpubAddr(toWatch)
+		param := &buildStoreWalletTxDetailParam{
+			tokenname:  "",
+			block:      block,
+			tx:         tx,
+			index:      index,
+			newbatch:   newbatch,
+			isprivacy:  false,
+			addDelType: AddTx,
+			//utxos:      nil,
+		}
 		// 执行钱包业务逻辑策略
 		if policy, ok := wcom.PolicyContainer[execer]; ok {
 			wtxdetail := policy.OnAddBlockTx(block, tx, int32(index), newbatch)
-			if wtxdetail == nil {
-				continue
-			}
-			if len(wtxdetail.Fromaddr) > 0 {
+			if wtxdetail != nil && len(wtxdetail.Fromaddr) > 0 {
 				txdetailbyte, err := proto.Marshal(wtxdetail)
 				if err != nil {
 					walletlog.Error("ProcWalletAddBlock", "Marshal txdetail error", err, "Height", block.Block.Height, "index", index)
@@ -933,25 +985,12 @@ func (wallet *Wallet) ProcWalletAddBlock(block *types.BlockDetail) {
 				heightstr := fmt.Sprintf("%018d", blockheight)
 				key := wcom.CalcTxKey(heightstr)
 				newbatch.Set(key, txdetailbyte)
+				continue
 			}
-
+			//业务策略没有记录的交易，继续检查是否是只读账户的交易
 		} else { // 默认的执行器类型处理
 			// TODO: 钱包基础功能模块，将会重新建立一个处理策略，将钱包变成一个容器
-			//获取from地址
-			pubkey := block.Block.Txs[index].Signature.GetPubkey()
-			addr := address.PubKeyToAddress(pubkey)
-			param := &buildStoreWalletTxDetailParam{
-				tokenname:  "",
-				block:      block,
-				tx:         tx,
-				index:      index,
-				newbatch:   newbatch,
-				isprivacy:  false,
-				addDelType: AddTx,
-				//utxos:      nil,
-			}
 			//from addr
-			fromaddress := addr.String()
 			param.senderRecver = fromaddress
 			if len(fromaddress) != 0 && wallet.AddrInWallet(fromaddress) {
 				param.sendRecvFlag = sendTx
@@ -960,7 +999,6 @@ func (wallet *Wallet) ProcWalletAddBlock(block *types.BlockDetail) {
 				continue
 			}
 			//toaddr
-			toaddr := tx.GetTo()
 			if len(toaddr) != 0 && wallet.AddrInWallet(toaddr) {
 				param.sendRecvFlag = recvTx
 				wallet.buildAndStoreWalletTxDetail(param)
@@ -968,6 +1006,17 @@ func (wallet *Wallet) ProcWalletAddBlock(block *types.BlockDetail) {
 				continue
 			}
 		}
+		//只读账户的交易
+		if fromWatch == nil && toWatch == nil {
+			continue
+		}
+		param.senderRecver = fromaddress
+		param.watchOnly = true
+		param.sendRecvFlag = recvTx
+		if fromWatch != nil {
+			param.sendRecvFlag = sendTx
+		}
+		wallet.buildAndStoreWalletTxDetail(param)
 	}
 	err := newbatch.Write()
 	if err != nil {
@@ -991,6 +1040,7 @@ type buildStoreWalletTxDetailParam struct {
 	isprivacy    bool
 	addDelType   int32
 	sendRecvFlag int32
+	watchOnly    bool
 	//utxos        []*types.UTXO
 }
 
@@ -1010,6 +1060,7 @@ func (wallet *Wallet) buildAndStoreWalletTxDetail(param *buildStoreWalletTxDetai
 		txdetail.ActionName = txdetail.Tx.ActionName()
 		txdetail.Amount, _ = param.tx.Amount()
 		txdetail.Fromaddr = param.senderRecver
+		txdetail.WatchOnly = param.watchOnly
 		//txdetail.Spendrecv = param.utxos
 
 		txdetailbyte, err := proto.Marshal(&txdetail)
@@ -1039,32 +1090,33 @@ func (wallet *Wallet) ProcWalletDelBlock(block *types.BlockDetail) {
 		tx := block.Block.Txs[index]
 
 		execer := string(tx.Execer)
+		//获取from地址
+		pubkey := tx.Signature.GetPubkey()
+		fromaddress := address.PubKeyToAddress(pubkey).String()
+		toaddr := tx.GetTo()
 		// 执行钱包业务逻辑策略
 		if policy, ok := wcom.PolicyContainer[execer]; ok {
 			wtxdetail := policy.OnDeleteBlockTx(block, tx, int32(index), newbatch)
-			if wtxdetail == nil {
-				continue
-			}
-			if len(wtxdetail.Fromaddr) > 0 {
+			if wtxdetail != nil && len(wtxdetail.Fromaddr) > 0 {
 				newbatch.Delete(wcom.CalcTxKey(heightstr))
+				continue
 			}
-
 		} else { // 默认的合约处理流程
 			// TODO:将钱包基础功能移动到专属钱包基础业务的模块中，将钱包模块变成容器
-			//获取from地址
-			pubkey := tx.Signature.GetPubkey()
-			addr := address.PubKeyToAddress(pubkey)
-			fromaddress := addr.String()
 			if len(fromaddress) != 0 && wallet.AddrInWallet(fromaddress) {
 				newbatch.Delete(wcom.CalcTxKey(heightstr))
 				continue
 			}
 			//toaddr
-			toaddr := tx.GetTo()
 			if len(toaddr) != 0 && wallet.AddrInWallet(toaddr) {
 				newbatch.Delete(wcom.CalcTxKey(heightstr))
+				continue
 			}
 		}
+		//只读账户的交易
+		if wallet.getWatchAccount(fromaddress) != nil || wallet.getWatchAccount(toaddr) != nil {
+			newbatch.Delete(wcom.CalcTxKey(heightstr))
+		}
 	}
 	newbatch.Write()
 	for _, policy := range wcom.PolicyContainer {
@@ -1089,7 +1141,16 @@ func (wallet *Wallet) GetTxDetailByHashs(ReqHashes *types.ReqHashes) {
 
 	//批量存储地址对应的所有交易的详细信息到wallet db中
 	newbatch := wallet.walletStore.NewBatch(true)
-	for _, txdetal := range TxDetails.Txs {
+	err = wallet.setTxDetails(TxDetails.Txs, newbatch)
+	if err != nil {
+		return
+	}
+	newbatch.Write()
+}
+
+//setTxDetails 把blockchain查询到的交易详情转换成钱包的交易记录写入batch
+func (wallet *Wallet) setTxDetails(txdetails []*types.TransactionDetail, newbatch dbm.Batch) error {
+	for _, txdetal := range txdetails {
 		height := txdetal.GetHeight()
 		txindex := txdetal.GetIndex()
 
@@ -1112,14 +1173,16 @@ func (wallet *Wallet) GetTxDetailByHashs(ReqHashes *types.ReqHashes) {
 			txdetail.Fromaddr, txdetail.Tx.To = txdetail.Tx.To, txdetail.Fromaddr
 		}
 
+		txdetail.WatchOnly = wallet.isWatchOnlyTx(txdetail.Fromaddr, txdetail.GetTx().GetTo())
+
 		txdetailbyte, err := proto.Marshal(&txdetail)
 		if err != nil {
-			walletlog.Error("GetTxDetailByHashs Marshal txdetail err", "Height", height, "index", txindex)
-			return
+			walletlog.Error("setTxDetails Marshal txdetail err", "Height", height, "index", txindex)
+			return types.ErrMarshal
 		}
 		newbatch.Set(wcom.CalcTxKey(heightstr), txdetailbyte)
 	}
-	newbatch.Write()
+	return nil
 }
 
 //生成一个随机的seed种子, 目前支持英文单词和简体中文
@@ -1145,7 +1208,12 @@ func (wallet *Wallet) getSeed(password string) (string, error) {
 		return "", err
 	}
 
-	seed, err := GetSeed(wallet.walletStore.GetDB(), password)
+	var seed string
+	if wallet.keystore != nil && password == wallet.Password {
+		seed, err = GetSeedByKeyStore(wallet.walletStore.GetDB(), wallet.keystore)
+	} else {
+		seed, err = GetSeed(wallet.walletStore.GetDB(), password)
+	}
 	if err != nil {
 		walletlog.Error("getSeed", "GetSeed err", err)
 		return "", err
@@ -1218,7 +1286,7 @@ func (wallet *Wallet) ProcDumpPrivkey(addr string) (string, error) {
 		return "", types.ErrInvalidParam
 	}
 
-	priv, err := wallet.getPrivKeyByAddr(addr)
+	priv, _, err := wallet.getPrivKeyByAddr(addr)
 	if err != nil {
 		return "", err
 	}
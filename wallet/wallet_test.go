@@ -6,22 +6,31 @@ package wallet
 
 import (
 	"fmt"
+	"math/big"
 	//	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/33cn/chain33/client/mocks"
 	"github.com/33cn/chain33/common"
 	"github.com/33cn/chain33/common/address"
 	"github.com/33cn/chain33/common/crypto"
+	dbm "github.com/33cn/chain33/common/db"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	// "github.com/33cn/chain33/common/log"
 
 	"github.com/33cn/chain33/queue"
 	"github.com/33cn/chain33/store"
+	"github.com/33cn/chain33/system/crypto/multisig"
 	"github.com/33cn/chain33/types"
 	"github.com/33cn/chain33/util"
+	"github.com/33cn/chain33/wallet/bipwallet"
+	wcom "github.com/33cn/chain33/wallet/common"
+	"github.com/tjfoc/gmsm/sm2"
 
 	_ "github.com/33cn/chain33/system"
 )
@@ -721,3 +730,551 @@ func testgetFatalFailure(t *testing.T, wallet *Wallet) {
 	println("testgetFatalFailure end")
 	println("--------------------------")
 }
+
+func TestSignMultiSig(t *testing.T) {
+	c, err := crypto.New(types.GetSignName("", types.SECP256K1))
+	require.NoError(t, err)
+	var privs []crypto.PrivKey
+	var pubs [][]byte
+	for i := 0; i < 4; i++ {
+		priv, err := c.GenKey()
+		require.NoError(t, err)
+		privs = append(privs, priv)
+		pubs = append(pubs, priv.PubKey().Bytes())
+	}
+	pub, err := multisig.NewPubKey(2, []int32{1, 1, 1}, pubs[:3])
+	require.NoError(t, err)
+	policy := common.ToHex(pub.Bytes())
+	tx := &types.Transaction{Execer: []byte("coins"), Payload: []byte("payload"), Fee: 1000000, To: ToAddr1}
+
+	//第一个成员签名时需要策略，地址由策略得到
+	require.NoError(t, signTx(tx, privs[0], types.SECP256K1, policy))
+	assert.Equal(t, int32(types.MULTISIG), tx.GetSignature().GetTy())
+	assert.Equal(t, address.PubKeyToAddress(pub.Bytes()).String(), tx.From())
+	assert.False(t, tx.CheckSign())
+	assert.True(t, hasMultiSig(tx, nil))
+
+	assert.Equal(t, multisig.ErrNotMember, signTx(tx, privs[3], types.SECP256K1, ""))
+	pub2, err := multisig.NewPubKey(1, []int32{1, 1, 1}, pubs[:3])
+	require.NoError(t, err)
+	assert.Equal(t, multisig.ErrPolicyChange, signTx(tx, privs[1], types.SECP256K1, common.ToHex(pub2.Bytes())))
+
+	//之后的成员从交易的签名中获取策略
+	require.NoError(t, signTx(tx, privs[2], types.SECP256K1, ""))
+	assert.True(t, tx.CheckSign())
+	tx.Fee++
+	assert.False(t, tx.CheckSign())
+
+	normal := &types.Transaction{Execer: []byte("coins"), Payload: []byte("payload"), Fee: 1000000, To: ToAddr1}
+	require.NoError(t, signTx(normal, privs[0], types.SECP256K1, ""))
+	assert.Equal(t, int32(SignType), normal.GetSignature().GetTy())
+	assert.True(t, normal.CheckSign())
+	assert.False(t, hasMultiSig(normal, nil))
+}
+
+func TestMultiSigFee(t *testing.T) {
+	oldFee := minFee
+	minFee = 100000
+	defer func() { minFee = oldFee }()
+	c, err := crypto.New(types.GetSignName("", types.SECP256K1))
+	require.NoError(t, err)
+	var privs []crypto.PrivKey
+	var pubs [][]byte
+	var tys []int32
+	for i := 0; i < multisig.MaxKeys; i++ {
+		priv, err := c.GenKey()
+		require.NoError(t, err)
+		privs = append(privs, priv)
+		pubs = append(pubs, priv.PubKey().Bytes())
+		tys = append(tys, types.SECP256K1)
+	}
+	pub, err := multisig.NewPubKey(multisig.MaxKeys, tys, pubs)
+	require.NoError(t, err)
+	policy := common.ToHex(pub.Bytes())
+
+	//第一个成员签名前按所有成员签名后的大小估算手续费
+	tx := &types.Transaction{Execer: []byte("coins"), Payload: []byte("payload"), Fee: minFee, To: ToAddr1}
+	require.NoError(t, setMultiSigFee(tx, policy))
+	require.NoError(t, signTx(tx, privs[0], types.SECP256K1, policy))
+	require.NoError(t, setMultiSigFee(tx, ""))
+	for _, priv := range privs[1:] {
+		require.NoError(t, signTx(tx, priv, types.SECP256K1, ""))
+	}
+	assert.True(t, tx.CheckSign())
+	assert.True(t, tx.Fee > minFee)
+	assert.True(t, tx.Fee >= int64(types.Size(tx)/1000+1)*minFee)
+	//没有到ForkMultiSig的高度时不接受多重签名的交易
+	assert.Equal(t, types.ErrMultiSigNotEnable, tx.Check(0, minFee))
+
+	//已经有部分签名之后不能再修改手续费
+	tx2 := &types.Transaction{Execer: []byte("coins"), Payload: []byte("payload"), Fee: minFee, To: ToAddr1}
+	require.NoError(t, signTx(tx2, privs[0], types.SECP256K1, policy))
+	assert.Equal(t, types.ErrTxFeeTooLow, setMultiSigFee(tx2, ""))
+
+	//交易组的手续费在第一笔交易中，修改后重新计算header
+	tx3 := &types.Transaction{Execer: []byte("coins"), Payload: []byte("payload"), Fee: minFee, To: ToAddr1}
+	tx4 := &types.Transaction{Execer: []byte("coins"), Payload: []byte("payload"), Fee: minFee, To: ToAddr1}
+	group, err := types.CreateTxGroup([]*types.Transaction{tx3, tx4})
+	require.NoError(t, err)
+	require.NoError(t, setGroupMultiSigFee(group, 0, policy))
+	for _, priv := range privs {
+		for i := range group.Txs {
+			require.NoError(t, signTx(group.Txs[i], priv, types.SECP256K1, policy))
+		}
+	}
+	assert.True(t, group.CheckSign())
+	totalfee := int64(0)
+	for _, gtx := range group.Txs {
+		assert.Equal(t, group.Txs[0].Hash(), gtx.Header)
+		totalfee += int64(types.Size(gtx)/1000+1) * minFee
+	}
+	assert.True(t, group.Txs[0].Fee >= totalfee)
+}
+
+func TestKeyStoreMigrate(t *testing.T) {
+	db := dbm.NewDB("wallet", "memdb", "", 0)
+	defer db.Close()
+	wallet := &Wallet{walletStore: NewStore(db), isWalletLocked: 1, EncryptFlag: 1}
+	password := "password"
+	seed := "seed for keystore migrate"
+
+	//升级之前的钱包：seed和私钥使用旧的加密方式
+	encseed, err := AesgcmEncrypter([]byte(password), []byte(seed))
+	require.NoError(t, err)
+	require.NoError(t, db.Set(WalletSeed, encseed))
+	batch := wallet.walletStore.NewBatch(true)
+	require.NoError(t, wallet.walletStore.SetPasswordHash(password, batch))
+	require.NoError(t, batch.Write())
+	c, err := crypto.New(types.GetSignName("", SignType))
+	require.NoError(t, err)
+	priv, err := c.GenKey()
+	require.NoError(t, err)
+	addr := address.PubKeyToAddress(priv.PubKey().Bytes()).String()
+	legacy := common.ToHex(wcom.CBCEncrypterPrivkey([]byte(password), priv.Bytes()))
+	acc := &types.WalletAccountStore{Privkey: legacy, Label: "label", Addr: addr}
+	require.NoError(t, wallet.walletStore.SetWalletAccount(false, addr, acc))
+	_, err = wallet.walletStore.GetKeyStore()
+	assert.Equal(t, types.ErrKeyStoreNotExist, err)
+
+	//解锁时迁移到新的加密方式
+	assert.Equal(t, types.ErrVerifyOldpasswdFail, wallet.ProcWalletUnLock(&types.WalletUnLock{Passwd: "wrong"}))
+	require.NoError(t, wallet.ProcWalletUnLock(&types.WalletUnLock{Passwd: password}))
+	ks, err := wallet.walletStore.GetKeyStore()
+	require.NoError(t, err)
+	assert.Equal(t, int32(wcom.KeyStoreVersion), ks.Version)
+	acc, err = wallet.walletStore.GetAccountByAddr(addr)
+	require.NoError(t, err)
+	assert.NotEqual(t, legacy, acc.Privkey)
+	key, _, err := wallet.getPrivKeyByAddr(addr)
+	require.NoError(t, err)
+	assert.True(t, priv.Equals(key))
+	seedstr, err := GetSeed(db, password)
+	require.NoError(t, err)
+	assert.Equal(t, seed, seedstr)
+
+	//修改密码时重新加密所有的私钥和seed
+	require.NoError(t, wallet.ProcWalletSetPasswd(&types.ReqWalletSetPasswd{OldPass: password, NewPass: "newpass"}))
+	_, err = GetSeed(db, password)
+	assert.Equal(t, types.ErrDecrypt, err)
+	seedstr, err = GetSeed(db, "newpass")
+	require.NoError(t, err)
+	assert.Equal(t, seed, seedstr)
+
+	//重启之后使用新的密码解锁
+	wallet2 := &Wallet{walletStore: NewStore(db), isWalletLocked: 1, EncryptFlag: 1}
+	_, _, err = wallet2.getPrivKeyByAddr(addr)
+	assert.Equal(t, types.ErrWalletIsLocked, err)
+	require.NoError(t, wallet2.ProcWalletUnLock(&types.WalletUnLock{Passwd: "newpass"}))
+	key, _, err = wallet2.getPrivKeyByAddr(addr)
+	require.NoError(t, err)
+	assert.True(t, priv.Equals(key))
+	encrypted, err := wallet2.Encrypt([]byte("data"))
+	require.NoError(t, err)
+	plain, err := wallet2.Decrypt(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("data"), plain)
+
+	//锁定之后清除内存中的密钥
+	ks = wallet2.keystore
+	require.NoError(t, wallet2.ProcWalletLock())
+	assert.Nil(t, wallet2.keystore)
+	_, err = ks.Encrypt([]byte("data"))
+	assert.Equal(t, types.ErrWalletIsLocked, err)
+	_, err = wallet2.Decrypt(encrypted)
+	assert.Equal(t, types.ErrWalletIsLocked, err)
+	_, _, err = wallet2.getPrivKeyByAddr(addr)
+	assert.Equal(t, types.ErrWalletIsLocked, err)
+	assert.Equal(t, types.ErrVerifyOldpasswdFail, wallet2.ProcWalletUnLock(&types.WalletUnLock{Passwd: password}))
+	require.NoError(t, wallet2.ProcWalletUnLock(&types.WalletUnLock{Passwd: "newpass"}))
+	plain, err = wallet2.Decrypt(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("data"), plain)
+}
+
+func TestAccountSignType(t *testing.T) {
+	db := dbm.NewDB("wallet", "memdb", "", 0)
+	defer db.Close()
+	wallet := &Wallet{walletStore: NewStore(db), isWalletLocked: 1, EncryptFlag: 1}
+	password := "password"
+	seed := "paper hedgehog hover unit lock arena turkey flock bench proof ankle bulk quarter cannon toss"
+	ok, err := SaveSeed(db, seed, password)
+	require.True(t, ok)
+	require.NoError(t, err)
+	batch := wallet.walletStore.NewBatch(true)
+	require.NoError(t, wallet.walletStore.SetPasswordHash(password, batch))
+	require.NoError(t, batch.Write())
+	require.NoError(t, wallet.ProcWalletUnLock(&types.WalletUnLock{Passwd: password}))
+
+	_, err = checkSignType(types.MULTISIG)
+	assert.Equal(t, types.ErrNotSupport, err)
+	ty, err := checkSignType(0)
+	require.NoError(t, err)
+	assert.Equal(t, SignType, ty)
+
+	//同一个seed生成不同签名类型的账户，每种类型的索引单独计数
+	addrs := make(map[string]bool)
+	for _, signType := range []int{types.SECP256K1, types.ED25519, types.SM2, types.SM2} {
+		privkeyhex, err := GetPrivkeyBySeed(db, seed, signType)
+		require.NoError(t, err)
+		privkey, err := common.FromHex(privkeyhex)
+		require.NoError(t, err)
+		priv, addr, err := privKeyFromBytes(signType, privkey)
+		require.NoError(t, err)
+		assert.False(t, addrs[addr])
+		addrs[addr] = true
+
+		encrypted, err := wallet.encryptPrivkey(privkey)
+		require.NoError(t, err)
+		acc := &types.WalletAccountStore{Privkey: encrypted, Label: addr, Addr: addr, SignType: int32(signType)}
+		require.NoError(t, wallet.walletStore.SetWalletAccount(false, addr, acc))
+		key, ty, err := wallet.getPrivKeyByAddr(addr)
+		require.NoError(t, err)
+		assert.True(t, priv.Equals(key))
+		assert.Equal(t, int32(signType), ty)
+		assert.Equal(t, int32(signType), wallet.keySignType(priv))
+
+		//按账户的签名类型签名
+		tx := &types.Transaction{Execer: []byte("coins"), Payload: []byte("payload"), Fee: 1000000, To: ToAddr1}
+		require.NoError(t, signTx(tx, key, ty, ""))
+		assert.Equal(t, int32(signType), tx.GetSignature().GetTy())
+		assert.Equal(t, addr, tx.From())
+		assert.True(t, tx.CheckSign())
+	}
+	index, err := db.Get(calcBackupKeyIndex(types.SM2))
+	require.NoError(t, err)
+	assert.Equal(t, "1", string(index))
+
+	//升级之前的账户没有签名类型，使用钱包配置的签名类型
+	assert.Equal(t, SignType, accountSignType(&types.WalletAccountStore{}))
+
+	//导出的扩展公钥生成的地址和secp256k1账户的地址一致
+	xpub, err := wallet.ProcExportXpub()
+	require.NoError(t, err)
+	pubWallet, err := bipwallet.NewWalletFromXpub(xpub.Data)
+	require.NoError(t, err)
+	pub, err := pubWallet.NewPubKey(0)
+	require.NoError(t, err)
+	assert.True(t, addrs[address.PubKeyToAddress(pub).String()])
+	require.NoError(t, wallet.ProcWalletLock())
+	_, err = wallet.ProcExportXpub()
+	assert.Equal(t, types.ErrWalletIsLocked, err)
+
+	//sm2的私钥超出范围时跳过
+	n := sm2.P256Sm2().Params().N
+	assert.False(t, validSM2Key(make([]byte, 32)))
+	assert.False(t, validSM2Key(n.Bytes()))
+	assert.False(t, validSM2Key(new(big.Int).Sub(n, big.NewInt(1)).Bytes()))
+	assert.True(t, validSM2Key(new(big.Int).Sub(n, big.NewInt(2)).Bytes()))
+	assert.True(t, validSM2Key(big.NewInt(1).Bytes()))
+}
+
+func TestWatchOnly(t *testing.T) {
+	db := dbm.NewDB("wallet", "memdb", "", 0)
+	defer db.Close()
+	api := new(mocks.QueueProtocolAPI)
+	wallet := &Wallet{walletStore: NewStore(db), isWalletLocked: 1, api: api}
+
+	//冷钱包导出扩展公钥
+	hd, err := bipwallet.NewWalletFromSeed(bipwallet.TypeBty, []byte("seed of cold wallet"))
+	require.NoError(t, err)
+	xpub, err := hd.Xpub(0)
+	require.NoError(t, err)
+	coldAddr := func(index uint32) string {
+		_, pub, err := hd.NewKeyPair(index)
+		require.NoError(t, err)
+		return address.PubKeyToAddress(pub).String()
+	}
+	//索引2的地址在链上已经有交易
+	api.On("GetAddrOverview", mock.Anything).Return(func(req *types.ReqAddr) *types.AddrOverview {
+		if req.Addr == coldAddr(2) {
+			return &types.AddrOverview{TxCount: 1}
+		}
+		return &types.AddrOverview{}
+	}, nil)
+
+	_, err = wallet.ProcImportWatch(&types.ReqWalletImportWatch{Addr: coldAddr(0), Xpub: xpub, Label: "cold"})
+	assert.Equal(t, types.ErrInvalidParam, err)
+	_, err = wallet.ProcImportWatch(&types.ReqWalletImportWatch{Xpub: xpub[:len(xpub)-1], Label: "cold"})
+	assert.Equal(t, types.ErrXpub, err)
+	accs, err := wallet.ProcImportWatch(&types.ReqWalletImportWatch{Xpub: xpub, Label: "cold", GapLimit: 3})
+	require.NoError(t, err)
+	require.Equal(t, 6, len(accs.Wallets))
+	for i, acc := range accs.Wallets {
+		assert.Equal(t, coldAddr(uint32(i)), acc.Acc.Addr)
+		assert.Equal(t, fmt.Sprintf("cold/%d", i), acc.Label)
+		assert.True(t, acc.WatchOnly)
+	}
+	_, err = wallet.ProcImportWatch(&types.ReqWalletImportWatch{Xpub: xpub, Label: "cold2"})
+	assert.Equal(t, types.ErrAddrExist, err)
+	_, err = wallet.ProcImportWatch(&types.ReqWalletImportWatch{Addr: address.PubKeyToAddress([]byte("other")).String(), Label: "cold"})
+	assert.Equal(t, types.ErrLabelHasUsed, err)
+	_, err = wallet.ProcImportWatch(&types.ReqWalletImportWatch{Addr: coldAddr(1), Label: "cold1"})
+	assert.Equal(t, types.ErrAddrExist, err)
+	_, err = wallet.ProcImportWatch(&types.ReqWalletImportWatch{Addr: "invalid", Label: "addr"})
+	assert.Equal(t, types.ErrInvalidAddress, err)
+	watchAddr := address.PubKeyToAddress([]byte("watch")).String()
+	_, err = wallet.ProcImportWatch(&types.ReqWalletImportWatch{Addr: watchAddr, Label: "addr"})
+	require.NoError(t, err)
+
+	list, err := wallet.ProcGetAccountList(&types.ReqAccountList{WithoutBalance: true})
+	require.NoError(t, err)
+	assert.Equal(t, 7, len(list.Wallets))
+	_, _, err = wallet.getPrivKeyByAddr(watchAddr)
+	assert.Equal(t, types.ErrWatchOnly, err)
+
+	//区块中使用了最后一个地址时继续生成地址，交易记录为只读账户的交易
+	tx := &types.Transaction{Execer: []byte("coins"), Payload: []byte("payload"), Fee: 1000000, To: coldAddr(5)}
+	block := &types.BlockDetail{Block: &types.Block{Height: 1, Txs: []*types.Transaction{tx}}, Receipts: []*types.ReceiptData{{}}}
+	wallet.ProcWalletAddBlock(block)
+	x, err := wallet.walletStore.GetXpub(xpub)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(6), x.Used)
+	assert.Equal(t, uint32(9), x.Next)
+	assert.NotNil(t, wallet.getWatchAccount(coldAddr(8)))
+	details, err := wallet.ProcWalletTxList(&types.ReqWalletTransactionList{Count: 10})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(details.TxDetails))
+	assert.True(t, details.TxDetails[0].WatchOnly)
+	assert.Equal(t, coldAddr(5), details.TxDetails[0].Tx.To)
+
+	wallet.ProcWalletDelBlock(block)
+	value, _ := wallet.walletStore.Get(wcom.CalcTxKey(fmt.Sprintf("%018d", block.Block.Height*maxTxNumPerBlock)))
+	assert.Equal(t, 0, len(value))
+
+	//钱包的账户转给扩展公钥的地址时也要继续生成地址
+	c, err := crypto.New(types.GetSignName("", types.SECP256K1))
+	require.NoError(t, err)
+	priv, err := c.GenKey()
+	require.NoError(t, err)
+	addr := address.PubKeyToAddress(priv.PubKey().Bytes()).String()
+	require.NoError(t, wallet.walletStore.SetWalletAccount(false, addr, &types.WalletAccountStore{Label: "hot", Addr: addr}))
+	tx = &types.Transaction{Execer: []byte("coins"), Payload: []byte("payload"), Fee: 1000000, To: coldAddr(8)}
+	tx.Sign(types.SECP256K1, priv)
+	block = &types.BlockDetail{Block: &types.Block{Height: 2, Txs: []*types.Transaction{tx}}, Receipts: []*types.ReceiptData{{}}}
+	wallet.ProcWalletAddBlock(block)
+	x, err = wallet.walletStore.GetXpub(xpub)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(9), x.Used)
+	assert.Equal(t, uint32(12), x.Next)
+
+	//业务策略没有记录的交易，按只读账户的交易记录
+	wcom.PolicyContainer["watchtest"] = &watchTestPolicy{}
+	defer delete(wcom.PolicyContainer, "watchtest")
+	tx = &types.Transaction{Execer: []byte("watchtest"), Payload: []byte("payload"), Fee: 1000000, To: coldAddr(11)}
+	block = &types.BlockDetail{Block: &types.Block{Height: 3, Txs: []*types.Transaction{tx}}, Receipts: []*types.ReceiptData{{}}}
+	wallet.ProcWalletAddBlock(block)
+	x, err = wallet.walletStore.GetXpub(xpub)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(12), x.Used)
+	key := wcom.CalcTxKey(fmt.Sprintf("%018d", block.Block.Height*maxTxNumPerBlock))
+	value, _ = wallet.walletStore.Get(key)
+	var detail types.WalletTxDetail
+	require.NoError(t, types.Decode(value, &detail))
+	assert.True(t, detail.WatchOnly)
+	wallet.ProcWalletDelBlock(block)
+	value, _ = wallet.walletStore.Get(key)
+	assert.Equal(t, 0, len(value))
+
+	//查询地址是否使用过失败时不能导入扩展公钥
+	api2 := new(mocks.QueueProtocolAPI)
+	api2.On("GetAddrOverview", mock.Anything).Return(nil, types.ErrNotFound)
+	wallet2 := &Wallet{walletStore: NewStore(dbm.NewDB("wallet", "memdb", "", 0)), isWalletLocked: 1, api: api2}
+	_, err = wallet2.ProcImportWatch(&types.ReqWalletImportWatch{Xpub: xpub, Label: "cold"})
+	assert.Equal(t, types.ErrNotFound, err)
+	_, err = wallet2.walletStore.GetXpub(xpub)
+	assert.Error(t, err)
+}
+
+//watchTestPolicy 不记录任何交易的业务策略
+type watchTestPolicy struct {
+	wcom.WalletBizPolicy
+}
+
+func (p *watchTestPolicy) OnAddBlockTx(block *types.BlockDetail, tx *types.Transaction, index int32, dbbatch dbm.Batch) *types.WalletTxDetail {
+	return nil
+}
+
+func (p *watchTestPolicy) OnDeleteBlockTx(block *types.BlockDetail, tx *types.Transaction, index int32, dbbatch dbm.Batch) *types.WalletTxDetail {
+	return nil
+}
+
+func (p *watchTestPolicy) OnAddBlockFinish(block *types.BlockDetail) {}
+
+func (p *watchTestPolicy) OnDeleteBlockFinish(block *types.BlockDetail) {}
+
+func TestWalletRescan(t *testing.T) {
+	db := dbm.NewDB("wallet", "memdb", "", 0)
+	defer db.Close()
+	api := new(mocks.QueueProtocolAPI)
+	wallet := &Wallet{walletStore: NewStore(db), isWalletLocked: 1, api: api, wg: &sync.WaitGroup{}, done: make(chan struct{})}
+
+	watchAddr := address.PubKeyToAddress([]byte("rescan")).String()
+	_, err := wallet.ProcImportWatch(&types.ReqWalletImportWatch{Addr: watchAddr, Label: "rescan"})
+	require.NoError(t, err)
+	api.On("GetLastHeader").Return(&types.Header{Height: 250}, nil)
+
+	//高度5和150的区块中有转给只读地址的交易，第一次扫描到高度100时等待取消
+	reached := make(chan struct{})
+	blocking := true
+	api.On("GetBlocks", mock.Anything).Return(func(req *types.ReqBlocks) *types.BlockDetails {
+		if req.Start == 100 && blocking {
+			blocking = false
+			close(reached)
+			<-wallet.rescanJob.cancel
+		}
+		blocks := &types.BlockDetails{}
+		for height := req.Start; height <= req.End; height++ {
+			to := "1JmFaA6unrCFYEWPGRi7uuXY1KthTJxJEP"
+			if height == 5 || height == 150 {
+				to = watchAddr
+			}
+			tx := &types.Transaction{Execer: []byte("coins"), Payload: []byte("payload"), Fee: 1000000, To: to}
+			blocks.Items = append(blocks.Items, &types.BlockDetail{Block: &types.Block{Height: height, Txs: []*types.Transaction{tx}}, Receipts: []*types.ReceiptData{{}}})
+		}
+		return blocks
+	}, nil)
+
+	_, err = wallet.ProcWalletRescan(&types.ReqWalletRescan{Flag: rescanCancel})
+	assert.Equal(t, types.ErrRescanNotExist, err)
+	_, err = wallet.ProcWalletRescan(&types.ReqWalletRescan{Flag: rescanResume})
+	assert.Equal(t, types.ErrRescanNotExist, err)
+	_, err = wallet.ProcWalletRescan(&types.ReqWalletRescan{FromHeight: 251})
+	assert.Equal(t, types.ErrStartHeight, err)
+	_, err = wallet.ProcWalletRescan(&types.ReqWalletRescan{Addrs: []string{"1JmFaA6unrCFYEWPGRi7uuXY1KthTJxJEP"}})
+	assert.Equal(t, types.ErrAddrNotExist, err)
+
+	status, err := wallet.ProcWalletRescan(&types.ReqWalletRescan{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{watchAddr}, status.Addrs)
+	assert.Equal(t, int64(250), status.EndHeight)
+	assert.Equal(t, int32(rescanScanning), status.Status)
+	_, err = wallet.ProcWalletRescan(&types.ReqWalletRescan{})
+	assert.Equal(t, types.ErrWalletRescanning, err)
+
+	<-reached
+	status, err = wallet.ProcWalletRescan(&types.ReqWalletRescan{Flag: rescanCancel})
+	require.NoError(t, err)
+	assert.Equal(t, int32(rescanCancelled), status.Status)
+	assert.Equal(t, int64(200), status.Height)
+	assert.Equal(t, int64(2), status.TxCount)
+
+	//继续取消的扫描
+	_, err = wallet.ProcWalletRescan(&types.ReqWalletRescan{Flag: rescanResume})
+	require.NoError(t, err)
+	<-wallet.rescanJob.done
+	status, err = wallet.walletStore.GetRescanStatus()
+	require.NoError(t, err)
+	assert.Equal(t, int32(rescanDone), status.Status)
+	assert.Equal(t, int64(251), status.Height)
+	assert.Equal(t, int64(2), status.TxCount)
+
+	details, err := wallet.ProcWalletTxList(&types.ReqWalletTransactionList{Count: 10})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(details.TxDetails))
+	assert.Equal(t, int64(150), details.TxDetails[0].Height)
+	assert.Equal(t, int64(5), details.TxDetails[1].Height)
+	assert.True(t, details.TxDetails[0].WatchOnly)
+}
+
+func TestWalletRescanByAddrIndex(t *testing.T) {
+	types.S("addrIndex", true)
+	defer types.S("addrIndex", false)
+	db := dbm.NewDB("wallet", "memdb", "", 0)
+	defer db.Close()
+	api := new(mocks.QueueProtocolAPI)
+	wallet := &Wallet{walletStore: NewStore(db), isWalletLocked: 1, api: api, wg: &sync.WaitGroup{}, done: make(chan struct{})}
+
+	watchAddr := address.PubKeyToAddress([]byte("rescan")).String()
+	_, err := wallet.ProcImportWatch(&types.ReqWalletImportWatch{Addr: watchAddr, Label: "rescan"})
+	require.NoError(t, err)
+	api.On("GetLastHeader").Return(&types.Header{Height: 250}, nil)
+
+	//fromHeight之前和最新高度之后的交易不处理
+	infos := &types.ReplyTxInfos{}
+	for _, height := range []int64{5, 20, 150, 300} {
+		infos.TxInfos = append(infos.TxInfos, &types.ReplyTxInfo{Hash: []byte(fmt.Sprint(height)), Height: height})
+	}
+	api.On("GetTransactionByAddr", &types.ReqAddr{Addr: watchAddr, Count: rescanTxBatch, Direction: dbm.ListASC, Height: -1}).Return(infos, nil)
+	//没有交易的地址返回ErrTxNotExist
+	emptyAddr := address.PubKeyToAddress([]byte("empty")).String()
+	_, err = wallet.ProcImportWatch(&types.ReqWalletImportWatch{Addr: emptyAddr, Label: "empty"})
+	require.NoError(t, err)
+	api.On("GetTransactionByAddr", &types.ReqAddr{Addr: emptyAddr, Count: rescanTxBatch, Direction: dbm.ListASC, Height: -1}).Return(nil, types.ErrTxNotExist)
+	api.On("GetTransactionByHash", mock.Anything).Return(func(req *types.ReqHashes) *types.TransactionDetails {
+		details := &types.TransactionDetails{}
+		for _, hash := range req.Hashes {
+			var height int64
+			fmt.Sscan(string(hash), &height)
+			tx := &types.Transaction{Execer: []byte("coins"), Payload: []byte("payload"), Fee: 1000000, To: watchAddr}
+			details.Txs = append(details.Txs, &types.TransactionDetail{Tx: tx, Height: height, Receipt: &types.ReceiptData{}})
+		}
+		return details
+	}, nil)
+
+	status, err := wallet.ProcWalletRescan(&types.ReqWalletRescan{FromHeight: 10})
+	require.NoError(t, err)
+	assert.True(t, status.UseAddrIndex)
+	<-wallet.rescanJob.done
+	status, err = wallet.walletStore.GetRescanStatus()
+	require.NoError(t, err)
+	assert.Equal(t, int32(rescanDone), status.Status)
+	assert.Equal(t, int32(2), status.AddrDone)
+	assert.Equal(t, int64(2), status.TxCount)
+
+	details, err := wallet.ProcWalletTxList(&types.ReqWalletTransactionList{Count: 10})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(details.TxDetails))
+	assert.Equal(t, int64(150), details.TxDetails[0].Height)
+	assert.Equal(t, int64(20), details.TxDetails[1].Height)
+	assert.True(t, details.TxDetails[1].WatchOnly)
+}
+
+func TestWalletRescanClose(t *testing.T) {
+	db := dbm.NewDB("wallet", "memdb", "", 0)
+	defer db.Close()
+	api := new(mocks.QueueProtocolAPI)
+	wallet := &Wallet{walletStore: NewStore(db), isWalletLocked: 1, api: api, wg: &sync.WaitGroup{}, done: make(chan struct{})}
+
+	watchAddr := address.PubKeyToAddress([]byte("rescan")).String()
+	_, err := wallet.ProcImportWatch(&types.ReqWalletImportWatch{Addr: watchAddr, Label: "rescan"})
+	require.NoError(t, err)
+	api.On("GetLastHeader").Return(&types.Header{Height: 250}, nil)
+	//钱包关闭时查询返回错误
+	reached := make(chan struct{})
+	api.On("GetBlocks", mock.Anything).Return(func(req *types.ReqBlocks) *types.BlockDetails {
+		close(reached)
+		<-wallet.done
+		return nil
+	}, types.ErrIsClosed)
+
+	_, err = wallet.ProcWalletRescan(&types.ReqWalletRescan{})
+	require.NoError(t, err)
+	<-reached
+	close(wallet.done)
+	<-wallet.rescanJob.done
+	//保持扫描中的状态，下次启动时继续
+	status, err := wallet.walletStore.GetRescanStatus()
+	require.NoError(t, err)
+	assert.Equal(t, int32(rescanScanning), status.Status)
+	assert.Equal(t, int64(0), status.Height)
+}
@@ -0,0 +1,235 @@
+// Copyright Fuzamei Corp. 2018 All Rights Reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wallet
+
+import (
+	"fmt"
+
+	"github.com/33cn/chain33/common/address"
+	"github.com/33cn/chain33/types"
+	"github.com/33cn/chain33/wallet/bipwallet"
+)
+
+//只读账户：钱包只保存地址不保存私钥，热节点通过只读账户跟踪冷钱包地址的余额和交易
+//为只读账户构造的交易需要在持有私钥的冷钱包中通过SignRawTx签名
+//通过BIP32扩展公钥导入时按gap limit生成地址：最后一个使用过的地址之后始终保留gapLimit个没有使用的地址
+
+const (
+	defaultGapLimit = 20
+	maxGapLimit     = 1000
+)
+
+//ProcImportWatch 导入只读的地址或者扩展公钥，只读账户没有私钥，不需要解锁钱包
+func (wallet *Wallet) ProcImportWatch(req *types.ReqWalletImportWatch) (*types.WalletAccounts, error) {
+	wallet.mtx.Lock()
+	defer wallet.mtx.Unlock()
+
+	if req == nil || len(req.GetLabel()) == 0 || (req.GetAddr() == "") == (req.GetXpub() == "") {
+		walletlog.Error("ProcImportWatch input parameter is invalid!")
+		return nil, types.ErrInvalidParam
+	}
+	if req.GetGapLimit() < 0 || req.GetGapLimit() > maxGapLimit {
+		walletlog.Error("ProcImportWatch", "gapLimit", req.GetGapLimit())
+		return nil, types.ErrInvalidParam
+	}
+	if wallet.labelUsed(req.GetLabel()) {
+		walletlog.Error("ProcImportWatch Label is exist in wallet!")
+		return nil, types.ErrLabelHasUsed
+	}
+
+	var watches []*types.WalletWatchStore
+	var err error
+	if req.GetAddr() != "" {
+		watches, err = wallet.importWatchAddr(req.GetAddr(), req.GetLabel())
+	} else {
+		watches, err = wallet.importXpub(req)
+	}
+	if err != nil {
+		walletlog.Error("ProcImportWatch", "err", err)
+		return nil, err
+	}
+	accounts := &types.WalletAccounts{}
+	for _, watch := range watches {
+		accounts.Wallets = append(accounts.Wallets, &types.WalletAccount{
+			Acc:       &types.Account{Addr: watch.Addr},
+			Label:     watch.Label,
+			WatchOnly: true,
+		})
+	}
+	return accounts, nil
+}
+
+//ProcExportXpub 导出secp256k1账户m/44'/coin'/0'的扩展公钥，和GetPrivkeyBySeed生成的地址一致
+//seed需要解密，钱包必须解锁
+func (wallet *Wallet) ProcExportXpub() (*types.ReplyString, error) {
+	wallet.mtx.Lock()
+	defer wallet.mtx.Unlock()
+
+	seed, err := wallet.getSeed(wallet.Password)
+	if err != nil {
+		return nil, err
+	}
+	hdWallet, err := newHDWallet(seed)
+	if err != nil {
+		return nil, err
+	}
+	xpub, err := hdWallet.Xpub(uint32(types.SECP256K1 - 1))
+	if err != nil {
+		walletlog.Error("ProcExportXpub", "Xpub err", err)
+		return nil, err
+	}
+	return &types.ReplyString{Data: xpub}, nil
+}
+
+func (wallet *Wallet) importWatchAddr(addr, label string) ([]*types.WalletWatchStore, error) {
+	if err := address.CheckAddress(addr); err != nil {
+		return nil, types.ErrInvalidAddress
+	}
+	if wallet.AddrInWallet(addr) || wallet.getWatchAccount(addr) != nil {
+		return nil, types.ErrAddrExist
+	}
+	watch := &types.WalletWatchStore{Addr: addr, Label: label, TimeStamp: fmt.Sprintf("%018d", types.Now().Unix())}
+	batch := wallet.walletStore.NewBatch(true)
+	wallet.walletStore.SetWatchAccount(watch, batch)
+	return []*types.WalletWatchStore{watch}, batch.Write()
+}
+
+//importXpub 第一次导入时查询链上的交易，找到最后一个使用过的地址，之后的地址在区块中出现时再继续生成
+func (wallet *Wallet) importXpub(req *types.ReqWalletImportWatch) ([]*types.WalletWatchStore, error) {
+	pubWallet, err := bipwallet.NewWalletFromXpub(req.GetXpub())
+	if err != nil {
+		walletlog.Error("importXpub", "NewWalletFromXpub err", err)
+		return nil, types.ErrXpub
+	}
+	if _, err := wallet.walletStore.GetXpub(req.GetXpub()); err == nil {
+		return nil, types.ErrAddrExist
+	}
+	xpub := &types.WalletXpubStore{
+		Xpub:      req.GetXpub(),
+		Label:     req.GetLabel(),
+		GapLimit:  req.GetGapLimit(),
+		TimeStamp: fmt.Sprintf("%018d", types.Now().Unix()),
+	}
+	if xpub.GapLimit == 0 {
+		xpub.GapLimit = defaultGapLimit
+	}
+	var watches []*types.WalletWatchStore
+	for {
+		derived := wallet.deriveXpub(pubWallet, xpub)
+		if len(derived) == 0 {
+			break
+		}
+		for _, watch := range derived {
+			used, err := wallet.addrUsed(watch.Addr)
+			if err != nil {
+				walletlog.Error("importXpub", "addr", watch.Addr, "addrUsed err", err)
+				return nil, err
+			}
+			if used && watch.Index >= xpub.Used {
+				xpub.Used = watch.Index + 1
+			}
+		}
+		watches = append(watches, derived...)
+	}
+	batch := wallet.walletStore.NewBatch(true)
+	for _, watch := range watches {
+		wallet.walletStore.SetWatchAccount(watch, batch)
+	}
+	wallet.walletStore.SetXpub(xpub, batch)
+	return watches, batch.Write()
+}
+
+//deriveXpub 生成地址直到最后一个使用过的地址之后有gapLimit个地址，已经在钱包中的地址跳过
+func (wallet *Wallet) deriveXpub(pubWallet *bipwallet.PubWallet, xpub *types.WalletXpubStore) []*types.WalletWatchStore {
+	var watches []*types.WalletWatchStore
+	for xpub.Next < xpub.Used+uint32(xpub.GapLimit) {
+		index := xpub.Next
+		xpub.Next++
+		//BIP32规定无效的子公钥跳过这个索引
+		pub, err := pubWallet.NewPubKey(index)
+		if err != nil {
+			walletlog.Error("deriveXpub", "index", index, "err", err)
+			continue
+		}
+		addr := address.PubKeyToAddress(pub).String()
+		if wallet.AddrInWallet(addr) || wallet.getWatchAccount(addr) != nil {
+			continue
+		}
+		watches = append(watches, &types.WalletWatchStore{
+			Addr:      addr,
+			Label:     fmt.Sprintf("%s/%d", xpub.Label, index),
+			Xpub:      xpub.Xpub,
+			Index:     index,
+			TimeStamp: xpub.TimeStamp,
+		})
+	}
+	return watches
+}
+
+//useXpubAddr 扩展公钥生成的地址在区块中出现时，继续生成地址保持gap limit
+func (wallet *Wallet) useXpubAddr(watch *types.WalletWatchStore) {
+	if watch == nil || watch.Xpub == "" {
+		return
+	}
+	xpub, err := wallet.walletStore.GetXpub(watch.Xpub)
+	if err != nil || watch.Index < xpub.Used {
+		return
+	}
+	pubWallet, err := bipwallet.NewWalletFromXpub(xpub.Xpub)
+	if err != nil {
+		walletlog.Error("useXpubAddr", "NewWalletFromXpub err", err)
+		return
+	}
+	xpub.Used = watch.Index + 1
+	//同一个区块中后面的交易可能用到新生成的地址，所以立即写入
+	batch := wallet.walletStore.NewBatch(true)
+	for _, derived := range wallet.deriveXpub(pubWallet, xpub) {
+		wallet.walletStore.SetWatchAccount(derived, batch)
+	}
+	wallet.walletStore.SetXpub(xpub, batch)
+	err = batch.Write()
+	if err != nil {
+		walletlog.Error("useXpubAddr", "batch.Write err", err)
+	}
+}
+
+//addrUsed 地址在链上是否有交易，需要blockchain开启地址索引
+//查询失败时返回错误，否则后面使用过的地址会被当成没有使用，gap limit之后的地址不会被导入
+func (wallet *Wallet) addrUsed(addr string) (bool, error) {
+	overview, err := wallet.api.GetAddrOverview(&types.ReqAddr{Addr: addr})
+	if err != nil {
+		return false, err
+	}
+	return overview.GetTxCount() > 0, nil
+}
+
+//getWatchAccount 地址是只读账户时返回账户信息，否则返回nil
+func (wallet *Wallet) getWatchAccount(addr string) *types.WalletWatchStore {
+	watch, err := wallet.walletStore.GetWatchAccount(addr)
+	if err != nil {
+		return nil
+	}
+	return watch
+}
+
+//labelUsed label是否已经被钱包中的账户或者只读账户使用
+func (wallet *Wallet) labelUsed(label string) bool {
+	if acc, _ := wallet.walletStore.GetAccountByLabel(label); acc != nil {
+		return true
+	}
+	watches, _ := wallet.walletStore.GetWatchAccounts()
+	for _, watch := range watches {
+		if watch.Label == label {
+			return true
+		}
+	}
+	xpubs, _ := wallet.walletStore.GetXpubs()
+	for _, xpub := range xpubs {
+		if xpub.Label == label {
+			return true
+		}
+	}
+	return false
+}